@@ -0,0 +1,360 @@
+// Package bonds - аналитика облигаций: график денежных потоков (купоны, амортизации, погашение и оферты), чистая и
+// грязная цена по котировке в процентах от номинала, доходность к погашению и к оферте, дюрация Маколея,
+// модифицированная дюрация и выпуклость. Сроки считаются в календарных днях, год - 365 дней, доходность -
+// эффективная годовая.
+package bonds
+
+import (
+	"context"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/russianinvestments/invest-api-go-sdk/investgo"
+	pb "github.com/russianinvestments/invest-api-go-sdk/proto"
+)
+
+// daysInYear - количество дней в году для сроков и купонных ставок
+const daysInYear = 365
+
+// FlowKind - вид денежного потока облигации
+type FlowKind int
+
+const (
+	// FlowCoupon - купон
+	FlowCoupon FlowKind = iota
+	// FlowAmortization - частичное погашение номинала
+	FlowAmortization
+	// FlowMaturity - погашение оставшегося номинала
+	FlowMaturity
+)
+
+// CashFlow - выплата по одной облигации
+type CashFlow struct {
+	Date time.Time
+	Kind FlowKind
+	// Number - номер купона или события погашения
+	Number int64
+	// Amount - выплата на одну облигацию в валюте номинала
+	Amount float64
+	// AccrualStart, AccrualEnd - купонный период, только для купонов
+	AccrualStart time.Time
+	AccrualEnd   time.Time
+	// Estimated - размер купона еще не объявлен (плавающий или переменный купон) и рассчитан по ставке
+	// Schedule.FloatingRate
+	Estimated bool
+}
+
+// Offer - оферта: право владельца продать облигацию эмитенту
+type Offer struct {
+	Date time.Time
+	// Price - цена выкупа в процентах от непогашенного номинала
+	Price float64
+}
+
+// Schedule - график денежных потоков одной облигации
+type Schedule struct {
+	// Currency - валюта номинала и выплат
+	Currency string
+	// Flows - денежные потоки по возрастанию даты, в один день купон идет перед погашением номинала
+	Flows []CashFlow
+	// Offers - оферты по возрастанию даты
+	Offers []Offer
+	// FloatingRate - годовая ставка необъявленных купонов, 0.16 = 16%. По умолчанию - ставка последнего
+	// объявленного купона, изменяется через ProjectFloating
+	FloatingRate float64
+}
+
+// coupon - купон из GetBondCoupons или GetBondEvents до расчета суммы
+type coupon struct {
+	date, start, end time.Time
+	period           int32
+	number           int64
+	amount           float64
+	// rate - ставка купона в процентах годовых, если сумма не объявлена
+	rate float64
+}
+
+// NewSchedule - Построение графика потоков облигации bond по купонам из GetBondCoupons и событиям из GetBondEvents.
+// Если купонов нет, они берутся из событий EVENT_TYPE_CPN. Погашения и амортизации берутся из событий
+// EVENT_TYPE_MTY, последнее из них - погашение. Без событий погашения номинал Bond.Nominal выплачивается в
+// Bond.MaturityDate, у бессрочных облигаций погашения нет. Оферты берутся из событий EVENT_TYPE_CALL и
+// Bond.CallDate. Необъявленные купоны рассчитываются по ставке последнего объявленного купона
+func NewSchedule(bond *pb.Bond, coupons []*pb.Coupon, events []*pb.GetBondEventsResponse_BondEvent) *Schedule {
+	s := &Schedule{Currency: bond.GetNominal().GetCurrency()}
+	if s.Currency == "" {
+		s.Currency = bond.GetCurrency()
+	}
+	initial := bond.GetInitialNominal().ToFloat()
+	if initial == 0 {
+		initial = bond.GetNominal().ToFloat()
+	}
+
+	var principal []CashFlow
+	var cpn []coupon
+	for _, e := range events {
+		date := eventDate(e)
+		switch e.GetEventType() {
+		case pb.GetBondEventsRequest_EVENT_TYPE_MTY:
+			// сумма погашения на одну облигацию, если она не указана - доля номинала в процентах
+			amount := e.GetPayOneBond().ToFloat()
+			if amount == 0 {
+				amount = e.GetValue().ToFloat() / 100 * initial
+			}
+			if amount > 0 {
+				principal = append(principal, CashFlow{
+					Date:   date,
+					Kind:   FlowAmortization,
+					Number: int64(e.GetEventNumber()),
+					Amount: amount,
+				})
+			}
+		case pb.GetBondEventsRequest_EVENT_TYPE_CALL:
+			price := e.GetValue().ToFloat()
+			if price == 0 {
+				price = 100
+			}
+			s.Offers = append(s.Offers, Offer{Date: date, Price: price})
+		case pb.GetBondEventsRequest_EVENT_TYPE_CPN:
+			if len(coupons) == 0 {
+				cpn = append(cpn, coupon{
+					date:   date,
+					start:  timeOf(e.GetCouponStartDate()),
+					end:    timeOf(e.GetCouponEndDate()),
+					period: e.GetCouponPeriod(),
+					number: int64(e.GetEventNumber()),
+					amount: e.GetPayOneBond().ToFloat(),
+					rate:   e.GetCouponInterestRate().ToFloat(),
+				})
+			}
+		}
+	}
+	for _, c := range coupons {
+		amount := c.GetPayOneBond().ToFloat()
+		if amount == 0 && c.GetCouponType() == pb.CouponType_COUPON_TYPE_DISCOUNT {
+			continue
+		}
+		cpn = append(cpn, coupon{
+			date:   timeOf(c.GetCouponDate()),
+			start:  timeOf(c.GetCouponStartDate()),
+			end:    timeOf(c.GetCouponEndDate()),
+			period: c.GetCouponPeriod(),
+			number: c.GetCouponNumber(),
+			amount: amount,
+		})
+	}
+
+	sort.SliceStable(principal, func(i, j int) bool {
+		return principal[i].Date.Before(principal[j].Date)
+	})
+	switch {
+	case len(principal) > 0:
+		principal[len(principal)-1].Kind = FlowMaturity
+	case !bond.GetPerpetualFlag() && bond.GetMaturityDate() != nil:
+		principal = append(principal, CashFlow{
+			Date:   bond.GetMaturityDate().AsTime(),
+			Kind:   FlowMaturity,
+			Amount: bond.GetNominal().ToFloat(),
+		})
+	}
+	s.Flows = principal
+
+	if call := bond.GetCallDate(); call != nil && !s.hasOffer(call.AsTime()) {
+		s.Offers = append(s.Offers, Offer{Date: call.AsTime(), Price: 100})
+	}
+	sort.SliceStable(s.Offers, func(i, j int) bool {
+		return s.Offers[i].Date.Before(s.Offers[j].Date)
+	})
+
+	s.addCoupons(cpn, bond.GetCouponQuantityPerYear())
+	s.sortFlows()
+	s.FloatingRate = s.lastCouponRate()
+	s.ProjectFloating(s.FloatingRate)
+	return s
+}
+
+// addCoupons - добавление купонов в график. Суммы купонов, объявленных ставкой, считаются от непогашенного
+// номинала, поэтому погашения уже должны быть в графике
+func (s *Schedule) addCoupons(cpn []coupon, perYear int32) {
+	sort.SliceStable(cpn, func(i, j int) bool {
+		return cpn[i].date.Before(cpn[j].date)
+	})
+	for i, c := range cpn {
+		if c.end.IsZero() {
+			c.end = c.date
+		}
+		if c.start.IsZero() {
+			switch {
+			case i > 0:
+				c.start = cpn[i-1].date
+			case c.period > 0:
+				c.start = c.end.AddDate(0, 0, -int(c.period))
+			case perYear > 0:
+				c.start = c.end.AddDate(0, 0, -daysInYear/int(perYear))
+			default:
+				c.start = c.end
+			}
+		}
+		flow := CashFlow{
+			Date:         c.date,
+			Kind:         FlowCoupon,
+			Number:       c.number,
+			Amount:       c.amount,
+			AccrualStart: c.start,
+			AccrualEnd:   c.end,
+		}
+		if flow.Amount == 0 {
+			if c.rate > 0 {
+				flow.Amount = c.rate / 100 * s.outstanding(c.start) * days(c.start, c.end) / daysInYear
+			} else {
+				flow.Estimated = true
+			}
+		}
+		s.Flows = append(s.Flows, flow)
+	}
+}
+
+func (s *Schedule) sortFlows() {
+	sort.SliceStable(s.Flows, func(i, j int) bool {
+		if !s.Flows[i].Date.Equal(s.Flows[j].Date) {
+			return s.Flows[i].Date.Before(s.Flows[j].Date)
+		}
+		return s.Flows[i].Kind < s.Flows[j].Kind
+	})
+}
+
+func (s *Schedule) hasOffer(date time.Time) bool {
+	for _, o := range s.Offers {
+		if day(o.Date).Equal(day(date)) {
+			return true
+		}
+	}
+	return false
+}
+
+// lastCouponRate - годовая ставка последнего объявленного купона
+func (s *Schedule) lastCouponRate() float64 {
+	for i := len(s.Flows) - 1; i >= 0; i-- {
+		f := s.Flows[i]
+		if f.Kind != FlowCoupon || f.Estimated || f.Amount <= 0 {
+			continue
+		}
+		nominal, period := s.outstanding(f.AccrualStart), days(f.AccrualStart, f.AccrualEnd)
+		if nominal > 0 && period > 0 {
+			return f.Amount / nominal * daysInYear / period
+		}
+	}
+	return 0
+}
+
+// ProjectFloating - Пересчет необъявленных купонов по годовой ставке rate, например ключевая ставка плюс спред
+// для флоатера. Купон считается от непогашенного на начало купонного периода номинала
+func (s *Schedule) ProjectFloating(rate float64) {
+	s.FloatingRate = rate
+	for i := range s.Flows {
+		f := &s.Flows[i]
+		if f.Kind == FlowCoupon && f.Estimated {
+			f.Amount = rate * s.outstanding(f.AccrualStart) * days(f.AccrualStart, f.AccrualEnd) / daysInYear
+		}
+	}
+}
+
+// outstanding - непогашенный номинал после выплат в день at: сумма погашений после at
+func (s *Schedule) outstanding(at time.Time) float64 {
+	var nominal float64
+	for _, f := range s.Flows {
+		if f.Kind != FlowCoupon && day(f.Date).After(day(at)) {
+			nominal += f.Amount
+		}
+	}
+	return nominal
+}
+
+// accrued - накопленный купонный доход на дату at без округления
+func (s *Schedule) accrued(at time.Time) float64 {
+	at = day(at)
+	for _, f := range s.Flows {
+		if f.Kind != FlowCoupon || at.Before(day(f.AccrualStart)) || !at.Before(day(f.AccrualEnd)) {
+			continue
+		}
+		return f.Amount * days(f.AccrualStart, at) / days(f.AccrualStart, f.AccrualEnd)
+	}
+	return 0
+}
+
+// Nominal - Непогашенный номинал одной облигации на дату at, после выплат в этот день
+func (s *Schedule) Nominal(at time.Time) *pb.MoneyValue {
+	return pb.MoneyFromDecimal(decimal.NewFromFloat(s.outstanding(at)), s.Currency)
+}
+
+// AccruedInterest - Накопленный купонный доход одной облигации на дату расчетов at, округленный до копеек
+func (s *Schedule) AccruedInterest(at time.Time) *pb.MoneyValue {
+	return pb.MoneyFromDecimal(decimal.NewFromFloat(s.accrued(at)).Round(2), s.Currency)
+}
+
+// CleanPrice - Чистая цена одной облигации по котировке price в процентах от непогашенного номинала
+// на дату расчетов at
+func (s *Schedule) CleanPrice(price *pb.Quotation, at time.Time) *pb.MoneyValue {
+	nominal := decimal.NewFromFloat(s.outstanding(at))
+	return pb.MoneyFromDecimal(price.ToDecimal().Mul(nominal).Div(decimal.NewFromInt(100)), s.Currency)
+}
+
+// DirtyPrice - Грязная цена одной облигации: чистая цена плюс накопленный купонный доход на дату расчетов at
+func (s *Schedule) DirtyPrice(price *pb.Quotation, at time.Time) *pb.MoneyValue {
+	clean := s.CleanPrice(price, at).ToDecimal()
+	return pb.MoneyFromDecimal(clean.Add(s.AccruedInterest(at).ToDecimal()), s.Currency)
+}
+
+// LoadSchedule - Загрузка облигации с идентификатором uid, ее купонов и событий и построение графика потоков,
+// см. NewSchedule
+func LoadSchedule(ctx context.Context, is *investgo.InstrumentsServiceClient, uid string) (*Schedule, error) {
+	bond, err := is.BondByUidCtx(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	var from, to time.Time
+	if bond.GetInstrument().GetPlacementDate() != nil {
+		from = bond.GetInstrument().GetPlacementDate().AsTime()
+	}
+	if bond.GetInstrument().GetMaturityDate() != nil {
+		to = bond.GetInstrument().GetMaturityDate().AsTime().AddDate(0, 0, 1)
+	}
+	coupons, err := is.GetBondCouponsCtx(ctx, uid, from, to)
+	if err != nil {
+		return nil, err
+	}
+	events, err := is.GetBondEventsCtx(ctx, uid, pb.GetBondEventsRequest_EVENT_TYPE_UNSPECIFIED, from, to)
+	if err != nil {
+		return nil, err
+	}
+	return NewSchedule(bond.GetInstrument(), coupons.GetEvents(), events.GetEvents()), nil
+}
+
+// eventDate - дата выплаты по событию, если она не указана - дата события
+func eventDate(e *pb.GetBondEventsResponse_BondEvent) time.Time {
+	if e.GetPayDate() != nil {
+		return e.GetPayDate().AsTime()
+	}
+	return timeOf(e.GetEventDate())
+}
+
+func timeOf(ts *timestamppb.Timestamp) time.Time {
+	if ts == nil {
+		return time.Time{}
+	}
+	return ts.AsTime()
+}
+
+// day - начало дня t по UTC
+func day(t time.Time) time.Time {
+	y, m, d := t.UTC().Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// days - количество календарных дней от from до to
+func days(from, to time.Time) float64 {
+	return math.Round(day(to).Sub(day(from)).Hours() / 24)
+}
@@ -0,0 +1,193 @@
+package bonds
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	pb "github.com/russianinvestments/invest-api-go-sdk/proto"
+)
+
+var (
+	// ErrNoFlows - у облигации нет денежных потоков после даты расчетов
+	ErrNoFlows = errors.New("bonds: no cash flows after settlement")
+	// ErrNoOffer - у облигации нет оферты после даты расчетов
+	ErrNoOffer = errors.New("bonds: no offer after settlement")
+	// ErrNoYield - цена неположительная, больше суммы потоков при минимальной доходности или метод не сошелся
+	ErrNoYield = errors.New("bonds: yield not found")
+)
+
+// Metrics - доходность и чувствительность цены облигации к доходности
+type Metrics struct {
+	// Yield - эффективная годовая доходность, 0.12 = 12%
+	Yield float64
+	// MacaulayDuration - дюрация Маколея в годах
+	MacaulayDuration float64
+	// ModifiedDuration - модифицированная дюрация: относительное изменение цены при росте доходности на 1
+	// (100 п.п.) с обратным знаком, на 1 п.п. - ModifiedDuration / 100
+	ModifiedDuration float64
+	// Convexity - выпуклость: вторая производная цены по доходности, деленная на цену
+	Convexity float64
+	// Horizon - дата последнего потока: погашение или оферта
+	Horizon time.Time
+}
+
+// flowTime - поток и срок до него в годах
+type flowTime struct {
+	t, amount float64
+}
+
+func futureFlows(flows []CashFlow, settlement time.Time) []flowTime {
+	var future []flowTime
+	for _, f := range flows {
+		if day(f.Date).After(day(settlement)) {
+			future = append(future, flowTime{t: days(settlement, f.Date) / daysInYear, amount: f.Amount})
+		}
+	}
+	return future
+}
+
+// presentValue - приведенная стоимость потоков и ее производная по доходности
+func presentValue(flows []flowTime, yield float64) (pv, dpv float64) {
+	for _, f := range flows {
+		v := f.amount * math.Pow(1+yield, -f.t)
+		pv += v
+		dpv -= f.t * v / (1 + yield)
+	}
+	return pv, dpv
+}
+
+// PresentValue - Грязная цена, при которой доходность потоков flows после даты расчетов settlement равна yield
+func PresentValue(flows []CashFlow, yield float64, settlement time.Time) float64 {
+	pv, _ := presentValue(futureFlows(flows, settlement), yield)
+	return pv
+}
+
+// Analyze - Доходность, дюрация и выпуклость потоков flows после даты расчетов settlement при грязной цене dirty
+// в валюте потоков
+func Analyze(flows []CashFlow, dirty float64, settlement time.Time) (Metrics, error) {
+	future := futureFlows(flows, settlement)
+	if len(future) == 0 {
+		return Metrics{}, ErrNoFlows
+	}
+	if !(dirty > 0) {
+		return Metrics{}, fmt.Errorf("%w: price %v", ErrNoYield, dirty)
+	}
+	yield, err := solveYield(future, dirty)
+	if err != nil {
+		return Metrics{}, err
+	}
+
+	var pv, duration, convexity float64
+	for _, f := range future {
+		v := f.amount * math.Pow(1+yield, -f.t)
+		pv += v
+		duration += f.t * v
+		convexity += f.t * (f.t + 1) * v
+	}
+	m := Metrics{
+		Yield:            yield,
+		MacaulayDuration: duration / pv,
+		Convexity:        convexity / pv / ((1 + yield) * (1 + yield)),
+	}
+	m.ModifiedDuration = m.MacaulayDuration / (1 + yield)
+	for _, f := range flows {
+		if f.Date.After(m.Horizon) {
+			m.Horizon = f.Date
+		}
+	}
+	return m, nil
+}
+
+// solveYield - доходность, при которой приведенная стоимость потоков равна price. Приведенная стоимость
+// убывает по доходности, поэтому метод Ньютона страхуется делением отрезка пополам
+func solveYield(flows []flowTime, price float64) (float64, error) {
+	const (
+		tolerance = 1e-12
+		maxIter   = 200
+	)
+	lo, hi := -0.99, 1.0
+	if pv, _ := presentValue(flows, lo); pv < price {
+		return 0, fmt.Errorf("%w: price %v is above %v", ErrNoYield, price, pv)
+	}
+	for {
+		pv, _ := presentValue(flows, hi)
+		if pv < price {
+			break
+		}
+		if hi > 1e6 {
+			return 0, fmt.Errorf("%w: price %v is too low", ErrNoYield, price)
+		}
+		hi *= 2
+	}
+
+	yield := 0.1
+	for i := 0; i < maxIter; i++ {
+		pv, dpv := presentValue(flows, yield)
+		diff := pv - price
+		if math.Abs(diff) < tolerance*price {
+			return yield, nil
+		}
+		if diff > 0 {
+			lo = yield
+		} else {
+			hi = yield
+		}
+		next := yield - diff/dpv
+		if dpv == 0 || !(next > lo && next < hi) {
+			next = (lo + hi) / 2
+		}
+		if math.Abs(next-yield) < tolerance {
+			return next, nil
+		}
+		yield = next
+	}
+	return 0, fmt.Errorf("%w: no convergence for price %v", ErrNoYield, price)
+}
+
+// NextOffer - Ближайшая оферта после даты at
+func (s *Schedule) NextOffer(at time.Time) (Offer, bool) {
+	for _, o := range s.Offers {
+		if day(o.Date).After(day(at)) {
+			return o, true
+		}
+	}
+	return Offer{}, false
+}
+
+// FlowsToOffer - Потоки при предъявлении облигации к оферте o: купоны до даты оферты включительно, амортизации
+// до даты оферты и выкуп непогашенного номинала по цене оферты
+func (s *Schedule) FlowsToOffer(o Offer) []CashFlow {
+	var flows []CashFlow
+	var nominal float64
+	for _, f := range s.Flows {
+		switch {
+		case day(f.Date).After(day(o.Date)):
+			if f.Kind != FlowCoupon {
+				nominal += f.Amount
+			}
+		case f.Kind == FlowCoupon || day(f.Date).Before(day(o.Date)):
+			flows = append(flows, f)
+		default:
+			nominal += f.Amount
+		}
+	}
+	return append(flows, CashFlow{Date: o.Date, Kind: FlowMaturity, Amount: nominal * o.Price / 100})
+}
+
+// YieldToMaturity - Доходность к погашению, дюрация и выпуклость по котировке price в процентах от номинала
+// на дату расчетов settlement
+func (s *Schedule) YieldToMaturity(price *pb.Quotation, settlement time.Time) (Metrics, error) {
+	return Analyze(s.Flows, s.DirtyPrice(price, settlement).ToFloat(), settlement)
+}
+
+// YieldToOffer - Доходность к ближайшей оферте после даты расчетов settlement, см. YieldToMaturity. Если оферт
+// нет - ErrNoOffer
+func (s *Schedule) YieldToOffer(price *pb.Quotation, settlement time.Time) (Metrics, error) {
+	o, ok := s.NextOffer(settlement)
+	if !ok {
+		return Metrics{}, ErrNoOffer
+	}
+	return Analyze(s.FlowsToOffer(o), s.DirtyPrice(price, settlement).ToFloat(), settlement)
+}
@@ -0,0 +1,258 @@
+package bonds_test
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/russianinvestments/invest-api-go-sdk/bonds"
+	pb "github.com/russianinvestments/invest-api-go-sdk/proto"
+)
+
+// Даты выбраны так, чтобы между выплатами был ровно год в 365 дней. Ожидаемые значения посчитаны отдельно:
+// доходность - делением отрезка пополам, дюрация и выпуклость - по определению через дисконтированные потоки
+
+func date(y int, m time.Month, d int) time.Time {
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+func ts(t time.Time) *timestamppb.Timestamp {
+	return timestamppb.New(t)
+}
+
+func rub(v int64) *pb.MoneyValue {
+	return &pb.MoneyValue{Currency: "rub", Units: v}
+}
+
+func percent(v string) *pb.Quotation {
+	return pb.QuotationFromDecimal(decimal.RequireFromString(v))
+}
+
+func near(t *testing.T, what string, got, want, tol float64) {
+	t.Helper()
+	if math.Abs(got-want) > tol {
+		t.Errorf("%v = %.12f, want %.12f ± %v", what, got, want, tol)
+	}
+}
+
+func checkMetrics(t *testing.T, m bonds.Metrics, yield, macaulay, modified, convexity float64) {
+	t.Helper()
+	near(t, "yield", m.Yield, yield, 1e-9)
+	near(t, "macaulay duration", m.MacaulayDuration, macaulay, 1e-9)
+	near(t, "modified duration", m.ModifiedDuration, modified, 1e-9)
+	near(t, "convexity", m.Convexity, convexity, 1e-8)
+}
+
+// checkSensitivity - модифицированная дюрация и выпуклость совпадают с конечными разностями PresentValue
+func checkSensitivity(t *testing.T, flows []bonds.CashFlow, m bonds.Metrics, settlement time.Time) {
+	t.Helper()
+	const h = 1e-4
+	pv := bonds.PresentValue(flows, m.Yield, settlement)
+	up := bonds.PresentValue(flows, m.Yield+h, settlement)
+	down := bonds.PresentValue(flows, m.Yield-h, settlement)
+	near(t, "modified duration by finite difference", -(up-down)/(2*h)/pv, m.ModifiedDuration, 1e-6)
+	near(t, "convexity by finite difference", (up-2*pv+down)/(h*h)/pv, m.Convexity, 1e-4)
+}
+
+// fixedBond - 10% годовых, купон раз в год, номинал 1000, погашение через 3 года после 2021-01-01
+func fixedBond() *bonds.Schedule {
+	bond := &pb.Bond{Nominal: rub(1000), MaturityDate: ts(date(2024, 1, 1)), CouponQuantityPerYear: 1}
+	var coupons []*pb.Coupon
+	for i, y := range []int{2022, 2023, 2024} {
+		coupons = append(coupons, &pb.Coupon{
+			CouponNumber:    int64(i + 1),
+			CouponDate:      ts(date(y, 1, 1)),
+			CouponStartDate: ts(date(y-1, 1, 1)),
+			CouponEndDate:   ts(date(y, 1, 1)),
+			PayOneBond:      rub(100),
+			CouponType:      pb.CouponType_COUPON_TYPE_CONSTANT,
+		})
+	}
+	events := []*pb.GetBondEventsResponse_BondEvent{{
+		EventType: pb.GetBondEventsRequest_EVENT_TYPE_CALL,
+		EventDate: ts(date(2022, 1, 1)),
+		Value:     percent("101"),
+	}}
+	return bonds.NewSchedule(bond, coupons, events)
+}
+
+func TestFixedCouponBond(t *testing.T) {
+	s := fixedBond()
+	settlement := date(2021, 1, 1)
+
+	// по номиналу доходность равна ставке купона
+	m, err := s.YieldToMaturity(percent("100"), settlement)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkMetrics(t, m, 0.1, 2.735537190083, 2.486851990984, 8.756232497780)
+	if !m.Horizon.Equal(date(2024, 1, 1)) {
+		t.Errorf("horizon = %v", m.Horizon)
+	}
+	checkSensitivity(t, s.Flows, m, settlement)
+
+	m, err = s.YieldToMaturity(percent("95"), settlement)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkMetrics(t, m, 0.120847783198, 2.728384001183, 2.434214566940, 8.404150147904)
+	checkSensitivity(t, s.Flows, m, settlement)
+
+	// в середине купонного периода: НКД 100 * 182 / 365 = 49.86, грязная цена 950 + 49.86
+	settlement = date(2021, 7, 2)
+	if aci := s.AccruedInterest(settlement).ToFloat(); aci != 49.86 {
+		t.Errorf("accrued interest = %v", aci)
+	}
+	if dirty := s.DirtyPrice(percent("95"), settlement).ToFloat(); dirty != 999.86 {
+		t.Errorf("dirty price = %v", dirty)
+	}
+	m, err = s.YieldToMaturity(percent("95"), settlement)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkMetrics(t, m, 0.123734041088, 2.228760361233, 1.983352180979, 6.005085419730)
+	checkSensitivity(t, s.Flows, m, settlement)
+}
+
+func TestYieldToOffer(t *testing.T) {
+	s := fixedBond()
+	settlement := date(2021, 1, 1)
+	// к оферте через год: купон 100 и выкуп по 101% номинала, 1110 / 1000 - 1 = 11%
+	m, err := s.YieldToOffer(percent("100"), settlement)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkMetrics(t, m, 0.11, 1, 1/1.11, 2/(1.11*1.11))
+	if !m.Horizon.Equal(date(2022, 1, 1)) {
+		t.Errorf("horizon = %v", m.Horizon)
+	}
+
+	if _, err := s.YieldToOffer(percent("100"), date(2022, 1, 1)); !errors.Is(err, bonds.ErrNoOffer) {
+		t.Errorf("after offer: err = %v, want ErrNoOffer", err)
+	}
+	if _, err := s.YieldToMaturity(percent("100"), date(2024, 1, 1)); !errors.Is(err, bonds.ErrNoFlows) {
+		t.Errorf("after maturity: err = %v, want ErrNoFlows", err)
+	}
+	if _, err := s.YieldToMaturity(percent("0"), settlement); !errors.Is(err, bonds.ErrNoYield) {
+		t.Errorf("zero price: err = %v, want ErrNoYield", err)
+	}
+}
+
+func TestAmortizingBond(t *testing.T) {
+	// 40% номинала погашается через год, остаток через два, купоны 10% годовых от непогашенного номинала
+	bond := &pb.Bond{Nominal: rub(1000), MaturityDate: ts(date(2023, 1, 1)), CouponQuantityPerYear: 1}
+	events := []*pb.GetBondEventsResponse_BondEvent{
+		{EventType: pb.GetBondEventsRequest_EVENT_TYPE_MTY, EventNumber: 1, EventDate: ts(date(2022, 1, 1)), Value: percent("40")},
+		{EventType: pb.GetBondEventsRequest_EVENT_TYPE_MTY, EventNumber: 2, EventDate: ts(date(2023, 1, 1)), PayOneBond: rub(600)},
+	}
+	for i, y := range []int{2022, 2023} {
+		events = append(events, &pb.GetBondEventsResponse_BondEvent{
+			EventType:          pb.GetBondEventsRequest_EVENT_TYPE_CPN,
+			EventNumber:        int32(i + 1),
+			EventDate:          ts(date(y, 1, 1)),
+			CouponStartDate:    ts(date(y-1, 1, 1)),
+			CouponEndDate:      ts(date(y, 1, 1)),
+			CouponInterestRate: percent("10"),
+		})
+	}
+	s := bonds.NewSchedule(bond, nil, events)
+
+	want := []struct {
+		kind   bonds.FlowKind
+		amount float64
+	}{
+		{bonds.FlowCoupon, 100}, {bonds.FlowAmortization, 400}, {bonds.FlowCoupon, 60}, {bonds.FlowMaturity, 600},
+	}
+	if len(s.Flows) != len(want) {
+		t.Fatalf("flows = %+v", s.Flows)
+	}
+	for i, w := range want {
+		if s.Flows[i].Kind != w.kind || math.Abs(s.Flows[i].Amount-w.amount) > 1e-9 {
+			t.Errorf("flow %v = %+v, want %+v", i, s.Flows[i], w)
+		}
+	}
+	if n := s.Nominal(date(2022, 1, 1)).ToFloat(); n != 600 {
+		t.Errorf("nominal after amortization = %v", n)
+	}
+
+	settlement := date(2021, 1, 1)
+	m, err := s.YieldToMaturity(percent("100"), settlement)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkMetrics(t, m, 0.1, 1.545454545455, 1.404958677686, 3.456048084147)
+	checkSensitivity(t, s.Flows, m, settlement)
+
+	m, err = s.YieldToMaturity(percent("98"), settlement)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkMetrics(t, m, 0.114489287047, 1.542208177716, 1.383780172354, 3.356314672146)
+	checkSensitivity(t, s.Flows, m, settlement)
+
+	// после амортизации котировка считается от оставшегося номинала
+	if clean := s.CleanPrice(percent("99"), date(2022, 6, 1)).ToFloat(); clean != 594 {
+		t.Errorf("clean price after amortization = %v", clean)
+	}
+}
+
+func TestFloatingRateBond(t *testing.T) {
+	// полугодовые купоны, объявлен только первый: 40 за 182 дня. Остальные купоны не объявлены
+	bond := &pb.Bond{Nominal: rub(1000), MaturityDate: ts(date(2023, 1, 1)), CouponQuantityPerYear: 2}
+	dates := []time.Time{date(2021, 1, 1), date(2021, 7, 2), date(2022, 1, 1), date(2022, 7, 2), date(2023, 1, 1)}
+	var coupons []*pb.Coupon
+	for i := 1; i < len(dates); i++ {
+		c := &pb.Coupon{
+			CouponNumber:    int64(i),
+			CouponDate:      ts(dates[i]),
+			CouponStartDate: ts(dates[i-1]),
+			CouponEndDate:   ts(dates[i]),
+			CouponType:      pb.CouponType_COUPON_TYPE_FLOATING,
+		}
+		if i == 1 {
+			c.PayOneBond = rub(40)
+		}
+		coupons = append(coupons, c)
+	}
+	s := bonds.NewSchedule(bond, coupons, nil)
+
+	// ставка необъявленных купонов по умолчанию - ставка последнего объявленного
+	near(t, "floating rate", s.FloatingRate, 40.0/1000*365/182, 1e-12)
+	estimated := 0
+	for _, f := range s.Flows {
+		if f.Estimated {
+			estimated++
+		}
+	}
+	if estimated != 3 {
+		t.Fatalf("estimated coupons = %v, want 3", estimated)
+	}
+	settlement := date(2021, 1, 1)
+	m, err := s.YieldToMaturity(percent("100"), settlement)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkMetrics(t, m, 0.081828660146, 1.887252391370, 1.744502120248, 4.758454927411)
+
+	// купоны 183 и 182 дня по 12% годовых: 60.16 и 59.84 на номинал 1000
+	s.ProjectFloating(0.12)
+	amounts := []float64{40, 0.12 * 1000 * 183 / 365, 0.12 * 1000 * 182 / 365, 0.12 * 1000 * 183 / 365}
+	i := 0
+	for _, f := range s.Flows {
+		if f.Kind != bonds.FlowCoupon {
+			continue
+		}
+		near(t, "coupon", f.Amount, amounts[i], 1e-9)
+		i++
+	}
+	m, err = s.YieldToMaturity(percent("100"), settlement)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkMetrics(t, m, 0.112261286642, 1.863377969528, 1.675305966238, 4.420984911246)
+	checkSensitivity(t, s.Flows, m, settlement)
+}
@@ -0,0 +1,453 @@
+// Package candles - построение свечей произвольных интервалов из сделок, последних цен и свечей стрима биржевой
+// информации. Поддерживаются временные свечи любой длительности, выровненные по началу торговых сессий, а также
+// свечи по количеству сделок, объему и обороту. Свечи возвращаются в виде pb.HistoricCandle, как незавершенные,
+// так и завершенные.
+package candles
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "github.com/russianinvestments/invest-api-go-sdk/proto"
+)
+
+var (
+	// ErrCandlesNotSupported - свечи стрима можно агрегировать только во временные свечи
+	ErrCandlesNotSupported = errors.New("candles: stream candles can be aggregated only by time")
+	// ErrInvalidRule - неизвестный тип свечей, неположительная длительность временных свечей или порог остальных
+	ErrInvalidRule = errors.New("candles: invalid rule")
+)
+
+// Kind - тип свечей
+type Kind int
+
+const (
+	// KindTime - свечи фиксированной длительности
+	KindTime Kind = iota
+	// KindTicks - свеча закрывается после заданного количества сделок
+	KindTicks
+	// KindVolume - свеча закрывается, когда объем в лотах достигает порога
+	KindVolume
+	// KindValue - свеча закрывается, когда оборот (цена * количество лотов * лотность) достигает порога
+	KindValue
+)
+
+// Rule - правило формирования свечей
+type Rule struct {
+	Kind Kind
+	// Interval - длительность свечи для KindTime
+	Interval time.Duration
+	// Threshold - порог закрытия свечи для KindTicks, KindVolume и KindValue
+	Threshold float64
+}
+
+// Every - Временные свечи длительностью d, например Every(7 * time.Minute)
+func Every(d time.Duration) Rule {
+	return Rule{Kind: KindTime, Interval: d}
+}
+
+// Ticks - Свечи по n сделок
+func Ticks(n int64) Rule {
+	return Rule{Kind: KindTicks, Threshold: float64(n)}
+}
+
+// Volume - Свечи по объему в лотах, последняя сделка свечи не делится, поэтому объем свечи может быть больше порога
+func Volume(lots int64) Rule {
+	return Rule{Kind: KindVolume, Threshold: float64(lots)}
+}
+
+// Value - Свечи по обороту в валюте инструмента
+func Value(amount float64) Rule {
+	return Rule{Kind: KindValue, Threshold: amount}
+}
+
+// Bar - свеча инструмента
+type Bar struct {
+	Figi          string
+	InstrumentUid string
+	*pb.HistoricCandle
+	// End - окончание интервала временной свечи, для остальных свечей - время последнего обновления
+	End time.Time
+	// Trades - количество сделок или свечей стрима в свече, последние цены не учитываются
+	Trades int64
+}
+
+// Option - настройка агрегатора
+type Option func(a *Aggregator)
+
+// WithSessions - Торговые сессии для выравнивания свечей, например из SessionsFromSchedules. Временные свечи
+// отсчитываются от начала сессии и закрываются с ее окончанием, остальные свечи закрываются с окончанием сессии.
+// Без сессий временные свечи отсчитываются от начала суток по UTC
+func WithSessions(s []Session) Option {
+	return func(a *Aggregator) {
+		a.sessions = append(sessions(nil), s...)
+		sort.Slice(a.sessions, func(i, j int) bool {
+			return a.sessions[i].Start.Before(a.sessions[j].Start)
+		})
+	}
+}
+
+// WithFlushInterval - Как часто Run закрывает временные свечи, по которым нет новых данных, по умолчанию раз в секунду
+func WithFlushInterval(d time.Duration) Option {
+	return func(a *Aggregator) {
+		a.flushInterval = d
+	}
+}
+
+// Aggregator - построение свечей по нескольким инструментам. Методы можно вызывать из разных горутин
+type Aggregator struct {
+	rule          Rule
+	sessions      sessions
+	flushInterval time.Duration
+
+	mu   sync.Mutex
+	bars map[string]*bar
+	lots map[string]int32
+	// closed - окончание последней закрытой свечи инструмента, более ранние данные отбрасываются
+	closed map[string]time.Time
+}
+
+// NewAggregator - Создание агрегатора свечей, для некорректного правила rule возвращается ErrInvalidRule
+func NewAggregator(rule Rule, opts ...Option) (*Aggregator, error) {
+	if err := rule.validate(); err != nil {
+		return nil, err
+	}
+	a := &Aggregator{
+		rule:          rule,
+		flushInterval: time.Second,
+		bars:          make(map[string]*bar),
+		lots:          make(map[string]int32),
+		closed:        make(map[string]time.Time),
+	}
+	for _, o := range opts {
+		o(a)
+	}
+	if a.flushInterval <= 0 {
+		return nil, fmt.Errorf("candles: flush interval must be positive, got %v", a.flushInterval)
+	}
+	return a, nil
+}
+
+// validate - проверка правила, нулевой интервал или порог привели бы к делению на ноль и бесконечным свечам
+func (r Rule) validate() error {
+	switch r.Kind {
+	case KindTime:
+		if r.Interval <= 0 {
+			return fmt.Errorf("%w: interval must be positive, got %v", ErrInvalidRule, r.Interval)
+		}
+	case KindTicks, KindVolume, KindValue:
+		if !(r.Threshold > 0) {
+			return fmt.Errorf("%w: threshold must be positive, got %v", ErrInvalidRule, r.Threshold)
+		}
+	default:
+		return fmt.Errorf("%w: unknown kind %v", ErrInvalidRule, r.Kind)
+	}
+	return nil
+}
+
+// SetLot - Задать лотность инструмента для свечей по обороту, по умолчанию 1. id - figi или instrument_uid
+func (a *Aggregator) SetLot(id string, lot int32) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.lots[id] = lot
+}
+
+// AddTrade - Добавить сделку. Возвращает свечи, закрытые этой сделкой, и текущую незавершенную свечу
+func (a *Aggregator) AddTrade(t *pb.Trade) []Bar {
+	return a.add(t.GetFigi(), t.GetInstrumentUid(), t.GetTime().AsTime(), point(t.GetPrice(), t.GetQuantity()))
+}
+
+// AddLastPrice - Добавить последнюю цену, она меняет цены свечи, но не учитывается в объеме и количестве сделок
+func (a *Aggregator) AddLastPrice(lp *pb.LastPrice) []Bar {
+	p := point(lp.GetPrice(), 0)
+	p.trades = 0
+	return a.add(lp.GetFigi(), lp.GetInstrumentUid(), lp.GetTime().AsTime(), p)
+}
+
+// AddCandle - Добавить свечу стрима, например минутную. Стрим присылает обновления свечи, пока она не завершена,
+// повторная свеча с тем же временем заменяет предыдущую. Свеча относится к интервалу по времени своего начала
+func (a *Aggregator) AddCandle(c *pb.Candle) ([]Bar, error) {
+	if a.rule.Kind != KindTime {
+		return nil, ErrCandlesNotSupported
+	}
+	p := ohlcv{
+		open:       c.GetOpen().ToDecimal(),
+		high:       c.GetHigh().ToDecimal(),
+		low:        c.GetLow().ToDecimal(),
+		close:      c.GetClose().ToDecimal(),
+		volume:     c.GetVolume(),
+		trades:     1,
+		set:        true,
+		source:     c.GetTime().AsTime(),
+		fromCandle: true,
+	}
+	return a.add(c.GetFigi(), c.GetInstrumentUid(), c.GetTime().AsTime(), p), nil
+}
+
+// Flush - Закрыть свечи, интервал или сессия которых закончились к моменту now
+func (a *Aggregator) Flush(now time.Time) []Bar {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	var res []Bar
+	for key, b := range a.bars {
+		if !b.end.IsZero() && !now.Before(b.end) {
+			res = append(res, a.close(key, b))
+		}
+	}
+	return res
+}
+
+// Current - Текущая незавершенная свеча инструмента, id - figi или instrument_uid
+func (a *Aggregator) Current(id string) (Bar, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for key, b := range a.bars {
+		if key == id || b.figi == id {
+			return b.result(false), true
+		}
+	}
+	return Bar{}, false
+}
+
+// Sources - каналы стрима биржевой информации, любой из каналов может быть nil
+type Sources struct {
+	Trades     <-chan *pb.Trade
+	LastPrices <-chan *pb.LastPrice
+	Candles    <-chan *pb.Candle
+}
+
+// Run - Построение свечей из каналов стрима до закрытия всех каналов или отмены контекста. handler вызывается
+// с каждой закрытой и обновленной незавершенной свечой
+func (a *Aggregator) Run(ctx context.Context, src Sources, handler func(b Bar)) error {
+	ticker := time.NewTicker(a.flushInterval)
+	defer ticker.Stop()
+	emit := func(bars []Bar) {
+		for _, b := range bars {
+			handler(b)
+		}
+	}
+	for src.Trades != nil || src.LastPrices != nil || src.Candles != nil {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case now := <-ticker.C:
+			emit(a.Flush(now))
+		case t, ok := <-src.Trades:
+			if !ok {
+				src.Trades = nil
+				continue
+			}
+			emit(a.AddTrade(t))
+		case lp, ok := <-src.LastPrices:
+			if !ok {
+				src.LastPrices = nil
+				continue
+			}
+			emit(a.AddLastPrice(lp))
+		case c, ok := <-src.Candles:
+			if !ok {
+				src.Candles = nil
+				continue
+			}
+			bars, err := a.AddCandle(c)
+			if err != nil {
+				return err
+			}
+			emit(bars)
+		}
+	}
+	return nil
+}
+
+func (a *Aggregator) add(figi, uid string, t time.Time, p ohlcv) []Bar {
+	key := uid
+	if key == "" {
+		key = figi
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if closed, ok := a.closed[key]; ok && t.Before(closed) {
+		// данные за уже закрытую свечу, в том числе закрытую Flush
+		return nil
+	}
+	var res []Bar
+	b, ok := a.bars[key]
+	if ok && !b.end.IsZero() && !t.Before(b.end) {
+		// интервал или сессия свечи закончились
+		res = append(res, a.close(key, b))
+		ok = false
+	}
+	if ok && a.rule.Kind == KindTime && t.Before(b.start) {
+		// данные за интервал до текущей свечи
+		return nil
+	}
+	if ok && a.rule.Kind != KindTime && a.sessions.find(t) != b.session {
+		res = append(res, a.close(key, b))
+		ok = false
+	}
+	if !ok {
+		b = a.newBar(figi, uid, t)
+		a.bars[key] = b
+	}
+
+	p.value = p.close.Mul(decimal.NewFromInt(p.volume)).Mul(decimal.NewFromInt(int64(a.lot(figi, uid))))
+	b.add(p, t)
+
+	if a.rule.Kind != KindTime && b.filled(a.rule) {
+		return append(res, a.close(key, b))
+	}
+	return append(res, b.result(false))
+}
+
+// close - закрытие свечи инструмента key. Временная свеча закрывает свой интервал, остальные - время своей
+// последней сделки
+func (a *Aggregator) close(key string, b *bar) Bar {
+	res := b.result(true)
+	a.closed[key] = res.End
+	delete(a.bars, key)
+	return res
+}
+
+func (a *Aggregator) newBar(figi, uid string, t time.Time) *bar {
+	b := &bar{figi: figi, uid: uid, start: t, timed: a.rule.Kind == KindTime}
+	if b.timed {
+		b.start, b.end, b.session = a.sessions.align(t, a.rule.Interval)
+		return b
+	}
+	b.session = a.sessions.find(t)
+	if b.session >= 0 {
+		b.end = a.sessions[b.session].End
+	}
+	return b
+}
+
+func (a *Aggregator) lot(figi, uid string) int32 {
+	if lot, ok := a.lots[uid]; ok {
+		return lot
+	}
+	if lot, ok := a.lots[figi]; ok {
+		return lot
+	}
+	return 1
+}
+
+// ohlcv - цены и объем части свечи
+type ohlcv struct {
+	open, high, low, close decimal.Decimal
+	volume                 int64
+	value                  decimal.Decimal
+	trades                 int64
+	set                    bool
+	// source, fromCandle - время начала свечи стрима, из которой получены данные
+	source     time.Time
+	fromCandle bool
+}
+
+func point(price *pb.Quotation, quantity int64) ohlcv {
+	p := price.ToDecimal()
+	return ohlcv{open: p, high: p, low: p, close: p, volume: quantity, trades: 1, set: true}
+}
+
+func (o ohlcv) merge(next ohlcv) ohlcv {
+	if !o.set {
+		return next
+	}
+	if !next.set {
+		return o
+	}
+	o.high = decimal.Max(o.high, next.high)
+	o.low = decimal.Min(o.low, next.low)
+	o.close = next.close
+	o.volume += next.volume
+	o.value = o.value.Add(next.value)
+	o.trades += next.trades
+	return o
+}
+
+// bar - свеча в процессе построения. Свечи стрима могут обновляться, поэтому они хранятся по времени своего начала
+type bar struct {
+	figi, uid  string
+	timed      bool
+	start, end time.Time
+	session    int
+	last       time.Time
+
+	base  ohlcv
+	parts map[time.Time]ohlcv
+}
+
+func (b *bar) add(p ohlcv, t time.Time) {
+	if t.After(b.last) {
+		b.last = t
+	}
+	if !p.fromCandle {
+		b.base = b.base.merge(p)
+		return
+	}
+	if b.parts == nil {
+		b.parts = make(map[time.Time]ohlcv)
+	}
+	b.parts[p.source] = p
+}
+
+func (b *bar) total() ohlcv {
+	if len(b.parts) == 0 {
+		return b.base
+	}
+	times := make([]time.Time, 0, len(b.parts))
+	for t := range b.parts {
+		times = append(times, t)
+	}
+	sort.Slice(times, func(i, j int) bool {
+		return times[i].Before(times[j])
+	})
+	t := b.base
+	for _, ts := range times {
+		t = t.merge(b.parts[ts])
+	}
+	return t
+}
+
+func (b *bar) filled(r Rule) bool {
+	t := b.total()
+	switch r.Kind {
+	case KindTicks:
+		return float64(t.trades) >= r.Threshold
+	case KindVolume:
+		return float64(t.volume) >= r.Threshold
+	case KindValue:
+		return t.value.InexactFloat64() >= r.Threshold
+	}
+	return false
+}
+
+func (b *bar) result(complete bool) Bar {
+	t := b.total()
+	end := b.end
+	if !b.timed {
+		end = b.last
+	}
+	return Bar{
+		Figi:          b.figi,
+		InstrumentUid: b.uid,
+		HistoricCandle: &pb.HistoricCandle{
+			Open:       pb.QuotationFromDecimal(t.open),
+			High:       pb.QuotationFromDecimal(t.high),
+			Low:        pb.QuotationFromDecimal(t.low),
+			Close:      pb.QuotationFromDecimal(t.close),
+			Volume:     t.volume,
+			Time:       timestamppb.New(b.start),
+			IsComplete: complete,
+		},
+		End:    end,
+		Trades: t.trades,
+	}
+}
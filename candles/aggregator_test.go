@@ -0,0 +1,253 @@
+package candles_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/russianinvestments/invest-api-go-sdk/candles"
+	pb "github.com/russianinvestments/invest-api-go-sdk/proto"
+)
+
+const uid = "e6123145-9665-43e0-8413-cd61b8aa9b13"
+
+var day = time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+
+func at(h, m, s int) time.Time {
+	return day.Add(time.Duration(h)*time.Hour + time.Duration(m)*time.Minute + time.Duration(s)*time.Second)
+}
+
+func trade(t time.Time, price float64, quantity int64) *pb.Trade {
+	return &pb.Trade{
+		InstrumentUid: uid,
+		Price:         pb.QuotationFromDecimal(decimal.NewFromFloat(price)),
+		Quantity:      quantity,
+		Time:          timestamppb.New(t),
+	}
+}
+
+func lastPrice(t time.Time, price float64) *pb.LastPrice {
+	return &pb.LastPrice{
+		InstrumentUid: uid,
+		Price:         pb.QuotationFromDecimal(decimal.NewFromFloat(price)),
+		Time:          timestamppb.New(t),
+	}
+}
+
+func newAggregator(t *testing.T, rule candles.Rule, opts ...candles.Option) *candles.Aggregator {
+	t.Helper()
+	a, err := candles.NewAggregator(rule, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a
+}
+
+// closed - завершенные свечи из результата Add* или Flush
+func closed(bars []candles.Bar) []candles.Bar {
+	var res []candles.Bar
+	for _, b := range bars {
+		if b.GetIsComplete() {
+			res = append(res, b)
+		}
+	}
+	return res
+}
+
+func checkBar(t *testing.T, b candles.Bar, start, end time.Time, open, high, low, close float64, volume, trades int64) {
+	t.Helper()
+	if !b.GetTime().AsTime().Equal(start) || !b.End.Equal(end) {
+		t.Fatalf("bar interval = %v - %v, want %v - %v", b.GetTime().AsTime(), b.End, start, end)
+	}
+	got := []float64{b.GetOpen().ToFloat(), b.GetHigh().ToFloat(), b.GetLow().ToFloat(), b.GetClose().ToFloat()}
+	want := []float64{open, high, low, close}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("bar ohlc = %v, want %v", got, want)
+		}
+	}
+	if b.GetVolume() != volume || b.Trades != trades {
+		t.Fatalf("bar volume, trades = %v, %v, want %v, %v", b.GetVolume(), b.Trades, volume, trades)
+	}
+}
+
+func TestNewAggregatorInvalidRule(t *testing.T) {
+	for _, rule := range []candles.Rule{candles.Every(0), candles.Ticks(0), candles.Volume(-1), candles.Value(0), {Kind: 10, Threshold: 1}} {
+		if _, err := candles.NewAggregator(rule); !errors.Is(err, candles.ErrInvalidRule) {
+			t.Fatalf("rule %+v: err = %v", rule, err)
+		}
+	}
+	a := newAggregator(t, candles.Ticks(10))
+	if _, err := a.AddCandle(&pb.Candle{InstrumentUid: uid}); !errors.Is(err, candles.ErrCandlesNotSupported) {
+		t.Fatalf("AddCandle err = %v", err)
+	}
+}
+
+func TestTimeBarsSessions(t *testing.T) {
+	// сессия начинается не с ровного интервала и заканчивается посреди интервала
+	session := candles.Session{Start: at(10, 3, 0), End: at(10, 25, 0)}
+	a := newAggregator(t, candles.Every(10*time.Minute), candles.WithSessions([]candles.Session{session}))
+
+	if res := closed(a.AddTrade(trade(at(10, 4, 0), 100, 1))); len(res) != 0 {
+		t.Fatalf("closed = %v", res)
+	}
+	a.AddTrade(trade(at(10, 12, 0), 102, 2))
+	res := closed(a.AddTrade(trade(at(10, 13, 0), 99, 3)))
+	if len(res) != 1 {
+		t.Fatalf("closed = %v", res)
+	}
+	checkBar(t, res[0], at(10, 3, 0), at(10, 13, 0), 100, 102, 100, 102, 3, 2)
+
+	res = closed(a.AddTrade(trade(at(10, 24, 0), 101, 1)))
+	if len(res) != 1 {
+		t.Fatalf("closed = %v", res)
+	}
+	checkBar(t, res[0], at(10, 13, 0), at(10, 23, 0), 99, 99, 99, 99, 3, 1)
+
+	// последний интервал сессии обрезается ее окончанием
+	if res := a.Flush(at(10, 24, 59)); len(res) != 0 {
+		t.Fatalf("flush before session end = %v", res)
+	}
+	res = a.Flush(at(10, 25, 0))
+	if len(res) != 1 {
+		t.Fatalf("flush = %v", res)
+	}
+	checkBar(t, res[0], at(10, 23, 0), at(10, 25, 0), 101, 101, 101, 101, 1, 1)
+
+	// без сессий интервалы отсчитываются от начала суток
+	a = newAggregator(t, candles.Every(7*time.Minute))
+	b := a.AddTrade(trade(at(10, 4, 0), 100, 1))
+	checkBar(t, b[0], at(10, 2, 0), at(10, 9, 0), 100, 100, 100, 100, 1, 1)
+}
+
+func TestTimeBarsLateData(t *testing.T) {
+	a := newAggregator(t, candles.Every(time.Minute))
+	a.AddTrade(trade(at(10, 0, 30), 100, 1))
+	if res := a.Flush(at(10, 1, 0)); len(res) != 1 {
+		t.Fatalf("flush = %v", res)
+	}
+	// сделка и свеча стрима за интервал, закрытый Flush, не открывают его заново
+	if res := a.AddTrade(trade(at(10, 0, 50), 90, 1)); len(res) != 0 {
+		t.Fatalf("late trade = %v", res)
+	}
+	bars, err := a.AddCandle(&pb.Candle{
+		InstrumentUid: uid,
+		Open:          pb.QuotationFromDecimal(decimal.NewFromInt(100)),
+		High:          pb.QuotationFromDecimal(decimal.NewFromInt(100)),
+		Low:           pb.QuotationFromDecimal(decimal.NewFromInt(90)),
+		Close:         pb.QuotationFromDecimal(decimal.NewFromInt(90)),
+		Volume:        2,
+		Time:          timestamppb.New(at(10, 0, 0)),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bars) != 0 {
+		t.Fatalf("late candle = %v", bars)
+	}
+	if _, ok := a.Current(uid); ok {
+		t.Fatal("late data opened a bar")
+	}
+	if res := a.Flush(at(10, 5, 0)); len(res) != 0 {
+		t.Fatalf("duplicate closed bar = %v", res)
+	}
+
+	bars = a.AddTrade(trade(at(10, 1, 10), 101, 1))
+	checkBar(t, bars[0], at(10, 1, 0), at(10, 2, 0), 101, 101, 101, 101, 1, 1)
+}
+
+func TestStreamCandles(t *testing.T) {
+	a := newAggregator(t, candles.Every(5*time.Minute))
+	candle := func(m int, open, high, low, close int64, volume int64) *pb.Candle {
+		return &pb.Candle{
+			InstrumentUid: uid,
+			Open:          pb.QuotationFromDecimal(decimal.NewFromInt(open)),
+			High:          pb.QuotationFromDecimal(decimal.NewFromInt(high)),
+			Low:           pb.QuotationFromDecimal(decimal.NewFromInt(low)),
+			Close:         pb.QuotationFromDecimal(decimal.NewFromInt(close)),
+			Volume:        volume,
+			Time:          timestamppb.New(at(10, m, 0)),
+		}
+	}
+	for _, c := range []*pb.Candle{candle(0, 100, 101, 99, 100, 5), candle(1, 100, 103, 100, 102, 3), candle(1, 100, 104, 98, 103, 4)} {
+		if _, err := a.AddCandle(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// обновление минутной свечи заменяет предыдущую
+	b, ok := a.Current(uid)
+	if !ok {
+		t.Fatal("no current bar")
+	}
+	checkBar(t, b, at(10, 0, 0), at(10, 5, 0), 100, 104, 98, 103, 9, 2)
+}
+
+func TestTickBars(t *testing.T) {
+	a := newAggregator(t, candles.Ticks(3))
+	a.AddTrade(trade(at(10, 0, 0), 100, 1))
+	// последние цены меняют цены свечи, но не считаются сделками
+	a.AddLastPrice(lastPrice(at(10, 0, 1), 105))
+	a.AddLastPrice(lastPrice(at(10, 0, 2), 95))
+	if res := closed(a.AddTrade(trade(at(10, 0, 3), 101, 2))); len(res) != 0 {
+		t.Fatalf("closed after two trades = %v", res)
+	}
+	res := closed(a.AddTrade(trade(at(10, 0, 4), 102, 3)))
+	if len(res) != 1 {
+		t.Fatalf("closed = %v", res)
+	}
+	checkBar(t, res[0], at(10, 0, 0), at(10, 0, 4), 100, 105, 95, 102, 6, 3)
+
+	if res := a.AddTrade(trade(at(10, 0, 3), 90, 1)); len(res) != 0 {
+		t.Fatalf("late trade = %v", res)
+	}
+	b := a.AddTrade(trade(at(10, 0, 5), 103, 1))
+	checkBar(t, b[0], at(10, 0, 5), at(10, 0, 5), 103, 103, 103, 103, 1, 1)
+}
+
+func TestVolumeBars(t *testing.T) {
+	a := newAggregator(t, candles.Volume(10))
+	a.AddTrade(trade(at(10, 0, 0), 100, 4))
+	a.AddTrade(trade(at(10, 0, 1), 101, 4))
+	// последняя сделка не делится, объем свечи больше порога
+	res := closed(a.AddTrade(trade(at(10, 0, 2), 99, 5)))
+	if len(res) != 1 {
+		t.Fatalf("closed = %v", res)
+	}
+	checkBar(t, res[0], at(10, 0, 0), at(10, 0, 2), 100, 101, 99, 99, 13, 3)
+}
+
+func TestValueBars(t *testing.T) {
+	a := newAggregator(t, candles.Value(1000))
+	a.SetLot(uid, 10)
+	// 10 * 5 лотов * 10 = 500
+	if res := closed(a.AddTrade(trade(at(10, 0, 0), 10, 5))); len(res) != 0 {
+		t.Fatalf("closed = %v", res)
+	}
+	// 500 + 11 * 5 лотов * 10 = 1050
+	res := closed(a.AddTrade(trade(at(10, 0, 1), 11, 5)))
+	if len(res) != 1 {
+		t.Fatalf("closed = %v", res)
+	}
+	checkBar(t, res[0], at(10, 0, 0), at(10, 0, 1), 10, 11, 10, 11, 10, 2)
+}
+
+func TestBarsClosedBySession(t *testing.T) {
+	sessions := []candles.Session{
+		{Start: at(10, 0, 0), End: at(11, 0, 0)},
+		{Start: at(12, 0, 0), End: at(13, 0, 0)},
+	}
+	a := newAggregator(t, candles.Volume(100), candles.WithSessions(sessions))
+	a.AddTrade(trade(at(10, 30, 0), 100, 1))
+	// свеча не переходит в следующую сессию, даже если порог не достигнут
+	res := closed(a.AddTrade(trade(at(12, 0, 0), 101, 1)))
+	if len(res) != 1 {
+		t.Fatalf("closed = %v", res)
+	}
+	checkBar(t, res[0], at(10, 30, 0), at(10, 30, 0), 100, 100, 100, 100, 1, 1)
+	if res := a.Flush(at(13, 0, 0)); len(res) != 1 {
+		t.Fatalf("flush = %v", res)
+	}
+}
@@ -0,0 +1,116 @@
+package candles
+
+import (
+	"sort"
+	"time"
+
+	pb "github.com/russianinvestments/invest-api-go-sdk/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Session - торговая сессия [Start, End)
+type Session struct {
+	Start time.Time
+	End   time.Time
+}
+
+func (s Session) contains(t time.Time) bool {
+	return !t.Before(s.Start) && t.Before(s.End)
+}
+
+// SessionsFromSchedules - Торговые сессии биржи exchange из ответа TradingSchedules, если exchange пустой, то
+// используется первая биржа ответа. Для каждого торгового дня возвращаются премаркет, основная и вечерняя сессии,
+// если они есть в расписании
+func SessionsFromSchedules(resp *pb.TradingSchedulesResponse, exchange string) []Session {
+	var res []Session
+	for _, schedule := range resp.GetExchanges() {
+		if exchange != "" && schedule.GetExchange() != exchange {
+			continue
+		}
+		for _, day := range schedule.GetDays() {
+			if !day.GetIsTradingDay() {
+				continue
+			}
+			res = append(res, daySessions(day)...)
+		}
+		break
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Start.Before(res[j].Start)
+	})
+	return res
+}
+
+func daySessions(day *pb.TradingDay) []Session {
+	var res []Session
+	if s, ok := session(day.GetPremarketStartTime(), day.GetPremarketEndTime()); ok {
+		res = append(res, s)
+	}
+	evening, hasEvening := session(day.GetEveningStartTime(), day.GetEveningEndTime())
+	if s, ok := session(day.GetStartTime(), day.GetEndTime()); ok {
+		// время окончания торгов может включать вечернюю сессию
+		if hasEvening && s.End.After(evening.Start) {
+			s.End = evening.Start
+		}
+		if s.End.After(s.Start) {
+			res = append(res, s)
+		}
+	}
+	if hasEvening {
+		res = append(res, evening)
+	}
+	return res
+}
+
+func session(start, end *timestamppb.Timestamp) (Session, bool) {
+	if !valid(start) || !valid(end) {
+		return Session{}, false
+	}
+	s := Session{Start: start.AsTime(), End: end.AsTime()}
+	return s, s.End.After(s.Start)
+}
+
+func valid(ts *timestamppb.Timestamp) bool {
+	return ts != nil && (ts.GetSeconds() != 0 || ts.GetNanos() != 0)
+}
+
+// sessions - поиск сессии по времени
+type sessions []Session
+
+// find - индекс сессии, в которую попадает t, -1 если t вне сессий
+func (ss sessions) find(t time.Time) int {
+	i := sort.Search(len(ss), func(i int) bool {
+		return ss[i].End.After(t)
+	})
+	if i < len(ss) && ss[i].contains(t) {
+		return i
+	}
+	return -1
+}
+
+// align - интервал свечи длительностью d, в который попадает t. Внутри сессии интервалы отсчитываются от начала
+// сессии и обрезаются ее окончанием, вне сессий - от окончания предыдущей сессии или начала суток по UTC
+func (ss sessions) align(t time.Time, d time.Duration) (time.Time, time.Time, int) {
+	idx := ss.find(t)
+	var origin, limit time.Time
+	if idx >= 0 {
+		origin, limit = ss[idx].Start, ss[idx].End
+	} else {
+		origin = t.UTC().Truncate(24 * time.Hour)
+		limit = origin.Add(24 * time.Hour)
+		// интервалы вне сессий отсчитываются от окончания предыдущей сессии и не заходят в следующую
+		next := sort.Search(len(ss), func(i int) bool { return ss[i].Start.After(t) })
+		if next < len(ss) && ss[next].Start.Before(limit) {
+			limit = ss[next].Start
+		}
+		if next > 0 && ss[next-1].End.After(origin) {
+			origin = ss[next-1].End
+		}
+	}
+	start := origin.Add(t.Sub(origin) / d * d)
+	end := start.Add(d)
+	if end.After(limit) {
+		end = limit
+	}
+	return start, end, idx
+}
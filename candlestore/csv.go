@@ -0,0 +1,161 @@
+package candlestore
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/russianinvestments/invest-api-go-sdk/investgo"
+	pb "github.com/russianinvestments/invest-api-go-sdk/proto"
+)
+
+// CSV - хранилище свечей в csv файлах. Для каждого инструмента и интервала в директории создаются файлы
+// <instrument>_<interval>.csv со свечами в формате HistoricCandle.ToCSV (time;open;close;high;low;volume), цены
+// записываются точно, без округления до 9 знаков через float64, и <instrument>_<interval>.ranges.csv с сохраненными интервалами в формате from;to (время в unix)
+type CSV struct {
+	dir string
+	mu  sync.Mutex
+}
+
+var _ investgo.CandleStore = (*CSV)(nil)
+
+// NewCSV - Создание хранилища в директории dir, директория создается, если ее нет
+func NewCSV(dir string) (*CSV, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &CSV{dir: dir}, nil
+}
+
+// Save - Сохранение свечей, загруженных за интервал r
+func (s *CSV) Save(instrument string, interval pb.CandleInterval, r investgo.CandleRange, candles []*pb.HistoricCandle) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	base := baseName(instrument, interval)
+
+	lines := make([]string, 0, len(candles))
+	for _, c := range candles {
+		lines = append(lines, fmt.Sprintf("%v;%v;%v;%v;%v;%v", c.GetTime().AsTime().Unix(), formatPrice(c.GetOpen()),
+			formatPrice(c.GetClose()), formatPrice(c.GetHigh()), formatPrice(c.GetLow()), c.GetVolume()))
+	}
+	if err := appendLines(filepath.Join(s.dir, base+".csv"), lines); err != nil {
+		return err
+	}
+	// интервал записывается после свечей, чтобы при ошибке он не считался сохраненным
+	return appendLines(filepath.Join(s.dir, base+".ranges.csv"),
+		[]string{fmt.Sprintf("%v;%v", r.From.Unix(), r.To.Unix())})
+}
+
+// Ranges - Сохраненные интервалы
+func (s *CSV) Ranges(instrument string, interval pb.CandleInterval) ([]investgo.CandleRange, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ranges := make([]investgo.CandleRange, 0)
+	err := readLines(filepath.Join(s.dir, baseName(instrument, interval)+".ranges.csv"), func(fields []string) error {
+		if len(fields) != 2 {
+			return fmt.Errorf("invalid range %v", fields)
+		}
+		from, err := strconv.ParseInt(fields[0], 10, 64)
+		if err != nil {
+			return err
+		}
+		to, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			return err
+		}
+		ranges = append(ranges, investgo.CandleRange{From: time.Unix(from, 0), To: time.Unix(to, 0)})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return investgo.MergeRanges(ranges), nil
+}
+
+// Load - Сохраненные свечи за [from, to)
+func (s *CSV) Load(instrument string, interval pb.CandleInterval, from, to time.Time) ([]*pb.HistoricCandle, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	candles := make([]*pb.HistoricCandle, 0)
+	err := readLines(filepath.Join(s.dir, baseName(instrument, interval)+".csv"), func(fields []string) error {
+		if len(fields) != 6 {
+			return fmt.Errorf("invalid candle %v", fields)
+		}
+		t, err := strconv.ParseInt(fields[0], 10, 64)
+		if err != nil {
+			return err
+		}
+		if t < from.Unix() || t >= to.Unix() {
+			return nil
+		}
+		prices := make([]*pb.Quotation, 4)
+		for i := range prices {
+			prices[i], err = parsePrice(fields[i+1])
+			if err != nil {
+				return err
+			}
+		}
+		volume, err := strconv.ParseInt(fields[5], 10, 64)
+		if err != nil {
+			return err
+		}
+		// порядок цен в ToCSV: open;close;high;low
+		candles = append(candles, candle(t, prices[0], prices[2], prices[3], prices[1], volume, true))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return normalize(candles, from, to), nil
+}
+
+// Close - Для CSV ничего не делает, файлы закрываются после каждой операции
+func (s *CSV) Close() error {
+	return nil
+}
+
+func appendLines(path string, lines []string) error {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(file)
+	for _, line := range lines {
+		if _, err := w.WriteString(line + "\n"); err != nil {
+			return errors.Join(err, file.Close())
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return errors.Join(err, file.Close())
+	}
+	return file.Close()
+}
+
+// readLines - чтение строк файла с разделителем ';', отсутствующий файл считается пустым
+func readLines(path string, fn func(fields []string) error) error {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if err := fn(strings.Split(line, ";")); err != nil {
+			return fmt.Errorf("%v: %w", path, err)
+		}
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,160 @@
+package candlestore
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/russianinvestments/invest-api-go-sdk/investgo"
+	pb "github.com/russianinvestments/invest-api-go-sdk/proto"
+)
+
+// Parquet - хранилище свечей в parquet файлах. Свечи каждого сохраненного интервала записываются в отдельный файл
+// <dir>/<instrument>_<interval>/<from>_<to>.parquet (время в unix), поэтому сохраненные интервалы определяются
+// по именам файлов. Цены хранятся точно, как в Quotation: целая часть в колонке <price>_units (INT64) и дробная
+// в нано в колонке <price>_nano (INT32), например open_units и open_nano
+type Parquet struct {
+	dir string
+}
+
+var _ investgo.CandleStore = (*Parquet)(nil)
+
+// parquetPrices - цены свечи в порядке колонок файла
+var parquetPrices = []string{"open", "close", "high", "low"}
+
+// NewParquet - Создание хранилища в директории dir, директория создается, если ее нет
+func NewParquet(dir string) (*Parquet, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &Parquet{dir: dir}, nil
+}
+
+// Save - Запись свечей, загруженных за интервал r, в новый файл
+func (s *Parquet) Save(instrument string, interval pb.CandleInterval, r investgo.CandleRange, candles []*pb.HistoricCandle) error {
+	dir := filepath.Join(s.dir, baseName(instrument, interval))
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	times := make([]int64, 0, len(candles))
+	units := make([][]int64, 4)
+	nanos := make([][]int32, 4)
+	volumes := make([]int64, 0, len(candles))
+	complete := make([]bool, 0, len(candles))
+	for _, c := range candles {
+		times = append(times, c.GetTime().AsTime().Unix())
+		for i, q := range []*pb.Quotation{c.GetOpen(), c.GetClose(), c.GetHigh(), c.GetLow()} {
+			units[i] = append(units[i], q.GetUnits())
+			nanos[i] = append(nanos[i], q.GetNano())
+		}
+		volumes = append(volumes, c.GetVolume())
+		complete = append(complete, c.GetIsComplete())
+	}
+	columns := []parquetColumn{{name: "time", values: times}}
+	for i, name := range parquetPrices {
+		columns = append(columns,
+			parquetColumn{name: name + "_units", values: units[i]},
+			parquetColumn{name: name + "_nano", values: nanos[i]})
+	}
+	columns = append(columns,
+		parquetColumn{name: "volume", values: volumes},
+		parquetColumn{name: "is_complete", values: complete})
+	// файл пишется под временным именем, чтобы незаписанный до конца файл не считался сохраненным интервалом
+	path := filepath.Join(dir, fmt.Sprintf("%v_%v.parquet", r.From.Unix(), r.To.Unix()))
+	if err := writeParquet(path+".tmp", columns); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// Ranges - Сохраненные интервалы
+func (s *Parquet) Ranges(instrument string, interval pb.CandleInterval) ([]investgo.CandleRange, error) {
+	files, err := s.files(instrument, interval)
+	if err != nil {
+		return nil, err
+	}
+	ranges := make([]investgo.CandleRange, 0, len(files))
+	for _, f := range files {
+		ranges = append(ranges, f.r)
+	}
+	return investgo.MergeRanges(ranges), nil
+}
+
+// Load - Сохраненные свечи за [from, to)
+func (s *Parquet) Load(instrument string, interval pb.CandleInterval, from, to time.Time) ([]*pb.HistoricCandle, error) {
+	files, err := s.files(instrument, interval)
+	if err != nil {
+		return nil, err
+	}
+	candles := make([]*pb.HistoricCandle, 0)
+	for _, f := range files {
+		if !f.r.From.Before(to) || !f.r.To.After(from) {
+			continue
+		}
+		columns, err := readParquet(f.path)
+		if err != nil {
+			return nil, err
+		}
+		times, _ := columns["time"].values.([]int64)
+		volumes, _ := columns["volume"].values.([]int64)
+		complete, _ := columns["is_complete"].values.([]bool)
+		if len(volumes) != len(times) || len(complete) != len(times) {
+			return nil, fmt.Errorf("parquet: %v: invalid columns", f.path)
+		}
+		prices := make([][]*pb.Quotation, len(parquetPrices))
+		for i, name := range parquetPrices {
+			units, _ := columns[name+"_units"].values.([]int64)
+			nanos, _ := columns[name+"_nano"].values.([]int32)
+			if len(units) != len(times) || len(nanos) != len(times) {
+				return nil, fmt.Errorf("parquet: %v: invalid columns", f.path)
+			}
+			for j := range times {
+				prices[i] = append(prices[i], &pb.Quotation{Units: units[j], Nano: nanos[j]})
+			}
+		}
+		for i, t := range times {
+			candles = append(candles, candle(t, prices[0][i], prices[2][i], prices[3][i], prices[1][i], volumes[i], complete[i]))
+		}
+	}
+	return normalize(candles, from, to), nil
+}
+
+// Close - Для Parquet ничего не делает, файлы закрываются после каждой операции
+func (s *Parquet) Close() error {
+	return nil
+}
+
+type parquetFile struct {
+	path string
+	r    investgo.CandleRange
+}
+
+// files - файлы инструмента с интервалами из имен файлов
+func (s *Parquet) files(instrument string, interval pb.CandleInterval) ([]parquetFile, error) {
+	dir := filepath.Join(s.dir, baseName(instrument, interval))
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	files := make([]parquetFile, 0, len(entries))
+	for _, e := range entries {
+		name, ok := strings.CutSuffix(e.Name(), ".parquet")
+		if !ok || e.IsDir() {
+			continue
+		}
+		var from, to int64
+		if _, err := fmt.Sscanf(name, "%d_%d", &from, &to); err != nil {
+			continue
+		}
+		files = append(files, parquetFile{
+			path: filepath.Join(dir, e.Name()),
+			r:    investgo.CandleRange{From: time.Unix(from, 0), To: time.Unix(to, 0)},
+		})
+	}
+	return files, nil
+}
@@ -0,0 +1,542 @@
+package candlestore
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+)
+
+// Минимальная реализация формата Apache Parquet для плоских таблиц: одна группа строк, по одной странице данных
+// на колонку, обязательные колонки BOOLEAN, INT32 и INT64, кодирование PLAIN без сжатия. Метаданные файла
+// записываются в формате Thrift Compact Protocol. Совместимость с другими реализациями формата проверяется
+// тестом с файлом testdata/candles.parquet
+
+const parquetMagic = "PAR1"
+
+// типы колонок parquet
+const (
+	parquetBoolean int32 = 0
+	parquetInt32   int32 = 1
+	parquetInt64   int32 = 2
+)
+
+// типы thrift compact protocol
+const (
+	thriftTrue   = 1
+	thriftFalse  = 2
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftDouble = 7
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// parquetColumn - колонка таблицы, values - []bool, []int32 или []int64
+type parquetColumn struct {
+	name   string
+	values any
+}
+
+func (c parquetColumn) kind() int32 {
+	switch c.values.(type) {
+	case []bool:
+		return parquetBoolean
+	case []int32:
+		return parquetInt32
+	}
+	return parquetInt64
+}
+
+func (c parquetColumn) len() int {
+	switch v := c.values.(type) {
+	case []bool:
+		return len(v)
+	case []int32:
+		return len(v)
+	case []int64:
+		return len(v)
+	}
+	return 0
+}
+
+// plain - значения колонки в кодировке PLAIN
+func (c parquetColumn) plain() []byte {
+	switch v := c.values.(type) {
+	case []bool:
+		buf := make([]byte, (len(v)+7)/8)
+		for i, b := range v {
+			if b {
+				buf[i/8] |= 1 << (i % 8)
+			}
+		}
+		return buf
+	case []int32:
+		buf := make([]byte, 4*len(v))
+		for i, n := range v {
+			binary.LittleEndian.PutUint32(buf[4*i:], uint32(n))
+		}
+		return buf
+	case []int64:
+		buf := make([]byte, 8*len(v))
+		for i, n := range v {
+			binary.LittleEndian.PutUint64(buf[8*i:], uint64(n))
+		}
+		return buf
+	}
+	return nil
+}
+
+// writeParquet - запись таблицы в файл, все колонки должны быть одной длины
+func writeParquet(path string, columns []parquetColumn) error {
+	rows := 0
+	if len(columns) > 0 {
+		rows = columns[0].len()
+	}
+	var buf bytes.Buffer
+	buf.WriteString(parquetMagic)
+
+	chunks := make([]func(w *thriftWriter), 0, len(columns))
+	var total int64
+	for _, c := range columns {
+		if c.len() != rows {
+			return fmt.Errorf("parquet: column %v has %v values, expected %v", c.name, c.len(), rows)
+		}
+		data := c.plain()
+		header := &thriftWriter{}
+		header.structBegin()
+		header.i32Field(1, 0) // DATA_PAGE
+		header.i32Field(2, int32(len(data)))
+		header.i32Field(3, int32(len(data)))
+		header.fieldBegin(5, thriftStruct)
+		header.structBegin()
+		header.i32Field(1, int32(rows))
+		header.i32Field(2, 0) // PLAIN
+		header.i32Field(3, 3) // RLE
+		header.i32Field(4, 3) // RLE
+		header.structEnd()
+		header.structEnd()
+
+		offset := int64(buf.Len())
+		size := int64(header.buf.Len() + len(data))
+		buf.Write(header.buf.Bytes())
+		buf.Write(data)
+		total += size
+
+		c := c
+		chunks = append(chunks, func(w *thriftWriter) {
+			w.structBegin()
+			w.i64Field(2, offset)
+			w.fieldBegin(3, thriftStruct)
+			w.structBegin()
+			w.i32Field(1, c.kind())
+			w.listField(2, thriftI32, 1, func() { w.varint(0) })
+			w.listField(3, thriftBinary, 1, func() { w.binary(c.name) })
+			w.i32Field(4, 0) // UNCOMPRESSED
+			w.i64Field(5, int64(rows))
+			w.i64Field(6, size)
+			w.i64Field(7, size)
+			w.i64Field(9, offset)
+			w.structEnd()
+			w.structEnd()
+		})
+	}
+
+	meta := &thriftWriter{}
+	meta.structBegin()
+	meta.i32Field(1, 1)
+	meta.listField(2, thriftStruct, len(columns)+1, func() {
+		meta.structBegin()
+		meta.binaryField(4, "schema")
+		meta.i32Field(5, int32(len(columns)))
+		meta.structEnd()
+		for _, c := range columns {
+			meta.structBegin()
+			meta.i32Field(1, c.kind())
+			meta.i32Field(3, 0) // REQUIRED
+			meta.binaryField(4, c.name)
+			meta.structEnd()
+		}
+	})
+	meta.i64Field(3, int64(rows))
+	meta.listField(4, thriftStruct, 1, func() {
+		meta.structBegin()
+		meta.listField(1, thriftStruct, len(chunks), func() {
+			for _, chunk := range chunks {
+				chunk(meta)
+			}
+		})
+		meta.i64Field(2, total)
+		meta.i64Field(3, int64(rows))
+		meta.structEnd()
+	})
+	meta.binaryField(6, "invest-api-go-sdk")
+	meta.structEnd()
+
+	buf.Write(meta.buf.Bytes())
+	if err := binary.Write(&buf, binary.LittleEndian, uint32(meta.buf.Len())); err != nil {
+		return err
+	}
+	buf.WriteString(parquetMagic)
+	return os.WriteFile(path, buf.Bytes(), 0o644)
+}
+
+// readParquet - чтение колонок файла по именам. Поддерживаются только файлы, которые может записать writeParquet
+func readParquet(path string) (map[string]parquetColumn, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	n := len(data)
+	if n < 12 || string(data[:4]) != parquetMagic || string(data[n-4:]) != parquetMagic {
+		return nil, fmt.Errorf("parquet: %v is not a parquet file", path)
+	}
+	metaLen := int(binary.LittleEndian.Uint32(data[n-8 : n-4]))
+	if metaLen > n-12 {
+		return nil, fmt.Errorf("parquet: %v: invalid footer", path)
+	}
+	r := &thriftReader{data: data[n-8-metaLen : n-8]}
+	meta, err := r.readStruct()
+	if err != nil {
+		return nil, fmt.Errorf("parquet: %v: %w", path, err)
+	}
+
+	columns := make(map[string]parquetColumn)
+	for _, rg := range meta.list(4) {
+		group, _ := rg.(thriftFields)
+		for _, chunk := range group.list(1) {
+			cc, _ := chunk.(thriftFields)
+			md := cc.structField(3)
+			if md == nil {
+				return nil, errUnsupportedParquet
+			}
+			if md.int(4) != 0 {
+				return nil, fmt.Errorf("parquet: %v: compressed columns are not supported", path)
+			}
+			names := md.list(3)
+			if len(names) != 1 {
+				return nil, errUnsupportedParquet
+			}
+			name, _ := names[0].([]byte)
+			values, err := readColumn(data, int32(md.int(1)), md.int(9), md.int(5))
+			if err != nil {
+				return nil, err
+			}
+			columns[string(name)] = appendColumn(columns[string(name)], string(name), values)
+		}
+	}
+	return columns, nil
+}
+
+var errUnsupportedParquet = errors.New("parquet: unsupported file layout")
+
+// readColumn - чтение страниц колонки начиная с offset, пока не прочитано count значений
+func readColumn(data []byte, kind int32, offset, count int64) (any, error) {
+	var res parquetColumn
+	for read := int64(0); read < count; {
+		if offset < 0 || offset >= int64(len(data)) {
+			return nil, errUnsupportedParquet
+		}
+		r := &thriftReader{data: data[offset:]}
+		header, err := r.readStruct()
+		if err != nil {
+			return nil, err
+		}
+		dph := header.structField(5)
+		if header.int(1) != 0 || dph == nil || dph.int(2) != 0 {
+			return nil, errUnsupportedParquet
+		}
+		size := header.int(3)
+		start := offset + int64(r.pos)
+		if start+size > int64(len(data)) {
+			return nil, errUnsupportedParquet
+		}
+		values, err := decodePlain(data[start:start+size], kind, int(dph.int(1)))
+		if err != nil {
+			return nil, err
+		}
+		res = appendColumn(res, "", values)
+		read += dph.int(1)
+		offset = start + size
+	}
+	return res.values, nil
+}
+
+func appendColumn(c parquetColumn, name string, values any) parquetColumn {
+	c.name = name
+	switch v := values.(type) {
+	case []bool:
+		prev, _ := c.values.([]bool)
+		c.values = append(prev, v...)
+	case []int32:
+		prev, _ := c.values.([]int32)
+		c.values = append(prev, v...)
+	case []int64:
+		prev, _ := c.values.([]int64)
+		c.values = append(prev, v...)
+	}
+	return c
+}
+
+func decodePlain(buf []byte, kind int32, n int) (any, error) {
+	switch kind {
+	case parquetBoolean:
+		if len(buf) < (n+7)/8 {
+			return nil, io.ErrUnexpectedEOF
+		}
+		res := make([]bool, n)
+		for i := range res {
+			res[i] = buf[i/8]&(1<<(i%8)) != 0
+		}
+		return res, nil
+	case parquetInt32:
+		if len(buf) < 4*n {
+			return nil, io.ErrUnexpectedEOF
+		}
+		res := make([]int32, n)
+		for i := range res {
+			res[i] = int32(binary.LittleEndian.Uint32(buf[4*i:]))
+		}
+		return res, nil
+	case parquetInt64:
+		if len(buf) < 8*n {
+			return nil, io.ErrUnexpectedEOF
+		}
+		res := make([]int64, n)
+		for i := range res {
+			res[i] = int64(binary.LittleEndian.Uint64(buf[8*i:]))
+		}
+		return res, nil
+	}
+	return nil, errUnsupportedParquet
+}
+
+// thriftWriter - запись в формате Thrift Compact Protocol
+type thriftWriter struct {
+	buf  bytes.Buffer
+	last []int16
+}
+
+func (w *thriftWriter) structBegin() {
+	w.last = append(w.last, 0)
+}
+
+func (w *thriftWriter) structEnd() {
+	w.buf.WriteByte(0)
+	w.last = w.last[:len(w.last)-1]
+}
+
+func (w *thriftWriter) fieldBegin(id int16, typ byte) {
+	last := &w.last[len(w.last)-1]
+	if delta := id - *last; delta > 0 && delta <= 15 {
+		w.buf.WriteByte(byte(delta)<<4 | typ)
+	} else {
+		w.buf.WriteByte(typ)
+		w.varint(int64(id))
+	}
+	*last = id
+}
+
+func (w *thriftWriter) varint(v int64) {
+	var tmp [binary.MaxVarintLen64]byte
+	w.buf.Write(tmp[:binary.PutVarint(tmp[:], v)])
+}
+
+func (w *thriftWriter) binary(s string) {
+	var tmp [binary.MaxVarintLen64]byte
+	w.buf.Write(tmp[:binary.PutUvarint(tmp[:], uint64(len(s)))])
+	w.buf.WriteString(s)
+}
+
+func (w *thriftWriter) i32Field(id int16, v int32) {
+	w.fieldBegin(id, thriftI32)
+	w.varint(int64(v))
+}
+
+func (w *thriftWriter) i64Field(id int16, v int64) {
+	w.fieldBegin(id, thriftI64)
+	w.varint(v)
+}
+
+func (w *thriftWriter) binaryField(id int16, s string) {
+	w.fieldBegin(id, thriftBinary)
+	w.binary(s)
+}
+
+func (w *thriftWriter) listField(id int16, elem byte, size int, elems func()) {
+	w.fieldBegin(id, thriftList)
+	if size < 15 {
+		w.buf.WriteByte(byte(size)<<4 | elem)
+	} else {
+		w.buf.WriteByte(0xF0 | elem)
+		var tmp [binary.MaxVarintLen64]byte
+		w.buf.Write(tmp[:binary.PutUvarint(tmp[:], uint64(size))])
+	}
+	elems()
+}
+
+// thriftFields - поля структуры по номерам
+type thriftFields map[int16]any
+
+func (f thriftFields) int(id int16) int64 {
+	v, _ := f[id].(int64)
+	return v
+}
+
+func (f thriftFields) list(id int16) []any {
+	v, _ := f[id].([]any)
+	return v
+}
+
+func (f thriftFields) structField(id int16) thriftFields {
+	v, _ := f[id].(thriftFields)
+	return v
+}
+
+// thriftReader - чтение формата Thrift Compact Protocol
+type thriftReader struct {
+	data []byte
+	pos  int
+}
+
+func (r *thriftReader) byte() (byte, error) {
+	if r.pos >= len(r.data) {
+		return 0, io.ErrUnexpectedEOF
+	}
+	b := r.data[r.pos]
+	r.pos++
+	return b, nil
+}
+
+func (r *thriftReader) uvarint() (uint64, error) {
+	v, n := binary.Uvarint(r.data[r.pos:])
+	if n <= 0 {
+		return 0, io.ErrUnexpectedEOF
+	}
+	r.pos += n
+	return v, nil
+}
+
+func (r *thriftReader) varint() (int64, error) {
+	v, n := binary.Varint(r.data[r.pos:])
+	if n <= 0 {
+		return 0, io.ErrUnexpectedEOF
+	}
+	r.pos += n
+	return v, nil
+}
+
+func (r *thriftReader) readStruct() (thriftFields, error) {
+	fields := make(thriftFields)
+	var last int16
+	for {
+		b, err := r.byte()
+		if err != nil {
+			return nil, err
+		}
+		if b == 0 {
+			return fields, nil
+		}
+		typ := b & 0x0F
+		id := last + int16(b>>4)
+		if b>>4 == 0 {
+			v, err := r.varint()
+			if err != nil {
+				return nil, err
+			}
+			id = int16(v)
+		}
+		last = id
+		switch typ {
+		case thriftTrue, thriftFalse:
+			fields[id] = typ == thriftTrue
+			continue
+		}
+		v, err := r.value(typ)
+		if err != nil {
+			return nil, err
+		}
+		fields[id] = v
+	}
+}
+
+func (r *thriftReader) value(typ byte) (any, error) {
+	switch typ {
+	case thriftTrue, thriftFalse:
+		// в списках bool записывается отдельным байтом
+		b, err := r.byte()
+		return b == thriftTrue, err
+	case 3: // byte
+		b, err := r.byte()
+		return int64(int8(b)), err
+	case 4, thriftI32, thriftI64:
+		return r.varint()
+	case thriftDouble:
+		if r.pos+8 > len(r.data) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		v := math.Float64frombits(binary.LittleEndian.Uint64(r.data[r.pos:]))
+		r.pos += 8
+		return v, nil
+	case thriftBinary:
+		n, err := r.uvarint()
+		if err != nil {
+			return nil, err
+		}
+		if uint64(len(r.data)-r.pos) < n {
+			return nil, io.ErrUnexpectedEOF
+		}
+		v := r.data[r.pos : r.pos+int(n)]
+		r.pos += int(n)
+		return v, nil
+	case thriftList, 10: // list, set
+		b, err := r.byte()
+		if err != nil {
+			return nil, err
+		}
+		size := uint64(b >> 4)
+		if size == 15 {
+			if size, err = r.uvarint(); err != nil {
+				return nil, err
+			}
+		}
+		if size > uint64(len(r.data)) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		res := make([]any, 0, size)
+		for i := uint64(0); i < size; i++ {
+			v, err := r.value(b & 0x0F)
+			if err != nil {
+				return nil, err
+			}
+			res = append(res, v)
+		}
+		return res, nil
+	case 11: // map
+		size, err := r.uvarint()
+		if err != nil || size == 0 {
+			return nil, err
+		}
+		kv, err := r.byte()
+		if err != nil {
+			return nil, err
+		}
+		for i := uint64(0); i < 2*size; i++ {
+			typ := kv >> 4
+			if i%2 == 1 {
+				typ = kv & 0x0F
+			}
+			if _, err := r.value(typ); err != nil {
+				return nil, err
+			}
+		}
+		return nil, nil
+	case thriftStruct:
+		return r.readStruct()
+	}
+	return nil, fmt.Errorf("thrift: unknown type %v", typ)
+}
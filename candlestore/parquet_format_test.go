@@ -0,0 +1,149 @@
+package candlestore
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/russianinvestments/invest-api-go-sdk/investgo"
+	pb "github.com/russianinvestments/invest-api-go-sdk/proto"
+)
+
+var update = flag.Bool("update", false, "перезаписать testdata")
+
+// goldenColumns - содержимое testdata/candles.parquet. Файл записан writeParquet и прочитан независимой
+// реализацией формата (github.com/parquet-go/parquet-go): схема time INT64, open_units INT64, open_nano INT32,
+// is_complete BOOLEAN и те же значения
+func goldenColumns() []parquetColumn {
+	return []parquetColumn{
+		{name: "time", values: []int64{1704067200, 1704070800, 1704074400}},
+		{name: "open_units", values: []int64{100, -1, 123456789012}},
+		{name: "open_nano", values: []int32{123456789, -500000000, 1}},
+		{name: "is_complete", values: []bool{true, false, true}},
+	}
+}
+
+func checkColumns(t *testing.T, got map[string]parquetColumn, want []parquetColumn) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("columns = %v, want %v", got, want)
+	}
+	for _, c := range want {
+		if !reflect.DeepEqual(got[c.name].values, c.values) {
+			t.Errorf("column %v = %v, want %v", c.name, got[c.name].values, c.values)
+		}
+	}
+}
+
+func TestParquetRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	// больше 15 колонок и строк, чтобы списки thrift записывались с длиной отдельным varint, и 9 значений
+	// bool, чтобы битовая упаковка заняла два байта
+	columns := make([]parquetColumn, 0)
+	for i := 0; i < 9; i++ {
+		columns = append(columns, goldenColumns()...)
+		for j := range columns[len(columns)-4:] {
+			columns[len(columns)-4+j].name += string(rune('a' + i))
+		}
+	}
+	rows := 20
+	for i := range columns {
+		switch v := columns[i].values.(type) {
+		case []int64:
+			columns[i].values = append(v, make([]int64, rows-len(v))...)
+		case []int32:
+			columns[i].values = append(v, make([]int32, rows-len(v))...)
+		case []bool:
+			columns[i].values = append(v, make([]bool, rows-len(v))...)
+		}
+	}
+	path := filepath.Join(dir, "table.parquet")
+	if err := writeParquet(path, columns); err != nil {
+		t.Fatal(err)
+	}
+	got, err := readParquet(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkColumns(t, got, columns)
+
+	empty := []parquetColumn{{name: "time", values: []int64{}}, {name: "flag", values: []bool{}}}
+	if err := writeParquet(path, empty); err != nil {
+		t.Fatal(err)
+	}
+	if got, err = readParquet(path); err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range empty {
+		if got[c.name].len() != 0 {
+			t.Errorf("column %v = %v, want empty", c.name, got[c.name].values)
+		}
+	}
+
+	bad := []parquetColumn{{name: "time", values: []int64{1, 2}}, {name: "flag", values: []bool{true}}}
+	if err := writeParquet(path, bad); err == nil {
+		t.Error("columns of different length are written")
+	}
+	if err := os.WriteFile(path, []byte("PAR1 not a parquet file PAR1"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readParquet(path); err == nil {
+		t.Error("invalid file is read")
+	}
+}
+
+func TestParquetGolden(t *testing.T) {
+	golden := filepath.Join("testdata", "candles.parquet")
+	path := filepath.Join(t.TempDir(), "candles.parquet")
+	if *update {
+		path = golden
+	}
+	if err := writeParquet(path, goldenColumns()); err != nil {
+		t.Fatal(err)
+	}
+	written, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(written, want) {
+		t.Error("written file differs from testdata/candles.parquet")
+	}
+	got, err := readParquet(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkColumns(t, got, goldenColumns())
+}
+
+// TestParquetOldColumns - файл без колонок с ценами не читается как свечи с нулевыми ценами
+func TestParquetOldColumns(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewParquet(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	if err := store.Save("uid", pb.CandleInterval_CANDLE_INTERVAL_HOUR, investgo.CandleRange{From: from, To: from.Add(time.Hour)},
+		nil); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, baseName("uid", pb.CandleInterval_CANDLE_INTERVAL_HOUR), "1704067200_1704070800.parquet")
+	if err := writeParquet(path, []parquetColumn{
+		{name: "time", values: []int64{1704067200}},
+		{name: "volume", values: []int64{10}},
+		{name: "is_complete", values: []bool{true}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Load("uid", pb.CandleInterval_CANDLE_INTERVAL_HOUR, from, from.Add(time.Hour)); err == nil {
+		t.Fatal("file without price columns is loaded")
+	}
+}
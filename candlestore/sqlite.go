@@ -0,0 +1,150 @@
+package candlestore
+
+import (
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	// драйвер sqlite является cgo пакетом, для сборки нужен gcc
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/russianinvestments/invest-api-go-sdk/investgo"
+	pb "github.com/russianinvestments/invest-api-go-sdk/proto"
+)
+
+var sqliteSchema = `
+create table if not exists candles (
+	instrument text,
+	interval integer,
+	open text,
+	close text,
+	high text,
+	low text,
+	volume integer,
+	time integer,
+	is_complete integer,
+	primary key (instrument, interval, time)
+);
+
+create table if not exists ranges (
+	instrument text,
+	interval integer,
+	from_time integer,
+	to_time integer
+);
+`
+
+// SQLite - хранилище свечей в базе sqlite, свечи хранятся в таблице candles, сохраненные интервалы - в таблице ranges.
+// Цены хранятся в виде десятичных строк, чтобы не терять точность Quotation
+type SQLite struct {
+	db *sqlx.DB
+}
+
+var _ investgo.CandleStore = (*SQLite)(nil)
+
+// NewSQLite - Открытие или создание базы по пути path
+func NewSQLite(path string) (*SQLite, error) {
+	db, err := sqlx.Open("sqlite3", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.Ping(); err != nil {
+		return nil, err
+	}
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		return nil, err
+	}
+	return &SQLite{db: db}, nil
+}
+
+// Save - Сохранение свечей, загруженных за интервал r, в одной транзакции
+func (s *SQLite) Save(instrument string, interval pb.CandleInterval, r investgo.CandleRange, candles []*pb.HistoricCandle) error {
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	insertCandle, err := tx.Preparex(`insert or replace into candles
+		(instrument, interval, open, close, high, low, volume, time, is_complete) values (?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer insertCandle.Close()
+
+	for _, c := range candles {
+		_, err := insertCandle.Exec(instrument, int32(interval),
+			formatPrice(c.GetOpen()),
+			formatPrice(c.GetClose()),
+			formatPrice(c.GetHigh()),
+			formatPrice(c.GetLow()),
+			c.GetVolume(),
+			c.GetTime().AsTime().Unix(),
+			c.GetIsComplete())
+		if err != nil {
+			return err
+		}
+	}
+	_, err = tx.Exec(`insert into ranges (instrument, interval, from_time, to_time) values (?, ?, ?, ?)`,
+		instrument, int32(interval), r.From.Unix(), r.To.Unix())
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Ranges - Сохраненные интервалы
+func (s *SQLite) Ranges(instrument string, interval pb.CandleInterval) ([]investgo.CandleRange, error) {
+	rows := make([]struct {
+		From int64 `db:"from_time"`
+		To   int64 `db:"to_time"`
+	}, 0)
+	err := s.db.Select(&rows, `select from_time, to_time from ranges where instrument = ? and interval = ?`,
+		instrument, int32(interval))
+	if err != nil {
+		return nil, err
+	}
+	ranges := make([]investgo.CandleRange, 0, len(rows))
+	for _, r := range rows {
+		ranges = append(ranges, investgo.CandleRange{From: time.Unix(r.From, 0), To: time.Unix(r.To, 0)})
+	}
+	return investgo.MergeRanges(ranges), nil
+}
+
+// Load - Сохраненные свечи за [from, to)
+func (s *SQLite) Load(instrument string, interval pb.CandleInterval, from, to time.Time) ([]*pb.HistoricCandle, error) {
+	rows := make([]struct {
+		Open       string `db:"open"`
+		Close      string `db:"close"`
+		High       string `db:"high"`
+		Low        string `db:"low"`
+		Volume     int64  `db:"volume"`
+		Time       int64  `db:"time"`
+		IsComplete bool   `db:"is_complete"`
+	}, 0)
+	err := s.db.Select(&rows, `select open, close, high, low, volume, time, is_complete from candles
+		where instrument = ? and interval = ? and time >= ? and time < ? order by time`,
+		instrument, int32(interval), from.Unix(), to.Unix())
+	if err != nil {
+		return nil, err
+	}
+	candles := make([]*pb.HistoricCandle, 0, len(rows))
+	for _, r := range rows {
+		// порядок как в аргументах candle: open, high, low, close
+		prices := make([]*pb.Quotation, 4)
+		for i, v := range []string{r.Open, r.High, r.Low, r.Close} {
+			prices[i], err = parsePrice(v)
+			if err != nil {
+				return nil, err
+			}
+		}
+		candles = append(candles, candle(r.Time, prices[0], prices[1], prices[2], prices[3], r.Volume, r.IsComplete))
+	}
+	return candles, nil
+}
+
+// Close - Закрытие базы
+func (s *SQLite) Close() error {
+	return s.db.Close()
+}
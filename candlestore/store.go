@@ -0,0 +1,73 @@
+// Package candlestore - реализации investgo.CandleStore для хранения исторических свечей в CSV, Parquet и SQLite.
+// Хранилище передается в DownloadCandlesRequest.Store или GetHistoricCandlesRequest.Store, загрузчик свечей
+// сохраняет в него свечи по мере загрузки и не запрашивает у сервера уже сохраненные интервалы.
+package candlestore
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "github.com/russianinvestments/invest-api-go-sdk/proto"
+)
+
+// intervalName - короткое название интервала для имен файлов, например 1_min
+func intervalName(interval pb.CandleInterval) string {
+	return strings.ToLower(strings.TrimPrefix(interval.String(), "CANDLE_INTERVAL_"))
+}
+
+// baseName - имя файлов свечей инструмента
+func baseName(instrument string, interval pb.CandleInterval) string {
+	return fmt.Sprintf("%v_%v", instrument, intervalName(interval))
+}
+
+// parsePrice - цена, сохраненная в виде десятичной строки
+func parsePrice(s string) (*pb.Quotation, error) {
+	d, err := decimal.NewFromString(s)
+	if err != nil {
+		return nil, err
+	}
+	return pb.QuotationFromDecimal(d), nil
+}
+
+// formatPrice - точная запись цены в виде десятичной строки
+func formatPrice(q *pb.Quotation) string {
+	return q.ToDecimal().String()
+}
+
+// candle - свеча из сохраненных значений
+func candle(t int64, open, high, low, closePrice *pb.Quotation, volume int64, complete bool) *pb.HistoricCandle {
+	return &pb.HistoricCandle{
+		Open:       open,
+		High:       high,
+		Low:        low,
+		Close:      closePrice,
+		Volume:     volume,
+		Time:       timestamppb.New(time.Unix(t, 0)),
+		IsComplete: complete,
+	}
+}
+
+// normalize - свечи из [from, to) по возрастанию времени, при повторе времени остается последняя сохраненная свеча
+func normalize(candles []*pb.HistoricCandle, from, to time.Time) []*pb.HistoricCandle {
+	byTime := make(map[int64]*pb.HistoricCandle, len(candles))
+	for _, c := range candles {
+		t := c.GetTime().AsTime()
+		if t.Before(from) || !t.Before(to) {
+			continue
+		}
+		byTime[t.Unix()] = c
+	}
+	res := make([]*pb.HistoricCandle, 0, len(byTime))
+	for _, c := range byTime {
+		res = append(res, c)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].GetTime().AsTime().Before(res[j].GetTime().AsTime())
+	})
+	return res
+}
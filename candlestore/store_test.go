@@ -0,0 +1,124 @@
+package candlestore_test
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/russianinvestments/invest-api-go-sdk/candlestore"
+	"github.com/russianinvestments/invest-api-go-sdk/investgo"
+	pb "github.com/russianinvestments/invest-api-go-sdk/proto"
+)
+
+const interval = pb.CandleInterval_CANDLE_INTERVAL_HOUR
+
+var start = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func hour(n int) time.Time {
+	return start.Add(time.Duration(n) * time.Hour)
+}
+
+func hours(from, to int) investgo.CandleRange {
+	return investgo.CandleRange{From: hour(from), To: hour(to)}
+}
+
+// hourCandle - свеча за час n, цены не представимы точно в float64
+func hourCandle(n int, nano int32) *pb.HistoricCandle {
+	return &pb.HistoricCandle{
+		Open:       &pb.Quotation{Units: 100, Nano: nano},
+		Close:      &pb.Quotation{Units: 0, Nano: 1},
+		High:       &pb.Quotation{Units: 123456789012, Nano: 999999999},
+		Low:        &pb.Quotation{Units: -1, Nano: -500000000},
+		Volume:     int64(n),
+		Time:       timestamppb.New(hour(n)),
+		IsComplete: true,
+	}
+}
+
+func stores(t *testing.T) map[string]investgo.CandleStore {
+	t.Helper()
+	dir := t.TempDir()
+	csv, err := candlestore.NewCSV(filepath.Join(dir, "csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pq, err := candlestore.NewParquet(filepath.Join(dir, "parquet"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlite, err := candlestore.NewSQLite(filepath.Join(dir, "candles.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = sqlite.Close()
+	})
+	return map[string]investgo.CandleStore{"csv": csv, "parquet": pq, "sqlite": sqlite}
+}
+
+func TestStores(t *testing.T) {
+	for name, store := range stores(t) {
+		store := store
+		t.Run(name, func(t *testing.T) {
+			if ranges, err := store.Ranges("uid", interval); err != nil || len(ranges) != 0 {
+				t.Fatalf("empty store ranges = %v, err = %v", ranges, err)
+			}
+			// соседние интервалы и интервал, пересекающийся с ними, в котором свеча за час 3 загружена заново
+			saves := []struct {
+				r       investgo.CandleRange
+				candles []*pb.HistoricCandle
+			}{
+				{hours(2, 4), []*pb.HistoricCandle{hourCandle(2, 1), hourCandle(3, 1)}},
+				{hours(0, 2), []*pb.HistoricCandle{hourCandle(0, 123456789), hourCandle(1, 1)}},
+				{hours(3, 5), []*pb.HistoricCandle{hourCandle(3, 2), hourCandle(4, 1)}},
+				{hours(8, 10), []*pb.HistoricCandle{hourCandle(9, 1)}},
+			}
+			for _, s := range saves {
+				if err := store.Save("uid", interval, s.r, s.candles); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			ranges, err := store.Ranges("uid", interval)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := []investgo.CandleRange{hours(0, 5), hours(8, 10)}
+			if len(ranges) != len(want) {
+				t.Fatalf("ranges = %v, want %v", ranges, want)
+			}
+			for i := range want {
+				if !ranges[i].From.Equal(want[i].From) || !ranges[i].To.Equal(want[i].To) {
+					t.Fatalf("ranges = %v, want %v", ranges, want)
+				}
+			}
+
+			candles, err := store.Load("uid", interval, hour(0), hour(4))
+			if err != nil {
+				t.Fatal(err)
+			}
+			expected := []*pb.HistoricCandle{hourCandle(0, 123456789), hourCandle(1, 1), hourCandle(2, 1), hourCandle(3, 2)}
+			if len(candles) != len(expected) {
+				t.Fatalf("loaded %v candles, want %v", len(candles), len(expected))
+			}
+			for i, c := range candles {
+				if !proto.Equal(c, expected[i]) {
+					t.Errorf("candle %v = %v, want %v", i, c, expected[i])
+				}
+			}
+
+			if candles, err := store.Load("uid", interval, hour(5), hour(8)); err != nil || len(candles) != 0 {
+				t.Fatalf("candles outside ranges = %v, err = %v", candles, err)
+			}
+			if ranges, err := store.Ranges("uid", pb.CandleInterval_CANDLE_INTERVAL_DAY); err != nil || len(ranges) != 0 {
+				t.Fatalf("other interval ranges = %v, err = %v", ranges, err)
+			}
+			if err := store.Close(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
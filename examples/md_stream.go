@@ -124,7 +124,8 @@ func main() {
 	}(ctx)
 
 	// Для еще одного стрима в этом grpc.conn //
-	secondMDStream, err := MDClient.MarketDataStream()
+	// с опцией WithReconnect стрим переподключается после обрыва соединения и восстанавливает подписки
+	secondMDStream, err := MDClient.MarketDataStream(investgo.WithReconnect(nil, 0))
 	if err != nil {
 		logger.Errorf(err.Error())
 	}
@@ -167,6 +168,11 @@ func main() {
 					return
 				}
 				fmt.Println("last price  = ", lp.GetPrice().ToFloat())
+			case e, ok := <-secondMDStream.ReconnectEvents():
+				if !ok {
+					return
+				}
+				logger.Infof("reconnect attempt = %v, success = %v, err = %v", e.Attempt, e.Success, e.Err)
 			}
 		}
 	}(ctx)
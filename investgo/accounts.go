@@ -0,0 +1,276 @@
+package investgo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+
+	pb "github.com/russianinvestments/invest-api-go-sdk/proto"
+)
+
+var (
+	// ErrAccountNotFound - счет не найден среди счетов токена
+	ErrAccountNotFound = errors.New("account not found")
+	// ErrAccountNotOpen - счет закрыт или еще не открыт, торговые операции по нему недоступны
+	ErrAccountNotOpen = errors.New("account is not open")
+	// ErrAccountReadOnly - токен имеет доступ к счету только на чтение
+	ErrAccountReadOnly = errors.New("account is read-only")
+)
+
+// tradingMethods - методы, которые реестр счетов не пропускает для счетов без права торговли
+var tradingMethods = map[string]bool{
+	pb.OrdersService_PostOrder_FullMethodName:            true,
+	pb.OrdersService_PostOrderAsync_FullMethodName:       true,
+	pb.OrdersService_CancelOrder_FullMethodName:          true,
+	pb.OrdersService_ReplaceOrder_FullMethodName:         true,
+	pb.StopOrdersService_PostStopOrder_FullMethodName:    true,
+	pb.StopOrdersService_CancelStopOrder_FullMethodName:  true,
+	pb.SandboxService_PostSandboxOrder_FullMethodName:    true,
+	pb.SandboxService_ReplaceSandboxOrder_FullMethodName: true,
+	pb.SandboxService_CancelSandboxOrder_FullMethodName:  true,
+}
+
+// Account - счет пользователя, доступный одному из токенов реестра
+type Account struct {
+	Id          string
+	Name        string
+	Type        pb.AccountType
+	Status      pb.AccountStatus
+	AccessLevel pb.AccessLevel
+	OpenedDate  time.Time
+	ClosedDate  time.Time
+	// Client - клиент с токеном, у которого самый полный доступ к счету, Config.AccountId = Id.
+	// Клиенты счетов одного токена используют общее соединение: Stop любого из них закрывает его для всех счетов
+	// токена, поэтому клиенты счетов не останавливают по отдельности, соединения закрывает AccountRegistry.Stop
+	Client *Client
+}
+
+// CanTrade - Проверка, что по счету можно выставлять и отменять заявки: счет открыт, а токен имеет полный доступ.
+// Возвращает ErrAccountNotOpen или ErrAccountReadOnly
+func (a *Account) CanTrade() error {
+	if a.Status != pb.AccountStatus_ACCOUNT_STATUS_OPEN {
+		return fmt.Errorf("%w: %v", ErrAccountNotOpen, a.Id)
+	}
+	if a.AccessLevel != pb.AccessLevel_ACCOUNT_ACCESS_LEVEL_FULL_ACCESS {
+		return fmt.Errorf("%w: %v", ErrAccountReadOnly, a.Id)
+	}
+	return nil
+}
+
+// accessRank - чем меньше значение, тем полнее доступ
+func accessRank(level pb.AccessLevel) int {
+	switch level {
+	case pb.AccessLevel_ACCOUNT_ACCESS_LEVEL_FULL_ACCESS:
+		return 0
+	case pb.AccessLevel_ACCOUNT_ACCESS_LEVEL_READ_ONLY:
+		return 1
+	case pb.AccessLevel_ACCOUNT_ACCESS_LEVEL_NO_ACCESS:
+		return 2
+	default:
+		return 3
+	}
+}
+
+// tokenAccounts - клиент одного токена и доступные ему счета
+type tokenAccounts struct {
+	client   *Client
+	accounts []*Account
+}
+
+func (t *tokenAccounts) find(id string) *Account {
+	for _, acc := range t.accounts {
+		if acc.Id == id {
+			return acc
+		}
+	}
+	return nil
+}
+
+// AccountRegistry - реестр счетов нескольких токенов. Для каждого конфига создается свой клиент, счета токенов
+// загружаются через UsersService.GetAccounts. Клиенты реестра не отправляют торговые запросы (выставление,
+// замена и отмена заявок и стоп-заявок) по счетам, которые закрыты или доступны токену только на чтение:
+//
+//	reg, err := investgo.NewAccountRegistry(ctx, []investgo.Config{brokerConf, iisConf}, logger)
+//	...
+//	acc, err := reg.Account(accountId)
+//	...
+//	resp, err := acc.Client.NewOrdersServiceClient().Buy(&investgo.PostOrderRequestShort{AccountId: acc.Id, ...})
+type AccountRegistry struct {
+	logger Logger
+	ctx    context.Context
+
+	mu     sync.RWMutex
+	tokens []*tokenAccounts
+}
+
+// NewAccountRegistry - Создание клиентов для конфигов configs и загрузка их счетов. AccountId в конфигах
+// не используется, dialOpts передаются каждому клиенту
+func NewAccountRegistry(ctx context.Context, configs []Config, l Logger, dialOpts ...grpc.DialOption) (*AccountRegistry, error) {
+	r := &AccountRegistry{
+		logger: l,
+		ctx:    ctx,
+		tokens: make([]*tokenAccounts, len(configs)),
+	}
+	for i, conf := range configs {
+		t := &tokenAccounts{}
+		r.tokens[i] = t
+		opts := append([]grpc.DialOption{}, dialOpts...)
+		opts = append(opts, grpc.WithChainUnaryInterceptor(r.tradeGuardInterceptor(t)))
+		client, err := dial(ctx, conf, l, opts...)
+		if err != nil {
+			_ = r.Stop()
+			return nil, err
+		}
+		t.client = client
+	}
+	if err := r.RefreshCtx(ctx); err != nil {
+		_ = r.Stop()
+		return nil, err
+	}
+	return r, nil
+}
+
+// Refresh - Повторная загрузка счетов всех токенов
+func (r *AccountRegistry) Refresh() error {
+	return r.RefreshCtx(r.ctx)
+}
+
+// RefreshCtx - то же, что и Refresh, но с контекстом запроса ctx
+func (r *AccountRegistry) RefreshCtx(ctx context.Context) error {
+	for _, t := range r.tokens {
+		if err := r.refreshToken(ctx, t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *AccountRegistry) refreshToken(ctx context.Context, t *tokenAccounts) error {
+	all := pb.AccountStatus_ACCOUNT_STATUS_ALL
+	resp, err := t.client.NewUsersServiceClient().GetAccountsCtx(ctx, &all)
+	if err != nil {
+		return err
+	}
+	accounts := make([]*Account, 0, len(resp.GetAccounts()))
+	for _, acc := range resp.GetAccounts() {
+		client := *t.client
+		client.Config.AccountId = acc.GetId()
+		account := &Account{
+			Id:          acc.GetId(),
+			Name:        acc.GetName(),
+			Type:        acc.GetType(),
+			Status:      acc.GetStatus(),
+			AccessLevel: acc.GetAccessLevel(),
+			Client:      &client,
+		}
+		if acc.GetOpenedDate() != nil {
+			account.OpenedDate = acc.GetOpenedDate().AsTime()
+		}
+		if acc.GetClosedDate() != nil {
+			account.ClosedDate = acc.GetClosedDate().AsTime()
+		}
+		accounts = append(accounts, account)
+	}
+	r.mu.Lock()
+	t.accounts = accounts
+	r.mu.Unlock()
+	return nil
+}
+
+// Accounts - Счета всех токенов в порядке конфигов. Если счет доступен нескольким токенам, он возвращается
+// один раз, с клиентом токена, у которого доступ полнее
+func (r *AccountRegistry) Accounts() []*Account {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var accounts []*Account
+	index := make(map[string]int)
+	for _, t := range r.tokens {
+		for _, acc := range t.accounts {
+			i, ok := index[acc.Id]
+			if !ok {
+				index[acc.Id] = len(accounts)
+				accounts = append(accounts, acc)
+				continue
+			}
+			if accessRank(acc.AccessLevel) < accessRank(accounts[i].AccessLevel) {
+				accounts[i] = acc
+			}
+		}
+	}
+	return accounts
+}
+
+// TradableAccounts - Счета, по которым можно торговать, см. Account.CanTrade
+func (r *AccountRegistry) TradableAccounts() []*Account {
+	var accounts []*Account
+	for _, acc := range r.Accounts() {
+		if acc.CanTrade() == nil {
+			accounts = append(accounts, acc)
+		}
+	}
+	return accounts
+}
+
+// Account - Счет с идентификатором id, если счета нет в реестре - ErrAccountNotFound
+func (r *AccountRegistry) Account(id string) (*Account, error) {
+	for _, acc := range r.Accounts() {
+		if acc.Id == id {
+			return acc, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %v", ErrAccountNotFound, id)
+}
+
+// Clients - Клиенты токенов в порядке конфигов
+func (r *AccountRegistry) Clients() []*Client {
+	clients := make([]*Client, 0, len(r.tokens))
+	for _, t := range r.tokens {
+		clients = append(clients, t.client)
+	}
+	return clients
+}
+
+// Stop - Завершение работы клиентов всех токенов, после него клиенты счетов реестра тоже не работают
+func (r *AccountRegistry) Stop() error {
+	var errs []error
+	for _, t := range r.tokens {
+		if t.client != nil {
+			errs = append(errs, t.client.Stop())
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// tradeGuardInterceptor - проверка права торговли по счету перед торговым запросом токена t. Если счета нет
+// среди известных счетов токена, например он открыт после загрузки реестра, счета токена загружаются заново
+func (r *AccountRegistry) tradeGuardInterceptor(t *tokenAccounts) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		accReq, ok := req.(interface{ GetAccountId() string })
+		if !tradingMethods[method] || !ok {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+		id := accReq.GetAccountId()
+		r.mu.RLock()
+		acc := t.find(id)
+		r.mu.RUnlock()
+		if acc == nil {
+			if err := r.refreshToken(ctx, t); err != nil {
+				return err
+			}
+			r.mu.RLock()
+			acc = t.find(id)
+			r.mu.RUnlock()
+		}
+		if acc == nil {
+			return fmt.Errorf("%w: %v", ErrAccountNotFound, id)
+		}
+		if err := acc.CanTrade(); err != nil {
+			return err
+		}
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
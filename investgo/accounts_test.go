@@ -0,0 +1,134 @@
+package investgo_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/russianinvestments/invest-api-go-sdk/investgo"
+	"github.com/russianinvestments/invest-api-go-sdk/investgotest"
+	pb "github.com/russianinvestments/invest-api-go-sdk/proto"
+)
+
+func TestAccountRegistry(t *testing.T) {
+	srv, client, uids := newTestServer(t, 1)
+	setPrice(t, srv, uids[0], 100)
+	readOnly := srv.OpenAccount("read only")
+	if err := srv.SetAccessLevel(readOnly, pb.AccessLevel_ACCOUNT_ACCESS_LEVEL_READ_ONLY); err != nil {
+		t.Fatal(err)
+	}
+	closed := srv.OpenAccount("closed")
+	for _, id := range []string{investgotest.DefaultAccountId, readOnly, closed} {
+		if err := srv.PayIn(id, "rub", 1_000_000); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := client.NewSandboxServiceClient().CloseSandboxAccount(closed); err != nil {
+		t.Fatal(err)
+	}
+
+	// два конфига одного токена: счета возвращаются по одному разу
+	reg, err := investgo.NewAccountRegistry(context.Background(), []investgo.Config{srv.Config(), srv.Config()}, nopLogger{}, srv.DialOptions()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = reg.Stop()
+	}()
+	if n := len(reg.Clients()); n != 2 {
+		t.Fatalf("clients = %v", n)
+	}
+	accounts := reg.Accounts()
+	if len(accounts) != 3 || accounts[0].Id != investgotest.DefaultAccountId || accounts[1].Id != readOnly || accounts[2].Id != closed {
+		t.Fatalf("accounts = %v", accounts)
+	}
+	if tradable := reg.TradableAccounts(); len(tradable) != 1 || tradable[0].Id != investgotest.DefaultAccountId {
+		t.Fatalf("tradable = %v", tradable)
+	}
+	if _, err := reg.Account("unknown"); !errors.Is(err, investgo.ErrAccountNotFound) {
+		t.Fatalf("unknown account: %v", err)
+	}
+
+	// клиент счета отправляет запросы от его имени по соединению клиента токена
+	for _, acc := range accounts {
+		if acc.Client.Config.AccountId != acc.Id || acc.Client.Conn != reg.Clients()[0].Conn {
+			t.Fatalf("account %v client: account id = %v", acc.Id, acc.Client.Config.AccountId)
+		}
+		if acc.Id == closed {
+			continue
+		}
+		resp, err := acc.Client.NewOperationsServiceClient().GetPortfolio(acc.Id, pb.PortfolioRequest_RUB)
+		if err != nil || resp.GetAccountId() != acc.Id {
+			t.Fatalf("portfolio %v: %v, %v", acc.Id, resp.GetAccountId(), err)
+		}
+	}
+
+	// торговые запросы по счетам без права торговли не доходят до сервера, заявка ниже последней цены остается
+	// активной и видна в GetOrders
+	buy := func(acc *investgo.Account) error {
+		order := limitOrder(uids[0], 1, 90)
+		order.AccountId = acc.Id
+		_, err := acc.Client.NewOrdersServiceClient().PostOrder(order)
+		return err
+	}
+	for _, tc := range []struct {
+		id   string
+		want error
+	}{
+		{id: readOnly, want: investgo.ErrAccountReadOnly},
+		{id: closed, want: investgo.ErrAccountNotOpen},
+		{id: investgotest.DefaultAccountId, want: nil},
+	} {
+		acc, err := reg.Account(tc.id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := buy(acc); !errors.Is(err, tc.want) {
+			t.Fatalf("buy %v: err = %v, want %v", tc.id, err, tc.want)
+		}
+		if tc.id == closed {
+			// сервер сам отклонил бы заявку по закрытому счету с ошибкой API, а не ErrAccountNotOpen
+			continue
+		}
+		orders, err := acc.Client.NewOrdersServiceClient().GetOrders(tc.id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n := len(orders.GetOrders()); (n == 1) != (tc.want == nil) {
+			t.Fatalf("%v: orders on server = %v", tc.id, n)
+		}
+	}
+	// неторговые запросы по счету без права торговли не ограничиваются
+	acc, _ := reg.Account(readOnly)
+	if _, err := acc.Client.NewOrdersServiceClient().GetOrders(readOnly); err != nil {
+		t.Fatal(err)
+	}
+
+	// после изменения доступа и Refresh торговля разрешена
+	if err := srv.SetAccessLevel(readOnly, pb.AccessLevel_ACCOUNT_ACCESS_LEVEL_FULL_ACCESS); err != nil {
+		t.Fatal(err)
+	}
+	if err := reg.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+	acc, _ = reg.Account(readOnly)
+	if err := buy(acc); err != nil {
+		t.Fatal(err)
+	}
+
+	// счет, открытый после загрузки реестра, находится при первом торговом запросе
+	opened := srv.OpenAccount("new")
+	if err := srv.PayIn(opened, "rub", 1_000_000); err != nil {
+		t.Fatal(err)
+	}
+	if err := buy(&investgo.Account{Id: opened, Client: reg.Clients()[0]}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := reg.Account(opened); err != nil {
+		t.Fatal(err)
+	}
+	err = buy(&investgo.Account{Id: "unknown", Client: reg.Clients()[0]})
+	if !errors.Is(err, investgo.ErrAccountNotFound) {
+		t.Fatalf("unknown account: %v", err)
+	}
+}
@@ -0,0 +1,217 @@
+package investgo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Коды ошибок API, https://tinkoff.github.io/investAPI/errors/
+const (
+	ErrCodeNotEnoughBalance          = 30034
+	ErrCodeNotEnoughAssets           = 30042
+	ErrCodeInstrumentForbiddenForAPI = 30052
+	ErrCodeInstrumentNotTradable     = 30079
+	ErrCodeInsufficientPrivileges    = 40002
+	ErrCodeTokenNotFound             = 40003
+	ErrCodeInstrumentNotFound        = 50002
+	ErrCodeAccountNotFound           = 50004
+	ErrCodeOrderNotFound             = 50005
+	ErrCodeStopOrderNotFound         = 50006
+	ErrCodeRateLimitExceeded         = 80002
+)
+
+// APIErrorCodes - описания известных кодов ошибок API
+var APIErrorCodes = map[int]string{
+	ErrCodeNotEnoughBalance:          "Недостаточно средств для совершения сделки",
+	ErrCodeNotEnoughAssets:           "Недостаточно активов для совершения сделки",
+	ErrCodeInstrumentForbiddenForAPI: "Торговля инструментом через API недоступна",
+	ErrCodeInstrumentNotTradable:     "Инструмент недоступен для торговли",
+	ErrCodeInsufficientPrivileges:    "Недостаточно прав для совершения операции",
+	ErrCodeTokenNotFound:             "Токен доступа не найден или не активен",
+	ErrCodeInstrumentNotFound:        "Инструмент не найден",
+	ErrCodeAccountNotFound:           "Счет не найден",
+	ErrCodeOrderNotFound:             "Заявка не найдена",
+	ErrCodeStopOrderNotFound:         "Стоп-заявка не найдена",
+	ErrCodeRateLimitExceeded:         "Превышен лимит запросов",
+}
+
+// APIError - ошибка запроса к API. Все методы сервисов и стримов клиента, созданного NewClient, возвращают ошибки
+// сервера в этом виде, получить ее можно через errors.As или AsAPIError. status.Code и status.FromError для APIError
+// работают так же, как для исходной ошибки grpc
+type APIError struct {
+	// Code - код grpc
+	Code codes.Code
+	// APICode - числовой код ошибки API из сообщения grpc, 0 если его нет
+	APICode int
+	// Message - описание ошибки из заголовка message, если его нет - из сообщения grpc
+	Message string
+	// TrackingId - идентификатор запроса для обращения в поддержку, заголовок x-tracking-id
+	TrackingId string
+	// RateLimitRemaining - остаток запросов, -1 если неизвестен
+	RateLimitRemaining int
+	// RateLimitReset - время до сброса лимита запросов, 0 если неизвестно
+	RateLimitReset time.Duration
+
+	status *status.Status
+}
+
+// NewAPIError - Создание APIError из ошибки grpc и метаданных ответа (обычно трейлера). Ошибки, которые не являются
+// ошибками grpc, возвращаются без изменений
+func NewAPIError(err error, md metadata.MD) error {
+	var apiErr *APIError
+	if err == nil || errors.As(err, &apiErr) {
+		return err
+	}
+	st, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+	e := &APIError{
+		Code:               st.Code(),
+		Message:            MessageFromHeader(md),
+		TrackingId:         firstValue(md, "x-tracking-id"),
+		RateLimitRemaining: RemainingLimitFromHeader(md),
+		RateLimitReset:     RateLimitResetFromHeader(md),
+		status:             st,
+	}
+	if code, err := strconv.Atoi(strings.TrimSpace(st.Message())); err == nil {
+		e.APICode = code
+	}
+	if e.Message == "" {
+		e.Message = st.Message()
+		if desc, ok := APIErrorCodes[e.APICode]; ok {
+			e.Message = desc
+		}
+	}
+	return e
+}
+
+func (e *APIError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "api error: code = %v", e.Code)
+	if e.APICode != 0 {
+		fmt.Fprintf(&b, ", api code = %v", e.APICode)
+	}
+	fmt.Fprintf(&b, ", message = %v", e.Message)
+	if e.TrackingId != "" {
+		fmt.Fprintf(&b, ", tracking id = %v", e.TrackingId)
+	}
+	return b.String()
+}
+
+// GRPCStatus - Исходный статус grpc
+func (e *APIError) GRPCStatus() *status.Status {
+	return e.status
+}
+
+// Unwrap - Исходная ошибка grpc
+func (e *APIError) Unwrap() error {
+	return e.status.Err()
+}
+
+// AsAPIError - Получение APIError из ошибки
+func AsAPIError(err error) (*APIError, bool) {
+	var apiErr *APIError
+	ok := errors.As(err, &apiErr)
+	return apiErr, ok
+}
+
+// IsAPIErrorCode - Является ли err ошибкой API с одним из кодов codes
+func IsAPIErrorCode(err error, codes ...int) bool {
+	apiErr, ok := AsAPIError(err)
+	if !ok {
+		return false
+	}
+	for _, c := range codes {
+		if apiErr.APICode == c {
+			return true
+		}
+	}
+	return false
+}
+
+// IsInsufficientBalance - Недостаточно средств или активов для сделки
+func IsInsufficientBalance(err error) bool {
+	return IsAPIErrorCode(err, ErrCodeNotEnoughBalance, ErrCodeNotEnoughAssets)
+}
+
+// IsInstrumentNotTradable - Инструмент недоступен для торговли, в том числе через API
+func IsInstrumentNotTradable(err error) bool {
+	return IsAPIErrorCode(err, ErrCodeInstrumentNotTradable, ErrCodeInstrumentForbiddenForAPI)
+}
+
+// IsOrderNotFound - Заявка или стоп-заявка не найдена
+func IsOrderNotFound(err error) bool {
+	return IsAPIErrorCode(err, ErrCodeOrderNotFound, ErrCodeStopOrderNotFound)
+}
+
+// IsInstrumentNotFound - Инструмент не найден
+func IsInstrumentNotFound(err error) bool {
+	return IsAPIErrorCode(err, ErrCodeInstrumentNotFound)
+}
+
+// IsRateLimitExceeded - Превышен лимит запросов
+func IsRateLimitExceeded(err error) bool {
+	return status.Code(err) == codes.ResourceExhausted || IsAPIErrorCode(err, ErrCodeRateLimitExceeded)
+}
+
+// IsUnauthenticated - Токен не найден, не активен или у него недостаточно прав
+func IsUnauthenticated(err error) bool {
+	switch status.Code(err) {
+	case codes.Unauthenticated, codes.PermissionDenied:
+		return true
+	}
+	return IsAPIErrorCode(err, ErrCodeTokenNotFound, ErrCodeInsufficientPrivileges)
+}
+
+func firstValue(md metadata.MD, key string) string {
+	if v := md.Get(key); len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+// apiErrorUnaryInterceptor - перевод ошибок unary запросов в APIError
+func apiErrorUnaryInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		var trailer metadata.MD
+		err := invoker(ctx, method, req, reply, cc, append(opts, grpc.Trailer(&trailer))...)
+		return NewAPIError(err, trailer)
+	}
+}
+
+// apiErrorStreamInterceptor - перевод ошибок стримов в APIError
+func apiErrorStreamInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		s, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			return nil, NewAPIError(err, nil)
+		}
+		return &apiErrorStream{ClientStream: s}, nil
+	}
+}
+
+type apiErrorStream struct {
+	grpc.ClientStream
+}
+
+func (s *apiErrorStream) RecvMsg(m any) error {
+	err := s.ClientStream.RecvMsg(m)
+	if err == nil {
+		return nil
+	}
+	return NewAPIError(err, s.Trailer())
+}
+
+func (s *apiErrorStream) SendMsg(m any) error {
+	return NewAPIError(s.ClientStream.SendMsg(m), nil)
+}
@@ -0,0 +1,137 @@
+package investgo_test
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/russianinvestments/invest-api-go-sdk/investgo"
+	"github.com/russianinvestments/invest-api-go-sdk/investgotest"
+)
+
+func TestNewAPIError(t *testing.T) {
+	grpcErr := status.Error(codes.NotFound, "50002")
+	md := metadata.Pairs("message", "instrument not found", "x-tracking-id", "abc",
+		"x-ratelimit-remaining", "10", "x-ratelimit-reset", "30")
+	err := investgo.NewAPIError(grpcErr, md)
+
+	apiErr, ok := investgo.AsAPIError(fmt.Errorf("get instrument: %w", err))
+	if !ok {
+		t.Fatalf("not api error: %v", err)
+	}
+	if apiErr.Code != codes.NotFound || apiErr.APICode != investgo.ErrCodeInstrumentNotFound || apiErr.Message != "instrument not found" ||
+		apiErr.TrackingId != "abc" || apiErr.RateLimitRemaining != 10 || apiErr.RateLimitReset != 30*time.Second {
+		t.Fatalf("api error = %+v", apiErr)
+	}
+	// исходная ошибка grpc доступна через errors.Is и status
+	if !errors.Is(err, grpcErr) || status.Code(err) != codes.NotFound || status.Convert(err).Message() != "50002" {
+		t.Fatalf("unwrap: %v, %v", errors.Is(err, grpcErr), status.Code(err))
+	}
+	if got := err.Error(); got != "api error: code = NotFound, api code = 50002, message = instrument not found, tracking id = abc" {
+		t.Fatalf("error = %v", got)
+	}
+	// повторный перевод не оборачивает APIError еще раз
+	if again := investgo.NewAPIError(err, nil); again != err {
+		t.Fatalf("again = %v", again)
+	}
+
+	// без заголовка message описание берется по коду ошибки, для неизвестного кода - из сообщения grpc
+	apiErr, _ = investgo.AsAPIError(investgo.NewAPIError(status.Error(codes.InvalidArgument, "30034"), nil))
+	if apiErr.Message != investgo.APIErrorCodes[investgo.ErrCodeNotEnoughBalance] || apiErr.RateLimitRemaining != -1 {
+		t.Fatalf("api error = %+v", apiErr)
+	}
+	apiErr, _ = investgo.AsAPIError(investgo.NewAPIError(status.Error(codes.Internal, "oops"), nil))
+	if apiErr.APICode != 0 || apiErr.Message != "oops" {
+		t.Fatalf("api error = %+v", apiErr)
+	}
+
+	// ошибки не grpc не меняются
+	if err := investgo.NewAPIError(io.EOF, nil); err != io.EOF {
+		t.Fatalf("eof = %v", err)
+	}
+	if err := investgo.NewAPIError(nil, nil); err != nil {
+		t.Fatalf("nil = %v", err)
+	}
+}
+
+func TestAPIErrorPredicates(t *testing.T) {
+	apiErr := func(code codes.Code, apiCode int) error {
+		return fmt.Errorf("wrapped: %w", investgo.NewAPIError(status.Error(code, fmt.Sprint(apiCode)), nil))
+	}
+	predicates := map[string]func(error) bool{
+		"balance":      investgo.IsInsufficientBalance,
+		"not tradable": investgo.IsInstrumentNotTradable,
+		"order":        investgo.IsOrderNotFound,
+		"instrument":   investgo.IsInstrumentNotFound,
+		"rate limit":   investgo.IsRateLimitExceeded,
+		"auth":         investgo.IsUnauthenticated,
+	}
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{err: apiErr(codes.InvalidArgument, investgo.ErrCodeNotEnoughBalance), want: "balance"},
+		{err: apiErr(codes.InvalidArgument, investgo.ErrCodeNotEnoughAssets), want: "balance"},
+		{err: apiErr(codes.FailedPrecondition, investgo.ErrCodeInstrumentNotTradable), want: "not tradable"},
+		{err: apiErr(codes.FailedPrecondition, investgo.ErrCodeInstrumentForbiddenForAPI), want: "not tradable"},
+		{err: apiErr(codes.NotFound, investgo.ErrCodeOrderNotFound), want: "order"},
+		{err: apiErr(codes.NotFound, investgo.ErrCodeStopOrderNotFound), want: "order"},
+		{err: apiErr(codes.NotFound, investgo.ErrCodeInstrumentNotFound), want: "instrument"},
+		{err: apiErr(codes.Internal, investgo.ErrCodeRateLimitExceeded), want: "rate limit"},
+		// по коду grpc без кода API
+		{err: status.Error(codes.ResourceExhausted, ""), want: "rate limit"},
+		{err: apiErr(codes.Internal, investgo.ErrCodeTokenNotFound), want: "auth"},
+		{err: apiErr(codes.Internal, investgo.ErrCodeInsufficientPrivileges), want: "auth"},
+		{err: status.Error(codes.PermissionDenied, ""), want: "auth"},
+		{err: apiErr(codes.Internal, 70001), want: ""},
+		{err: errors.New("30034"), want: ""},
+		{err: nil, want: ""},
+	}
+	for _, tc := range tests {
+		for name, is := range predicates {
+			if got := is(tc.err); got != (name == tc.want) {
+				t.Errorf("%v(%v) = %v", name, tc.err, got)
+			}
+		}
+	}
+	if !investgo.IsAPIErrorCode(apiErr(codes.Internal, 70001), 1, 70001) || investgo.IsAPIErrorCode(apiErr(codes.Internal, 70001)) {
+		t.Fatal("IsAPIErrorCode")
+	}
+}
+
+func TestAPIErrorFromServer(t *testing.T) {
+	srv, client, uids := newTestServer(t, 1)
+
+	// unary запрос: код и описание ошибки, идентификатор запроса из трейлера
+	_, err := client.NewInstrumentsServiceClient().InstrumentByUid("unknown")
+	apiErr, ok := investgo.AsAPIError(err)
+	if !ok || !investgo.IsInstrumentNotFound(err) || apiErr.Message != "Инструмент не найден" || apiErr.TrackingId == "" {
+		t.Fatalf("instrument: %#v", err)
+	}
+	_, err = client.NewOrdersServiceClient().CancelOrder(investgotest.DefaultAccountId, "unknown", nil)
+	if !investgo.IsOrderNotFound(err) || status.Code(err) != codes.NotFound {
+		t.Fatalf("cancel order: %v", err)
+	}
+	_, err = client.NewOrdersServiceClient().PostOrder(limitOrder(uids[0], 1, 100))
+	if !investgo.IsInsufficientBalance(err) {
+		t.Fatalf("post order: %v", err)
+	}
+
+	// стрим: описание ошибки берется по коду, сервер не передает его в трейлере стрима
+	stream, err := client.NewMarketDataStreamClient().MarketDataStream()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Stop()
+	srv.SetToken("rotated")
+	err = stream.Listen()
+	if apiErr, ok := investgo.AsAPIError(err); !ok || !investgo.IsUnauthenticated(err) || apiErr.Message != investgo.APIErrorCodes[investgo.ErrCodeTokenNotFound] {
+		t.Fatalf("stream: %v", err)
+	}
+}
@@ -0,0 +1,124 @@
+package investgo
+
+import (
+	"sort"
+	"time"
+
+	pb "github.com/russianinvestments/invest-api-go-sdk/proto"
+)
+
+// CandleStore - локальное хранилище исторических свечей. Реализации для CSV, Parquet и SQLite находятся в пакете
+// candlestore. Хранилище запоминает интервалы, за которые свечи загружены полностью, поэтому загрузчик свечей
+// запрашивает у сервера только недостающие интервалы
+type CandleStore interface {
+	// Save - Сохранение свечей инструмента, загруженных за интервал r полностью
+	Save(instrument string, interval pb.CandleInterval, r CandleRange, candles []*pb.HistoricCandle) error
+	// Ranges - Интервалы, за которые свечи инструмента уже сохранены
+	Ranges(instrument string, interval pb.CandleInterval) ([]CandleRange, error)
+	// Load - Сохраненные свечи инструмента за [from, to) по возрастанию времени
+	Load(instrument string, interval pb.CandleInterval, from, to time.Time) ([]*pb.HistoricCandle, error)
+	// Close - Закрытие хранилища
+	Close() error
+}
+
+// CandleRange - интервал времени [From, To)
+type CandleRange struct {
+	From time.Time
+	To   time.Time
+}
+
+// MergeRanges - Объединение пересекающихся и соседних интервалов, результат отсортирован по времени
+func MergeRanges(ranges []CandleRange) []CandleRange {
+	sorted := make([]CandleRange, 0, len(ranges))
+	for _, r := range ranges {
+		if r.To.After(r.From) {
+			sorted = append(sorted, r)
+		}
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].From.Before(sorted[j].From)
+	})
+	merged := make([]CandleRange, 0, len(sorted))
+	for _, r := range sorted {
+		if n := len(merged); n > 0 && !r.From.After(merged[n-1].To) {
+			if r.To.After(merged[n-1].To) {
+				merged[n-1].To = r.To
+			}
+			continue
+		}
+		merged = append(merged, r)
+	}
+	return merged
+}
+
+// planChunks - деление периода [from, to) на части не длиннее d: интервалы, которые уже есть в хранилище,
+// помечаются как локальные, остальные загружаются с сервера
+func planChunks(from, to time.Time, d time.Duration, stored []CandleRange) []candlesChunk {
+	chunks := make([]candlesChunk, 0)
+	add := func(r CandleRange, local bool) {
+		for _, c := range splitRange(r.From, r.To, d) {
+			c.local = local
+			chunks = append(chunks, c)
+		}
+	}
+	cur := from
+	for _, r := range MergeRanges(stored) {
+		if !r.To.After(cur) {
+			continue
+		}
+		if !r.From.Before(to) {
+			break
+		}
+		if r.From.After(cur) {
+			add(CandleRange{From: cur, To: r.From}, false)
+			cur = r.From
+		}
+		end := r.To
+		if end.After(to) {
+			end = to
+		}
+		add(CandleRange{From: cur, To: end}, true)
+		cur = end
+	}
+	if cur.Before(to) {
+		add(CandleRange{From: cur, To: to}, false)
+	}
+	return chunks
+}
+
+// completeCandles - свечи части периода для сохранения: только свечи из [from, to) до первой незавершенной свечи.
+// Возвращает сохраняемый интервал, который заканчивается на первой незавершенной свече и не позже начала текущей
+// свечи интервала interval: по текущему и будущему периоду свечи еще могут появиться, даже если сервер их не вернул
+func completeCandles(c candlesChunk, interval pb.CandleInterval, now time.Time) (CandleRange, []*pb.HistoricCandle) {
+	r := CandleRange{From: c.from, To: c.to}
+	if current := candleStart(now, interval); r.To.After(current) {
+		r.To = current
+	}
+	res := make([]*pb.HistoricCandle, 0, len(c.candles))
+	for _, candle := range c.candles {
+		t := candle.GetTime().AsTime()
+		if t.Before(c.from) || !t.Before(r.To) {
+			continue
+		}
+		if !candle.GetIsComplete() {
+			r.To = t
+			break
+		}
+		res = append(res, candle)
+	}
+	if r.To.Before(r.From) {
+		r.To = r.From
+	}
+	return r, res
+}
+
+// candleStart - начало свечи интервала interval, в которую попадает t. Дневные и более длинные свечи
+// начинаются в полночь UTC, недельные - в понедельник, месячные - первого числа
+func candleStart(t time.Time, interval pb.CandleInterval) time.Time {
+	t = t.UTC()
+	if interval == pb.CandleInterval_CANDLE_INTERVAL_MONTH {
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	}
+	// нулевое время в Go - понедельник, полночь UTC, поэтому Truncate выравнивает и недельные свечи
+	return t.Truncate(candleStep(interval))
+}
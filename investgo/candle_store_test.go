@@ -0,0 +1,41 @@
+package investgo_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/russianinvestments/invest-api-go-sdk/investgo"
+)
+
+func TestMergeRanges(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	r := func(from, to int) investgo.CandleRange {
+		return investgo.CandleRange{From: start.Add(time.Duration(from) * time.Hour), To: start.Add(time.Duration(to) * time.Hour)}
+	}
+	tests := []struct {
+		name   string
+		ranges []investgo.CandleRange
+		want   []investgo.CandleRange
+	}{
+		{name: "empty", ranges: nil, want: nil},
+		{name: "disjoint unsorted", ranges: []investgo.CandleRange{r(5, 6), r(0, 1), r(2, 3)}, want: []investgo.CandleRange{r(0, 1), r(2, 3), r(5, 6)}},
+		{name: "adjacent", ranges: []investgo.CandleRange{r(1, 2), r(0, 1), r(2, 4)}, want: []investgo.CandleRange{r(0, 4)}},
+		{name: "overlapping", ranges: []investgo.CandleRange{r(0, 3), r(2, 5), r(7, 9), r(8, 10)}, want: []investgo.CandleRange{r(0, 5), r(7, 10)}},
+		{name: "nested", ranges: []investgo.CandleRange{r(0, 10), r(2, 3), r(0, 1)}, want: []investgo.CandleRange{r(0, 10)}},
+		// пустые и перевернутые интервалы не сохраняются и не склеивают соседние
+		{name: "empty ranges", ranges: []investgo.CandleRange{r(0, 1), r(1, 1), r(3, 2), r(2, 3)}, want: []investgo.CandleRange{r(0, 1), r(2, 3)}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := investgo.MergeRanges(tc.ranges)
+			if len(got) != len(tc.want) {
+				t.Fatalf("merged = %v, want %v", got, tc.want)
+			}
+			for i := range got {
+				if !got[i].From.Equal(tc.want[i].From) || !got[i].To.Equal(tc.want[i].To) {
+					t.Fatalf("merged = %v, want %v", got, tc.want)
+				}
+			}
+		})
+	}
+}
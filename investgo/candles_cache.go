@@ -0,0 +1,356 @@
+package investgo
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/russianinvestments/invest-api-go-sdk/proto"
+)
+
+// cacheDayLayout - имя файла дня в кэше свечей
+const cacheDayLayout = "2006-01-02"
+
+// CandlesCacheConfig - настройки кэша свечей
+type CandlesCacheConfig struct {
+	// Dir - директория кэша
+	Dir string
+	// MaxSize - максимальный размер кэша в байтах, при превышении удаляются дни, которые дольше всего не запрашивались.
+	// 0 - без ограничения
+	MaxSize int64
+	// TTL - время жизни дня в кэше, после которого свечи за этот день запрашиваются заново. 0 - без ограничения
+	TTL time.Duration
+}
+
+// CandlesCache - локальный кэш исторических свечей для MarketDataServiceClient.GetCandles. Свечи хранятся на диске
+// по одному файлу на инструмент, интервал, источник и день (по UTC). В кэш попадают только завершенные дни,
+// поэтому недостающие дни и текущий день всегда запрашиваются у сервера. Недельные и месячные свечи не кэшируются
+type CandlesCache struct {
+	conf CandlesCacheConfig
+
+	mu    sync.Mutex
+	files map[string]*cacheFile
+	size  int64
+}
+
+// cacheFile - файл дня в кэше
+type cacheFile struct {
+	size    int64
+	written time.Time
+	used    time.Time
+}
+
+// NewCandlesCache - Создание кэша свечей, уже сохраненные в директории дни используются повторно
+func NewCandlesCache(conf CandlesCacheConfig) (*CandlesCache, error) {
+	if conf.Dir == "" {
+		return nil, errors.New("candles cache dir is empty")
+	}
+	if err := os.MkdirAll(conf.Dir, 0o755); err != nil {
+		return nil, err
+	}
+	c := &CandlesCache{
+		conf:  conf,
+		files: make(map[string]*cacheFile),
+	}
+	err := filepath.WalkDir(conf.Dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || filepath.Ext(path) != ".pb" {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		c.files[path] = &cacheFile{size: info.Size(), written: info.ModTime(), used: info.ModTime()}
+		c.size += info.Size()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c, c.evictLocked()
+}
+
+// Size - Текущий размер кэша в байтах
+func (c *CandlesCache) Size() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.size
+}
+
+// Invalidate - Удаление из кэша дней инструмента, пересекающихся с [from, to), для всех интервалов и источников
+func (c *CandlesCache) Invalidate(instrument string, from, to time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	prefix := filepath.Join(c.conf.Dir, cacheDir(instrument)) + string(filepath.Separator)
+	var errs []error
+	for path := range c.files {
+		if !strings.HasPrefix(path, prefix) {
+			continue
+		}
+		day, err := time.Parse(cacheDayLayout, strings.TrimSuffix(filepath.Base(path), ".pb"))
+		if err != nil || !day.Before(to) || !day.Add(DAY).After(from) {
+			continue
+		}
+		errs = append(errs, c.removeLocked(path))
+	}
+	return errors.Join(errs...)
+}
+
+// InvalidateInstrument - Удаление из кэша всех свечей инструмента
+func (c *CandlesCache) InvalidateInstrument(instrument string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	prefix := filepath.Join(c.conf.Dir, cacheDir(instrument)) + string(filepath.Separator)
+	var errs []error
+	for path := range c.files {
+		if strings.HasPrefix(path, prefix) {
+			errs = append(errs, c.removeLocked(path))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Clear - Удаление всех свечей из кэша
+func (c *CandlesCache) Clear() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var errs []error
+	for path := range c.files {
+		errs = append(errs, c.removeLocked(path))
+	}
+	return errors.Join(errs...)
+}
+
+// cacheable - свечи интервала умещаются в сутки и могут храниться по дням
+func cacheable(interval pb.CandleInterval) bool {
+	switch interval {
+	case pb.CandleInterval_CANDLE_INTERVAL_WEEK, pb.CandleInterval_CANDLE_INTERVAL_MONTH,
+		pb.CandleInterval_CANDLE_INTERVAL_UNSPECIFIED:
+		return false
+	}
+	return true
+}
+
+// cacheDir - имя директории инструмента в кэше. Идентификатор экранируется, чтобы "/", ".." и символы, недопустимые
+// в именах файлов, не выводили за пределы директории кэша. uid, figi и тикеры без спецсимволов не меняются
+func cacheDir(instrument string) string {
+	return strings.ReplaceAll(url.QueryEscape(instrument), ".", "%2E")
+}
+
+func (c *CandlesCache) path(instrument string, interval pb.CandleInterval, source pb.GetCandlesRequest_CandleSource, day time.Time) string {
+	return filepath.Join(c.conf.Dir, cacheDir(instrument),
+		strings.ToLower(strings.TrimPrefix(interval.String(), "CANDLE_INTERVAL_")),
+		strings.ToLower(strings.TrimPrefix(source.String(), "CANDLE_SOURCE_")),
+		day.Format(cacheDayLayout)+".pb")
+}
+
+// load - свечи дня из кэша, ok = false, если дня нет в кэше или он устарел
+func (c *CandlesCache) load(path string) ([]*pb.HistoricCandle, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	f, ok := c.files[path]
+	if !ok {
+		return nil, false
+	}
+	if c.conf.TTL > 0 && time.Since(f.written) > c.conf.TTL {
+		_ = c.removeLocked(path)
+		return nil, false
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		_ = c.removeLocked(path)
+		return nil, false
+	}
+	day := &pb.GetCandlesResponse{}
+	if err := proto.Unmarshal(data, day); err != nil {
+		_ = c.removeLocked(path)
+		return nil, false
+	}
+	f.used = time.Now()
+	return day.GetCandles(), true
+}
+
+// store - сохранение свечей завершенного дня
+func (c *CandlesCache) store(path string, candles []*pb.HistoricCandle) error {
+	data, err := proto.Marshal(&pb.GetCandlesResponse{Candles: candles})
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	// запись во временный файл, чтобы в кэше не оказалось недописанного дня
+	if err := os.WriteFile(path+".tmp", data, 0o644); err != nil {
+		return err
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if f, ok := c.files[path]; ok {
+		c.size -= f.size
+	}
+	now := time.Now()
+	c.files[path] = &cacheFile{size: int64(len(data)), written: now, used: now}
+	c.size += int64(len(data))
+	return c.evictLocked()
+}
+
+// evictLocked - удаление дней, которые дольше всего не запрашивались, пока размер кэша больше MaxSize
+func (c *CandlesCache) evictLocked() error {
+	if c.conf.MaxSize <= 0 || c.size <= c.conf.MaxSize {
+		return nil
+	}
+	paths := make([]string, 0, len(c.files))
+	for path := range c.files {
+		paths = append(paths, path)
+	}
+	sort.Slice(paths, func(i, j int) bool {
+		return c.files[paths[i]].used.Before(c.files[paths[j]].used)
+	})
+	for _, path := range paths {
+		if c.size <= c.conf.MaxSize {
+			break
+		}
+		if err := c.removeLocked(path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *CandlesCache) removeLocked(path string) error {
+	f, ok := c.files[path]
+	if !ok {
+		return nil
+	}
+	delete(c.files, path)
+	c.size -= f.size
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// SetCandlesCache - Включение кэша свечей для GetCandles и методов, которые его используют (GetHistoricCandles,
+// DownloadCandles). Запросы с limit не кэшируются. Метод нужно вызвать до начала использования клиента, nil отключает кэш
+func (md *MarketDataServiceClient) SetCandlesCache(cache *CandlesCache) {
+	md.cache = cache
+}
+
+// cachedCandles - GetCandles с кэшем: завершенные дни читаются из кэша или загружаются целиком и сохраняются,
+// текущий день запрашивается без кэша
+func (md *MarketDataServiceClient) cachedCandles(
+	ctx context.Context,
+	instrumentId string,
+	interval pb.CandleInterval,
+	from, to time.Time,
+	source pb.GetCandlesRequest_CandleSource,
+) (*GetCandlesResponse, error) {
+	from, to = from.UTC(), to.UTC()
+	today := time.Now().UTC().Truncate(DAY)
+	candles := make([]*pb.HistoricCandle, 0)
+	var header metadata.MD
+
+	// дни, которых нет в кэше, загружаются частями не длиннее максимального периода запроса
+	var missing []time.Time
+	flush := func() error {
+		if len(missing) == 0 {
+			return nil
+		}
+		perRequest := int(selectDuration(interval) / DAY)
+		if perRequest < 1 {
+			perRequest = 1
+		}
+		for len(missing) > 0 {
+			n := perRequest
+			if n > len(missing) {
+				n = len(missing)
+			}
+			days := missing[:n]
+			missing = missing[n:]
+			resp, err := md.getCandles(ctx, instrumentId, interval, days[0], days[n-1].Add(DAY), source, 0)
+			header = resp.Header
+			if err != nil {
+				return err
+			}
+			byDay := make(map[time.Time][]*pb.HistoricCandle, n)
+			complete := make(map[time.Time]bool, n)
+			for _, day := range days {
+				complete[day] = true
+			}
+			for _, candle := range resp.GetCandles() {
+				day := candle.GetTime().AsTime().UTC().Truncate(DAY)
+				byDay[day] = append(byDay[day], candle)
+				if !candle.GetIsComplete() {
+					complete[day] = false
+				}
+			}
+			for _, day := range days {
+				if complete[day] {
+					if err := md.cache.store(md.cache.path(instrumentId, interval, source, day), byDay[day]); err != nil {
+						md.logger.Errorf("candles cache: %v", err.Error())
+					}
+				}
+				candles = append(candles, byDay[day]...)
+			}
+		}
+		return nil
+	}
+
+	for day := from.Truncate(DAY); day.Before(to); day = day.Add(DAY) {
+		if !day.Before(today) {
+			if err := flush(); err != nil {
+				return &GetCandlesResponse{Header: header}, err
+			}
+			start := day
+			if start.Before(from) {
+				start = from
+			}
+			resp, err := md.getCandles(ctx, instrumentId, interval, start, to, source, 0)
+			header = resp.Header
+			if err != nil {
+				return &GetCandlesResponse{Header: header}, err
+			}
+			candles = append(candles, resp.GetCandles()...)
+			break
+		}
+		if cached, ok := md.cache.load(md.cache.path(instrumentId, interval, source, day)); ok {
+			if err := flush(); err != nil {
+				return &GetCandlesResponse{Header: header}, err
+			}
+			candles = append(candles, cached...)
+			continue
+		}
+		missing = append(missing, day)
+	}
+	if err := flush(); err != nil {
+		return &GetCandlesResponse{Header: header}, err
+	}
+
+	res := make([]*pb.HistoricCandle, 0, len(candles))
+	for _, candle := range candles {
+		t := candle.GetTime().AsTime()
+		if !t.Before(from) && t.Before(to) {
+			res = append(res, candle)
+		}
+	}
+	return &GetCandlesResponse{
+		GetCandlesResponse: &pb.GetCandlesResponse{Candles: res},
+		Header:             header,
+	}, nil
+}
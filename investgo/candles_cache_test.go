@@ -0,0 +1,182 @@
+package investgo_test
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/russianinvestments/invest-api-go-sdk/investgo"
+	"github.com/russianinvestments/invest-api-go-sdk/investgotest"
+	pb "github.com/russianinvestments/invest-api-go-sdk/proto"
+)
+
+// cacheClient - клиент сервиса котировок с кэшем свечей в dir и часовые свечи инструмента с понедельника по среду
+func cacheClient(t *testing.T, dir string, conf investgo.CandlesCacheConfig) (*investgotest.Server, *investgo.MarketDataServiceClient, *investgo.CandlesCache, string) {
+	t.Helper()
+	srv, client, uids := newTestServer(t, 1)
+	if err := srv.AddCandles(uids[0], pb.CandleInterval_CANDLE_INTERVAL_HOUR, hourCandles([]int{0, 1, 2})...); err != nil {
+		t.Fatal(err)
+	}
+	conf.Dir = dir
+	cache, err := investgo.NewCandlesCache(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	md := client.NewMarketDataServiceClient()
+	md.SetCandlesCache(cache)
+	return srv, md, cache, uids[0]
+}
+
+func getCandles(t *testing.T, md *investgo.MarketDataServiceClient, id string, from, to time.Time) int {
+	t.Helper()
+	resp, err := md.GetCandles(id, pb.CandleInterval_CANDLE_INTERVAL_HOUR, from, to, pb.GetCandlesRequest_CANDLE_SOURCE_UNSPECIFIED, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return len(resp.GetCandles())
+}
+
+// cachedDays - дни в файлах кэша
+func cachedDays(t *testing.T, dir string) []string {
+	t.Helper()
+	var days []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			days = append(days, strings.TrimSuffix(filepath.Base(path), ".pb"))
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return days
+}
+
+// extraCandle - новая свеча дня day, которая видна только при запросе к серверу
+func extraCandle(t *testing.T, srv *investgotest.Server, uid string, day int) {
+	t.Helper()
+	c := hourCandles([]int{day})[0]
+	c.Time.Seconds -= 3600
+	if err := srv.AddCandles(uid, pb.CandleInterval_CANDLE_INTERVAL_HOUR, c); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCandlesCache(t *testing.T) {
+	dir := t.TempDir()
+	srv, md, cache, uid := cacheClient(t, dir, investgo.CandlesCacheConfig{})
+	from, to := monday, monday.AddDate(0, 0, 3)
+	if n := getCandles(t, md, uid, from, to); n != 27 {
+		t.Fatalf("candles = %v", n)
+	}
+	if days := cachedDays(t, dir); len(days) != 3 || cache.Size() == 0 {
+		t.Fatalf("cached days = %v, size = %v", days, cache.Size())
+	}
+
+	// повторный запрос читается из кэша и не видит новых свечей сервера
+	extraCandle(t, srv, uid, 1)
+	if n := getCandles(t, md, uid, from, to); n != 27 {
+		t.Fatalf("cached candles = %v", n)
+	}
+	// кэш с той же директорией использует сохраненные дни
+	reopened, err := investgo.NewCandlesCache(investgo.CandlesCacheConfig{Dir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reopened.Size() != cache.Size() {
+		t.Fatalf("reopened size = %v, want %v", reopened.Size(), cache.Size())
+	}
+
+	// после инвалидации дня он запрашивается заново
+	if err := cache.Invalidate(uid, monday.AddDate(0, 0, 1).Add(time.Hour), monday.AddDate(0, 0, 1).Add(2*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if days := cachedDays(t, dir); len(days) != 2 {
+		t.Fatalf("cached days after invalidate = %v", days)
+	}
+	if n := getCandles(t, md, uid, from, to); n != 28 {
+		t.Fatalf("candles after invalidate = %v", n)
+	}
+
+	if err := cache.InvalidateInstrument(uid); err != nil {
+		t.Fatal(err)
+	}
+	if days := cachedDays(t, dir); len(days) != 0 || cache.Size() != 0 {
+		t.Fatalf("cached days after instrument invalidate = %v, size = %v", days, cache.Size())
+	}
+	getCandles(t, md, uid, from, to)
+	if err := cache.Clear(); err != nil {
+		t.Fatal(err)
+	}
+	if days := cachedDays(t, dir); len(days) != 0 || cache.Size() != 0 {
+		t.Fatalf("cached days after clear = %v, size = %v", days, cache.Size())
+	}
+}
+
+func TestCandlesCacheTTL(t *testing.T) {
+	srv, md, _, uid := cacheClient(t, t.TempDir(), investgo.CandlesCacheConfig{TTL: time.Millisecond})
+	from, to := monday, monday.AddDate(0, 0, 1)
+	getCandles(t, md, uid, from, to)
+	extraCandle(t, srv, uid, 0)
+	time.Sleep(5 * time.Millisecond)
+	// устаревший день запрашивается у сервера
+	if n := getCandles(t, md, uid, from, to); n != 10 {
+		t.Fatalf("candles after ttl = %v", n)
+	}
+}
+
+func TestCandlesCacheMaxSize(t *testing.T) {
+	dir := t.TempDir()
+	_, md, cache, uid := cacheClient(t, dir, investgo.CandlesCacheConfig{})
+	// у свечей среды самый большой размер
+	getCandles(t, md, uid, monday.AddDate(0, 0, 2), monday.AddDate(0, 0, 3))
+	daySize := cache.Size()
+
+	// в кэш помещаются два дня, вытесняется день, который дольше всего не запрашивался
+	dir = t.TempDir()
+	_, md, cache, uid = cacheClient(t, dir, investgo.CandlesCacheConfig{MaxSize: 2 * daySize})
+	getCandles(t, md, uid, monday, monday.AddDate(0, 0, 2))
+	getCandles(t, md, uid, monday, monday.AddDate(0, 0, 1))
+	getCandles(t, md, uid, monday.AddDate(0, 0, 2), monday.AddDate(0, 0, 3))
+	days := cachedDays(t, dir)
+	if len(days) != 2 || days[0] != "2024-03-04" || days[1] != "2024-03-06" || cache.Size() > 2*daySize {
+		t.Fatalf("cached days = %v, size = %v", days, cache.Size())
+	}
+}
+
+func TestCandlesCachePath(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "a", "cache")
+	srv, md, cache, _ := cacheClient(t, dir, investgo.CandlesCacheConfig{})
+	// идентификатор с "/" и ".." не выводит файлы за пределы директории кэша
+	for _, id := range []string{"../../escape", "..", "a/../../b"} {
+		uid := srv.AddShare(&pb.Share{Figi: id, Ticker: "ESC", ClassCode: "TQBR"})
+		if err := srv.AddCandles(uid, pb.CandleInterval_CANDLE_INTERVAL_HOUR, hourCandles([]int{0})...); err != nil {
+			t.Fatal(err)
+		}
+		if n := getCandles(t, md, id, monday, monday.AddDate(0, 0, 1)); n != 9 {
+			t.Fatalf("%v: candles = %v", id, n)
+		}
+	}
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() && !strings.HasPrefix(path, dir+string(filepath.Separator)) {
+			t.Errorf("file outside cache: %v", path)
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cache.InvalidateInstrument(".."); err != nil {
+		t.Fatal(err)
+	}
+	if days := cachedDays(t, dir); len(days) != 2 {
+		t.Fatalf("cached days = %v", days)
+	}
+	if _, err := os.Stat(dir); err != nil {
+		t.Fatal(err)
+	}
+}
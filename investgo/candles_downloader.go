@@ -0,0 +1,413 @@
+package investgo
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	pb "github.com/russianinvestments/invest-api-go-sdk/proto"
+)
+
+const (
+	// DefaultDownloadWorkers - количество одновременных запросов загрузчика свечей по умолчанию
+	DefaultDownloadWorkers = 4
+	// DefaultCandlesRequestsPerMinute - ограничение запросов GetCandles в минуту для загрузчика свечей по умолчанию
+	DefaultCandlesRequestsPerMinute = 299
+	// scheduleWindow - период, за который загружается торговый календарь для поиска пропусков
+	scheduleWindow = DAY * 7
+)
+
+// CandlesCheckpoint - точка, с которой можно продолжить загрузку свечей после ошибки. Структуру можно сохранить,
+// например, в json и передать в DownloadCandlesRequest.Checkpoint при следующем запуске
+type CandlesCheckpoint struct {
+	Instrument string            `json:"instrument"`
+	Interval   pb.CandleInterval `json:"interval"`
+	// From - начало интервала, с которого нужно продолжить загрузку
+	From time.Time `json:"from"`
+	// LastCandle - время последней полученной свечи, свечи не позже этого времени при продолжении пропускаются
+	LastCandle time.Time `json:"last_candle"`
+}
+
+// CandlesGap - интервал торгового времени по календарю биржи, за который нет ни одной свечи
+type CandlesGap struct {
+	From time.Time
+	To   time.Time
+}
+
+// CandlesIterator - итератор по загружаемым свечам. Свечи возвращаются по возрастанию времени без повторов.
+// После завершения работы с итератором нужно вызвать Close
+type CandlesIterator struct {
+	md     *MarketDataServiceClient
+	req    DownloadCandlesRequest
+	ctx    context.Context
+	cancel context.CancelFunc
+	order  chan chan candlesChunk
+
+	chunk      candlesChunk
+	pos        int
+	candle     *pb.HistoricCandle
+	err        error
+	done       bool
+	checkpoint CandlesCheckpoint
+
+	step     time.Duration
+	calendar *tradingCalendar
+	// gapFrom - время, с которого еще не было свечей
+	gapFrom time.Time
+
+	mu   sync.Mutex
+	gaps []CandlesGap
+}
+
+// candlesChunk - часть интервала загрузки, которая запрашивается одним запросом
+type candlesChunk struct {
+	from, to time.Time
+	// local - свечи части периода читаются из хранилища
+	local   bool
+	candles []*pb.HistoricCandle
+	err     error
+}
+
+// DownloadCandles - Метод загрузки исторических свечей за любой период. Период делится на части, которые
+// загружаются параллельно с ограничением количества запросов в минуту, а свечи возвращаются итератором по мере загрузки.
+// Если указано хранилище Store, то загруженные свечи сохраняются в него, а уже сохраненные интервалы читаются из него
+func (md *MarketDataServiceClient) DownloadCandles(req *DownloadCandlesRequest) *CandlesIterator {
+	return md.DownloadCandlesCtx(md.ctx, req)
+}
+
+// DownloadCandlesCtx - то же, что и DownloadCandles, но с контекстом запроса ctx
+func (md *MarketDataServiceClient) DownloadCandlesCtx(ctx context.Context, req *DownloadCandlesRequest) *CandlesIterator {
+	ctx, cancel := context.WithCancel(ctx)
+	it := &CandlesIterator{
+		md:     md,
+		req:    *req,
+		ctx:    ctx,
+		cancel: cancel,
+	}
+	if it.req.Interval == pb.CandleInterval_CANDLE_INTERVAL_UNSPECIFIED {
+		it.req.Interval = pb.CandleInterval_CANDLE_INTERVAL_HOUR
+	}
+	if it.req.Workers < 1 {
+		it.req.Workers = DefaultDownloadWorkers
+	}
+	if it.req.RequestsPerMinute < 1 {
+		it.req.RequestsPerMinute = DefaultCandlesRequestsPerMinute
+	}
+	it.step = candleStep(it.req.Interval)
+	it.checkpoint = CandlesCheckpoint{
+		Instrument: it.req.Instrument,
+		Interval:   it.req.Interval,
+		From:       it.req.From,
+	}
+	if cp := it.req.Checkpoint; cp != nil {
+		if cp.Instrument != it.req.Instrument || cp.Interval != it.req.Interval {
+			it.finish(fmt.Errorf("checkpoint for %v %v does not match request", cp.Instrument, cp.Interval))
+			return it
+		}
+		it.checkpoint = *cp
+	}
+	it.gapFrom = it.checkpoint.From
+	if !it.checkpoint.LastCandle.IsZero() {
+		it.gapFrom = it.checkpoint.LastCandle.Add(it.step)
+	}
+	if it.req.Exchange != "" {
+		it.calendar = &tradingCalendar{
+			instruments: md.instrumentsClient(),
+			exchange:    it.req.Exchange,
+		}
+	}
+
+	var stored []CandleRange
+	if it.req.Store != nil {
+		var err error
+		stored, err = it.req.Store.Ranges(it.req.Instrument, it.req.Interval)
+		if err != nil {
+			it.finish(err)
+			return it
+		}
+	}
+
+	it.order = make(chan chan candlesChunk, it.req.Workers-1)
+	go it.dispatch(planChunks(it.checkpoint.From, it.req.To, selectDuration(it.req.Interval), stored))
+	return it
+}
+
+// dispatch - запуск загрузки частей периода, не больше Workers одновременно. Результаты передаются в порядке частей
+func (it *CandlesIterator) dispatch(chunks []candlesChunk) {
+	defer close(it.order)
+	limiter := newRequestLimiter(time.Minute / time.Duration(it.req.RequestsPerMinute))
+	for _, c := range chunks {
+		res := make(chan candlesChunk, 1)
+		select {
+		case it.order <- res:
+		case <-it.ctx.Done():
+			return
+		}
+		if c.local {
+			c.candles, c.err = it.req.Store.Load(it.req.Instrument, it.req.Interval, c.from, c.to)
+			res <- c
+			continue
+		}
+		go func(c candlesChunk) {
+			if err := limiter.wait(it.ctx); err != nil {
+				c.err = err
+				res <- c
+				return
+			}
+			resp, err := it.md.GetCandlesCtx(it.ctx, it.req.Instrument, it.req.Interval, c.from, c.to, it.req.Source, 0)
+			c.candles, c.err = resp.GetCandles(), err
+			res <- c
+		}(c)
+	}
+}
+
+// Next - Переход к следующей свече, возвращает false, если свечи закончились или произошла ошибка
+func (it *CandlesIterator) Next() bool {
+	for !it.done {
+		if it.pos < len(it.chunk.candles) {
+			c := it.chunk.candles[it.pos]
+			it.pos++
+			t := c.GetTime().AsTime()
+			last := it.checkpoint.LastCandle
+			// соседние части периода могут возвращать одну и ту же свечу
+			if !last.IsZero() && !t.After(last) {
+				continue
+			}
+			if err := it.detectGap(it.gapFrom, t); err != nil {
+				it.finish(err)
+				return false
+			}
+			it.gapFrom = t.Add(it.step)
+			it.candle = c
+			it.checkpoint.LastCandle = t
+			return true
+		}
+		if !it.chunk.to.IsZero() {
+			it.checkpoint.From = it.chunk.to
+		}
+
+		var res chan candlesChunk
+		var ok bool
+		select {
+		case res, ok = <-it.order:
+		case <-it.ctx.Done():
+			it.finish(it.ctx.Err())
+			return false
+		}
+		if !ok {
+			// после последней свечи до конца периода, но не позже текущего времени
+			to := it.req.To
+			if now := time.Now(); now.Before(to) {
+				to = now
+			}
+			it.finish(it.detectGap(it.gapFrom, to))
+			return false
+		}
+		chunk := <-res
+		if chunk.err != nil {
+			it.finish(chunk.err)
+			return false
+		}
+		if it.req.Store != nil && !chunk.local {
+			r, candles := completeCandles(chunk, it.req.Interval, time.Now())
+			// часть периода целиком в текущей свече нечего сохранять
+			if r.To.After(r.From) {
+				if err := it.req.Store.Save(it.req.Instrument, it.req.Interval, r, candles); err != nil {
+					it.finish(err)
+					return false
+				}
+			}
+		}
+		it.chunk = chunk
+		it.pos = 0
+	}
+	return false
+}
+
+// Candle - Текущая свеча
+func (it *CandlesIterator) Candle() *pb.HistoricCandle {
+	return it.candle
+}
+
+// Err - Ошибка, на которой остановилась загрузка, nil если все свечи получены
+func (it *CandlesIterator) Err() error {
+	return it.err
+}
+
+// Checkpoint - Точка продолжения загрузки: все свечи до нее уже получены через Next
+func (it *CandlesIterator) Checkpoint() CandlesCheckpoint {
+	return it.checkpoint
+}
+
+// Gaps - Найденные пропуски свечей в торговое время, если в запросе указана биржа. Пропуски ищутся между свечами,
+// от начала периода до первой свечи и, когда загружен весь период, от последней свечи до его конца
+func (it *CandlesIterator) Gaps() []CandlesGap {
+	it.mu.Lock()
+	defer it.mu.Unlock()
+	return append([]CandlesGap(nil), it.gaps...)
+}
+
+// Close - Остановка загрузки
+func (it *CandlesIterator) Close() {
+	if !it.done {
+		it.finish(nil)
+	}
+}
+
+func (it *CandlesIterator) finish(err error) {
+	it.done = true
+	it.err = err
+	it.candle = nil
+	it.cancel()
+}
+
+// detectGap - проверка интервала [from, to) без свечей по торговому календарю
+func (it *CandlesIterator) detectGap(from, to time.Time) error {
+	if it.calendar == nil || !to.After(from) {
+		return nil
+	}
+	trading, err := it.calendar.trading(it.ctx, from, to)
+	if err != nil {
+		return err
+	}
+	if trading {
+		it.mu.Lock()
+		it.gaps = append(it.gaps, CandlesGap{From: from, To: to})
+		it.mu.Unlock()
+	}
+	return nil
+}
+
+// splitRange - деление периода [from, to) на части длительностью не больше d
+func splitRange(from, to time.Time, d time.Duration) []candlesChunk {
+	chunks := make([]candlesChunk, 0)
+	for start := from; start.Before(to); start = start.Add(d) {
+		end := start.Add(d)
+		if end.After(to) {
+			end = to
+		}
+		chunks = append(chunks, candlesChunk{from: start, to: end})
+	}
+	return chunks
+}
+
+// candleStep - длительность свечи интервала
+func candleStep(interval pb.CandleInterval) time.Duration {
+	switch interval {
+	case pb.CandleInterval_CANDLE_INTERVAL_1_MIN:
+		return time.Minute
+	case pb.CandleInterval_CANDLE_INTERVAL_2_MIN:
+		return 2 * time.Minute
+	case pb.CandleInterval_CANDLE_INTERVAL_3_MIN:
+		return 3 * time.Minute
+	case pb.CandleInterval_CANDLE_INTERVAL_5_MIN:
+		return 5 * time.Minute
+	case pb.CandleInterval_CANDLE_INTERVAL_10_MIN:
+		return 10 * time.Minute
+	case pb.CandleInterval_CANDLE_INTERVAL_15_MIN:
+		return 15 * time.Minute
+	case pb.CandleInterval_CANDLE_INTERVAL_30_MIN:
+		return 30 * time.Minute
+	case pb.CandleInterval_CANDLE_INTERVAL_HOUR:
+		return time.Hour
+	case pb.CandleInterval_CANDLE_INTERVAL_2_HOUR:
+		return 2 * time.Hour
+	case pb.CandleInterval_CANDLE_INTERVAL_4_HOUR:
+		return 4 * time.Hour
+	case pb.CandleInterval_CANDLE_INTERVAL_DAY:
+		return DAY
+	case pb.CandleInterval_CANDLE_INTERVAL_WEEK:
+		return DAY * 7
+	case pb.CandleInterval_CANDLE_INTERVAL_MONTH:
+		return DAY * 31
+	}
+	return time.Hour
+}
+
+// requestLimiter - равномерное распределение запросов во времени
+type requestLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+func newRequestLimiter(interval time.Duration) *requestLimiter {
+	return &requestLimiter{interval: interval}
+}
+
+// wait - ожидание очереди на запрос
+func (l *requestLimiter) wait(ctx context.Context) error {
+	l.mu.Lock()
+	now := time.Now()
+	slot := l.next
+	if slot.Before(now) {
+		slot = now
+	}
+	l.next = slot.Add(l.interval)
+	l.mu.Unlock()
+
+	timer := time.NewTimer(slot.Sub(now))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// tradingCalendar - торговые сессии биржи, загружаются по мере необходимости
+type tradingCalendar struct {
+	instruments *InstrumentsServiceClient
+	exchange    string
+
+	loadedFrom time.Time
+	loadedTo   time.Time
+	sessions   []tradingSession
+}
+
+// tradingSession - торговое время дня [from, to)
+type tradingSession struct {
+	from, to time.Time
+}
+
+// trading - пересекается ли интервал [from, to) с торговыми сессиями
+func (c *tradingCalendar) trading(ctx context.Context, from, to time.Time) (bool, error) {
+	if err := c.load(ctx, from, to); err != nil {
+		return false, err
+	}
+	i := sort.Search(len(c.sessions), func(i int) bool {
+		return c.sessions[i].to.After(from)
+	})
+	return i < len(c.sessions) && c.sessions[i].from.Before(to), nil
+}
+
+// load - загрузка расписания, чтобы оно покрывало [from, to). Интервалы запрашиваются по возрастанию времени,
+// поэтому загруженное расписание продлевается, а если интервал начинается после него - загружается заново
+func (c *tradingCalendar) load(ctx context.Context, from, to time.Time) error {
+	if c.loadedTo.IsZero() || from.Before(c.loadedFrom) || from.After(c.loadedTo) {
+		c.loadedFrom, c.loadedTo, c.sessions = from, from, nil
+	}
+	for c.loadedTo.Before(to) {
+		end := c.loadedTo.Add(scheduleWindow)
+		resp, err := c.instruments.TradingSchedulesCtx(ctx, c.exchange, c.loadedTo, end)
+		if err != nil {
+			return fmt.Errorf("trading schedules: %w", err)
+		}
+		for _, schedule := range resp.GetExchanges() {
+			for _, day := range schedule.GetDays() {
+				if !day.GetIsTradingDay() || day.GetStartTime() == nil || day.GetEndTime() == nil {
+					continue
+				}
+				c.sessions = append(c.sessions, tradingSession{from: day.GetStartTime().AsTime(), to: day.GetEndTime().AsTime()})
+			}
+		}
+		c.loadedTo = end
+	}
+	sort.Slice(c.sessions, func(i, j int) bool {
+		return c.sessions[i].from.Before(c.sessions[j].from)
+	})
+	return nil
+}
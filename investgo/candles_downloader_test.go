@@ -0,0 +1,189 @@
+package investgo_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/russianinvestments/invest-api-go-sdk/investgo"
+	pb "github.com/russianinvestments/invest-api-go-sdk/proto"
+)
+
+// monday - понедельник, тестовый сервер без расписания биржи торгует по будням с 07:00 до 15:40 UTC
+var monday = time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC)
+
+// hourCandles - часовые свечи торговых часов с 07:00 до 15:00 в дни days от monday, кроме skip
+func hourCandles(days []int, skip ...time.Time) []*pb.HistoricCandle {
+	skipped := make(map[time.Time]bool, len(skip))
+	for _, t := range skip {
+		skipped[t] = true
+	}
+	var candles []*pb.HistoricCandle
+	for _, d := range days {
+		for h := 7; h <= 15; h++ {
+			t := monday.AddDate(0, 0, d).Add(time.Duration(h) * time.Hour)
+			if skipped[t] {
+				continue
+			}
+			p := pb.QuotationFromDecimal(decimal.NewFromInt(int64(100 + d*10 + h)))
+			candles = append(candles, &pb.HistoricCandle{
+				Open: p, High: p, Low: p, Close: p, Volume: 1, Time: timestamppb.New(t), IsComplete: true,
+			})
+		}
+	}
+	return candles
+}
+
+func downloadServer(t *testing.T, candles []*pb.HistoricCandle) (*investgo.MarketDataServiceClient, string) {
+	t.Helper()
+	srv, client, uids := newTestServer(t, 1)
+	if err := srv.AddCandles(uids[0], pb.CandleInterval_CANDLE_INTERVAL_HOUR, candles...); err != nil {
+		t.Fatal(err)
+	}
+	return client.NewMarketDataServiceClient(), uids[0]
+}
+
+// collect - все свечи итератора, итератор закрывается
+func collect(t *testing.T, it *investgo.CandlesIterator) []time.Time {
+	t.Helper()
+	defer it.Close()
+	var times []time.Time
+	for it.Next() {
+		times = append(times, it.Candle().GetTime().AsTime())
+	}
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return times
+}
+
+func checkTimes(t *testing.T, got []time.Time, want []*pb.HistoricCandle) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %v candles, want %v", len(got), len(want))
+	}
+	for i := range want {
+		if !got[i].Equal(want[i].GetTime().AsTime()) {
+			t.Fatalf("candle %v time = %v, want %v", i, got[i], want[i].GetTime().AsTime())
+		}
+	}
+}
+
+func TestDownloadCandles(t *testing.T) {
+	// три недели часовых свечей загружаются тремя частями по 7 дней
+	days := []int{0, 1, 2, 3, 4, 7, 8, 9, 10, 11, 14, 15, 16, 17, 18}
+	candles := hourCandles(days)
+	md, uid := downloadServer(t, candles)
+
+	it := md.DownloadCandles(&investgo.DownloadCandlesRequest{
+		Instrument:        uid,
+		Interval:          pb.CandleInterval_CANDLE_INTERVAL_HOUR,
+		From:              monday,
+		To:                monday.AddDate(0, 0, 21),
+		Workers:           3,
+		RequestsPerMinute: 6000,
+	})
+	checkTimes(t, collect(t, it), candles)
+	if cp := it.Checkpoint(); !cp.LastCandle.Equal(candles[len(candles)-1].GetTime().AsTime()) {
+		t.Fatalf("checkpoint = %+v", cp)
+	}
+}
+
+func TestDownloadCandlesCheckpoint(t *testing.T) {
+	candles := hourCandles([]int{0, 1, 2, 3, 4, 7, 8, 9, 10, 11})
+	md, uid := downloadServer(t, candles)
+	req := &investgo.DownloadCandlesRequest{
+		Instrument:        uid,
+		Interval:          pb.CandleInterval_CANDLE_INTERVAL_HOUR,
+		From:              monday,
+		To:                monday.AddDate(0, 0, 14),
+		RequestsPerMinute: 6000,
+	}
+
+	// загрузка прерывается посреди второй части периода
+	it := md.DownloadCandles(req)
+	var got []time.Time
+	for len(got) < 50 && it.Next() {
+		got = append(got, it.Candle().GetTime().AsTime())
+	}
+	it.Close()
+	cp := it.Checkpoint()
+	if !cp.LastCandle.Equal(got[len(got)-1]) || !cp.From.Equal(monday.AddDate(0, 0, 7)) {
+		t.Fatalf("checkpoint = %+v", cp)
+	}
+
+	// продолжение с точки возвращает оставшиеся свечи без повторов, From запроса не используется
+	resume := *req
+	resume.From = monday.AddDate(-1, 0, 0)
+	resume.Checkpoint = &cp
+	got = append(got, collect(t, md.DownloadCandles(&resume))...)
+	checkTimes(t, got, candles)
+
+	// точка другого инструмента или интервала не принимается
+	other := cp
+	other.Interval = pb.CandleInterval_CANDLE_INTERVAL_DAY
+	resume.Checkpoint = &other
+	it = md.DownloadCandles(&resume)
+	if it.Next() || it.Err() == nil {
+		t.Fatalf("checkpoint mismatch: err = %v", it.Err())
+	}
+}
+
+func TestDownloadCandlesGaps(t *testing.T) {
+	at := func(day, hour int) time.Time {
+		return monday.AddDate(0, 0, day).Add(time.Duration(hour) * time.Hour)
+	}
+	// нет свечей в начале понедельника, в среду, в середине четверга и в конце пятницы
+	candles := hourCandles([]int{0, 1, 3, 4}, at(0, 7), at(0, 8), at(3, 11), at(4, 14), at(4, 15))
+	md, uid := downloadServer(t, candles)
+
+	it := md.DownloadCandles(&investgo.DownloadCandlesRequest{
+		Instrument:        uid,
+		Interval:          pb.CandleInterval_CANDLE_INTERVAL_HOUR,
+		From:              monday,
+		To:                monday.AddDate(0, 0, 7),
+		RequestsPerMinute: 6000,
+		Exchange:          "MOEX",
+	})
+	checkTimes(t, collect(t, it), candles)
+	want := []investgo.CandlesGap{
+		{From: at(0, 0), To: at(0, 9)},
+		{From: at(1, 16), To: at(3, 7)},
+		{From: at(3, 11), To: at(3, 12)},
+		{From: at(4, 14), To: at(7, 0)},
+	}
+	gaps := it.Gaps()
+	if len(gaps) != len(want) {
+		t.Fatalf("gaps = %v, want %v", gaps, want)
+	}
+	for i := range want {
+		if !gaps[i].From.Equal(want[i].From) || !gaps[i].To.Equal(want[i].To) {
+			t.Fatalf("gaps = %v, want %v", gaps, want)
+		}
+	}
+
+	// период без свечей целиком - один пропуск, выходные без торгов - не пропуск
+	md, uid = downloadServer(t, nil)
+	for _, tc := range []struct {
+		from, to time.Time
+		gaps     int
+	}{
+		{from: at(2, 0), to: at(3, 0), gaps: 1},
+		{from: at(5, 0), to: at(7, 0), gaps: 0},
+	} {
+		it = md.DownloadCandles(&investgo.DownloadCandlesRequest{
+			Instrument:        uid,
+			Interval:          pb.CandleInterval_CANDLE_INTERVAL_HOUR,
+			From:              tc.from,
+			To:                tc.to,
+			RequestsPerMinute: 6000,
+			Exchange:          "MOEX",
+		})
+		collect(t, it)
+		if gaps := it.Gaps(); len(gaps) != tc.gaps {
+			t.Fatalf("%v - %v: gaps = %v, want %v", tc.from, tc.to, gaps, tc.gaps)
+		}
+	}
+}
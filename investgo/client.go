@@ -3,14 +3,11 @@ package investgo
 import (
 	"context"
 	"crypto/tls"
-	"fmt"
 	"time"
 
-	"golang.org/x/oauth2"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials"
-	"google.golang.org/grpc/credentials/oauth"
 	"google.golang.org/grpc/metadata"
 
 	pb "github.com/russianinvestments/invest-api-go-sdk/proto"
@@ -24,8 +21,6 @@ const (
 	headerAppName = "x-app-name"
 )
 
-type ctxKey string
-
 type Client struct {
 	Conn   *grpc.ClientConn
 	Config Config
@@ -35,10 +30,44 @@ type Client struct {
 
 // NewClient - создание клиента для API Тинькофф инвестиций
 func NewClient(ctx context.Context, conf Config, l Logger, dialOpts ...grpc.DialOption) (*Client, error) {
-	setDefaultConfig(&conf)
+	client, err := dial(ctx, conf, l, dialOpts...)
+	if err != nil {
+		return nil, err
+	}
+
+	// в реальном контуре счет песочницы не открывается, счет нужно указать в конфиге
+	if client.Config.AccountId == "" && client.Config.Environment != EnvironmentProduction {
+		s := client.NewSandboxServiceClient()
+		accountsResp, err := s.GetSandboxAccounts()
+		if err != nil {
+			return nil, err
+		}
+		accs := accountsResp.GetAccounts()
+		if len(accs) < 1 {
+			resp, err := s.OpenSandboxAccount()
+			if err != nil {
+				return nil, err
+			}
+			client.Config.AccountId = resp.GetAccountId()
+		} else {
+			for _, acc := range accs {
+				if acc.GetStatus() == pb.AccountStatus_ACCOUNT_STATUS_OPEN {
+					client.Config.AccountId = acc.GetId()
+					break
+				}
+			}
+		}
+	}
 
-	var authKey ctxKey = "authorization"
-	ctx = context.WithValue(ctx, authKey, fmt.Sprintf("Bearer %s", conf.Token))
+	return client, nil
+}
+
+// dial - подключение клиента без выбора счета
+func dial(ctx context.Context, conf Config, l Logger, dialOpts ...grpc.DialOption) (*Client, error) {
+	if err := setEnvironment(&conf); err != nil {
+		return nil, err
+	}
+	setDefaultConfig(&conf)
 
 	opts := []retry.CallOption{
 		retry.WithCodes(codes.Unavailable, codes.Internal, codes.Canceled),
@@ -46,6 +75,16 @@ func NewClient(ctx context.Context, conf Config, l Logger, dialOpts ...grpc.Dial
 		retry.WithMax(conf.MaxRetries),
 	}
 
+	// стримы переподключаются и после Unauthenticated, чтобы продолжить работу с новым токеном из TokenProvider
+	streamOpts := []retry.CallOption{
+		retry.WithCodes(codes.Unavailable, codes.Internal, codes.Canceled, codes.Unauthenticated),
+		retry.WithBackoff(retry.BackoffLinear(WAIT_BETWEEN)),
+		retry.WithMax(conf.MaxRetries),
+		retry.WithOnRetryCallback(func(ctx context.Context, attempt uint, err error) {
+			invalidateToken(conf.TokenProvider, err)
+		}),
+	}
+
 	// при исчерпывании лимита запросов в минуту, нужно ждать дольше
 	exhaustedOpts := []retry.CallOption{
 		retry.WithCodes(codes.ResourceExhausted),
@@ -56,7 +95,7 @@ func NewClient(ctx context.Context, conf Config, l Logger, dialOpts ...grpc.Dial
 	}
 
 	streamInterceptors := []grpc.StreamClientInterceptor{
-		retry.StreamClientInterceptor(opts...),
+		retry.StreamClientInterceptor(streamOpts...),
 		outgoingAppNameStreamInterceptor(conf.AppName),
 	}
 
@@ -74,12 +113,39 @@ func NewClient(ctx context.Context, conf Config, l Logger, dialOpts ...grpc.Dial
 		}
 	}
 
+	// заявки в реальном контуре проверяются до ретраев и ограничителя, отклоненная заявка не тратит лимит
+	if conf.Environment == EnvironmentProduction {
+		unaryInterceptors = append([]grpc.UnaryClientInterceptor{
+			newProductionGuard(conf).UnaryClientInterceptor(),
+		}, unaryInterceptors...)
+	}
+
+	if conf.EnableRateLimiter {
+		limits := make(map[string]int, len(DefaultRateLimits)+len(conf.RateLimits))
+		for k, v := range DefaultRateLimits {
+			limits[k] = v
+		}
+		for k, v := range conf.RateLimits {
+			limits[k] = v
+		}
+		// ограничитель идет после ретраеров, чтобы повторные запросы тоже ждали своей очереди
+		unaryInterceptors = append(unaryInterceptors, NewRateLimiter(limits).UnaryClientInterceptor())
+	}
+
+	unaryInterceptors = append(unaryInterceptors, tokenUnaryInterceptor(conf.TokenProvider))
+
+	// ошибки сервера переводятся в APIError ближе всего к вызову, чтобы остальные интерсепторы тоже получали APIError
+	unaryInterceptors = append(unaryInterceptors, apiErrorUnaryInterceptor())
+	streamInterceptors = append(streamInterceptors, apiErrorStreamInterceptor())
+
+	// транспорт по умолчанию идет первым, чтобы его можно было переопределить через dialOpts,
+	// например для тестового сервера из пакета investgotest
+	dialOpts = append([]grpc.DialOption{
+		grpc.WithTransportCredentials(credentials.NewTLS(&tls.Config{})),
+	}, dialOpts...)
 	dialOpts = append(
 		dialOpts,
-		grpc.WithTransportCredentials(credentials.NewTLS(&tls.Config{})),
-		grpc.WithPerRPCCredentials(oauth.TokenSource{
-			TokenSource: oauth2.StaticTokenSource(&oauth2.Token{AccessToken: conf.Token}),
-		}),
+		grpc.WithPerRPCCredentials(tokenCredentials{p: conf.TokenProvider}),
 		grpc.WithChainUnaryInterceptor(unaryInterceptors...),
 		grpc.WithChainStreamInterceptor(streamInterceptors...),
 	)
@@ -88,45 +154,27 @@ func NewClient(ctx context.Context, conf Config, l Logger, dialOpts ...grpc.Dial
 		return nil, err
 	}
 
-	client := &Client{
+	return &Client{
 		Conn:   conn,
 		Config: conf,
 		Logger: l,
 		ctx:    ctx,
-	}
-
-	if conf.AccountId == "" {
-		s := client.NewSandboxServiceClient()
-		accountsResp, err := s.GetSandboxAccounts()
-		if err != nil {
-			return nil, err
-		}
-		accs := accountsResp.GetAccounts()
-		if len(accs) < 1 {
-			resp, err := s.OpenSandboxAccount()
-			if err != nil {
-				return nil, err
-			}
-			client.Config.AccountId = resp.GetAccountId()
-		} else {
-			for _, acc := range accs {
-				if acc.GetStatus() == pb.AccountStatus_ACCOUNT_STATUS_OPEN {
-					client.Config.AccountId = acc.GetId()
-					break
-				}
-			}
-		}
-	}
-
-	return client, nil
+	}, nil
 }
 
 func setDefaultConfig(conf *Config) {
 	if conf.AppName == "" {
 		conf.AppName = "invest-api-go-sdk"
 	}
-	if conf.EndPoint == "" {
-		conf.EndPoint = "sandbox-invest-public-api.tinkoff.ru:443"
+	if conf.TokenProvider == nil {
+		switch {
+		case conf.TokenFile != "":
+			conf.TokenProvider = NewFileToken(conf.TokenFile)
+		case conf.TokenEnv != "":
+			conf.TokenProvider = EnvToken(conf.TokenEnv)
+		default:
+			conf.TokenProvider = StaticToken(conf.Token)
+		}
 	}
 	if conf.DisableAllRetry {
 		conf.MaxRetries = 0
@@ -10,11 +10,29 @@ import (
 // Config - структура для кофигурации SDK
 type Config struct {
 	// EndPoint - Для работы с реальным контуром и контуром песочницы нужны разные эндпоинты.
-	// По умолчанию = sandbox-invest-public-api.tinkoff.ru:443
+	// По умолчанию = эндпоинт Environment, без Environment = sandbox-invest-public-api.tinkoff.ru:443
 	//https://tinkoff.github.io/investAPI/url_difference/
 	EndPoint string `yaml:"EndPoint"`
+	// Environment - Контур: sandbox, production или custom. Если не задан, определяется по EndPoint, для
+	// неизвестного эндпоинта custom нужно указать явно. Если задан вместе с EndPoint, эндпоинт должен
+	// принадлежать этому контуру
+	Environment Environment `yaml:"Environment"`
+	// AllowLiveTrading - Разрешение выставлять заявки в реальном контуре. По умолчанию = false, выставление и замена
+	// заявок и стоп-заявок в production возвращают ErrLiveTradingDisabled без обращения к серверу
+	AllowLiveTrading bool `yaml:"AllowLiveTrading"`
+	// MaxOrderNotional - Максимальная стоимость одной заявки в реальном контуре в валюте инструмента
+	// (цена * лоты * лотность, цена облигаций - от номинала, фьючерсов - по стоимости шага цены), для рыночных
+	// заявок считается по цене последней сделки. 0 - без ограничения
+	MaxOrderNotional float64 `yaml:"MaxOrderNotional"`
 	// Token - Ваш токен для Tinkoff InvestAPI
 	Token string `yaml:"APIToken"`
+	// TokenFile - Файл с токеном, файл перечитывается после изменения, см. FileToken. Используется вместо Token
+	TokenFile string `yaml:"APITokenFile"`
+	// TokenEnv - Переменная окружения с токеном, читается перед каждым запросом. Используется вместо Token
+	TokenEnv string `yaml:"APITokenEnv"`
+	// TokenProvider - Источник токена, важнее Token, TokenFile и TokenEnv. Токен запрашивается перед каждым запросом
+	// и открытием стрима, поэтому его можно менять без пересоздания клиента
+	TokenProvider TokenProvider `yaml:"-"`
 	// AppName - Название вашего приложения, по умолчанию = tinkoff-api-go-sdk
 	AppName string `yaml:"AppName"`
 	// AccountId - Если уже есть аккаунт для апи можно указать напрямую,
@@ -28,6 +46,12 @@ type Config struct {
 	// MaxRetries - Максимальное количество попыток переподключения, по умолчанию = 3
 	// (если указать значение 0 это не отключит ретраи, для отключения нужно прописать DisableAllRetry = true)
 	MaxRetries uint `yaml:"MaxRetries"`
+	// EnableRateLimiter - Если true, то unary запросы сверх лимита не отправляются на сервер, а ждут своей очереди
+	// на клиенте, см. RateLimiter. По умолчанию = false
+	EnableRateLimiter bool `yaml:"EnableRateLimiter"`
+	// RateLimits - Лимиты запросов в минуту для RateLimiter по сервисам (MarketDataService) или методам
+	// (MarketDataService/GetCandles), дополняют и переопределяют DefaultRateLimits
+	RateLimits map[string]int `yaml:"RateLimits"`
 }
 
 // LoadConfig - загрузка конфигурации для сдк из .yaml файла
@@ -1,7 +1,6 @@
 package investgo
 
 import (
-	"math"
 	"time"
 
 	"github.com/shopspring/decimal"
@@ -17,21 +16,8 @@ func TimeToTimestamp(t time.Time) *timestamppb.Timestamp {
 	return timestamppb.New(t)
 }
 
-// FloatToQuotation - Перевод float в Quotation, step - шаг цены для инструмента (min_price_increment)
+// FloatToQuotation - Перевод float в Quotation, step - шаг цены для инструмента (min_price_increment).
+// Число округляется до ближайшего шага, для других способов округления см. pb.Quotation.RoundToIncrement
 func FloatToQuotation(number float64, step *pb.Quotation) *pb.Quotation {
-	// делим дробь на дробь и округляем до ближайшего целого
-	k := math.Round(number / step.ToFloat())
-	// целое умножаем на дробный шаг и получаем готовое дробное значение
-	roundedNumber := step.ToFloat() * k
-	// разделяем дробную и целую части
-	decNumber := decimal.NewFromFloat(roundedNumber)
-
-	intPart := decNumber.IntPart()
-	fracPart := decNumber.Sub(decimal.NewFromInt(intPart))
-
-	nano := fracPart.Mul(decimal.NewFromInt(BILLION)).IntPart()
-	return &pb.Quotation{
-		Units: intPart,
-		Nano:  int32(nano),
-	}
+	return pb.QuotationFromDecimal(decimal.NewFromFloat(number)).RoundToIncrement(step, pb.RoundNearest)
 }
@@ -0,0 +1,32 @@
+package investgo_test
+
+import (
+	"testing"
+
+	"github.com/russianinvestments/invest-api-go-sdk/investgo"
+	pb "github.com/russianinvestments/invest-api-go-sdk/proto"
+)
+
+func TestFloatToQuotation(t *testing.T) {
+	tests := []struct {
+		number float64
+		step   *pb.Quotation
+		want   *pb.Quotation
+	}{
+		// number / step во float64 дает 22.999999999999996, раньше цена получалась на шаг меньше
+		{number: 1.15, step: &pb.Quotation{Nano: 50_000_000}, want: &pb.Quotation{Units: 1, Nano: 150_000_000}},
+		{number: 0.29, step: &pb.Quotation{Nano: 10_000_000}, want: &pb.Quotation{Nano: 290_000_000}},
+		{number: 95.67, step: &pb.Quotation{Nano: 10_000_000}, want: &pb.Quotation{Units: 95, Nano: 670_000_000}},
+		{number: 0.0071, step: &pb.Quotation{Nano: 500_000}, want: &pb.Quotation{Nano: 7_000_000}},
+		{number: 250.3, step: &pb.Quotation{Units: 1}, want: &pb.Quotation{Units: 250}},
+		{number: -1.17, step: &pb.Quotation{Nano: 50_000_000}, want: &pb.Quotation{Units: -1, Nano: -150_000_000}},
+		// без шага цены число не округляется
+		{number: 1.123456789, step: nil, want: &pb.Quotation{Units: 1, Nano: 123_456_789}},
+	}
+	for _, tc := range tests {
+		got := investgo.FloatToQuotation(tc.number, tc.step)
+		if got.GetUnits() != tc.want.GetUnits() || got.GetNano() != tc.want.GetNano() {
+			t.Fatalf("FloatToQuotation(%v, %v) = %v, want %v", tc.number, tc.step, got, tc.want)
+		}
+	}
+}
@@ -7,6 +7,13 @@ Package investgo предоставляет инструменты для раб
 есть свой конфиг, который привязывает его к определенному счету и токену. Если есть потребность использовать разные счета и токены, нужно
 создавать разных клиентов. investgo.Client предоставляет функции-конcтрукторы для всех сервисов Tinkoff InvestAPI.
 
+# Context
+
+Методы сервисов используют контекст, переданный в investgo.NewClient(). Для каждого метода есть вариант с суффиксом Ctx,
+например OrdersServiceClient.PostOrderCtx(), который принимает context.Context первым аргументом. Это позволяет задавать
+дедлайны, отменять отдельные запросы и передавать значения контекста, например для трассировки. Конструкторы стримов
+(MarketDataStreamCtx, TradesStreamCtx и др.) используют переданный контекст на все время жизни стрима.
+
 Подробнее смотрите в директории examples.
 */
 package investgo
@@ -0,0 +1,237 @@
+package investgo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"google.golang.org/grpc"
+
+	pb "github.com/russianinvestments/invest-api-go-sdk/proto"
+)
+
+// Environment - контур API, с которым работает клиент
+type Environment string
+
+const (
+	// EnvironmentSandbox - песочница, заявки исполняются на виртуальных счетах
+	EnvironmentSandbox Environment = "sandbox"
+	// EnvironmentProduction - реальный контур, заявки исполняются на бирже за реальные деньги
+	EnvironmentProduction Environment = "production"
+	// EnvironmentCustom - собственный эндпоинт, например прокси или тестовый сервер. Торговые запросы идут в
+	// OrdersService, защита реального контура не применяется
+	EnvironmentCustom Environment = "custom"
+)
+
+const (
+	// SandboxEndPoint - эндпоинт песочницы
+	SandboxEndPoint = "sandbox-invest-public-api.tinkoff.ru:443"
+	// ProductionEndPoint - эндпоинт реального контура
+	ProductionEndPoint = "invest-public-api.tinkoff.ru:443"
+)
+
+var (
+	// ErrEnvironmentMismatch - EndPoint в конфиге принадлежит другому контуру, чем Environment
+	ErrEnvironmentMismatch = errors.New("endpoint does not match environment")
+	// ErrUnknownEndPoint - EndPoint не относится к известным контурам, а Environment не задан
+	ErrUnknownEndPoint = errors.New("unknown endpoint, set Environment to custom to use it")
+	// ErrLiveTradingDisabled - заявка в реальном контуре без AllowLiveTrading в конфиге
+	ErrLiveTradingDisabled = errors.New("live trading is disabled, set AllowLiveTrading to send orders in production")
+	// ErrOrderNotionalExceeded - стоимость заявки в реальном контуре больше MaxOrderNotional
+	ErrOrderNotionalExceeded = errors.New("order notional exceeds MaxOrderNotional")
+)
+
+// endPoints - известные эндпоинты контуров
+var endPoints = map[string]Environment{
+	SandboxEndPoint:                          EnvironmentSandbox,
+	"sandbox-invest-public-api.tbank.ru:443": EnvironmentSandbox,
+	ProductionEndPoint:                       EnvironmentProduction,
+	"invest-public-api.tbank.ru:443":         EnvironmentProduction,
+}
+
+// setEnvironment - определение контура и эндпоинта. Без Environment контур определяется по EndPoint, пустой
+// EndPoint - песочница. Для неизвестного эндпоинта EnvironmentCustom нужно указать явно: за ним может быть
+// прокси к реальному контуру, а в EnvironmentCustom защита реального контура не применяется
+func setEnvironment(conf *Config) error {
+	known, isKnown := endPoints[conf.EndPoint]
+	switch conf.Environment {
+	case "":
+		switch {
+		case conf.EndPoint == "":
+			conf.Environment = EnvironmentSandbox
+		case isKnown:
+			conf.Environment = known
+		default:
+			return fmt.Errorf("%w: %v", ErrUnknownEndPoint, conf.EndPoint)
+		}
+	case EnvironmentSandbox, EnvironmentProduction:
+		if conf.EndPoint != "" && known != conf.Environment {
+			return fmt.Errorf("%w: %v is not a %v endpoint", ErrEnvironmentMismatch, conf.EndPoint, conf.Environment)
+		}
+	case EnvironmentCustom:
+		if conf.EndPoint == "" {
+			return fmt.Errorf("%w: custom environment requires EndPoint", ErrEnvironmentMismatch)
+		}
+	default:
+		return fmt.Errorf("unknown environment %q", conf.Environment)
+	}
+	if conf.EndPoint == "" {
+		switch conf.Environment {
+		case EnvironmentSandbox:
+			conf.EndPoint = SandboxEndPoint
+		case EnvironmentProduction:
+			conf.EndPoint = ProductionEndPoint
+		}
+	}
+	return nil
+}
+
+// productionGuard - проверка заявок перед отправкой в реальный контур: без AllowLiveTrading заявки не
+// отправляются, с MaxOrderNotional > 0 заявки дороже лимита отклоняются на клиенте
+type productionGuard struct {
+	allow bool
+	max   decimal.Decimal
+
+	mu    sync.Mutex
+	costs map[string]instrumentCost
+}
+
+// instrumentCost - лотность и стоимость единицы цены инструмента: номинал / 100 для облигаций, цена которых
+// в процентах от номинала, стоимость шага цены / шаг цены для фьючерсов, цена которых в пунктах, и 1 для остальных
+type instrumentCost struct {
+	lot  decimal.Decimal
+	unit decimal.Decimal
+}
+
+func newProductionGuard(conf Config) *productionGuard {
+	return &productionGuard{
+		allow: conf.AllowLiveTrading,
+		max:   decimal.NewFromFloat(conf.MaxOrderNotional),
+		costs: make(map[string]instrumentCost),
+	}
+}
+
+// cost - лотность и стоимость единицы цены инструмента, instrumentId - figi или uid
+func (g *productionGuard) cost(ctx context.Context, cc *grpc.ClientConn, instrumentId string) (instrumentCost, error) {
+	g.mu.Lock()
+	c, ok := g.costs[instrumentId]
+	g.mu.Unlock()
+	if ok {
+		return c, nil
+	}
+	req := &pb.InstrumentRequest{IdType: pb.InstrumentIdType_INSTRUMENT_ID_TYPE_FIGI, Id: instrumentId}
+	if _, err := uuid.Parse(instrumentId); err == nil {
+		req.IdType = pb.InstrumentIdType_INSTRUMENT_ID_TYPE_UID
+	}
+	instruments := pb.NewInstrumentsServiceClient(cc)
+	resp, err := instruments.GetInstrumentBy(ctx, req)
+	if err != nil {
+		return c, err
+	}
+	c = instrumentCost{lot: decimal.NewFromInt32(resp.GetInstrument().GetLot()), unit: decimal.NewFromInt(1)}
+	switch resp.GetInstrument().GetInstrumentKind() {
+	case pb.InstrumentType_INSTRUMENT_TYPE_BOND:
+		bond, err := instruments.BondBy(ctx, req)
+		if err != nil {
+			return c, err
+		}
+		c.unit = bond.GetInstrument().GetNominal().ToDecimal().Div(decimal.NewFromInt(100))
+	case pb.InstrumentType_INSTRUMENT_TYPE_FUTURES:
+		margin, err := instruments.GetFuturesMargin(ctx, &pb.GetFuturesMarginRequest{InstrumentId: instrumentId})
+		if err != nil {
+			return c, err
+		}
+		inc := margin.GetMinPriceIncrement().ToDecimal()
+		if inc.IsZero() {
+			return c, fmt.Errorf("no min price increment for %v", instrumentId)
+		}
+		c.unit = margin.GetMinPriceIncrementAmount().ToDecimal().Div(inc)
+	}
+	g.mu.Lock()
+	g.costs[instrumentId] = c
+	g.mu.Unlock()
+	return c, nil
+}
+
+// price - цена заявки, для рыночной заявки - цена последней сделки
+func (g *productionGuard) price(ctx context.Context, cc *grpc.ClientConn, instrumentId string, price *pb.Quotation) (decimal.Decimal, error) {
+	if p := price.ToDecimal(); p.IsPositive() {
+		return p, nil
+	}
+	resp, err := pb.NewMarketDataServiceClient(cc).GetLastPrices(ctx, &pb.GetLastPricesRequest{
+		InstrumentId: []string{instrumentId},
+	})
+	if err != nil {
+		return decimal.Zero, err
+	}
+	for _, lp := range resp.GetLastPrices() {
+		return lp.GetPrice().ToDecimal(), nil
+	}
+	return decimal.Zero, fmt.Errorf("no last price for %v", instrumentId)
+}
+
+// checkNotional - проверка стоимости заявки: цена в валюте * количество лотов * лотность. Цена облигаций
+// переводится из процентов от номинала, цена фьючерсов - из пунктов по стоимости шага цены, НКД не учитывается
+func (g *productionGuard) checkNotional(ctx context.Context, cc *grpc.ClientConn, instrumentId string, quantity int64, price *pb.Quotation) error {
+	c, err := g.cost(ctx, cc, instrumentId)
+	if err != nil {
+		return err
+	}
+	p, err := g.price(ctx, cc, instrumentId, price)
+	if err != nil {
+		return err
+	}
+	notional := p.Mul(c.unit).Mul(decimal.NewFromInt(quantity)).Mul(c.lot)
+	if notional.GreaterThan(g.max) {
+		return fmt.Errorf("%w: %v %v lots at %v = %v > %v", ErrOrderNotionalExceeded, instrumentId, quantity, p, notional, g.max)
+	}
+	return nil
+}
+
+func (g *productionGuard) check(ctx context.Context, cc *grpc.ClientConn, req any) error {
+	if !g.allow {
+		return ErrLiveTradingDisabled
+	}
+	if !g.max.IsPositive() {
+		return nil
+	}
+	switch req := req.(type) {
+	case *pb.PostOrderRequest:
+		return g.checkNotional(ctx, cc, req.GetInstrumentId(), req.GetQuantity(), req.GetPrice())
+	case *pb.PostOrderAsyncRequest:
+		return g.checkNotional(ctx, cc, req.GetInstrumentId(), req.GetQuantity(), req.GetPrice())
+	case *pb.PostStopOrderRequest:
+		price := req.GetPrice()
+		if price.IsZero() {
+			price = req.GetStopPrice()
+		}
+		return g.checkNotional(ctx, cc, req.GetInstrumentId(), req.GetQuantity(), price)
+	case *pb.ReplaceOrderRequest:
+		state, err := pb.NewOrdersServiceClient(cc).GetOrderState(ctx, &pb.GetOrderStateRequest{
+			AccountId: req.GetAccountId(),
+			OrderId:   req.GetOrderId(),
+		})
+		if err != nil {
+			return err
+		}
+		return g.checkNotional(ctx, cc, state.GetInstrumentUid(), req.GetQuantity(), req.GetPrice())
+	}
+	return nil
+}
+
+// UnaryClientInterceptor - Интерсептор, который проверяет выставление и замену заявок и стоп-заявок
+func (g *productionGuard) UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		switch method {
+		case pb.OrdersService_PostOrder_FullMethodName, pb.OrdersService_PostOrderAsync_FullMethodName,
+			pb.OrdersService_ReplaceOrder_FullMethodName, pb.StopOrdersService_PostStopOrder_FullMethodName:
+			if err := g.check(ctx, cc, req); err != nil {
+				return err
+			}
+		}
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
@@ -0,0 +1,265 @@
+package investgo_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/russianinvestments/invest-api-go-sdk/investgo"
+	"github.com/russianinvestments/invest-api-go-sdk/investgotest"
+	pb "github.com/russianinvestments/invest-api-go-sdk/proto"
+)
+
+func TestEnvironment(t *testing.T) {
+	srv, err := investgotest.NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(srv.Stop)
+	tests := []struct {
+		name         string
+		env          investgo.Environment
+		endPoint     string
+		wantEnv      investgo.Environment
+		wantEndPoint string
+		wantErr      error
+	}{
+		{name: "default", wantEnv: investgo.EnvironmentSandbox, wantEndPoint: investgo.SandboxEndPoint},
+		{name: "sandbox", env: investgo.EnvironmentSandbox, wantEnv: investgo.EnvironmentSandbox, wantEndPoint: investgo.SandboxEndPoint},
+		{name: "production", env: investgo.EnvironmentProduction, wantEnv: investgo.EnvironmentProduction, wantEndPoint: investgo.ProductionEndPoint},
+		{name: "sandbox endpoint", endPoint: investgo.SandboxEndPoint, wantEnv: investgo.EnvironmentSandbox, wantEndPoint: investgo.SandboxEndPoint},
+		{name: "production endpoint", endPoint: "invest-public-api.tbank.ru:443", wantEnv: investgo.EnvironmentProduction, wantEndPoint: "invest-public-api.tbank.ru:443"},
+		{name: "custom", env: investgo.EnvironmentCustom, endPoint: "localhost:8080", wantEnv: investgo.EnvironmentCustom, wantEndPoint: "localhost:8080"},
+		// за неизвестным эндпоинтом может быть прокси к реальному контуру, контур нужно указать явно
+		{name: "unknown endpoint", endPoint: "localhost:8080", wantErr: investgo.ErrUnknownEndPoint},
+		{name: "production with sandbox endpoint", env: investgo.EnvironmentProduction, endPoint: investgo.SandboxEndPoint, wantErr: investgo.ErrEnvironmentMismatch},
+		{name: "sandbox with unknown endpoint", env: investgo.EnvironmentSandbox, endPoint: "localhost:8080", wantErr: investgo.ErrEnvironmentMismatch},
+		{name: "custom without endpoint", env: investgo.EnvironmentCustom, wantErr: investgo.ErrEnvironmentMismatch},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			conf := srv.Config()
+			conf.Environment, conf.EndPoint = tc.env, tc.endPoint
+			client, err := investgo.NewClient(context.Background(), conf, nopLogger{}, srv.DialOptions()...)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tc.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			defer client.Stop()
+			if client.Config.Environment != tc.wantEnv || client.Config.EndPoint != tc.wantEndPoint {
+				t.Fatalf("environment = %v %v, want %v %v", client.Config.Environment, client.Config.EndPoint, tc.wantEnv, tc.wantEndPoint)
+			}
+		})
+	}
+	conf := srv.Config()
+	conf.Environment = "staging"
+	if _, err := investgo.NewClient(context.Background(), conf, nopLogger{}, srv.DialOptions()...); err == nil {
+		t.Fatal("unknown environment accepted")
+	}
+}
+
+// newProductionClient - клиент реального контура, подключенный к тестовому серверу
+func newProductionClient(t *testing.T, srv *investgotest.Server, allow bool, maxNotional float64) *investgo.Client {
+	t.Helper()
+	conf := srv.Config()
+	conf.Environment, conf.EndPoint = investgo.EnvironmentProduction, ""
+	conf.AllowLiveTrading, conf.MaxOrderNotional = allow, maxNotional
+	client, err := investgo.NewClient(context.Background(), conf, nopLogger{}, srv.DialOptions()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = client.Stop()
+	})
+	return client
+}
+
+// guardServer - тестовый сервер с деньгами на счете, акцией лотом 10 по 100, облигацией номиналом 1000 по 95% и
+// фьючерсом по 80000 пунктов с шагом 10 пунктов стоимостью 5 рублей
+func guardServer(t *testing.T) (srv *investgotest.Server, share, bond, future string) {
+	t.Helper()
+	srv, err := investgotest.NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(srv.Stop)
+	share = srv.AddShare(&pb.Share{Figi: "SHARE", Ticker: "SHARE", ClassCode: "TQBR", Lot: 10})
+	bond = srv.AddBond(&pb.Bond{Figi: "BOND", Ticker: "BOND", ClassCode: "TQCB", Lot: 1,
+		Nominal: pb.MoneyFromDecimal(decimal.NewFromInt(1000), "rub")})
+	future = srv.AddFuture(&pb.Future{Figi: "FUT", Ticker: "FUT", ClassCode: "SPBFUT", Lot: 1,
+		MinPriceIncrement: pb.QuotationFromDecimal(decimal.NewFromInt(10)), MinPriceIncrementAmount: pb.QuotationFromDecimal(decimal.NewFromInt(5))})
+	for id, price := range map[string]float64{share: 100, bond: 95, future: 80000} {
+		if err := srv.SetPrice(id, price); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := srv.PayIn(investgotest.DefaultAccountId, "rub", 1_000_000); err != nil {
+		t.Fatal(err)
+	}
+	return srv, share, bond, future
+}
+
+func limitOrder(id string, quantity int64, price int64) *investgo.PostOrderRequest {
+	return &investgo.PostOrderRequest{
+		InstrumentId: id,
+		Quantity:     quantity,
+		Price:        pb.QuotationFromDecimal(decimal.NewFromInt(price)),
+		Direction:    pb.OrderDirection_ORDER_DIRECTION_BUY,
+		AccountId:    investgotest.DefaultAccountId,
+		OrderType:    pb.OrderType_ORDER_TYPE_LIMIT,
+	}
+}
+
+func TestProductionGuardLiveTradingDisabled(t *testing.T) {
+	srv, share, _, _ := guardServer(t)
+	client := newProductionClient(t, srv, false, 0)
+	orders := client.NewOrdersServiceClient()
+
+	if _, err := orders.PostOrder(limitOrder(share, 1, 90)); !errors.Is(err, investgo.ErrLiveTradingDisabled) {
+		t.Fatalf("PostOrder err = %v", err)
+	}
+	if _, err := orders.PostOrderAsync(limitOrder(share, 1, 90)); !errors.Is(err, investgo.ErrLiveTradingDisabled) {
+		t.Fatalf("PostOrderAsync err = %v", err)
+	}
+	_, err := client.NewStopOrdersServiceClient().PostStopOrder(&investgo.PostStopOrderRequest{
+		InstrumentId:   share,
+		Quantity:       1,
+		StopPrice:      pb.QuotationFromDecimal(decimal.NewFromInt(120)),
+		Direction:      pb.StopOrderDirection_STOP_ORDER_DIRECTION_BUY,
+		AccountId:      investgotest.DefaultAccountId,
+		ExpirationType: pb.StopOrderExpirationType_STOP_ORDER_EXPIRATION_TYPE_GOOD_TILL_CANCEL,
+		StopOrderType:  pb.StopOrderType_STOP_ORDER_TYPE_STOP_LOSS,
+	})
+	if !errors.Is(err, investgo.ErrLiveTradingDisabled) {
+		t.Fatalf("PostStopOrder err = %v", err)
+	}
+	_, err = orders.ReplaceOrder(&investgo.ReplaceOrderRequest{
+		AccountId: investgotest.DefaultAccountId,
+		OrderId:   "order",
+		Quantity:  1,
+		Price:     pb.QuotationFromDecimal(decimal.NewFromInt(90)),
+	})
+	if !errors.Is(err, investgo.ErrLiveTradingDisabled) {
+		t.Fatalf("ReplaceOrder err = %v", err)
+	}
+	// отклоненные заявки не доходят до сервера
+	resp, err := orders.GetOrders(investgotest.DefaultAccountId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.GetOrders()) != 0 {
+		t.Fatalf("orders = %v", resp.GetOrders())
+	}
+
+	// с AllowLiveTrading и без MaxOrderNotional заявки не ограничены
+	client = newProductionClient(t, srv, true, 0)
+	if _, err := client.NewOrdersServiceClient().PostOrder(limitOrder(share, 100, 90)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestProductionGuardNotional(t *testing.T) {
+	srv, share, bond, future := guardServer(t)
+	client := newProductionClient(t, srv, true, 10_000)
+	orders := client.NewOrdersServiceClient()
+
+	tests := []struct {
+		name    string
+		req     *investgo.PostOrderRequest
+		wantErr bool
+	}{
+		// 90 * 11 лотов * 10 = 9900
+		{name: "share", req: limitOrder(share, 11, 90)},
+		// 90 * 12 лотов * 10 = 10800
+		{name: "share over limit", req: limitOrder(share, 12, 90), wantErr: true},
+		// рыночная заявка по цене последней сделки: 100 * 10 лотов * 10 = 10000
+		{name: "share market", req: &investgo.PostOrderRequest{InstrumentId: share, Quantity: 10, Direction: pb.OrderDirection_ORDER_DIRECTION_BUY,
+			AccountId: investgotest.DefaultAccountId, OrderType: pb.OrderType_ORDER_TYPE_MARKET}},
+		{name: "share market over limit", req: &investgo.PostOrderRequest{InstrumentId: share, Quantity: 11, Direction: pb.OrderDirection_ORDER_DIRECTION_BUY,
+			AccountId: investgotest.DefaultAccountId, OrderType: pb.OrderType_ORDER_TYPE_MARKET}, wantErr: true},
+		// 94% от номинала 1000 * 10 лотов = 9400, без перевода из процентов было бы 940
+		{name: "bond", req: limitOrder(bond, 10, 94)},
+		{name: "bond over limit", req: limitOrder(bond, 11, 94), wantErr: true},
+		// 19000 пунктов * 5 / 10 = 9500, без стоимости шага цены было бы 19000
+		{name: "future", req: limitOrder(future, 1, 19000)},
+		{name: "future over limit", req: limitOrder(future, 1, 21000), wantErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := orders.PostOrder(tc.req)
+			if tc.wantErr != errors.Is(err, investgo.ErrOrderNotionalExceeded) {
+				t.Fatalf("PostOrder err = %v, want notional exceeded = %v", err, tc.wantErr)
+			}
+			if !tc.wantErr && err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+
+	// стоп-заявка без цены исполнения проверяется по стоп-цене: 110 * 10 лотов * 10 = 11000
+	_, err := client.NewStopOrdersServiceClient().PostStopOrder(&investgo.PostStopOrderRequest{
+		InstrumentId:   share,
+		Quantity:       10,
+		StopPrice:      pb.QuotationFromDecimal(decimal.NewFromInt(110)),
+		Direction:      pb.StopOrderDirection_STOP_ORDER_DIRECTION_BUY,
+		AccountId:      investgotest.DefaultAccountId,
+		ExpirationType: pb.StopOrderExpirationType_STOP_ORDER_EXPIRATION_TYPE_GOOD_TILL_CANCEL,
+		StopOrderType:  pb.StopOrderType_STOP_ORDER_TYPE_STOP_LOSS,
+	})
+	if !errors.Is(err, investgo.ErrOrderNotionalExceeded) {
+		t.Fatalf("PostStopOrder err = %v", err)
+	}
+
+	// замена заявки проверяется по инструменту исходной заявки
+	if _, err := orders.PostOrderAsync(limitOrder(share, 1, 90)); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := orders.PostOrder(limitOrder(share, 1, 80))
+	if err != nil {
+		t.Fatal(err)
+	}
+	replace := &investgo.ReplaceOrderRequest{
+		AccountId: investgotest.DefaultAccountId,
+		OrderId:   resp.GetOrderId(),
+		Quantity:  12,
+		Price:     pb.QuotationFromDecimal(decimal.NewFromInt(90)),
+	}
+	if _, err := orders.ReplaceOrder(replace); !errors.Is(err, investgo.ErrOrderNotionalExceeded) {
+		t.Fatalf("ReplaceOrder err = %v", err)
+	}
+	replace.Quantity = 11
+	if _, err := orders.ReplaceOrder(replace); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestProductionGuardBypass(t *testing.T) {
+	srv, share, _, _ := guardServer(t)
+	ctx := context.Background()
+
+	// на собственном эндпоинте и в песочнице защита реального контура не применяется
+	custom, err := srv.NewClient(ctx, nopLogger{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer custom.Stop()
+	if _, err := custom.NewTradingServiceClient().PostOrder(limitOrder(share, 100, 90)); err != nil {
+		t.Fatalf("custom: %v", err)
+	}
+
+	conf := srv.Config()
+	conf.Environment, conf.EndPoint = investgo.EnvironmentSandbox, ""
+	conf.MaxOrderNotional = 1
+	sandbox, err := investgo.NewClient(ctx, conf, nopLogger{}, srv.DialOptions()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sandbox.Stop()
+	if _, err := sandbox.NewTradingServiceClient().PostOrder(limitOrder(share, 100, 90)); err != nil {
+		t.Fatalf("sandbox: %v", err)
+	}
+}
@@ -2,6 +2,7 @@ package investgo
 
 import (
 	"strconv"
+	"time"
 
 	"github.com/google/uuid"
 	"google.golang.org/grpc/metadata"
@@ -34,3 +35,16 @@ func RemainingLimitFromHeader(md metadata.MD) int {
 	}
 	return -1
 }
+
+// RateLimitResetFromHeader - Метод извлечения времени до сброса лимита запросов из заголовка, возвращает 0 при ошибке
+func RateLimitResetFromHeader(md metadata.MD) time.Duration {
+	resets := md.Get("x-ratelimit-reset")
+	if len(resets) > 0 {
+		sec, err := strconv.Atoi(resets[0])
+		if err != nil {
+			return 0
+		}
+		return time.Duration(sec) * time.Second
+	}
+	return 0
+}
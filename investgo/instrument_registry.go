@@ -0,0 +1,533 @@
+package investgo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/russianinvestments/invest-api-go-sdk/proto"
+)
+
+var (
+	// ErrInstrumentNotFound - идентификатор не найден в реестре инструментов
+	ErrInstrumentNotFound = errors.New("instrument not found in registry")
+	// ErrAmbiguousInstrument - тикер, isin или position uid есть у инструментов в нескольких режимах торгов,
+	// нужно указать uid, figi или тикер с class code
+	ErrAmbiguousInstrument = errors.New("ambiguous instrument id")
+)
+
+// registryKinds - типы инструментов реестра по умолчанию
+var registryKinds = []pb.InstrumentType{
+	pb.InstrumentType_INSTRUMENT_TYPE_SHARE,
+	pb.InstrumentType_INSTRUMENT_TYPE_BOND,
+	pb.InstrumentType_INSTRUMENT_TYPE_ETF,
+	pb.InstrumentType_INSTRUMENT_TYPE_FUTURES,
+	pb.InstrumentType_INSTRUMENT_TYPE_CURRENCY,
+	pb.InstrumentType_INSTRUMENT_TYPE_OPTION,
+}
+
+// InstrumentRegistryConfig - настройки реестра инструментов
+type InstrumentRegistryConfig struct {
+	// Status - статус загружаемых инструментов, по умолчанию INSTRUMENT_STATUS_BASE
+	Status pb.InstrumentStatus
+	// Kinds - типы загружаемых инструментов: акции, облигации, фонды, фьючерсы, валюты и опционы.
+	// По умолчанию загружаются все
+	Kinds []pb.InstrumentType
+	// SnapshotPath - файл снимка реестра. Если файл есть, инструменты при создании реестра загружаются из него,
+	// после каждого обновления снимок перезаписывается. Снимок инструментов с другим Status не используется.
+	// Пустой путь - без снимка
+	SnapshotPath string
+	// MaxSnapshotAge - снимок старше MaxSnapshotAge при создании реестра не используется, инструменты загружаются
+	// с сервера. 0 - снимок любого возраста
+	MaxSnapshotAge time.Duration
+	// RefreshInterval - период фонового обновления реестра. 0 - реестр не обновляется
+	RefreshInterval time.Duration
+}
+
+// InstrumentInfo - инструмент реестра в едином виде для всех типов инструментов
+type InstrumentInfo struct {
+	Kind        pb.InstrumentType
+	Uid         string
+	PositionUid string
+	// Figi, Isin - пустые, если у инструмента их нет, например у опционов
+	Figi      string
+	Isin      string
+	Ticker    string
+	ClassCode string
+	Name      string
+	Exchange  string
+	// Lot - лотность инструмента
+	Lot      int32
+	Currency string
+	// MinPriceIncrement - шаг цены
+	MinPriceIncrement *pb.Quotation
+	TradingStatus     pb.SecurityTradingStatus
+
+	BuyAvailable      bool
+	SellAvailable     bool
+	ApiTradeAvailable bool
+	ShortEnabled      bool
+	ForQualInvestor   bool
+	WeekendAvailable  bool
+	Otc               bool
+
+	// Message - полное описание инструмента: *pb.Share, *pb.Bond, *pb.Etf, *pb.Future, *pb.Currency или *pb.Option
+	Message proto.Message
+}
+
+// Share - Описание акции, nil для других типов инструментов
+func (i *InstrumentInfo) Share() *pb.Share {
+	v, _ := i.Message.(*pb.Share)
+	return v
+}
+
+// Bond - Описание облигации, nil для других типов инструментов
+func (i *InstrumentInfo) Bond() *pb.Bond {
+	v, _ := i.Message.(*pb.Bond)
+	return v
+}
+
+// Etf - Описание фонда, nil для других типов инструментов
+func (i *InstrumentInfo) Etf() *pb.Etf {
+	v, _ := i.Message.(*pb.Etf)
+	return v
+}
+
+// Future - Описание фьючерса, nil для других типов инструментов
+func (i *InstrumentInfo) Future() *pb.Future {
+	v, _ := i.Message.(*pb.Future)
+	return v
+}
+
+// CurrencyInstrument - Описание валюты, nil для других типов инструментов
+func (i *InstrumentInfo) CurrencyInstrument() *pb.Currency {
+	v, _ := i.Message.(*pb.Currency)
+	return v
+}
+
+// Option - Описание опциона, nil для других типов инструментов
+func (i *InstrumentInfo) Option() *pb.Option {
+	v, _ := i.Message.(*pb.Option)
+	return v
+}
+
+// Tradable - Инструмент доступен для покупки и продажи через API в текущем торговом статусе
+func (i *InstrumentInfo) Tradable() bool {
+	return i.ApiTradeAvailable && i.BuyAvailable && i.SellAvailable &&
+		i.TradingStatus == pb.SecurityTradingStatus_SECURITY_TRADING_STATUS_NORMAL_TRADING
+}
+
+// instrumentMessage - поля, общие для всех типов инструментов
+type instrumentMessage interface {
+	proto.Message
+	GetUid() string
+	GetPositionUid() string
+	GetTicker() string
+	GetClassCode() string
+	GetName() string
+	GetExchange() string
+	GetLot() int32
+	GetCurrency() string
+	GetMinPriceIncrement() *pb.Quotation
+	GetTradingStatus() pb.SecurityTradingStatus
+	GetBuyAvailableFlag() bool
+	GetSellAvailableFlag() bool
+	GetApiTradeAvailableFlag() bool
+	GetShortEnabledFlag() bool
+	GetForQualInvestorFlag() bool
+	GetWeekendFlag() bool
+	GetOtcFlag() bool
+}
+
+func newInstrumentInfo(kind pb.InstrumentType, m instrumentMessage) *InstrumentInfo {
+	info := &InstrumentInfo{
+		Kind:              kind,
+		Uid:               m.GetUid(),
+		PositionUid:       m.GetPositionUid(),
+		Ticker:            m.GetTicker(),
+		ClassCode:         m.GetClassCode(),
+		Name:              m.GetName(),
+		Exchange:          m.GetExchange(),
+		Lot:               m.GetLot(),
+		Currency:          m.GetCurrency(),
+		MinPriceIncrement: m.GetMinPriceIncrement(),
+		TradingStatus:     m.GetTradingStatus(),
+		BuyAvailable:      m.GetBuyAvailableFlag(),
+		SellAvailable:     m.GetSellAvailableFlag(),
+		ApiTradeAvailable: m.GetApiTradeAvailableFlag(),
+		ShortEnabled:      m.GetShortEnabledFlag(),
+		ForQualInvestor:   m.GetForQualInvestorFlag(),
+		WeekendAvailable:  m.GetWeekendFlag(),
+		Otc:               m.GetOtcFlag(),
+		Message:           m,
+	}
+	if f, ok := m.(interface{ GetFigi() string }); ok {
+		info.Figi = f.GetFigi()
+	}
+	if f, ok := m.(interface{ GetIsin() string }); ok {
+		info.Isin = f.GetIsin()
+	}
+	return info
+}
+
+// instrumentIndex - инструменты реестра и их индексы по идентификаторам
+type instrumentIndex struct {
+	list []*InstrumentInfo
+	// ids - uid, figi и ticker_classcode в верхнем регистре
+	ids map[string]*InstrumentInfo
+	// shared - инструменты по тикеру, isin и position uid, которые совпадают у инструмента в разных режимах торгов
+	shared map[string][]*InstrumentInfo
+}
+
+func newInstrumentIndex(list []*InstrumentInfo) *instrumentIndex {
+	idx := &instrumentIndex{
+		list:   list,
+		ids:    make(map[string]*InstrumentInfo, len(list)*3),
+		shared: make(map[string][]*InstrumentInfo, len(list)*3),
+	}
+	for _, info := range list {
+		for _, id := range []string{info.Uid, info.Figi, info.Ticker + "_" + info.ClassCode} {
+			key := strings.ToUpper(id)
+			if id == "" || key == "_" {
+				continue
+			}
+			if _, ok := idx.ids[key]; !ok {
+				idx.ids[key] = info
+			}
+		}
+		for _, id := range []string{info.Ticker, info.Isin, info.PositionUid} {
+			if id == "" {
+				continue
+			}
+			key := strings.ToUpper(id)
+			idx.shared[key] = append(idx.shared[key], info)
+		}
+	}
+	return idx
+}
+
+// InstrumentRegistry - локальный реестр инструментов. Реестр загружает все инструменты выбранных типов одним
+// запросом на тип и находит инструмент по uid, position uid, figi, isin, тикеру с class code или тикеру без
+// обращения к серверу:
+//
+//	reg, err := investgo.NewInstrumentRegistry(client.NewInstrumentsServiceClient(), investgo.InstrumentRegistryConfig{
+//		SnapshotPath:    "instruments.json",
+//		RefreshInterval: 12 * time.Hour,
+//	})
+//	...
+//	defer reg.Stop()
+//	sber, err := reg.Resolve("SBER_TQBR")
+type InstrumentRegistry struct {
+	is   *InstrumentsServiceClient
+	conf InstrumentRegistryConfig
+
+	mu      sync.RWMutex
+	idx     *instrumentIndex
+	updated time.Time
+
+	stop     context.CancelFunc
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+// NewInstrumentRegistry - Создание реестра. Инструменты загружаются из снимка conf.SnapshotPath, если он есть и не
+// старше conf.MaxSnapshotAge, иначе с сервера. Если сервер недоступен, используется снимок любого возраста
+func NewInstrumentRegistry(is *InstrumentsServiceClient, conf InstrumentRegistryConfig) (*InstrumentRegistry, error) {
+	if conf.Status == pb.InstrumentStatus_INSTRUMENT_STATUS_UNSPECIFIED {
+		conf.Status = pb.InstrumentStatus_INSTRUMENT_STATUS_BASE
+	}
+	if len(conf.Kinds) == 0 {
+		conf.Kinds = registryKinds
+	}
+	ctx, cancel := context.WithCancel(is.ctx)
+	r := &InstrumentRegistry{
+		is:   is,
+		conf: conf,
+		idx:  newInstrumentIndex(nil),
+		stop: cancel,
+		done: make(chan struct{}),
+	}
+
+	snapshotErr := errors.New("no snapshot")
+	if conf.SnapshotPath != "" {
+		snapshotErr = r.loadSnapshot()
+	}
+	stale := snapshotErr == nil && conf.MaxSnapshotAge > 0 && time.Since(r.LastRefresh()) > conf.MaxSnapshotAge
+	if snapshotErr != nil || stale {
+		if err := r.RefreshCtx(ctx); err != nil {
+			if snapshotErr != nil {
+				cancel()
+				return nil, err
+			}
+			is.logger.Errorf("instrument registry refresh: %v, using snapshot from %v", err, r.LastRefresh())
+		}
+	}
+
+	if conf.RefreshInterval > 0 {
+		go r.refreshLoop(ctx)
+	} else {
+		close(r.done)
+	}
+	return r, nil
+}
+
+func (r *InstrumentRegistry) refreshLoop(ctx context.Context) {
+	defer close(r.done)
+	ticker := time.NewTicker(r.conf.RefreshInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := r.RefreshCtx(ctx); err != nil && ctx.Err() == nil {
+				r.is.logger.Errorf("instrument registry refresh: %v", err)
+			}
+		}
+	}
+}
+
+// Stop - Остановка фонового обновления реестра
+func (r *InstrumentRegistry) Stop() {
+	r.stopOnce.Do(r.stop)
+	<-r.done
+}
+
+// Refresh - Загрузка всех инструментов с сервера и перезапись снимка
+func (r *InstrumentRegistry) Refresh() error {
+	return r.RefreshCtx(r.is.ctx)
+}
+
+// RefreshCtx - то же, что и Refresh, но с контекстом запроса ctx
+func (r *InstrumentRegistry) RefreshCtx(ctx context.Context) error {
+	var list []*InstrumentInfo
+	for _, kind := range r.conf.Kinds {
+		infos, err := r.load(ctx, kind)
+		if err != nil {
+			return err
+		}
+		list = append(list, infos...)
+	}
+	r.set(list, time.Now())
+	if r.conf.SnapshotPath != "" {
+		if err := r.saveSnapshot(); err != nil {
+			return fmt.Errorf("instrument registry snapshot: %w", err)
+		}
+	}
+	return nil
+}
+
+func (r *InstrumentRegistry) load(ctx context.Context, kind pb.InstrumentType) ([]*InstrumentInfo, error) {
+	status := r.conf.Status
+	switch kind {
+	case pb.InstrumentType_INSTRUMENT_TYPE_SHARE:
+		resp, err := r.is.SharesCtx(ctx, status)
+		return instrumentInfos(kind, resp.GetInstruments()), err
+	case pb.InstrumentType_INSTRUMENT_TYPE_BOND:
+		resp, err := r.is.BondsCtx(ctx, status)
+		return instrumentInfos(kind, resp.GetInstruments()), err
+	case pb.InstrumentType_INSTRUMENT_TYPE_ETF:
+		resp, err := r.is.EtfsCtx(ctx, status)
+		return instrumentInfos(kind, resp.GetInstruments()), err
+	case pb.InstrumentType_INSTRUMENT_TYPE_FUTURES:
+		resp, err := r.is.FuturesCtx(ctx, status)
+		return instrumentInfos(kind, resp.GetInstruments()), err
+	case pb.InstrumentType_INSTRUMENT_TYPE_CURRENCY:
+		resp, err := r.is.CurrenciesCtx(ctx, status)
+		return instrumentInfos(kind, resp.GetInstruments()), err
+	case pb.InstrumentType_INSTRUMENT_TYPE_OPTION:
+		resp, err := r.is.OptionsCtx(ctx, status)
+		return instrumentInfos(kind, resp.GetInstruments()), err
+	}
+	return nil, fmt.Errorf("instrument registry: unsupported instrument type %v", kind)
+}
+
+func instrumentInfos[T instrumentMessage](kind pb.InstrumentType, list []T) []*InstrumentInfo {
+	infos := make([]*InstrumentInfo, 0, len(list))
+	for _, m := range list {
+		infos = append(infos, newInstrumentInfo(kind, m))
+	}
+	return infos
+}
+
+func (r *InstrumentRegistry) set(list []*InstrumentInfo, updated time.Time) {
+	idx := newInstrumentIndex(list)
+	r.mu.Lock()
+	r.idx = idx
+	r.updated = updated
+	r.mu.Unlock()
+}
+
+// LastRefresh - Время загрузки инструментов с сервера, для инструментов из снимка - время создания снимка
+func (r *InstrumentRegistry) LastRefresh() time.Time {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.updated
+}
+
+// Resolve - Поиск инструмента по uid, position uid, figi, isin, тикеру с class code (SBER_TQBR) или тикеру.
+// Регистр не важен. Если тикер без class code, isin или position uid есть у инструментов в нескольких режимах
+// торгов, возвращается ErrAmbiguousInstrument
+func (r *InstrumentRegistry) Resolve(id string) (*InstrumentInfo, error) {
+	key := strings.ToUpper(strings.TrimSpace(id))
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if info, ok := r.idx.ids[key]; ok {
+		return info, nil
+	}
+	switch infos := r.idx.shared[key]; len(infos) {
+	case 0:
+		return nil, fmt.Errorf("%w: %v", ErrInstrumentNotFound, id)
+	case 1:
+		return infos[0], nil
+	default:
+		return nil, fmt.Errorf("%w: %v matches %v instruments", ErrAmbiguousInstrument, id, len(infos))
+	}
+}
+
+// ByTicker - Поиск инструмента по тикеру и class code
+func (r *InstrumentRegistry) ByTicker(ticker, classCode string) (*InstrumentInfo, error) {
+	return r.Resolve(ticker + "_" + classCode)
+}
+
+// Instruments - Инструменты типа kind, INSTRUMENT_TYPE_UNSPECIFIED - все инструменты реестра
+func (r *InstrumentRegistry) Instruments(kind pb.InstrumentType) []*InstrumentInfo {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	list := make([]*InstrumentInfo, 0)
+	for _, info := range r.idx.list {
+		if kind == pb.InstrumentType_INSTRUMENT_TYPE_UNSPECIFIED || info.Kind == kind {
+			list = append(list, info)
+		}
+	}
+	return list
+}
+
+// instrumentSnapshot - файл снимка реестра, списки инструментов хранятся в формате ответов API
+type instrumentSnapshot struct {
+	Time time.Time `json:"time"`
+	// Status - статус инструментов, с которым они загружены
+	Status pb.InstrumentStatus `json:"status"`
+
+	Shares     json.RawMessage `json:"shares,omitempty"`
+	Bonds      json.RawMessage `json:"bonds,omitempty"`
+	Etfs       json.RawMessage `json:"etfs,omitempty"`
+	Futures    json.RawMessage `json:"futures,omitempty"`
+	Currencies json.RawMessage `json:"currencies,omitempty"`
+	Options    json.RawMessage `json:"options,omitempty"`
+}
+
+func (r *InstrumentRegistry) saveSnapshot() error {
+	r.mu.RLock()
+	snap := instrumentSnapshot{Time: r.updated, Status: r.conf.Status}
+	shares, bonds, etfs := &pb.SharesResponse{}, &pb.BondsResponse{}, &pb.EtfsResponse{}
+	futures, currencies, options := &pb.FuturesResponse{}, &pb.CurrenciesResponse{}, &pb.OptionsResponse{}
+	for _, info := range r.idx.list {
+		switch m := info.Message.(type) {
+		case *pb.Share:
+			shares.Instruments = append(shares.Instruments, m)
+		case *pb.Bond:
+			bonds.Instruments = append(bonds.Instruments, m)
+		case *pb.Etf:
+			etfs.Instruments = append(etfs.Instruments, m)
+		case *pb.Future:
+			futures.Instruments = append(futures.Instruments, m)
+		case *pb.Currency:
+			currencies.Instruments = append(currencies.Instruments, m)
+		case *pb.Option:
+			options.Instruments = append(options.Instruments, m)
+		}
+	}
+	r.mu.RUnlock()
+
+	var err error
+	for _, part := range []struct {
+		dst *json.RawMessage
+		m   proto.Message
+	}{
+		{&snap.Shares, shares}, {&snap.Bonds, bonds}, {&snap.Etfs, etfs},
+		{&snap.Futures, futures}, {&snap.Currencies, currencies}, {&snap.Options, options},
+	} {
+		if *part.dst, err = protojson.Marshal(part.m); err != nil {
+			return err
+		}
+	}
+	data, err := json.Marshal(snap)
+	if err != nil {
+		return err
+	}
+	// снимок пишется во временный файл и переименовывается, чтобы при сбое не остался обрезанный файл
+	tmp, err := os.CreateTemp(filepath.Dir(r.conf.SnapshotPath), filepath.Base(r.conf.SnapshotPath)+".tmp*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), r.conf.SnapshotPath)
+}
+
+func (r *InstrumentRegistry) loadSnapshot() error {
+	data, err := os.ReadFile(r.conf.SnapshotPath)
+	if err != nil {
+		return err
+	}
+	var snap instrumentSnapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return err
+	}
+	if snap.Status != r.conf.Status {
+		return fmt.Errorf("instrument snapshot status %v, want %v", snap.Status, r.conf.Status)
+	}
+	shares, bonds, etfs := &pb.SharesResponse{}, &pb.BondsResponse{}, &pb.EtfsResponse{}
+	futures, currencies, options := &pb.FuturesResponse{}, &pb.CurrenciesResponse{}, &pb.OptionsResponse{}
+	unmarshal := protojson.UnmarshalOptions{DiscardUnknown: true}
+	for _, part := range []struct {
+		src json.RawMessage
+		m   proto.Message
+	}{
+		{snap.Shares, shares}, {snap.Bonds, bonds}, {snap.Etfs, etfs},
+		{snap.Futures, futures}, {snap.Currencies, currencies}, {snap.Options, options},
+	} {
+		if len(part.src) == 0 {
+			continue
+		}
+		if err := unmarshal.Unmarshal(part.src, part.m); err != nil {
+			return err
+		}
+	}
+	var list []*InstrumentInfo
+	for _, kind := range r.conf.Kinds {
+		switch kind {
+		case pb.InstrumentType_INSTRUMENT_TYPE_SHARE:
+			list = append(list, instrumentInfos(kind, shares.GetInstruments())...)
+		case pb.InstrumentType_INSTRUMENT_TYPE_BOND:
+			list = append(list, instrumentInfos(kind, bonds.GetInstruments())...)
+		case pb.InstrumentType_INSTRUMENT_TYPE_ETF:
+			list = append(list, instrumentInfos(kind, etfs.GetInstruments())...)
+		case pb.InstrumentType_INSTRUMENT_TYPE_FUTURES:
+			list = append(list, instrumentInfos(kind, futures.GetInstruments())...)
+		case pb.InstrumentType_INSTRUMENT_TYPE_CURRENCY:
+			list = append(list, instrumentInfos(kind, currencies.GetInstruments())...)
+		case pb.InstrumentType_INSTRUMENT_TYPE_OPTION:
+			list = append(list, instrumentInfos(kind, options.GetInstruments())...)
+		}
+	}
+	r.set(list, snap.Time)
+	return nil
+}
@@ -0,0 +1,87 @@
+package investgo_test
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/russianinvestments/invest-api-go-sdk/investgo"
+	pb "github.com/russianinvestments/invest-api-go-sdk/proto"
+)
+
+func TestInstrumentRegistryResolve(t *testing.T) {
+	srv, client, _ := newTestServer(t, 0)
+	// одна бумага в двух режимах торгов: isin и position uid общие, uid и figi разные
+	const isin, positionUid = "RU0009029540", "41eb2102-5333-4713-bf15-72b204c4bf7b"
+	tqbr := srv.AddShare(&pb.Share{Figi: "BBG004730N88", Ticker: "SBER", ClassCode: "TQBR", Isin: isin, PositionUid: positionUid})
+	smal := srv.AddShare(&pb.Share{Figi: "BBG00QPYJ5H0", Ticker: "SBER", ClassCode: "SMAL", Isin: isin, PositionUid: positionUid})
+	gazp := srv.AddShare(&pb.Share{Figi: "BBG004730RP0", Ticker: "GAZP", ClassCode: "TQBR", Isin: "RU0007661625"})
+	reg, err := investgo.NewInstrumentRegistry(client.NewInstrumentsServiceClient(), investgo.InstrumentRegistryConfig{
+		Kinds: []pb.InstrumentType{pb.InstrumentType_INSTRUMENT_TYPE_SHARE},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reg.Stop()
+
+	for id, uid := range map[string]string{
+		tqbr:           tqbr,
+		"bbg00qpyj5h0": smal,
+		"SBER_SMAL":    smal,
+		"gazp":         gazp,
+		"RU0007661625": gazp,
+	} {
+		info, err := reg.Resolve(id)
+		if err != nil {
+			t.Fatalf("resolve %v: %v", id, err)
+		}
+		if info.Uid != uid {
+			t.Fatalf("resolve %v = %v, want %v", id, info.Uid, uid)
+		}
+	}
+	for _, id := range []string{"SBER", isin, positionUid} {
+		if _, err := reg.Resolve(id); !errors.Is(err, investgo.ErrAmbiguousInstrument) {
+			t.Fatalf("resolve %v: err = %v, want ErrAmbiguousInstrument", id, err)
+		}
+	}
+	if _, err := reg.Resolve("unknown"); !errors.Is(err, investgo.ErrInstrumentNotFound) {
+		t.Fatalf("resolve unknown: err = %v", err)
+	}
+}
+
+func TestInstrumentRegistrySnapshotStatus(t *testing.T) {
+	srv, client, _ := newTestServer(t, 1)
+	path := filepath.Join(t.TempDir(), "instruments.json")
+	open := func(status pb.InstrumentStatus) *investgo.InstrumentRegistry {
+		t.Helper()
+		reg, err := investgo.NewInstrumentRegistry(client.NewInstrumentsServiceClient(), investgo.InstrumentRegistryConfig{
+			Status:       status,
+			Kinds:        []pb.InstrumentType{pb.InstrumentType_INSTRUMENT_TYPE_SHARE},
+			SnapshotPath: path,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(reg.Stop)
+		return reg
+	}
+	open(pb.InstrumentStatus_INSTRUMENT_STATUS_UNSPECIFIED)
+	srv.AddShare(&pb.Share{Figi: "BBG004730RP0", Ticker: "GAZP", ClassCode: "TQBR"})
+
+	// реестр с тем же статусом загружается из снимка и не видит новый инструмент
+	if n := len(open(pb.InstrumentStatus_INSTRUMENT_STATUS_BASE).Instruments(pb.InstrumentType_INSTRUMENT_TYPE_UNSPECIFIED)); n != 1 {
+		t.Fatalf("instruments from snapshot = %v", n)
+	}
+	// снимок с другим статусом не используется, инструменты загружаются с сервера
+	if n := len(open(pb.InstrumentStatus_INSTRUMENT_STATUS_ALL).Instruments(pb.InstrumentType_INSTRUMENT_TYPE_UNSPECIFIED)); n != 2 {
+		t.Fatalf("instruments from server = %v", n)
+	}
+	// теперь в снимке статус ALL, и без сервера реестр с BASE не создается
+	srv.Stop()
+	if _, err := investgo.NewInstrumentRegistry(client.NewInstrumentsServiceClient(), investgo.InstrumentRegistryConfig{
+		Kinds:        []pb.InstrumentType{pb.InstrumentType_INSTRUMENT_TYPE_SHARE},
+		SnapshotPath: path,
+	}); err == nil {
+		t.Fatal("snapshot with another status used")
+	}
+}
@@ -20,8 +20,13 @@ type InstrumentsServiceClient struct {
 
 // TradingSchedules - Метод получения расписания торгов торговых площадок
 func (is *InstrumentsServiceClient) TradingSchedules(exchange string, from, to time.Time) (*TradingSchedulesResponse, error) {
+	return is.TradingSchedulesCtx(is.ctx, exchange, from, to)
+}
+
+// TradingSchedulesCtx - то же, что и TradingSchedules, но с контекстом запроса ctx
+func (is *InstrumentsServiceClient) TradingSchedulesCtx(ctx context.Context, exchange string, from, to time.Time) (*TradingSchedulesResponse, error) {
 	var header, trailer metadata.MD
-	resp, err := is.pbClient.TradingSchedules(is.ctx, &pb.TradingSchedulesRequest{
+	resp, err := is.pbClient.TradingSchedules(ctx, &pb.TradingSchedulesRequest{
 		Exchange: &exchange,
 		From:     TimeToTimestamp(from),
 		To:       TimeToTimestamp(to),
@@ -37,27 +42,47 @@ func (is *InstrumentsServiceClient) TradingSchedules(exchange string, from, to t
 
 // BondByFigi - Метод получения облигации по figi
 func (is *InstrumentsServiceClient) BondByFigi(id string) (*BondResponse, error) {
-	return is.bondBy(id, pb.InstrumentIdType_INSTRUMENT_ID_TYPE_FIGI, "")
+	return is.BondByFigiCtx(is.ctx, id)
+}
+
+// BondByFigiCtx - то же, что и BondByFigi, но с контекстом запроса ctx
+func (is *InstrumentsServiceClient) BondByFigiCtx(ctx context.Context, id string) (*BondResponse, error) {
+	return is.bondBy(ctx, id, pb.InstrumentIdType_INSTRUMENT_ID_TYPE_FIGI, "")
 }
 
 // BondByTicker - Метод получения облигации по Ticker
 func (is *InstrumentsServiceClient) BondByTicker(id string, classCode string) (*BondResponse, error) {
-	return is.bondBy(id, pb.InstrumentIdType_INSTRUMENT_ID_TYPE_TICKER, classCode)
+	return is.BondByTickerCtx(is.ctx, id, classCode)
+}
+
+// BondByTickerCtx - то же, что и BondByTicker, но с контекстом запроса ctx
+func (is *InstrumentsServiceClient) BondByTickerCtx(ctx context.Context, id string, classCode string) (*BondResponse, error) {
+	return is.bondBy(ctx, id, pb.InstrumentIdType_INSTRUMENT_ID_TYPE_TICKER, classCode)
 }
 
 // BondByUid - Метод получения облигации по Uid
 func (is *InstrumentsServiceClient) BondByUid(id string) (*BondResponse, error) {
-	return is.bondBy(id, pb.InstrumentIdType_INSTRUMENT_ID_TYPE_UID, "")
+	return is.BondByUidCtx(is.ctx, id)
+}
+
+// BondByUidCtx - то же, что и BondByUid, но с контекстом запроса ctx
+func (is *InstrumentsServiceClient) BondByUidCtx(ctx context.Context, id string) (*BondResponse, error) {
+	return is.bondBy(ctx, id, pb.InstrumentIdType_INSTRUMENT_ID_TYPE_UID, "")
 }
 
 // BondByPositionUid - Метод получения облигации по PositionUid
 func (is *InstrumentsServiceClient) BondByPositionUid(id string) (*BondResponse, error) {
-	return is.bondBy(id, pb.InstrumentIdType_INSTRUMENT_ID_TYPE_POSITION_UID, "")
+	return is.BondByPositionUidCtx(is.ctx, id)
 }
 
-func (is *InstrumentsServiceClient) bondBy(id string, idType pb.InstrumentIdType, classCode string) (*BondResponse, error) {
+// BondByPositionUidCtx - то же, что и BondByPositionUid, но с контекстом запроса ctx
+func (is *InstrumentsServiceClient) BondByPositionUidCtx(ctx context.Context, id string) (*BondResponse, error) {
+	return is.bondBy(ctx, id, pb.InstrumentIdType_INSTRUMENT_ID_TYPE_POSITION_UID, "")
+}
+
+func (is *InstrumentsServiceClient) bondBy(ctx context.Context, id string, idType pb.InstrumentIdType, classCode string) (*BondResponse, error) {
 	var header, trailer metadata.MD
-	resp, err := is.pbClient.BondBy(is.ctx, &pb.InstrumentRequest{
+	resp, err := is.pbClient.BondBy(ctx, &pb.InstrumentRequest{
 		IdType:    idType,
 		ClassCode: &classCode,
 		Id:        id,
@@ -73,8 +98,13 @@ func (is *InstrumentsServiceClient) bondBy(id string, idType pb.InstrumentIdType
 
 // Bonds - Метод получения списка облигаций
 func (is *InstrumentsServiceClient) Bonds(status pb.InstrumentStatus) (*BondsResponse, error) {
+	return is.BondsCtx(is.ctx, status)
+}
+
+// BondsCtx - то же, что и Bonds, но с контекстом запроса ctx
+func (is *InstrumentsServiceClient) BondsCtx(ctx context.Context, status pb.InstrumentStatus) (*BondsResponse, error) {
 	var header, trailer metadata.MD
-	resp, err := is.pbClient.Bonds(is.ctx, &pb.InstrumentsRequest{
+	resp, err := is.pbClient.Bonds(ctx, &pb.InstrumentsRequest{
 		InstrumentStatus: &status,
 	}, grpc.Header(&header), grpc.Trailer(&trailer))
 	if err != nil {
@@ -88,8 +118,13 @@ func (is *InstrumentsServiceClient) Bonds(status pb.InstrumentStatus) (*BondsRes
 
 // GetBondCoupons - Метод получения графика выплат купонов по облигации
 func (is *InstrumentsServiceClient) GetBondCoupons(instrumentID string, from, to time.Time) (*GetBondCouponsResponse, error) {
+	return is.GetBondCouponsCtx(is.ctx, instrumentID, from, to)
+}
+
+// GetBondCouponsCtx - то же, что и GetBondCoupons, но с контекстом запроса ctx
+func (is *InstrumentsServiceClient) GetBondCouponsCtx(ctx context.Context, instrumentID string, from, to time.Time) (*GetBondCouponsResponse, error) {
 	var header, trailer metadata.MD
-	resp, err := is.pbClient.GetBondCoupons(is.ctx, &pb.GetBondCouponsRequest{
+	resp, err := is.pbClient.GetBondCoupons(ctx, &pb.GetBondCouponsRequest{
 		InstrumentId: instrumentID,
 		From:         TimeToTimestamp(from),
 		To:           TimeToTimestamp(to),
@@ -105,27 +140,47 @@ func (is *InstrumentsServiceClient) GetBondCoupons(instrumentID string, from, to
 
 // CurrencyByFigi - Метод получения валюты по Figi
 func (is *InstrumentsServiceClient) CurrencyByFigi(id string) (*CurrencyResponse, error) {
-	return is.currenceBy(id, pb.InstrumentIdType_INSTRUMENT_ID_TYPE_FIGI, "")
+	return is.CurrencyByFigiCtx(is.ctx, id)
+}
+
+// CurrencyByFigiCtx - то же, что и CurrencyByFigi, но с контекстом запроса ctx
+func (is *InstrumentsServiceClient) CurrencyByFigiCtx(ctx context.Context, id string) (*CurrencyResponse, error) {
+	return is.currenceBy(ctx, id, pb.InstrumentIdType_INSTRUMENT_ID_TYPE_FIGI, "")
 }
 
 // CurrencyByTicker - Метод получения валюты по Ticker
 func (is *InstrumentsServiceClient) CurrencyByTicker(id string, classCode string) (*CurrencyResponse, error) {
-	return is.currenceBy(id, pb.InstrumentIdType_INSTRUMENT_ID_TYPE_TICKER, classCode)
+	return is.CurrencyByTickerCtx(is.ctx, id, classCode)
+}
+
+// CurrencyByTickerCtx - то же, что и CurrencyByTicker, но с контекстом запроса ctx
+func (is *InstrumentsServiceClient) CurrencyByTickerCtx(ctx context.Context, id string, classCode string) (*CurrencyResponse, error) {
+	return is.currenceBy(ctx, id, pb.InstrumentIdType_INSTRUMENT_ID_TYPE_TICKER, classCode)
 }
 
 // CurrencyByUid - Метод получения валюты по Uid
 func (is *InstrumentsServiceClient) CurrencyByUid(id string) (*CurrencyResponse, error) {
-	return is.currenceBy(id, pb.InstrumentIdType_INSTRUMENT_ID_TYPE_UID, "")
+	return is.CurrencyByUidCtx(is.ctx, id)
+}
+
+// CurrencyByUidCtx - то же, что и CurrencyByUid, но с контекстом запроса ctx
+func (is *InstrumentsServiceClient) CurrencyByUidCtx(ctx context.Context, id string) (*CurrencyResponse, error) {
+	return is.currenceBy(ctx, id, pb.InstrumentIdType_INSTRUMENT_ID_TYPE_UID, "")
 }
 
 // CurrencyByPositionUid - Метод получения валюты по PositionUid
 func (is *InstrumentsServiceClient) CurrencyByPositionUid(id string) (*CurrencyResponse, error) {
-	return is.currenceBy(id, pb.InstrumentIdType_INSTRUMENT_ID_TYPE_POSITION_UID, "")
+	return is.CurrencyByPositionUidCtx(is.ctx, id)
 }
 
-func (is *InstrumentsServiceClient) currenceBy(id string, idType pb.InstrumentIdType, classCode string) (*CurrencyResponse, error) {
+// CurrencyByPositionUidCtx - то же, что и CurrencyByPositionUid, но с контекстом запроса ctx
+func (is *InstrumentsServiceClient) CurrencyByPositionUidCtx(ctx context.Context, id string) (*CurrencyResponse, error) {
+	return is.currenceBy(ctx, id, pb.InstrumentIdType_INSTRUMENT_ID_TYPE_POSITION_UID, "")
+}
+
+func (is *InstrumentsServiceClient) currenceBy(ctx context.Context, id string, idType pb.InstrumentIdType, classCode string) (*CurrencyResponse, error) {
 	var header, trailer metadata.MD
-	resp, err := is.pbClient.CurrencyBy(is.ctx, &pb.InstrumentRequest{
+	resp, err := is.pbClient.CurrencyBy(ctx, &pb.InstrumentRequest{
 		IdType:    idType,
 		ClassCode: &classCode,
 		Id:        id,
@@ -141,8 +196,13 @@ func (is *InstrumentsServiceClient) currenceBy(id string, idType pb.InstrumentId
 
 // Currencies - Метод получения списка валют
 func (is *InstrumentsServiceClient) Currencies(status pb.InstrumentStatus) (*CurrenciesResponse, error) {
+	return is.CurrenciesCtx(is.ctx, status)
+}
+
+// CurrenciesCtx - то же, что и Currencies, но с контекстом запроса ctx
+func (is *InstrumentsServiceClient) CurrenciesCtx(ctx context.Context, status pb.InstrumentStatus) (*CurrenciesResponse, error) {
 	var header, trailer metadata.MD
-	resp, err := is.pbClient.Currencies(is.ctx, &pb.InstrumentsRequest{
+	resp, err := is.pbClient.Currencies(ctx, &pb.InstrumentsRequest{
 		InstrumentStatus: &status,
 	}, grpc.Header(&header), grpc.Trailer(&trailer))
 	if err != nil {
@@ -156,27 +216,47 @@ func (is *InstrumentsServiceClient) Currencies(status pb.InstrumentStatus) (*Cur
 
 // EtfByFigi - Метод получения инвестиционного фонда по Figi
 func (is *InstrumentsServiceClient) EtfByFigi(id string) (*EtfResponse, error) {
-	return is.etfBy(id, pb.InstrumentIdType_INSTRUMENT_ID_TYPE_FIGI, "")
+	return is.EtfByFigiCtx(is.ctx, id)
+}
+
+// EtfByFigiCtx - то же, что и EtfByFigi, но с контекстом запроса ctx
+func (is *InstrumentsServiceClient) EtfByFigiCtx(ctx context.Context, id string) (*EtfResponse, error) {
+	return is.etfBy(ctx, id, pb.InstrumentIdType_INSTRUMENT_ID_TYPE_FIGI, "")
 }
 
 // EtfByTicker - Метод получения инвестиционного фонда по Ticker
 func (is *InstrumentsServiceClient) EtfByTicker(id string, classCode string) (*EtfResponse, error) {
-	return is.etfBy(id, pb.InstrumentIdType_INSTRUMENT_ID_TYPE_TICKER, classCode)
+	return is.EtfByTickerCtx(is.ctx, id, classCode)
+}
+
+// EtfByTickerCtx - то же, что и EtfByTicker, но с контекстом запроса ctx
+func (is *InstrumentsServiceClient) EtfByTickerCtx(ctx context.Context, id string, classCode string) (*EtfResponse, error) {
+	return is.etfBy(ctx, id, pb.InstrumentIdType_INSTRUMENT_ID_TYPE_TICKER, classCode)
 }
 
 // EtfByUid - Метод получения инвестиционного фонда по Uid
 func (is *InstrumentsServiceClient) EtfByUid(id string) (*EtfResponse, error) {
-	return is.etfBy(id, pb.InstrumentIdType_INSTRUMENT_ID_TYPE_UID, "")
+	return is.EtfByUidCtx(is.ctx, id)
+}
+
+// EtfByUidCtx - то же, что и EtfByUid, но с контекстом запроса ctx
+func (is *InstrumentsServiceClient) EtfByUidCtx(ctx context.Context, id string) (*EtfResponse, error) {
+	return is.etfBy(ctx, id, pb.InstrumentIdType_INSTRUMENT_ID_TYPE_UID, "")
 }
 
 // EtfByPositionUid - Метод получения инвестиционного фонда по PositionUid
 func (is *InstrumentsServiceClient) EtfByPositionUid(id string) (*EtfResponse, error) {
-	return is.etfBy(id, pb.InstrumentIdType_INSTRUMENT_ID_TYPE_POSITION_UID, "")
+	return is.EtfByPositionUidCtx(is.ctx, id)
+}
+
+// EtfByPositionUidCtx - то же, что и EtfByPositionUid, но с контекстом запроса ctx
+func (is *InstrumentsServiceClient) EtfByPositionUidCtx(ctx context.Context, id string) (*EtfResponse, error) {
+	return is.etfBy(ctx, id, pb.InstrumentIdType_INSTRUMENT_ID_TYPE_POSITION_UID, "")
 }
 
-func (is *InstrumentsServiceClient) etfBy(id string, idType pb.InstrumentIdType, classCode string) (*EtfResponse, error) {
+func (is *InstrumentsServiceClient) etfBy(ctx context.Context, id string, idType pb.InstrumentIdType, classCode string) (*EtfResponse, error) {
 	var header, trailer metadata.MD
-	resp, err := is.pbClient.EtfBy(is.ctx, &pb.InstrumentRequest{
+	resp, err := is.pbClient.EtfBy(ctx, &pb.InstrumentRequest{
 		IdType:    idType,
 		ClassCode: &classCode,
 		Id:        id,
@@ -192,8 +272,13 @@ func (is *InstrumentsServiceClient) etfBy(id string, idType pb.InstrumentIdType,
 
 // Etfs - Метод получения списка инвестиционных фондов
 func (is *InstrumentsServiceClient) Etfs(status pb.InstrumentStatus) (*EtfsResponse, error) {
+	return is.EtfsCtx(is.ctx, status)
+}
+
+// EtfsCtx - то же, что и Etfs, но с контекстом запроса ctx
+func (is *InstrumentsServiceClient) EtfsCtx(ctx context.Context, status pb.InstrumentStatus) (*EtfsResponse, error) {
 	var header, trailer metadata.MD
-	resp, err := is.pbClient.Etfs(is.ctx, &pb.InstrumentsRequest{
+	resp, err := is.pbClient.Etfs(ctx, &pb.InstrumentsRequest{
 		InstrumentStatus: &status,
 	}, grpc.Header(&header), grpc.Trailer(&trailer))
 	if err != nil {
@@ -207,27 +292,47 @@ func (is *InstrumentsServiceClient) Etfs(status pb.InstrumentStatus) (*EtfsRespo
 
 // FutureByFigi - Метод получения фьючерса по Figi
 func (is *InstrumentsServiceClient) FutureByFigi(id string) (*FutureResponse, error) {
-	return is.futureBy(id, pb.InstrumentIdType_INSTRUMENT_ID_TYPE_FIGI, "")
+	return is.FutureByFigiCtx(is.ctx, id)
+}
+
+// FutureByFigiCtx - то же, что и FutureByFigi, но с контекстом запроса ctx
+func (is *InstrumentsServiceClient) FutureByFigiCtx(ctx context.Context, id string) (*FutureResponse, error) {
+	return is.futureBy(ctx, id, pb.InstrumentIdType_INSTRUMENT_ID_TYPE_FIGI, "")
 }
 
 // FutureByTicker - Метод получения фьючерса по Ticker
 func (is *InstrumentsServiceClient) FutureByTicker(id string, classCode string) (*FutureResponse, error) {
-	return is.futureBy(id, pb.InstrumentIdType_INSTRUMENT_ID_TYPE_TICKER, classCode)
+	return is.FutureByTickerCtx(is.ctx, id, classCode)
+}
+
+// FutureByTickerCtx - то же, что и FutureByTicker, но с контекстом запроса ctx
+func (is *InstrumentsServiceClient) FutureByTickerCtx(ctx context.Context, id string, classCode string) (*FutureResponse, error) {
+	return is.futureBy(ctx, id, pb.InstrumentIdType_INSTRUMENT_ID_TYPE_TICKER, classCode)
 }
 
 // FutureByUid - Метод получения фьючерса по Uid
 func (is *InstrumentsServiceClient) FutureByUid(id string) (*FutureResponse, error) {
-	return is.futureBy(id, pb.InstrumentIdType_INSTRUMENT_ID_TYPE_UID, "")
+	return is.FutureByUidCtx(is.ctx, id)
+}
+
+// FutureByUidCtx - то же, что и FutureByUid, но с контекстом запроса ctx
+func (is *InstrumentsServiceClient) FutureByUidCtx(ctx context.Context, id string) (*FutureResponse, error) {
+	return is.futureBy(ctx, id, pb.InstrumentIdType_INSTRUMENT_ID_TYPE_UID, "")
 }
 
 // FutureByPositionUid - Метод получения фьючерса по PositionUid
 func (is *InstrumentsServiceClient) FutureByPositionUid(id string) (*FutureResponse, error) {
-	return is.futureBy(id, pb.InstrumentIdType_INSTRUMENT_ID_TYPE_POSITION_UID, "")
+	return is.FutureByPositionUidCtx(is.ctx, id)
+}
+
+// FutureByPositionUidCtx - то же, что и FutureByPositionUid, но с контекстом запроса ctx
+func (is *InstrumentsServiceClient) FutureByPositionUidCtx(ctx context.Context, id string) (*FutureResponse, error) {
+	return is.futureBy(ctx, id, pb.InstrumentIdType_INSTRUMENT_ID_TYPE_POSITION_UID, "")
 }
 
-func (is *InstrumentsServiceClient) futureBy(id string, idType pb.InstrumentIdType, classCode string) (*FutureResponse, error) {
+func (is *InstrumentsServiceClient) futureBy(ctx context.Context, id string, idType pb.InstrumentIdType, classCode string) (*FutureResponse, error) {
 	var header, trailer metadata.MD
-	resp, err := is.pbClient.FutureBy(is.ctx, &pb.InstrumentRequest{
+	resp, err := is.pbClient.FutureBy(ctx, &pb.InstrumentRequest{
 		IdType:    idType,
 		ClassCode: &classCode,
 		Id:        id,
@@ -243,8 +348,13 @@ func (is *InstrumentsServiceClient) futureBy(id string, idType pb.InstrumentIdTy
 
 // Futures - Метод получения списка фьючерсов
 func (is *InstrumentsServiceClient) Futures(status pb.InstrumentStatus) (*FuturesResponse, error) {
+	return is.FuturesCtx(is.ctx, status)
+}
+
+// FuturesCtx - то же, что и Futures, но с контекстом запроса ctx
+func (is *InstrumentsServiceClient) FuturesCtx(ctx context.Context, status pb.InstrumentStatus) (*FuturesResponse, error) {
 	var header, trailer metadata.MD
-	resp, err := is.pbClient.Futures(is.ctx, &pb.InstrumentsRequest{
+	resp, err := is.pbClient.Futures(ctx, &pb.InstrumentsRequest{
 		InstrumentStatus: &status,
 	}, grpc.Header(&header), grpc.Trailer(&trailer))
 	if err != nil {
@@ -258,22 +368,37 @@ func (is *InstrumentsServiceClient) Futures(status pb.InstrumentStatus) (*Future
 
 // OptionByTicker - Метод получения опциона по Ticker
 func (is *InstrumentsServiceClient) OptionByTicker(id string, classCode string) (*OptionResponse, error) {
-	return is.optionBy(id, pb.InstrumentIdType_INSTRUMENT_ID_TYPE_TICKER, classCode)
+	return is.OptionByTickerCtx(is.ctx, id, classCode)
+}
+
+// OptionByTickerCtx - то же, что и OptionByTicker, но с контекстом запроса ctx
+func (is *InstrumentsServiceClient) OptionByTickerCtx(ctx context.Context, id string, classCode string) (*OptionResponse, error) {
+	return is.optionBy(ctx, id, pb.InstrumentIdType_INSTRUMENT_ID_TYPE_TICKER, classCode)
 }
 
 // OptionByUid - Метод получения опциона по Uid
 func (is *InstrumentsServiceClient) OptionByUid(id string) (*OptionResponse, error) {
-	return is.optionBy(id, pb.InstrumentIdType_INSTRUMENT_ID_TYPE_UID, "")
+	return is.OptionByUidCtx(is.ctx, id)
+}
+
+// OptionByUidCtx - то же, что и OptionByUid, но с контекстом запроса ctx
+func (is *InstrumentsServiceClient) OptionByUidCtx(ctx context.Context, id string) (*OptionResponse, error) {
+	return is.optionBy(ctx, id, pb.InstrumentIdType_INSTRUMENT_ID_TYPE_UID, "")
 }
 
 // OptionByPositionUid - Метод получения опциона по PositionUid
 func (is *InstrumentsServiceClient) OptionByPositionUid(id string) (*OptionResponse, error) {
-	return is.optionBy(id, pb.InstrumentIdType_INSTRUMENT_ID_TYPE_POSITION_UID, "")
+	return is.OptionByPositionUidCtx(is.ctx, id)
+}
+
+// OptionByPositionUidCtx - то же, что и OptionByPositionUid, но с контекстом запроса ctx
+func (is *InstrumentsServiceClient) OptionByPositionUidCtx(ctx context.Context, id string) (*OptionResponse, error) {
+	return is.optionBy(ctx, id, pb.InstrumentIdType_INSTRUMENT_ID_TYPE_POSITION_UID, "")
 }
 
-func (is *InstrumentsServiceClient) optionBy(id string, idType pb.InstrumentIdType, classCode string) (*OptionResponse, error) {
+func (is *InstrumentsServiceClient) optionBy(ctx context.Context, id string, idType pb.InstrumentIdType, classCode string) (*OptionResponse, error) {
 	var header, trailer metadata.MD
-	resp, err := is.pbClient.OptionBy(is.ctx, &pb.InstrumentRequest{
+	resp, err := is.pbClient.OptionBy(ctx, &pb.InstrumentRequest{
 		IdType:    idType,
 		ClassCode: &classCode,
 		Id:        id,
@@ -291,8 +416,13 @@ func (is *InstrumentsServiceClient) optionBy(id string, idType pb.InstrumentIdTy
 //
 // Deprecated: Do not use
 func (is *InstrumentsServiceClient) Options(status pb.InstrumentStatus) (*OptionsResponse, error) {
+	return is.OptionsCtx(is.ctx, status)
+}
+
+// OptionsCtx - то же, что и Options, но с контекстом запроса ctx
+func (is *InstrumentsServiceClient) OptionsCtx(ctx context.Context, status pb.InstrumentStatus) (*OptionsResponse, error) {
 	var header, trailer metadata.MD
-	resp, err := is.pbClient.Options(is.ctx, &pb.InstrumentsRequest{
+	resp, err := is.pbClient.Options(ctx, &pb.InstrumentsRequest{
 		InstrumentStatus: &status,
 	}, grpc.Header(&header), grpc.Trailer(&trailer))
 	if err != nil {
@@ -304,29 +434,75 @@ func (is *InstrumentsServiceClient) Options(status pb.InstrumentStatus) (*Option
 	}, err
 }
 
+// OptionsBy - Метод получения списка опционов по базовому активу. Базовый актив задается uid или position uid,
+// пустое значение не используется в фильтре
+func (is *InstrumentsServiceClient) OptionsBy(basicAssetUid, basicAssetPositionUid string) (*OptionsResponse, error) {
+	return is.OptionsByCtx(is.ctx, basicAssetUid, basicAssetPositionUid)
+}
+
+// OptionsByCtx - то же, что и OptionsBy, но с контекстом запроса ctx
+func (is *InstrumentsServiceClient) OptionsByCtx(ctx context.Context, basicAssetUid, basicAssetPositionUid string) (*OptionsResponse, error) {
+	req := &pb.FilterOptionsRequest{}
+	if basicAssetUid != "" {
+		req.BasicAssetUid = &basicAssetUid
+	}
+	if basicAssetPositionUid != "" {
+		req.BasicAssetPositionUid = &basicAssetPositionUid
+	}
+	var header, trailer metadata.MD
+	resp, err := is.pbClient.OptionsBy(ctx, req, grpc.Header(&header), grpc.Trailer(&trailer))
+	if err != nil {
+		header = trailer
+	}
+	return &OptionsResponse{
+		OptionsResponse: resp,
+		Header:          header,
+	}, err
+}
+
 // ShareByFigi - Метод получения акции по Figi
 func (is *InstrumentsServiceClient) ShareByFigi(id string) (*ShareResponse, error) {
-	return is.shareBy(id, pb.InstrumentIdType_INSTRUMENT_ID_TYPE_FIGI, "")
+	return is.ShareByFigiCtx(is.ctx, id)
+}
+
+// ShareByFigiCtx - то же, что и ShareByFigi, но с контекстом запроса ctx
+func (is *InstrumentsServiceClient) ShareByFigiCtx(ctx context.Context, id string) (*ShareResponse, error) {
+	return is.shareBy(ctx, id, pb.InstrumentIdType_INSTRUMENT_ID_TYPE_FIGI, "")
 }
 
 // ShareByTicker - Метод получения акции по Ticker
 func (is *InstrumentsServiceClient) ShareByTicker(id string, classCode string) (*ShareResponse, error) {
-	return is.shareBy(id, pb.InstrumentIdType_INSTRUMENT_ID_TYPE_TICKER, classCode)
+	return is.ShareByTickerCtx(is.ctx, id, classCode)
+}
+
+// ShareByTickerCtx - то же, что и ShareByTicker, но с контекстом запроса ctx
+func (is *InstrumentsServiceClient) ShareByTickerCtx(ctx context.Context, id string, classCode string) (*ShareResponse, error) {
+	return is.shareBy(ctx, id, pb.InstrumentIdType_INSTRUMENT_ID_TYPE_TICKER, classCode)
 }
 
 // ShareByUid - Метод получения акции по Uid
 func (is *InstrumentsServiceClient) ShareByUid(id string) (*ShareResponse, error) {
-	return is.shareBy(id, pb.InstrumentIdType_INSTRUMENT_ID_TYPE_UID, "")
+	return is.ShareByUidCtx(is.ctx, id)
+}
+
+// ShareByUidCtx - то же, что и ShareByUid, но с контекстом запроса ctx
+func (is *InstrumentsServiceClient) ShareByUidCtx(ctx context.Context, id string) (*ShareResponse, error) {
+	return is.shareBy(ctx, id, pb.InstrumentIdType_INSTRUMENT_ID_TYPE_UID, "")
 }
 
 // ShareByPositionUid - Метод получения акции по PositionUid
 func (is *InstrumentsServiceClient) ShareByPositionUid(id string) (*ShareResponse, error) {
-	return is.shareBy(id, pb.InstrumentIdType_INSTRUMENT_ID_TYPE_POSITION_UID, "")
+	return is.ShareByPositionUidCtx(is.ctx, id)
+}
+
+// ShareByPositionUidCtx - то же, что и ShareByPositionUid, но с контекстом запроса ctx
+func (is *InstrumentsServiceClient) ShareByPositionUidCtx(ctx context.Context, id string) (*ShareResponse, error) {
+	return is.shareBy(ctx, id, pb.InstrumentIdType_INSTRUMENT_ID_TYPE_POSITION_UID, "")
 }
 
-func (is *InstrumentsServiceClient) shareBy(id string, idType pb.InstrumentIdType, classCode string) (*ShareResponse, error) {
+func (is *InstrumentsServiceClient) shareBy(ctx context.Context, id string, idType pb.InstrumentIdType, classCode string) (*ShareResponse, error) {
 	var header, trailer metadata.MD
-	resp, err := is.pbClient.ShareBy(is.ctx, &pb.InstrumentRequest{
+	resp, err := is.pbClient.ShareBy(ctx, &pb.InstrumentRequest{
 		IdType:    idType,
 		ClassCode: &classCode,
 		Id:        id,
@@ -342,8 +518,13 @@ func (is *InstrumentsServiceClient) shareBy(id string, idType pb.InstrumentIdTyp
 
 // Shares - Метод получения списка акций
 func (is *InstrumentsServiceClient) Shares(status pb.InstrumentStatus) (*SharesResponse, error) {
+	return is.SharesCtx(is.ctx, status)
+}
+
+// SharesCtx - то же, что и Shares, но с контекстом запроса ctx
+func (is *InstrumentsServiceClient) SharesCtx(ctx context.Context, status pb.InstrumentStatus) (*SharesResponse, error) {
 	var header, trailer metadata.MD
-	resp, err := is.pbClient.Shares(is.ctx, &pb.InstrumentsRequest{
+	resp, err := is.pbClient.Shares(ctx, &pb.InstrumentsRequest{
 		InstrumentStatus: &status,
 	}, grpc.Header(&header), grpc.Trailer(&trailer))
 	if err != nil {
@@ -357,27 +538,52 @@ func (is *InstrumentsServiceClient) Shares(status pb.InstrumentStatus) (*SharesR
 
 // InstrumentByFigi - Метод получения основной информации об инструменте
 func (is *InstrumentsServiceClient) InstrumentByFigi(id string) (*InstrumentResponse, error) {
-	return is.instrumentBy(id, pb.InstrumentIdType_INSTRUMENT_ID_TYPE_FIGI, "")
+	return is.InstrumentByFigiCtx(is.ctx, id)
+}
+
+// InstrumentByFigiCtx - то же, что и InstrumentByFigi, но с контекстом запроса ctx
+func (is *InstrumentsServiceClient) InstrumentByFigiCtx(ctx context.Context, id string) (*InstrumentResponse, error) {
+	return is.instrumentBy(ctx, id, pb.InstrumentIdType_INSTRUMENT_ID_TYPE_FIGI, "")
 }
 
 // InstrumentByTicker - Метод получения основной информации об инструменте
 func (is *InstrumentsServiceClient) InstrumentByTicker(id string, classCode string) (*InstrumentResponse, error) {
-	return is.instrumentBy(id, pb.InstrumentIdType_INSTRUMENT_ID_TYPE_TICKER, classCode)
+	return is.InstrumentByTickerCtx(is.ctx, id, classCode)
+}
+
+// InstrumentByTickerCtx - то же, что и InstrumentByTicker, но с контекстом запроса ctx
+func (is *InstrumentsServiceClient) InstrumentByTickerCtx(ctx context.Context, id string, classCode string) (*InstrumentResponse, error) {
+	return is.instrumentBy(ctx, id, pb.InstrumentIdType_INSTRUMENT_ID_TYPE_TICKER, classCode)
 }
 
 // InstrumentByUid - Метод получения основной информации об инструменте
 func (is *InstrumentsServiceClient) InstrumentByUid(id string) (*InstrumentResponse, error) {
-	return is.instrumentBy(id, pb.InstrumentIdType_INSTRUMENT_ID_TYPE_UID, "")
+	return is.InstrumentByUidCtx(is.ctx, id)
+}
+
+// InstrumentByUidCtx - то же, что и InstrumentByUid, но с контекстом запроса ctx
+func (is *InstrumentsServiceClient) InstrumentByUidCtx(ctx context.Context, id string) (*InstrumentResponse, error) {
+	return is.instrumentBy(ctx, id, pb.InstrumentIdType_INSTRUMENT_ID_TYPE_UID, "")
 }
 
 // InstrumentByPositionUid - Метод получения основной информации об инструменте
 func (is *InstrumentsServiceClient) InstrumentByPositionUid(id string) (*InstrumentResponse, error) {
-	return is.instrumentBy(id, pb.InstrumentIdType_INSTRUMENT_ID_TYPE_POSITION_UID, "")
+	return is.InstrumentByPositionUidCtx(is.ctx, id)
+}
+
+// InstrumentByPositionUidCtx - то же, что и InstrumentByPositionUid, но с контекстом запроса ctx
+func (is *InstrumentsServiceClient) InstrumentByPositionUidCtx(ctx context.Context, id string) (*InstrumentResponse, error) {
+	return is.instrumentBy(ctx, id, pb.InstrumentIdType_INSTRUMENT_ID_TYPE_POSITION_UID, "")
 }
 
 // LotByUid - Метод получения лотности инструмента по его Uid
 func (is *InstrumentsServiceClient) LotByUid(uid string) (int64, error) {
-	resp, err := is.InstrumentByUid(uid)
+	return is.LotByUidCtx(is.ctx, uid)
+}
+
+// LotByUidCtx - то же, что и LotByUid, но с контекстом запроса ctx
+func (is *InstrumentsServiceClient) LotByUidCtx(ctx context.Context, uid string) (int64, error) {
+	resp, err := is.InstrumentByUidCtx(ctx, uid)
 	if err != nil {
 		return 0, err
 	}
@@ -386,16 +592,21 @@ func (is *InstrumentsServiceClient) LotByUid(uid string) (int64, error) {
 
 // LotByFigi - Метод получения лотности инструмента по его FIGI
 func (is *InstrumentsServiceClient) LotByFigi(figi string) (int64, error) {
-	resp, err := is.InstrumentByFigi(figi)
+	return is.LotByFigiCtx(is.ctx, figi)
+}
+
+// LotByFigiCtx - то же, что и LotByFigi, но с контекстом запроса ctx
+func (is *InstrumentsServiceClient) LotByFigiCtx(ctx context.Context, figi string) (int64, error) {
+	resp, err := is.InstrumentByFigiCtx(ctx, figi)
 	if err != nil {
 		return 0, err
 	}
 	return int64(resp.GetInstrument().GetLot()), nil
 }
 
-func (is *InstrumentsServiceClient) instrumentBy(id string, idType pb.InstrumentIdType, classCode string) (*InstrumentResponse, error) {
+func (is *InstrumentsServiceClient) instrumentBy(ctx context.Context, id string, idType pb.InstrumentIdType, classCode string) (*InstrumentResponse, error) {
 	var header, trailer metadata.MD
-	resp, err := is.pbClient.GetInstrumentBy(is.ctx, &pb.InstrumentRequest{
+	resp, err := is.pbClient.GetInstrumentBy(ctx, &pb.InstrumentRequest{
 		IdType:    idType,
 		ClassCode: &classCode,
 		Id:        id,
@@ -411,8 +622,13 @@ func (is *InstrumentsServiceClient) instrumentBy(id string, idType pb.Instrument
 
 // GetAccruedInterests - Метод получения накопленного купонного дохода по облигации
 func (is *InstrumentsServiceClient) GetAccruedInterests(instrumentID string, from, to time.Time) (*GetAccruedInterestsResponse, error) {
+	return is.GetAccruedInterestsCtx(is.ctx, instrumentID, from, to)
+}
+
+// GetAccruedInterestsCtx - то же, что и GetAccruedInterests, но с контекстом запроса ctx
+func (is *InstrumentsServiceClient) GetAccruedInterestsCtx(ctx context.Context, instrumentID string, from, to time.Time) (*GetAccruedInterestsResponse, error) {
 	var header, trailer metadata.MD
-	resp, err := is.pbClient.GetAccruedInterests(is.ctx, &pb.GetAccruedInterestsRequest{
+	resp, err := is.pbClient.GetAccruedInterests(ctx, &pb.GetAccruedInterestsRequest{
 		InstrumentId: instrumentID,
 		From:         TimeToTimestamp(from),
 		To:           TimeToTimestamp(to),
@@ -428,8 +644,13 @@ func (is *InstrumentsServiceClient) GetAccruedInterests(instrumentID string, fro
 
 // GetFuturesMargin - Метод получения размера гарантийного обеспечения по фьючерсам
 func (is *InstrumentsServiceClient) GetFuturesMargin(instrumentID string) (*GetFuturesMarginResponse, error) {
+	return is.GetFuturesMarginCtx(is.ctx, instrumentID)
+}
+
+// GetFuturesMarginCtx - то же, что и GetFuturesMargin, но с контекстом запроса ctx
+func (is *InstrumentsServiceClient) GetFuturesMarginCtx(ctx context.Context, instrumentID string) (*GetFuturesMarginResponse, error) {
 	var header, trailer metadata.MD
-	resp, err := is.pbClient.GetFuturesMargin(is.ctx, &pb.GetFuturesMarginRequest{
+	resp, err := is.pbClient.GetFuturesMargin(ctx, &pb.GetFuturesMarginRequest{
 		InstrumentId: instrumentID,
 	}, grpc.Header(&header), grpc.Trailer(&trailer))
 	if err != nil {
@@ -443,8 +664,13 @@ func (is *InstrumentsServiceClient) GetFuturesMargin(instrumentID string) (*GetF
 
 // GetDividents - Метод для получения событий выплаты дивидендов по инструменту
 func (is *InstrumentsServiceClient) GetDividents(instrumentID string, from, to time.Time) (*GetDividendsResponse, error) {
+	return is.GetDividentsCtx(is.ctx, instrumentID, from, to)
+}
+
+// GetDividentsCtx - то же, что и GetDividents, но с контекстом запроса ctx
+func (is *InstrumentsServiceClient) GetDividentsCtx(ctx context.Context, instrumentID string, from, to time.Time) (*GetDividendsResponse, error) {
 	var header, trailer metadata.MD
-	resp, err := is.pbClient.GetDividends(is.ctx, &pb.GetDividendsRequest{
+	resp, err := is.pbClient.GetDividends(ctx, &pb.GetDividendsRequest{
 		InstrumentId: instrumentID,
 		From:         TimeToTimestamp(from),
 		To:           TimeToTimestamp(to),
@@ -460,8 +686,13 @@ func (is *InstrumentsServiceClient) GetDividents(instrumentID string, from, to t
 
 // GetAssetBy - Метод получения актива по его uid идентификатору.
 func (is *InstrumentsServiceClient) GetAssetBy(id string) (*AssetResponse, error) {
+	return is.GetAssetByCtx(is.ctx, id)
+}
+
+// GetAssetByCtx - то же, что и GetAssetBy, но с контекстом запроса ctx
+func (is *InstrumentsServiceClient) GetAssetByCtx(ctx context.Context, id string) (*AssetResponse, error) {
 	var header, trailer metadata.MD
-	resp, err := is.pbClient.GetAssetBy(is.ctx, &pb.AssetRequest{
+	resp, err := is.pbClient.GetAssetBy(ctx, &pb.AssetRequest{
 		Id: id,
 	}, grpc.Header(&header), grpc.Trailer(&trailer))
 	if err != nil {
@@ -475,8 +706,13 @@ func (is *InstrumentsServiceClient) GetAssetBy(id string) (*AssetResponse, error
 
 // GetAssets - Метод получения списка активов
 func (is *InstrumentsServiceClient) GetAssets() (*AssetsResponse, error) {
+	return is.GetAssetsCtx(is.ctx)
+}
+
+// GetAssetsCtx - то же, что и GetAssets, но с контекстом запроса ctx
+func (is *InstrumentsServiceClient) GetAssetsCtx(ctx context.Context) (*AssetsResponse, error) {
 	var header, trailer metadata.MD
-	resp, err := is.pbClient.GetAssets(is.ctx, &pb.AssetsRequest{}, grpc.Header(&header), grpc.Trailer(&trailer))
+	resp, err := is.pbClient.GetAssets(ctx, &pb.AssetsRequest{}, grpc.Header(&header), grpc.Trailer(&trailer))
 	if err != nil {
 		header = trailer
 	}
@@ -488,8 +724,13 @@ func (is *InstrumentsServiceClient) GetAssets() (*AssetsResponse, error) {
 
 // GetFavorites - Метод получения списка избранных инструментов
 func (is *InstrumentsServiceClient) GetFavorites() (*GetFavoritesResponse, error) {
+	return is.GetFavoritesCtx(is.ctx)
+}
+
+// GetFavoritesCtx - то же, что и GetFavorites, но с контекстом запроса ctx
+func (is *InstrumentsServiceClient) GetFavoritesCtx(ctx context.Context) (*GetFavoritesResponse, error) {
 	var header, trailer metadata.MD
-	resp, err := is.pbClient.GetFavorites(is.ctx, &pb.GetFavoritesRequest{}, grpc.Header(&header), grpc.Trailer(&trailer))
+	resp, err := is.pbClient.GetFavorites(ctx, &pb.GetFavoritesRequest{}, grpc.Header(&header), grpc.Trailer(&trailer))
 	if err != nil {
 		header = trailer
 	}
@@ -501,6 +742,11 @@ func (is *InstrumentsServiceClient) GetFavorites() (*GetFavoritesResponse, error
 
 // EditFavorites - Метод редактирования списка избранных инструментов
 func (is *InstrumentsServiceClient) EditFavorites(instrumentIDs []string, actionType pb.EditFavoritesActionType) (*EditFavoritesResponse, error) {
+	return is.EditFavoritesCtx(is.ctx, instrumentIDs, actionType)
+}
+
+// EditFavoritesCtx - то же, что и EditFavorites, но с контекстом запроса ctx
+func (is *InstrumentsServiceClient) EditFavoritesCtx(ctx context.Context, instrumentIDs []string, actionType pb.EditFavoritesActionType) (*EditFavoritesResponse, error) {
 	var header, trailer metadata.MD
 	ids := make([]*pb.EditFavoritesRequestInstrument, 0, len(instrumentIDs))
 	for _, id := range instrumentIDs {
@@ -508,7 +754,7 @@ func (is *InstrumentsServiceClient) EditFavorites(instrumentIDs []string, action
 			InstrumentId: id,
 		})
 	}
-	resp, err := is.pbClient.EditFavorites(is.ctx, &pb.EditFavoritesRequest{
+	resp, err := is.pbClient.EditFavorites(ctx, &pb.EditFavoritesRequest{
 		Instruments: ids,
 		ActionType:  actionType,
 	}, grpc.Header(&header), grpc.Trailer(&trailer))
@@ -523,8 +769,13 @@ func (is *InstrumentsServiceClient) EditFavorites(instrumentIDs []string, action
 
 // GetCountries - Метод получения списка стран
 func (is *InstrumentsServiceClient) GetCountries() (*GetCountriesResponse, error) {
+	return is.GetCountriesCtx(is.ctx)
+}
+
+// GetCountriesCtx - то же, что и GetCountries, но с контекстом запроса ctx
+func (is *InstrumentsServiceClient) GetCountriesCtx(ctx context.Context) (*GetCountriesResponse, error) {
 	var header, trailer metadata.MD
-	resp, err := is.pbClient.GetCountries(is.ctx, &pb.GetCountriesRequest{}, grpc.Header(&header), grpc.Trailer(&trailer))
+	resp, err := is.pbClient.GetCountries(ctx, &pb.GetCountriesRequest{}, grpc.Header(&header), grpc.Trailer(&trailer))
 	if err != nil {
 		header = trailer
 	}
@@ -536,8 +787,13 @@ func (is *InstrumentsServiceClient) GetCountries() (*GetCountriesResponse, error
 
 // GetBrands - Метод получения списка брендов
 func (is *InstrumentsServiceClient) GetBrands() (*GetBrandsResponse, error) {
+	return is.GetBrandsCtx(is.ctx)
+}
+
+// GetBrandsCtx - то же, что и GetBrands, но с контекстом запроса ctx
+func (is *InstrumentsServiceClient) GetBrandsCtx(ctx context.Context) (*GetBrandsResponse, error) {
 	var header, trailer metadata.MD
-	resp, err := is.pbClient.GetBrands(is.ctx, &pb.GetBrandsRequest{}, grpc.Header(&header), grpc.Trailer(&trailer))
+	resp, err := is.pbClient.GetBrands(ctx, &pb.GetBrandsRequest{}, grpc.Header(&header), grpc.Trailer(&trailer))
 	if err != nil {
 		header = trailer
 	}
@@ -549,8 +805,13 @@ func (is *InstrumentsServiceClient) GetBrands() (*GetBrandsResponse, error) {
 
 // GetBrandBy - Метод получения бренда по его uid идентификатору
 func (is *InstrumentsServiceClient) GetBrandBy(id string) (*Brand, error) {
+	return is.GetBrandByCtx(is.ctx, id)
+}
+
+// GetBrandByCtx - то же, что и GetBrandBy, но с контекстом запроса ctx
+func (is *InstrumentsServiceClient) GetBrandByCtx(ctx context.Context, id string) (*Brand, error) {
 	var header, trailer metadata.MD
-	resp, err := is.pbClient.GetBrandBy(is.ctx, &pb.GetBrandRequest{
+	resp, err := is.pbClient.GetBrandBy(ctx, &pb.GetBrandRequest{
 		Id: id,
 	}, grpc.Header(&header), grpc.Trailer(&trailer))
 	if err != nil {
@@ -564,8 +825,13 @@ func (is *InstrumentsServiceClient) GetBrandBy(id string) (*Brand, error) {
 
 // FindInstrument - Метод поиска инструмента, например по тикеру или названию компании
 func (is *InstrumentsServiceClient) FindInstrument(query string) (*FindInstrumentResponse, error) {
+	return is.FindInstrumentCtx(is.ctx, query)
+}
+
+// FindInstrumentCtx - то же, что и FindInstrument, но с контекстом запроса ctx
+func (is *InstrumentsServiceClient) FindInstrumentCtx(ctx context.Context, query string) (*FindInstrumentResponse, error) {
 	var header, trailer metadata.MD
-	resp, err := is.pbClient.FindInstrument(is.ctx, &pb.FindInstrumentRequest{
+	resp, err := is.pbClient.FindInstrument(ctx, &pb.FindInstrumentRequest{
 		Query: query,
 	}, grpc.Header(&header), grpc.Trailer(&trailer))
 	if err != nil {
@@ -579,8 +845,13 @@ func (is *InstrumentsServiceClient) FindInstrument(query string) (*FindInstrumen
 
 // GetAssetFundamentals - Метод получения фундаментальных показателей по активу
 func (is *InstrumentsServiceClient) GetAssetFundamentals(assets []string) (*GetAssetFundamentalsResponse, error) {
+	return is.GetAssetFundamentalsCtx(is.ctx, assets)
+}
+
+// GetAssetFundamentalsCtx - то же, что и GetAssetFundamentals, но с контекстом запроса ctx
+func (is *InstrumentsServiceClient) GetAssetFundamentalsCtx(ctx context.Context, assets []string) (*GetAssetFundamentalsResponse, error) {
 	var header, trailer metadata.MD
-	resp, err := is.pbClient.GetAssetFundamentals(is.ctx, &pb.GetAssetFundamentalsRequest{
+	resp, err := is.pbClient.GetAssetFundamentals(ctx, &pb.GetAssetFundamentalsRequest{
 		Assets: assets,
 	}, grpc.Header(&header), grpc.Trailer(&trailer))
 	if err != nil {
@@ -594,9 +865,15 @@ func (is *InstrumentsServiceClient) GetAssetFundamentals(assets []string) (*GetA
 
 // GetBondEvents - Метод получения событий по облигации
 func (is *InstrumentsServiceClient) GetBondEvents(instrumentID string,
+	eventType pb.GetBondEventsRequest_EventType, from, to time.Time) (*GetBondEventsResponse, error) {
+	return is.GetBondEventsCtx(is.ctx, instrumentID, eventType, from, to)
+}
+
+// GetBondEventsCtx - то же, что и GetBondEvents, но с контекстом запроса ctx
+func (is *InstrumentsServiceClient) GetBondEventsCtx(ctx context.Context, instrumentID string,
 	eventType pb.GetBondEventsRequest_EventType, from, to time.Time) (*GetBondEventsResponse, error) {
 	var header, trailer metadata.MD
-	resp, err := is.pbClient.GetBondEvents(is.ctx, &pb.GetBondEventsRequest{
+	resp, err := is.pbClient.GetBondEvents(ctx, &pb.GetBondEventsRequest{
 		From:         TimeToTimestamp(from),
 		To:           TimeToTimestamp(to),
 		InstrumentId: instrumentID,
@@ -613,8 +890,13 @@ func (is *InstrumentsServiceClient) GetBondEvents(instrumentID string,
 
 // Indicatives - Метод получения индикативных инструментов (индексов, товаров и др.)
 func (is *InstrumentsServiceClient) Indicatives() (*IndicativesResponse, error) {
+	return is.IndicativesCtx(is.ctx)
+}
+
+// IndicativesCtx - то же, что и Indicatives, но с контекстом запроса ctx
+func (is *InstrumentsServiceClient) IndicativesCtx(ctx context.Context) (*IndicativesResponse, error) {
 	var header, trailer metadata.MD
-	resp, err := is.pbClient.Indicatives(is.ctx, &pb.IndicativesRequest{}, grpc.Header(&header), grpc.Trailer(&trailer))
+	resp, err := is.pbClient.Indicatives(ctx, &pb.IndicativesRequest{}, grpc.Header(&header), grpc.Trailer(&trailer))
 	if err != nil {
 		header = trailer
 	}
@@ -626,8 +908,13 @@ func (is *InstrumentsServiceClient) Indicatives() (*IndicativesResponse, error)
 
 // GetAssetReports - Метод получения расписания выхода отчетностей эмитентов
 func (is *InstrumentsServiceClient) GetAssetReports(instrumentID string, from, to time.Time) (*GetAssetReportsResponse, error) {
+	return is.GetAssetReportsCtx(is.ctx, instrumentID, from, to)
+}
+
+// GetAssetReportsCtx - то же, что и GetAssetReports, но с контекстом запроса ctx
+func (is *InstrumentsServiceClient) GetAssetReportsCtx(ctx context.Context, instrumentID string, from, to time.Time) (*GetAssetReportsResponse, error) {
 	var header, trailer metadata.MD
-	resp, err := is.pbClient.GetAssetReports(is.ctx, &pb.GetAssetReportsRequest{
+	resp, err := is.pbClient.GetAssetReports(ctx, &pb.GetAssetReportsRequest{
 		InstrumentId: instrumentID,
 		From:         TimeToTimestamp(from),
 		To:           TimeToTimestamp(to),
@@ -643,8 +930,13 @@ func (is *InstrumentsServiceClient) GetAssetReports(instrumentID string, from, t
 
 // GetConsensusForecasts - Метод получения мнения аналитиков по инструменту
 func (is *InstrumentsServiceClient) GetConsensusForecasts(limit, pageNumber int32) (*GetConsensusForecastsResponse, error) {
+	return is.GetConsensusForecastsCtx(is.ctx, limit, pageNumber)
+}
+
+// GetConsensusForecastsCtx - то же, что и GetConsensusForecasts, но с контекстом запроса ctx
+func (is *InstrumentsServiceClient) GetConsensusForecastsCtx(ctx context.Context, limit, pageNumber int32) (*GetConsensusForecastsResponse, error) {
 	var header, trailer metadata.MD
-	resp, err := is.pbClient.GetConsensusForecasts(is.ctx, &pb.GetConsensusForecastsRequest{
+	resp, err := is.pbClient.GetConsensusForecasts(ctx, &pb.GetConsensusForecastsRequest{
 		Paging: &pb.Page{
 			Limit:      limit,
 			PageNumber: pageNumber,
@@ -661,8 +953,13 @@ func (is *InstrumentsServiceClient) GetConsensusForecasts(limit, pageNumber int3
 
 // GetForecastBy - Метод получения прогнозов инвестдомов по инструменту
 func (is *InstrumentsServiceClient) GetForecastBy(instrumentID string) (*GetForecastResponse, error) {
+	return is.GetForecastByCtx(is.ctx, instrumentID)
+}
+
+// GetForecastByCtx - то же, что и GetForecastBy, но с контекстом запроса ctx
+func (is *InstrumentsServiceClient) GetForecastByCtx(ctx context.Context, instrumentID string) (*GetForecastResponse, error) {
 	var header, trailer metadata.MD
-	resp, err := is.pbClient.GetForecastBy(is.ctx, &pb.GetForecastRequest{
+	resp, err := is.pbClient.GetForecastBy(ctx, &pb.GetForecastRequest{
 		InstrumentId: instrumentID,
 	}, grpc.Header(&header), grpc.Trailer(&trailer))
 	if err != nil {
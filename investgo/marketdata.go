@@ -18,6 +18,7 @@ type MarketDataServiceClient struct {
 	logger   Logger
 	ctx      context.Context
 	pbClient pb.MarketDataServiceClient
+	cache    *CandlesCache
 }
 
 // GetCandles - Метод запроса исторических свечей по инструменту
@@ -27,13 +28,39 @@ func (md *MarketDataServiceClient) GetCandles(
 	from, to time.Time,
 	source pb.GetCandlesRequest_CandleSource,
 	limit int32,
+) (*GetCandlesResponse, error) {
+	return md.GetCandlesCtx(md.ctx, instrumentId, interval, from, to, source, limit)
+}
+
+// GetCandlesCtx - то же, что и GetCandles, но с контекстом запроса ctx
+func (md *MarketDataServiceClient) GetCandlesCtx(
+	ctx context.Context,
+	instrumentId string,
+	interval pb.CandleInterval,
+	from, to time.Time,
+	source pb.GetCandlesRequest_CandleSource,
+	limit int32,
+) (*GetCandlesResponse, error) {
+	if md.cache != nil && limit == 0 && cacheable(interval) {
+		return md.cachedCandles(ctx, instrumentId, interval, from, to, source)
+	}
+	return md.getCandles(ctx, instrumentId, interval, from, to, source, limit)
+}
+
+func (md *MarketDataServiceClient) getCandles(
+	ctx context.Context,
+	instrumentId string,
+	interval pb.CandleInterval,
+	from, to time.Time,
+	source pb.GetCandlesRequest_CandleSource,
+	limit int32,
 ) (*GetCandlesResponse, error) {
 	var header, trailer metadata.MD
 	var limitp *int32
 	if limit != 0 {
 		limitp = &limit
 	}
-	resp, err := md.pbClient.GetCandles(md.ctx, &pb.GetCandlesRequest{
+	resp, err := md.pbClient.GetCandles(ctx, &pb.GetCandlesRequest{
 		From:             TimeToTimestamp(from),
 		To:               TimeToTimestamp(to),
 		Interval:         interval,
@@ -52,8 +79,13 @@ func (md *MarketDataServiceClient) GetCandles(
 
 // GetLastPrices - Метод запроса цен последних сделок по инструментам
 func (md *MarketDataServiceClient) GetLastPrices(instrumentIds []string) (*GetLastPricesResponse, error) {
+	return md.GetLastPricesCtx(md.ctx, instrumentIds)
+}
+
+// GetLastPricesCtx - то же, что и GetLastPrices, но с контекстом запроса ctx
+func (md *MarketDataServiceClient) GetLastPricesCtx(ctx context.Context, instrumentIds []string) (*GetLastPricesResponse, error) {
 	var header, trailer metadata.MD
-	resp, err := md.pbClient.GetLastPrices(md.ctx, &pb.GetLastPricesRequest{
+	resp, err := md.pbClient.GetLastPrices(ctx, &pb.GetLastPricesRequest{
 		InstrumentId: instrumentIds,
 	}, grpc.Header(&header), grpc.Trailer(&trailer))
 	if err != nil {
@@ -67,8 +99,13 @@ func (md *MarketDataServiceClient) GetLastPrices(instrumentIds []string) (*GetLa
 
 // GetOrderBook - Метод получения стакана по инструменту
 func (md *MarketDataServiceClient) GetOrderBook(instrumentId string, depth int32) (*GetOrderBookResponse, error) {
+	return md.GetOrderBookCtx(md.ctx, instrumentId, depth)
+}
+
+// GetOrderBookCtx - то же, что и GetOrderBook, но с контекстом запроса ctx
+func (md *MarketDataServiceClient) GetOrderBookCtx(ctx context.Context, instrumentId string, depth int32) (*GetOrderBookResponse, error) {
 	var header, trailer metadata.MD
-	resp, err := md.pbClient.GetOrderBook(md.ctx, &pb.GetOrderBookRequest{
+	resp, err := md.pbClient.GetOrderBook(ctx, &pb.GetOrderBookRequest{
 		Depth:        depth,
 		InstrumentId: &instrumentId,
 	}, grpc.Header(&header), grpc.Trailer(&trailer))
@@ -83,8 +120,13 @@ func (md *MarketDataServiceClient) GetOrderBook(instrumentId string, depth int32
 
 // GetTradingStatus - Метод запроса статуса торгов по инструменту
 func (md *MarketDataServiceClient) GetTradingStatus(instrumentId string) (*GetTradingStatusResponse, error) {
+	return md.GetTradingStatusCtx(md.ctx, instrumentId)
+}
+
+// GetTradingStatusCtx - то же, что и GetTradingStatus, но с контекстом запроса ctx
+func (md *MarketDataServiceClient) GetTradingStatusCtx(ctx context.Context, instrumentId string) (*GetTradingStatusResponse, error) {
 	var header, trailer metadata.MD
-	resp, err := md.pbClient.GetTradingStatus(md.ctx, &pb.GetTradingStatusRequest{
+	resp, err := md.pbClient.GetTradingStatus(ctx, &pb.GetTradingStatusRequest{
 		InstrumentId: &instrumentId,
 	}, grpc.Header(&header), grpc.Trailer(&trailer))
 	if err != nil {
@@ -98,8 +140,13 @@ func (md *MarketDataServiceClient) GetTradingStatus(instrumentId string) (*GetTr
 
 // GetTradingStatuses - Метод запроса статуса торгов по инструментам
 func (md *MarketDataServiceClient) GetTradingStatuses(instrumentIds []string) (*GetTradingStatusesResponse, error) {
+	return md.GetTradingStatusesCtx(md.ctx, instrumentIds)
+}
+
+// GetTradingStatusesCtx - то же, что и GetTradingStatuses, но с контекстом запроса ctx
+func (md *MarketDataServiceClient) GetTradingStatusesCtx(ctx context.Context, instrumentIds []string) (*GetTradingStatusesResponse, error) {
 	var header, trailer metadata.MD
-	resp, err := md.pbClient.GetTradingStatuses(md.ctx, &pb.GetTradingStatusesRequest{
+	resp, err := md.pbClient.GetTradingStatuses(ctx, &pb.GetTradingStatusesRequest{
 		InstrumentId: instrumentIds,
 	}, grpc.Header(&header), grpc.Trailer(&trailer))
 	if err != nil {
@@ -113,8 +160,13 @@ func (md *MarketDataServiceClient) GetTradingStatuses(instrumentIds []string) (*
 
 // GetLastTrades - Метод запроса обезличенных сделок за последний час
 func (md *MarketDataServiceClient) GetLastTrades(instrumentId string, from, to time.Time) (*GetLastTradesResponse, error) {
+	return md.GetLastTradesCtx(md.ctx, instrumentId, from, to)
+}
+
+// GetLastTradesCtx - то же, что и GetLastTrades, но с контекстом запроса ctx
+func (md *MarketDataServiceClient) GetLastTradesCtx(ctx context.Context, instrumentId string, from, to time.Time) (*GetLastTradesResponse, error) {
 	var header, trailer metadata.MD
-	resp, err := md.pbClient.GetLastTrades(md.ctx, &pb.GetLastTradesRequest{
+	resp, err := md.pbClient.GetLastTrades(ctx, &pb.GetLastTradesRequest{
 		From:         TimeToTimestamp(from),
 		To:           TimeToTimestamp(to),
 		InstrumentId: &instrumentId,
@@ -130,12 +182,17 @@ func (md *MarketDataServiceClient) GetLastTrades(instrumentId string, from, to t
 
 // GetClosePrices - Метод запроса цен закрытия торговой сессии по инструментам
 func (md *MarketDataServiceClient) GetClosePrices(instrumentIds []string) (*GetClosePricesResponse, error) {
+	return md.GetClosePricesCtx(md.ctx, instrumentIds)
+}
+
+// GetClosePricesCtx - то же, что и GetClosePrices, но с контекстом запроса ctx
+func (md *MarketDataServiceClient) GetClosePricesCtx(ctx context.Context, instrumentIds []string) (*GetClosePricesResponse, error) {
 	var header, trailer metadata.MD
 	instruments := make([]*pb.InstrumentClosePriceRequest, 0, len(instrumentIds))
 	for _, id := range instrumentIds {
 		instruments = append(instruments, &pb.InstrumentClosePriceRequest{InstrumentId: id})
 	}
-	resp, err := md.pbClient.GetClosePrices(md.ctx, &pb.GetClosePricesRequest{
+	resp, err := md.pbClient.GetClosePrices(ctx, &pb.GetClosePricesRequest{
 		Instruments: instruments,
 	}, grpc.Header(&header), grpc.Trailer(&trailer))
 	if err != nil {
@@ -149,8 +206,13 @@ func (md *MarketDataServiceClient) GetClosePrices(instrumentIds []string) (*GetC
 
 // GetTechAnalysis - Метод получения индикаторов технического анализа
 func (md *MarketDataServiceClient) GetTechAnalysis(req *GetTechAnalysisRequest) (*GetTechAnalysisResponse, error) {
+	return md.GetTechAnalysisCtx(md.ctx, req)
+}
+
+// GetTechAnalysisCtx - то же, что и GetTechAnalysis, но с контекстом запроса ctx
+func (md *MarketDataServiceClient) GetTechAnalysisCtx(ctx context.Context, req *GetTechAnalysisRequest) (*GetTechAnalysisResponse, error) {
 	var header, trailer metadata.MD
-	resp, err := md.pbClient.GetTechAnalysis(md.ctx, &pb.GetTechAnalysisRequest{
+	resp, err := md.pbClient.GetTechAnalysis(ctx, &pb.GetTechAnalysisRequest{
 		IndicatorType: req.IndicatorType,
 		InstrumentUid: req.InstrumentUID,
 		From:          TimeToTimestamp(req.From),
@@ -175,53 +237,31 @@ func (md *MarketDataServiceClient) GetTechAnalysis(req *GetTechAnalysisRequest)
 // свечей в формате: instrumentId;time;open;close;high;low;volume.
 // Имя файла по умолчанию: "candles hh:mm:ss"
 func (md *MarketDataServiceClient) GetHistoricCandles(req *GetHistoricCandlesRequest) ([]*pb.HistoricCandle, error) {
+	return md.GetHistoricCandlesCtx(md.ctx, req)
+}
+
+// GetHistoricCandlesCtx - то же, что и GetHistoricCandles, но с контекстом запроса ctx
+func (md *MarketDataServiceClient) GetHistoricCandlesCtx(ctx context.Context, req *GetHistoricCandlesRequest) ([]*pb.HistoricCandle, error) {
 	// by default 1 hour
 	if req.Interval == pb.CandleInterval_CANDLE_INTERVAL_UNSPECIFIED {
 		req.Interval = pb.CandleInterval_CANDLE_INTERVAL_HOUR
 	}
-	duration := selectDuration(req.Interval)
-	// если запрашиваемый интервал больше чем возможный, то нужно разделить его на несколько
-	intervals := make([]time.Time, 0)
-	if req.To.Sub(req.From) > duration {
-		lowTime := req.To
-		for lowTime.After(req.From) || lowTime.Equal(req.From) {
-			intervals = append(intervals, lowTime)
-			lowTime = lowTime.Add(-duration)
-		}
-		intervals = append(intervals, req.From)
-	} else {
-		intervals = []time.Time{req.To, req.From}
-	}
-	// intervals = {to, ... , from}
+	it := md.DownloadCandlesCtx(ctx, &DownloadCandlesRequest{
+		Instrument: req.Instrument,
+		Interval:   req.Interval,
+		From:       req.From,
+		To:         req.To,
+		Source:     req.Source,
+		Store:      req.Store,
+	})
+	defer it.Close()
 
 	candles := make([]*pb.HistoricCandle, 0)
-	requests := 0
-	for i := len(intervals) - 1; i > 0; i-- {
-		// идем с конца слайса так как там более раннее время
-		// from - i элемент
-		// to - i-1 элемент
-		requests++
-		resp, err := md.GetCandles(
-			req.Instrument,
-			req.Interval,
-			intervals[i],
-			intervals[i-1],
-			req.Source,
-			0,
-		)
-		if err != nil {
-			return nil, err
-		}
-		if len(resp.GetCandles()) < 1 {
-			continue
-		}
-		candles = append(candles, resp.GetCandles()[1:]...)
-		if requests == 299 {
-			if md.config.DisableResourceExhaustedRetry {
-				time.Sleep(time.Minute)
-			}
-			requests = 0
-		}
+	for it.Next() {
+		candles = append(candles, it.Candle())
+	}
+	if err := it.Err(); err != nil {
+		return nil, err
 	}
 
 	if req.File {
@@ -236,15 +276,12 @@ func (md *MarketDataServiceClient) GetHistoricCandles(req *GetHistoricCandlesReq
 
 // GetAllHistoricCandles - Метод получения всех свечей по инструменту, поля from, to игнорируются
 func (md *MarketDataServiceClient) GetAllHistoricCandles(req *GetHistoricCandlesRequest) ([]*pb.HistoricCandle, error) {
-	instrumentsService := &InstrumentsServiceClient{
-		conn:     md.conn,
-		config:   md.config,
-		logger:   md.logger,
-		ctx:      md.ctx,
-		pbClient: pb.NewInstrumentsServiceClient(md.conn),
-	}
+	return md.GetAllHistoricCandlesCtx(md.ctx, req)
+}
 
-	resp, err := instrumentsService.FindInstrument(req.Instrument)
+// GetAllHistoricCandlesCtx - то же, что и GetAllHistoricCandles, но с контекстом запроса ctx
+func (md *MarketDataServiceClient) GetAllHistoricCandlesCtx(ctx context.Context, req *GetHistoricCandlesRequest) ([]*pb.HistoricCandle, error) {
+	resp, err := md.instrumentsClient().FindInstrumentCtx(ctx, req.Instrument)
 	if err != nil {
 		return nil, err
 	}
@@ -261,16 +298,28 @@ func (md *MarketDataServiceClient) GetAllHistoricCandles(req *GetHistoricCandles
 		from = instruments[0].GetFirst_1MinCandleDate().AsTime()
 	}
 
-	return md.GetHistoricCandles(&GetHistoricCandlesRequest{
+	return md.GetHistoricCandlesCtx(ctx, &GetHistoricCandlesRequest{
 		Instrument: req.Instrument,
 		Interval:   req.Interval,
 		From:       from,
 		To:         time.Now(),
 		File:       req.File,
 		FileName:   req.FileName,
+		Store:      req.Store,
 	})
 }
 
+// instrumentsClient - клиент сервиса инструментов на том же соединении
+func (md *MarketDataServiceClient) instrumentsClient() *InstrumentsServiceClient {
+	return &InstrumentsServiceClient{
+		conn:     md.conn,
+		config:   md.config,
+		logger:   md.logger,
+		ctx:      md.ctx,
+		pbClient: pb.NewInstrumentsServiceClient(md.conn),
+	}
+}
+
 func selectDuration(interval pb.CandleInterval) time.Duration {
 	var duration time.Duration
 	switch interval {
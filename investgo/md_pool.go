@@ -0,0 +1,516 @@
+package investgo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	pb "github.com/russianinvestments/invest-api-go-sdk/proto"
+)
+
+const (
+	// DefaultMaxSubscriptionsPerStream - лимит подписок в одном стриме биржевой информации
+	DefaultMaxSubscriptionsPerStream = 300
+	// DefaultMaxMarketDataStreams - лимит одновременно открытых стримов биржевой информации для одного токена
+	DefaultMaxMarketDataStreams = 16
+)
+
+// ErrPoolLimitExceeded - для новых подписок не хватает места даже с учетом открытия новых стримов
+var ErrPoolLimitExceeded = errors.New("market data pool subscriptions limit exceeded")
+
+// MarketDataPool - пул стримов биржевой информации. Подписки распределяются по стримам так, чтобы не превышать
+// лимит подписок на один стрим, новые стримы открываются по мере необходимости, а информация из всех стримов
+// приходит в общие каналы пула. При отписке пул закрывает лишние стримы, перенося их подписки в оставшиеся
+type MarketDataPool struct {
+	mdsClient  *MarketDataStreamClient
+	opts       []StreamOption
+	perStream  int
+	maxStreams int
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// mu - защищает стримы и распределение подписок, операции с подписками выполняются последовательно
+	mu        sync.Mutex
+	shards    []*poolShard
+	assigned  map[subRef]*poolShard
+	listening bool
+	err       error
+	wg        sync.WaitGroup
+
+	marketDataChannels
+	// droppedClosed - сообщения, отброшенные уже закрытыми стримами пула
+	droppedClosed atomic.Uint64
+}
+
+// poolShard - стрим пула и количество подписок в нем, started - для стрима запущен Listen
+type poolShard struct {
+	stream  *MarketDataStream
+	count   int
+	started bool
+}
+
+// Candles - Метод возвращает канал для чтения свечей из всех стримов пула
+func (p *MarketDataPool) Candles() <-chan *pb.Candle {
+	return p.candle.out()
+}
+
+// OrderBooks - Метод возвращает канал для чтения стаканов из всех стримов пула
+func (p *MarketDataPool) OrderBooks() <-chan *pb.OrderBook {
+	return p.orderBook.out()
+}
+
+// Trades - Метод возвращает канал для чтения ленты обезличенных сделок из всех стримов пула
+func (p *MarketDataPool) Trades() <-chan *pb.Trade {
+	return p.trade.out()
+}
+
+// LastPrices - Метод возвращает канал для чтения последних цен из всех стримов пула
+func (p *MarketDataPool) LastPrices() <-chan *pb.LastPrice {
+	return p.lastPrice.out()
+}
+
+// TradingStatuses - Метод возвращает канал для чтения торговых статусов из всех стримов пула
+func (p *MarketDataPool) TradingStatuses() <-chan *pb.TradingStatus {
+	return p.tradingStatus.out()
+}
+
+// SubscribeCandle - Метод подписки на свечи с заданным интервалом
+func (p *MarketDataPool) SubscribeCandle(ids []string, interval pb.SubscriptionInterval, waitingClose bool, candleSrc *pb.GetCandlesRequest_CandleSource) error {
+	sub := newCandleSub(interval, waitingClose, candleSrc)
+	return p.subscribe(refsOf(ids, func(id string) subRef {
+		return subRef{kind: SubscriptionCandles, id: id, candle: sub}
+	}))
+}
+
+// UnSubscribeCandle - Метод отписки от свечей
+func (p *MarketDataPool) UnSubscribeCandle(ids []string, interval pb.SubscriptionInterval, waitingClose bool, candleSrc *pb.GetCandlesRequest_CandleSource) error {
+	sub := newCandleSub(interval, waitingClose, candleSrc)
+	return p.unsubscribe(refsOf(ids, func(id string) subRef {
+		return subRef{kind: SubscriptionCandles, id: id, candle: sub}
+	}))
+}
+
+// SubscribeOrderBook - метод подписки на стаканы инструментов с одинаковой глубиной
+func (p *MarketDataPool) SubscribeOrderBook(ids []string, depth int32) error {
+	return p.subscribe(refsOf(ids, func(id string) subRef {
+		return subRef{kind: SubscriptionOrderBooks, id: id, depth: depth}
+	}))
+}
+
+// UnSubscribeOrderBook - метод отписки от стаканов инструментов
+func (p *MarketDataPool) UnSubscribeOrderBook(ids []string, depth int32) error {
+	return p.unsubscribe(refsOf(ids, func(id string) subRef {
+		return subRef{kind: SubscriptionOrderBooks, id: id, depth: depth}
+	}))
+}
+
+// SubscribeTrade - метод подписки на ленту обезличенных сделок
+func (p *MarketDataPool) SubscribeTrade(ids []string, tradeSrc pb.TradeSourceType) error {
+	return p.subscribe(refsOf(ids, func(id string) subRef {
+		return subRef{kind: SubscriptionTrades, id: id, tradeSrc: tradeSrc}
+	}))
+}
+
+// UnSubscribeTrade - метод отписки от ленты обезличенных сделок
+func (p *MarketDataPool) UnSubscribeTrade(ids []string, tradeSrc pb.TradeSourceType) error {
+	return p.unsubscribe(refsOf(ids, func(id string) subRef {
+		return subRef{kind: SubscriptionTrades, id: id, tradeSrc: tradeSrc}
+	}))
+}
+
+// SubscribeInfo - метод подписки на торговые статусы инструментов
+func (p *MarketDataPool) SubscribeInfo(ids []string) error {
+	return p.subscribe(refsOf(ids, func(id string) subRef {
+		return subRef{kind: SubscriptionInfo, id: id}
+	}))
+}
+
+// UnSubscribeInfo - метод отписки от торговых статусов инструментов
+func (p *MarketDataPool) UnSubscribeInfo(ids []string) error {
+	return p.unsubscribe(refsOf(ids, func(id string) subRef {
+		return subRef{kind: SubscriptionInfo, id: id}
+	}))
+}
+
+// SubscribeLastPrice - метод подписки на последние цены инструментов
+func (p *MarketDataPool) SubscribeLastPrice(ids []string) error {
+	return p.subscribe(refsOf(ids, func(id string) subRef {
+		return subRef{kind: SubscriptionLastPrices, id: id}
+	}))
+}
+
+// UnSubscribeLastPrice - метод отписки от последних цен инструментов
+func (p *MarketDataPool) UnSubscribeLastPrice(ids []string) error {
+	return p.unsubscribe(refsOf(ids, func(id string) subRef {
+		return subRef{kind: SubscriptionLastPrices, id: id}
+	}))
+}
+
+// Subscriptions - Метод возвращает количество подписок в пуле
+func (p *MarketDataPool) Subscriptions() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.assigned)
+}
+
+// Streams - Метод возвращает количество открытых стримов пула
+func (p *MarketDataPool) Streams() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.shards)
+}
+
+// Dropped - Метод возвращает количество сообщений, отброшенных при переполнении каналов пула и его стримов.
+// Сообщения отбрасываются только с политикой переполнения, заданной опцией WithBuffer
+func (p *MarketDataPool) Dropped() uint64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	dropped := p.droppedCount() + p.droppedClosed.Load()
+	for _, sh := range p.shards {
+		dropped += sh.stream.Dropped()
+	}
+	return dropped
+}
+
+// Listen - метод запускает все стримы пула, стримы, открытые позже, запускаются сразу. Метод блокируется до
+// вызова Stop или ошибки одного из стримов, после чего закрывает все стримы и каналы пула
+func (p *MarketDataPool) Listen() error {
+	p.mu.Lock()
+	if p.listening {
+		p.mu.Unlock()
+		return errors.New("market data pool is already listening")
+	}
+	p.listening = true
+	if p.ctx.Err() == nil {
+		for _, sh := range p.shards {
+			p.start(sh)
+		}
+	}
+	p.mu.Unlock()
+
+	<-p.ctx.Done()
+	p.mdsClient.logger.Infof("stop listening market data pool")
+	// разблокируем отправку в каналы пула, чтобы стримы могли завершиться
+	p.marketDataChannels.stop()
+	p.mu.Lock()
+	for _, sh := range p.shards {
+		p.closeShard(sh)
+	}
+	p.mu.Unlock()
+	p.wg.Wait()
+	p.marketDataChannels.close()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, sh := range p.shards {
+		p.droppedClosed.Add(sh.stream.Dropped())
+	}
+	p.shards = nil
+	return p.err
+}
+
+// Stop - Завершение работы пула и всех его стримов
+func (p *MarketDataPool) Stop() {
+	p.cancel()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.listening {
+		return
+	}
+	// Listen не вызывался, поэтому стримы и каналы пула закрываются здесь
+	for _, sh := range p.shards {
+		p.droppedClosed.Add(sh.stream.Dropped())
+		p.closeShard(sh)
+	}
+	p.shards = nil
+	p.marketDataChannels.close()
+}
+
+// start - запуск стрима и пересылки информации из него в каналы пула, вызывается под mu
+func (p *MarketDataPool) start(sh *poolShard) {
+	sh.started = true
+	p.wg.Add(6)
+	go func() {
+		defer p.wg.Done()
+		err := sh.stream.Listen()
+		if err != nil && p.ctx.Err() == nil {
+			p.mdsClient.logger.Errorf("market data pool stream err = %v", err.Error())
+			p.fail(err)
+		}
+	}()
+	go forward(&p.wg, sh.stream.candle.out(), p.candle)
+	go forward(&p.wg, sh.stream.orderBook.out(), p.orderBook)
+	go forward(&p.wg, sh.stream.trade.out(), p.trade)
+	go forward(&p.wg, sh.stream.lastPrice.out(), p.lastPrice)
+	go forward(&p.wg, sh.stream.tradingStatus.out(), p.tradingStatus)
+}
+
+func forward[T any](wg *sync.WaitGroup, src <-chan T, dst *streamQueue[T]) {
+	defer wg.Done()
+	for v := range src {
+		dst.send(v)
+	}
+}
+
+func (p *MarketDataPool) fail(err error) {
+	p.mu.Lock()
+	if p.err == nil {
+		p.err = err
+	}
+	p.mu.Unlock()
+	p.cancel()
+}
+
+// subscribe - распределение новых подписок по стримам, в первую очередь в стримы с наибольшим запасом
+func (p *MarketDataPool) subscribe(refs []subRef) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err := p.ctx.Err(); err != nil {
+		return err
+	}
+
+	pending := make([]subRef, 0, len(refs))
+	seen := make(map[subRef]struct{}, len(refs))
+	for _, r := range refs {
+		if _, ok := p.assigned[r]; ok {
+			continue
+		}
+		if _, ok := seen[r]; ok {
+			continue
+		}
+		seen[r] = struct{}{}
+		pending = append(pending, r)
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+
+	free := (p.maxStreams - len(p.shards)) * p.perStream
+	for _, sh := range p.shards {
+		free += p.perStream - sh.count
+	}
+	if len(pending) > free {
+		return fmt.Errorf("%w: requested = %v, available = %v", ErrPoolLimitExceeded, len(pending), free)
+	}
+
+	var (
+		rejected []SubscriptionResult
+		err      error
+	)
+	for len(pending) > 0 {
+		sh := p.mostFree(nil)
+		if sh == nil || sh.count >= p.perStream {
+			sh, err = p.newShard()
+			if err != nil {
+				break
+			}
+		}
+		n := p.perStream - sh.count
+		if n > len(pending) {
+			n = len(pending)
+		}
+		var r []SubscriptionResult
+		r, err = p.assign(sh, pending[:n])
+		rejected = append(rejected, r...)
+		if err != nil {
+			break
+		}
+		pending = pending[n:]
+	}
+	p.closeEmpty()
+	return errors.Join(err, subscriptionError(rejected))
+}
+
+// unsubscribe - отписка и перераспределение подписок, чтобы в пуле не оставалось лишних стримов
+func (p *MarketDataPool) unsubscribe(refs []subRef) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	batches := make(map[*poolShard][]subRef)
+	for _, r := range refs {
+		if sh, ok := p.assigned[r]; ok {
+			batches[sh] = append(batches[sh], r)
+		}
+	}
+	var (
+		errs     []error
+		rejected []SubscriptionResult
+	)
+	for sh, batch := range batches {
+		applied, r, err := apply(sh.stream, batch, false)
+		rejected = append(rejected, r...)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		for _, r := range applied {
+			delete(p.assigned, r)
+		}
+		sh.count -= len(applied)
+	}
+	r, err := p.rebalance()
+	rejected = append(rejected, r...)
+	return errors.Join(append(errs, err, subscriptionError(rejected))...)
+}
+
+// assign - подписка в стриме sh, вызывается под mu. Отклоненные сервером подписки не учитываются в стриме,
+// как и в MarketDataStream, и возвращаются отдельно от ошибки отправки запроса
+func (p *MarketDataPool) assign(sh *poolShard, refs []subRef) ([]SubscriptionResult, error) {
+	applied, rejected, err := apply(sh.stream, refs, true)
+	for _, r := range applied {
+		p.assigned[r] = sh
+	}
+	sh.count += len(applied)
+	return rejected, err
+}
+
+// rebalance - закрытие пустых стримов и перенос подписок из наименее загруженного стрима в остальные,
+// если они там помещаются, вызывается под mu. Возвращает отклоненные при переносе подписки и ошибку отправки запроса
+func (p *MarketDataPool) rebalance() ([]SubscriptionResult, error) {
+	var rejected []SubscriptionResult
+	for {
+		p.closeEmpty()
+		if len(p.shards) < 2 {
+			return rejected, nil
+		}
+		src := p.shards[0]
+		for _, sh := range p.shards[1:] {
+			if sh.count < src.count {
+				src = sh
+			}
+		}
+		free := 0
+		for _, sh := range p.shards {
+			if sh != src {
+				free += p.perStream - sh.count
+			}
+		}
+		if src.count > free {
+			return rejected, nil
+		}
+
+		moved := make([]subRef, 0, src.count)
+		for r, sh := range p.assigned {
+			if sh == src {
+				moved = append(moved, r)
+			}
+		}
+		// сначала подписываемся в других стримах, чтобы не пропустить информацию, затем закрываем стрим
+		for len(moved) > 0 {
+			dst := p.mostFree(src)
+			n := p.perStream - dst.count
+			if n > len(moved) {
+				n = len(moved)
+			}
+			before := dst.count
+			r, err := p.assign(dst, moved[:n])
+			rejected = append(rejected, r...)
+			src.count -= dst.count - before
+			if err != nil {
+				return rejected, err
+			}
+			moved = moved[n:]
+		}
+	}
+}
+
+// mostFree - стрим с наименьшим количеством подписок, кроме except, вызывается под mu
+func (p *MarketDataPool) mostFree(except *poolShard) *poolShard {
+	var res *poolShard
+	for _, sh := range p.shards {
+		if sh != except && (res == nil || sh.count < res.count) {
+			res = sh
+		}
+	}
+	return res
+}
+
+// newShard - открытие нового стрима, вызывается под mu
+func (p *MarketDataPool) newShard() (*poolShard, error) {
+	stream, err := p.mdsClient.MarketDataStreamCtx(p.ctx, p.opts...)
+	if err != nil {
+		return nil, err
+	}
+	sh := &poolShard{stream: stream}
+	p.shards = append(p.shards, sh)
+	if p.listening {
+		p.start(sh)
+	}
+	return sh, nil
+}
+
+// closeEmpty - закрытие стримов без подписок, вызывается под mu
+func (p *MarketDataPool) closeEmpty() {
+	shards := p.shards[:0]
+	for _, sh := range p.shards {
+		if sh.count > 0 {
+			shards = append(shards, sh)
+			continue
+		}
+		p.droppedClosed.Add(sh.stream.Dropped())
+		p.closeShard(sh)
+	}
+	p.shards = shards
+}
+
+// closeShard - остановка стрима пула. Каналы стрима закрывает его Listen, поэтому у стрима, для которого Listen
+// не запускался, они закрываются сразу, иначе горутины каналов с OverflowKeepLatest не завершатся
+func (p *MarketDataPool) closeShard(sh *poolShard) {
+	if sh.started {
+		sh.stream.Stop()
+		return
+	}
+	sh.stream.shutdown()
+}
+
+// apply - подписка или отписка в стриме, подписки группируются по параметрам. Возвращает подписки, которые
+// сервер принял, отклоненные сервером подписки и ошибки отправки остальных запросов
+func apply(mds *MarketDataStream, refs []subRef, subscribe bool) (applied []subRef, rejected []SubscriptionResult, err error) {
+	groups := make(map[subRef][]subRef)
+	for _, r := range refs {
+		params := r
+		params.id = ""
+		groups[params] = append(groups[params], r)
+	}
+	var errs []error
+	for params, group := range groups {
+		var err error
+		if subscribe {
+			err = mds.subscribeCommon(group, mds.sendFunc(params))
+		} else {
+			err = mds.unsubscribeCommon(group, mds.sendFunc(params))
+		}
+		var subErr *SubscriptionError
+		switch {
+		case errors.As(err, &subErr):
+			rejected = append(rejected, subErr.Results...)
+			// отклоненные подписки не сохраняются в стриме и не занимают в нем место, а отклоненная отписка
+			// означает, что подписки на сервере уже нет
+			if subscribe {
+				group = withoutRefs(group, rejectedRefs(group, err))
+			}
+		case err != nil:
+			errs = append(errs, err)
+			continue
+		}
+		applied = append(applied, group...)
+	}
+	return applied, rejected, errors.Join(errs...)
+}
+
+// withoutRefs - подписки из refs, кроме подписок из exclude
+func withoutRefs(refs, exclude []subRef) []subRef {
+	skip := make(map[subRef]struct{}, len(exclude))
+	for _, r := range exclude {
+		skip[r] = struct{}{}
+	}
+	res := make([]subRef, 0, len(refs))
+	for _, r := range refs {
+		if _, ok := skip[r]; !ok {
+			res = append(res, r)
+		}
+	}
+	return res
+}
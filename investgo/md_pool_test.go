@@ -0,0 +1,264 @@
+package investgo_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/russianinvestments/invest-api-go-sdk/investgo"
+	"github.com/russianinvestments/invest-api-go-sdk/investgotest"
+	pb "github.com/russianinvestments/invest-api-go-sdk/proto"
+	"github.com/russianinvestments/invest-api-go-sdk/retry"
+)
+
+// receivePrice - установка цены инструмента, пока она не придет в канал ch. Подписка в пуле без подтверждения
+// может дойти до сервера позже первой цены
+func receivePrice(t *testing.T, srv *investgotest.Server, uid string, price float64, ch <-chan *pb.LastPrice) {
+	t.Helper()
+	deadline := time.Now().Add(waitTime)
+	for time.Now().Before(deadline) {
+		setPrice(t, srv, uid, price)
+		select {
+		case p, ok := <-ch:
+			if !ok {
+				t.Fatal("channel closed")
+			}
+			if p.GetInstrumentUid() == uid && p.GetPrice().ToFloat() == price {
+				return
+			}
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	t.Fatalf("timeout waiting for price %v of %v", price, uid)
+}
+
+func TestMarketDataPoolDistribution(t *testing.T) {
+	srv, client, uids := newTestServer(t, 7)
+	pool := client.NewMarketDataStreamClient().NewMarketDataPool(3, 3)
+	listen(t, pool.Listen, pool.Stop)
+
+	if err := pool.SubscribeLastPrice(uids); err != nil {
+		t.Fatal(err)
+	}
+	if pool.Streams() != 3 || pool.Subscriptions() != 7 {
+		t.Fatalf("streams = %v, subscriptions = %v", pool.Streams(), pool.Subscriptions())
+	}
+	for i, uid := range uids {
+		receivePrice(t, srv, uid, float64(100+i), pool.LastPrices())
+	}
+
+	err := pool.SubscribeCandle(uids[:3], pb.SubscriptionInterval_SUBSCRIPTION_INTERVAL_ONE_MINUTE, false, nil)
+	if !errors.Is(err, investgo.ErrPoolLimitExceeded) {
+		t.Fatalf("err = %v, want ErrPoolLimitExceeded", err)
+	}
+	if pool.Subscriptions() != 7 {
+		t.Fatalf("subscriptions after limit = %v", pool.Subscriptions())
+	}
+
+	// оставшиеся три подписки переносятся в один стрим, лишние стримы закрываются
+	if err := pool.UnSubscribeLastPrice(uids[:4]); err != nil {
+		t.Fatal(err)
+	}
+	if pool.Streams() != 1 || pool.Subscriptions() != 3 {
+		t.Fatalf("streams = %v, subscriptions = %v after unsubscribe", pool.Streams(), pool.Subscriptions())
+	}
+	for i, uid := range uids[4:] {
+		receivePrice(t, srv, uid, float64(200+i), pool.LastPrices())
+	}
+
+	if err := pool.UnSubscribeLastPrice(uids[4:]); err != nil {
+		t.Fatal(err)
+	}
+	if pool.Streams() != 0 || pool.Subscriptions() != 0 {
+		t.Fatalf("streams = %v, subscriptions = %v after unsubscribe all", pool.Streams(), pool.Subscriptions())
+	}
+}
+
+func TestMarketDataPoolConcurrent(t *testing.T) {
+	srv, client, uids := newTestServer(t, 6)
+	pool := client.NewMarketDataStreamClient().NewMarketDataPool(4, 16, investgo.WithBuffer(16, investgo.OverflowDropOldest))
+	stop := listen(t, pool.Listen, pool.Stop)
+
+	var drained sync.WaitGroup
+	drain(&drained, pool.Candles())
+	drain(&drained, pool.OrderBooks())
+	drain(&drained, pool.Trades())
+	drain(&drained, pool.LastPrices())
+	drain(&drained, pool.TradingStatuses())
+
+	pushDone := make(chan struct{})
+	var pusher sync.WaitGroup
+	pusher.Add(1)
+	go func() {
+		defer pusher.Done()
+		for i := 0; ; i++ {
+			select {
+			case <-pushDone:
+				return
+			default:
+			}
+			_ = srv.SetPrice(uids[i%len(uids)], float64(100+i%50))
+			time.Sleep(100 * time.Microsecond)
+		}
+	}()
+
+	interval := pb.SubscriptionInterval_SUBSCRIPTION_INTERVAL_ONE_MINUTE
+	ops := []func(ids []string) error{
+		func(ids []string) error {
+			if err := pool.SubscribeLastPrice(ids); err != nil {
+				return err
+			}
+			return pool.UnSubscribeLastPrice(ids)
+		},
+		func(ids []string) error {
+			if err := pool.SubscribeCandle(ids, interval, false, nil); err != nil {
+				return err
+			}
+			return pool.UnSubscribeCandle(ids, interval, false, nil)
+		},
+		func(ids []string) error {
+			if err := pool.SubscribeOrderBook(ids, 10); err != nil {
+				return err
+			}
+			return pool.UnSubscribeOrderBook(ids, 10)
+		},
+		func(ids []string) error {
+			if err := pool.SubscribeInfo(ids); err != nil {
+				return err
+			}
+			_ = pool.Streams()
+			_ = pool.Dropped()
+			return pool.UnSubscribeInfo(ids)
+		},
+	}
+
+	var workers sync.WaitGroup
+	errs := make(chan error, 64)
+	for w := 0; w < 8; w++ {
+		workers.Add(1)
+		go func(w int) {
+			defer workers.Done()
+			for i := 0; i < 20; i++ {
+				ids := uids[(w+i)%len(uids):]
+				// одинаковые подписки из разных горутин учитываются один раз, а отписка от подписки, уже снятой
+				// другой горутиной, ничего не делает
+				if err := ops[(w+i)%len(ops)](ids); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}(w)
+	}
+	workers.Wait()
+	close(pushDone)
+	pusher.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	if pool.Streams() != 0 || pool.Subscriptions() != 0 {
+		t.Fatalf("streams = %v, subscriptions = %v", pool.Streams(), pool.Subscriptions())
+	}
+	stop()
+	drained.Wait()
+}
+
+func TestMarketDataPoolReconnect(t *testing.T) {
+	srv, client, uids := newTestServer(t, 4)
+	pool := client.NewMarketDataStreamClient().NewMarketDataPool(2, 4,
+		investgo.WithReconnect(retry.BackoffLinear(10*time.Millisecond), 0))
+	listen(t, pool.Listen, pool.Stop)
+
+	if err := pool.SubscribeLastPrice(uids); err != nil {
+		t.Fatal(err)
+	}
+	for i, uid := range uids {
+		receivePrice(t, srv, uid, float64(100+i), pool.LastPrices())
+	}
+
+	// все стримы пула разрываются и переподключаются с прежними подписками
+	srv.SetToken(investgotest.DefaultToken)
+	for i, uid := range uids {
+		receivePrice(t, srv, uid, float64(200+i), pool.LastPrices())
+	}
+	if pool.Streams() != 2 || pool.Subscriptions() != 4 {
+		t.Fatalf("streams = %v, subscriptions = %v after reconnect", pool.Streams(), pool.Subscriptions())
+	}
+}
+
+func TestMarketDataPoolOverflow(t *testing.T) {
+	t.Run("Block", func(t *testing.T) {
+		srv, client, uids := newTestServer(t, 1)
+		pool := client.NewMarketDataStreamClient().NewMarketDataPool(1, 1, investgo.WithBuffer(2, investgo.OverflowBlock))
+		listen(t, pool.Listen, pool.Stop)
+		if err := pool.SubscribeLastPrice(uids); err != nil {
+			t.Fatal(err)
+		}
+		waitSubscribed(t, srv, uids[0])
+		checkBlock(t, srv, uids[0], pool.LastPrices(), pool.Dropped)
+	})
+	for name, tc := range overflowCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			srv, client, uids := newTestServer(t, 1)
+			pool := client.NewMarketDataStreamClient().NewMarketDataPool(1, 1, investgo.WithBuffer(2, tc.policy))
+			listen(t, pool.Listen, pool.Stop)
+			if err := pool.SubscribeLastPrice(uids); err != nil {
+				t.Fatal(err)
+			}
+			waitSubscribed(t, srv, uids[0])
+			checkOverflow(t, srv, uids[0], tc, pool.LastPrices(), pool.Dropped)
+		})
+	}
+}
+
+func TestMarketDataPoolStopWithoutListen(t *testing.T) {
+	_, client, uids := newTestServer(t, 3)
+	pool := client.NewMarketDataStreamClient().NewMarketDataPool(1, 3, investgo.WithBuffer(2, investgo.OverflowKeepLatest))
+	if err := pool.SubscribeLastPrice(uids); err != nil {
+		t.Fatal(err)
+	}
+	if pool.Streams() != 3 {
+		t.Fatalf("streams = %v", pool.Streams())
+	}
+	pool.Stop()
+
+	// каналы пула закрываются и без запуска Listen
+	select {
+	case _, ok := <-pool.LastPrices():
+		if ok {
+			t.Fatal("unexpected price")
+		}
+	case <-time.After(waitTime):
+		t.Fatal("pool channels are not closed after stop")
+	}
+	if pool.Streams() != 0 {
+		t.Fatalf("streams after stop = %v", pool.Streams())
+	}
+	if err := pool.SubscribeLastPrice(uids); err == nil {
+		t.Fatal("subscription after stop succeeded")
+	}
+}
+
+func TestMarketDataPoolRejected(t *testing.T) {
+	_, client, uids := newTestServer(t, 2)
+	pool := client.NewMarketDataStreamClient().NewMarketDataPool(2, 2, investgo.WithSubscriptionConfirm(waitTime))
+	listen(t, pool.Listen, pool.Stop)
+	// ответы на подписку читает Listen стрима, поэтому подписка с подтверждением ждет запуска пула
+	time.Sleep(20 * time.Millisecond)
+
+	err := pool.SubscribeLastPrice([]string{uids[0], uids[1], "unknown"})
+	var subErr *investgo.SubscriptionError
+	if !errors.As(err, &subErr) {
+		t.Fatalf("err = %v, want *SubscriptionError", err)
+	}
+	if len(subErr.Results) != 1 || subErr.Results[0].InstrumentUid != "unknown" {
+		t.Fatalf("rejected = %+v", subErr.Results)
+	}
+	// отклоненные подписки не занимают место в стримах пула, как и в MarketDataStream
+	if pool.Subscriptions() != 2 || pool.Streams() != 1 {
+		t.Fatalf("streams = %v, subscriptions = %v", pool.Streams(), pool.Subscriptions())
+	}
+}
@@ -0,0 +1,134 @@
+package investgo
+
+import (
+	"context"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/russianinvestments/invest-api-go-sdk/proto"
+)
+
+// MarketDataServerSideStream - server-side стрим биржевой информации
+type MarketDataServerSideStream struct {
+	stream    pb.MarketDataStreamService_MarketDataServerSideStreamClient
+	mdsClient *MarketDataStreamClient
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	marketDataChannels
+}
+
+// Candles - Метод возвращает канал для чтения свечей
+func (s *MarketDataServerSideStream) Candles() <-chan *pb.Candle {
+	return s.candle.out()
+}
+
+// OrderBooks - Метод возвращает канал для чтения стаканов
+func (s *MarketDataServerSideStream) OrderBooks() <-chan *pb.OrderBook {
+	return s.orderBook.out()
+}
+
+// Trades - Метод возвращает канал для чтения ленты обезличенных сделок
+func (s *MarketDataServerSideStream) Trades() <-chan *pb.Trade {
+	return s.trade.out()
+}
+
+// LastPrices - Метод возвращает канал для чтения последних цен
+func (s *MarketDataServerSideStream) LastPrices() <-chan *pb.LastPrice {
+	return s.lastPrice.out()
+}
+
+// TradingStatuses - Метод возвращает канал для чтения торговых статусов
+func (s *MarketDataServerSideStream) TradingStatuses() <-chan *pb.TradingStatus {
+	return s.tradingStatus.out()
+}
+
+// SubscriptionResults - Метод возвращает канал статусов подписок по каждому инструменту из ответов сервера.
+// Если канал не читать, старые статусы отбрасываются
+func (s *MarketDataServerSideStream) SubscriptionResults() <-chan SubscriptionResult {
+	return s.results.out()
+}
+
+// ControlMessages - Метод возвращает канал служебных сообщений стрима: ответов на запросы подписки, пингов и т.д.
+// Если канал не читать, старые сообщения отбрасываются
+func (s *MarketDataServerSideStream) ControlMessages() <-chan *pb.MarketDataResponse {
+	return s.control.out()
+}
+
+// Listen - метод начинает слушать стрим и отправлять информацию в каналы
+func (s *MarketDataServerSideStream) Listen() error {
+	defer s.shutdown()
+	for {
+		select {
+		case <-s.ctx.Done():
+			s.mdsClient.logger.Infof("stop listening market data server side stream")
+			return nil
+		default:
+			resp, err := s.stream.Recv()
+			if err != nil {
+				switch {
+				case status.Code(err) == codes.Canceled:
+					s.mdsClient.logger.Infof("stop listening market data server side stream")
+					return nil
+				default:
+					return err
+				}
+			} else {
+				if !s.dispatch(resp) {
+					s.dispatchControl(resp)
+					s.mdsClient.logger.Infof("info from MD server side stream %v", resp.String())
+				}
+			}
+		}
+	}
+}
+
+func (s *MarketDataServerSideStream) restart(_ context.Context, attempt uint, err error) {
+	s.mdsClient.logger.Infof("try to restart md server side stream err = %v, attempt = %v", err.Error(), attempt)
+}
+
+// Dropped - Метод возвращает количество сообщений, отброшенных при переполнении каналов стрима. Сообщения
+// отбрасываются только с политикой переполнения, заданной опцией WithBuffer
+func (s *MarketDataServerSideStream) Dropped() uint64 {
+	return s.droppedCount()
+}
+
+func (s *MarketDataServerSideStream) shutdown() {
+	s.mdsClient.logger.Infof("close market data server side stream")
+	s.marketDataChannels.close()
+}
+
+// Stop - Завершение работы стрима
+func (s *MarketDataServerSideStream) Stop() {
+	s.cancel()
+}
+
+func (r *MarketDataServerSideStreamRequest) toPB() *pb.MarketDataServerSideStreamRequest {
+	req := &pb.MarketDataServerSideStreamRequest{}
+	if len(r.CandleInstruments) > 0 {
+		req.SubscribeCandlesRequest = candlesRequest(r.CandleInstruments, r.CandleInterval,
+			pb.SubscriptionAction_SUBSCRIPTION_ACTION_SUBSCRIBE, r.WaitingClose, r.CandleSource).GetSubscribeCandlesRequest()
+	}
+	if len(r.OrderBookInstruments) > 0 {
+		req.SubscribeOrderBookRequest = orderBookRequest(r.OrderBookInstruments, r.OrderBookDepth,
+			pb.SubscriptionAction_SUBSCRIPTION_ACTION_SUBSCRIBE).GetSubscribeOrderBookRequest()
+	}
+	if len(r.TradeInstruments) > 0 {
+		req.SubscribeTradesRequest = tradesRequest(r.TradeInstruments,
+			pb.SubscriptionAction_SUBSCRIPTION_ACTION_SUBSCRIBE, r.TradeSource).GetSubscribeTradesRequest()
+	}
+	if len(r.InfoInstruments) > 0 {
+		req.SubscribeInfoRequest = infoRequest(r.InfoInstruments,
+			pb.SubscriptionAction_SUBSCRIPTION_ACTION_SUBSCRIBE).GetSubscribeInfoRequest()
+	}
+	if len(r.LastPriceInstruments) > 0 {
+		req.SubscribeLastPriceRequest = lastPriceRequest(r.LastPriceInstruments,
+			pb.SubscriptionAction_SUBSCRIPTION_ACTION_SUBSCRIBE).GetSubscribeLastPriceRequest()
+	}
+	if r.PingDelayMs != nil {
+		req.PingSettings = pingSettingsRequest(*r.PingDelayMs).GetPingSettings()
+	}
+	return req
+}
@@ -2,76 +2,440 @@ package investgo
 
 import (
 	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 
 	pb "github.com/russianinvestments/invest-api-go-sdk/proto"
+	"github.com/russianinvestments/invest-api-go-sdk/retry"
 )
 
+// reconnectEventsBuffer - размер буфера канала событий переподключения
+const reconnectEventsBuffer = 8
+
+// mySubscriptionsTimeout - время ожидания ответа на GetMySubscriptions
+const mySubscriptionsTimeout = 10 * time.Second
+
 // Deprecated: Use MarketDataStream
 type MDStream struct {
 	*MarketDataStream
 }
 
-// MarketDataStream - стрим биржевой информации
+// MarketDataStream - стрим биржевой информации. Методы подписки, отписки и пинга можно вызывать одновременно
+// из разных горутин, запросы в стрим отправляются последовательно
 type MarketDataStream struct {
 	stream    pb.MarketDataStreamService_MarketDataStreamClient
+	streamMu  sync.RWMutex
 	mdsClient *MarketDataStreamClient
+	opts      *streamOptions
 
 	ctx    context.Context
 	cancel context.CancelFunc
 
-	candle        chan *pb.Candle
-	trade         chan *pb.Trade
-	orderBook     chan *pb.OrderBook
-	lastPrice     chan *pb.LastPrice
-	tradingStatus chan *pb.TradingStatus
+	marketDataChannels
+
+	reconnects chan ReconnectEvent
+	// attempt - номер последней попытки переподключения, сбрасывается, когда из стрима приходит сообщение.
+	// Стрим с отозванным токеном открывается без ошибки и разрывается на первом чтении, поэтому попытки
+	// считаются подряд до получения данных. Используется только в Listen
+	attempt uint
+
+	routers routers
 
-	tech chan *pb.MarketDataResponse
+	// subsMu - защищает подписки и счетчики ссылок подписок с отдельными каналами
+	subsMu      sync.Mutex
+	subs        subscriptions
+	refs        map[subRef]int
+	pingDelayMs *int32
+	// aliases - uid инструментов по идентификаторам из запросов подписки (figi, тикер, uid), заполняется
+	// по ответам сервера. Счетчики ссылок ведутся по uid, поэтому подписки на один инструмент по разным
+	// идентификаторам не отписывают друг друга
+	aliases map[string]string
 
-	subs subscriptions
+	// opMu - операции подписки и отписки выполняются последовательно, чтобы сохраненные подписки
+	// соответствовали подпискам на сервере
+	opMu sync.Mutex
+	// sendMu - отправка запроса и постановка его в очередь ожидания ответа выполняются атомарно,
+	// чтобы порядок запросов в pending совпадал с порядком ответов сервера
+	sendMu  sync.Mutex
+	pending *pendingResults
+}
+
+// marketDataChannels - каналы биржевой информации, общие для MarketDataStream и MarketDataServerSideStream
+type marketDataChannels struct {
+	candle        *streamQueue[*pb.Candle]
+	trade         *streamQueue[*pb.Trade]
+	orderBook     *streamQueue[*pb.OrderBook]
+	lastPrice     *streamQueue[*pb.LastPrice]
+	tradingStatus *streamQueue[*pb.TradingStatus]
+
+	// results, control - результаты подписок и служебные сообщения стрима, при переполнении отбрасываются старые
+	results *streamQueue[SubscriptionResult]
+	control *streamQueue[*pb.MarketDataResponse]
+
+	// dropped - общее количество сообщений, отброшенных каналами стрима
+	dropped *atomic.Uint64
+}
+
+func newMarketDataChannels(opts *streamOptions) marketDataChannels {
+	dropped := &atomic.Uint64{}
+	size := opts.size(1)
+	return marketDataChannels{
+		candle:        newStreamQueue(size, opts.overflow, candleKey, dropped),
+		trade:         newStreamQueue(size, opts.overflow, instrumentKey[*pb.Trade], dropped),
+		orderBook:     newStreamQueue(size, opts.overflow, orderBookKey, dropped),
+		lastPrice:     newStreamQueue(size, opts.overflow, instrumentKey[*pb.LastPrice], dropped),
+		tradingStatus: newStreamQueue(size, opts.overflow, instrumentKey[*pb.TradingStatus], dropped),
+		results:       newStreamQueue[SubscriptionResult](subscriptionResultsBuffer, OverflowDropOldest, nil, nil),
+		control:       newStreamQueue[*pb.MarketDataResponse](subscriptionResultsBuffer, OverflowDropOldest, nil, nil),
+		dropped:       dropped,
+	}
+}
+
+// instrumentKey - ключ инструмента для политики OverflowKeepLatest
+func instrumentKey[T marketDataMessage](v T) string {
+	if uid := v.GetInstrumentUid(); uid != "" {
+		return uid
+	}
+	return v.GetFigi()
+}
+
+func candleKey(c *pb.Candle) string {
+	return instrumentKey(c) + "/" + c.GetInterval().String()
+}
+
+func orderBookKey(ob *pb.OrderBook) string {
+	return instrumentKey(ob) + "/" + strconv.Itoa(int(ob.GetDepth()))
+}
+
+// dispatch - отправка биржевой информации в нужный канал, возвращает false, если в ответе нет биржевой информации
+func (c *marketDataChannels) dispatch(resp *pb.MarketDataResponse) bool {
+	switch resp.GetPayload().(type) {
+	case *pb.MarketDataResponse_Candle:
+		c.candle.send(resp.GetCandle())
+	case *pb.MarketDataResponse_Orderbook:
+		c.orderBook.send(resp.GetOrderbook())
+	case *pb.MarketDataResponse_Trade:
+		c.trade.send(resp.GetTrade())
+	case *pb.MarketDataResponse_LastPrice:
+		c.lastPrice.send(resp.GetLastPrice())
+	case *pb.MarketDataResponse_TradingStatus:
+		c.tradingStatus.send(resp.GetTradingStatus())
+	default:
+		return false
+	}
+	return true
+}
+
+// dispatchControl - отправка служебного сообщения в канал служебных сообщений, а статусов подписок в канал
+// результатов подписок. ok = true, если сообщение является ответом на запрос подписки
+func (c *marketDataChannels) dispatchControl(resp *pb.MarketDataResponse) (t SubscriptionType, results []SubscriptionResult, ok bool) {
+	c.control.send(resp)
+	t, results, ok = subscriptionResults(resp)
+	for _, r := range results {
+		c.results.send(r)
+	}
+	return t, results, ok
+}
+
+func (c *marketDataChannels) close() {
+	c.candle.close()
+	c.trade.close()
+	c.lastPrice.close()
+	c.orderBook.close()
+	c.tradingStatus.close()
+	c.results.close()
+	c.control.close()
+}
+
+// stop - разблокирует ожидающие отправки в каналы, после stop сообщения больше не доставляются
+func (c *marketDataChannels) stop() {
+	c.candle.stop()
+	c.trade.stop()
+	c.lastPrice.stop()
+	c.orderBook.stop()
+	c.tradingStatus.stop()
+}
+
+func (c *marketDataChannels) droppedCount() uint64 {
+	return c.dropped.Load()
+}
+
+// ReconnectEvent - событие переподключения стрима, отправляется в канал ReconnectEvents()
+type ReconnectEvent struct {
+	// Attempt - номер попытки переподключения, начиная с 1
+	Attempt uint
+	// Err - ошибка, из-за которой стрим был разорван, или ошибка неудачной попытки переподключения
+	Err error
+	// Success - true, если стрим открыт заново и все подписки восстановлены
+	Success bool
+	// Time - время события
+	Time time.Time
 }
 
 type candleSub struct {
 	interval     pb.SubscriptionInterval
 	waitingClose bool
+	candleSrc    pb.GetCandlesRequest_CandleSource
+}
+
+func newCandleSub(interval pb.SubscriptionInterval, waitingClose bool, candleSrc *pb.GetCandlesRequest_CandleSource) candleSub {
+	src := pb.GetCandlesRequest_CANDLE_SOURCE_UNSPECIFIED
+	if candleSrc != nil {
+		src = *candleSrc
+	}
+	return candleSub{interval: interval, waitingClose: waitingClose, candleSrc: src}
+}
+
+// source - источник свечей для запроса подписки, nil если источник не указан
+func (c candleSub) source() *pb.GetCandlesRequest_CandleSource {
+	if c.candleSrc == pb.GetCandlesRequest_CANDLE_SOURCE_UNSPECIFIED {
+		return nil
+	}
+	src := c.candleSrc
+	return &src
 }
 
+// subscriptions - подписки, сделанные методами Subscribe*, информация по ним приходит в общие каналы. На свечи
+// и стаканы одного инструмента можно подписаться одновременно с разными параметрами, поэтому они хранятся
+// по инструменту и параметрам подписки
 type subscriptions struct {
-	candles         map[string]candleSub
-	orderBooks      map[string]int32
+	candles         map[string]map[candleSub]struct{}
+	orderBooks      map[string]map[int32]struct{}
 	trades          map[string]pb.TradeSourceType
 	tradingStatuses map[string]struct{}
 	lastPrices      map[string]struct{}
 }
 
+func newSubscriptions() subscriptions {
+	return subscriptions{
+		candles:         make(map[string]map[candleSub]struct{}, 0),
+		orderBooks:      make(map[string]map[int32]struct{}, 0),
+		trades:          make(map[string]pb.TradeSourceType, 0),
+		tradingStatuses: make(map[string]struct{}, 0),
+		lastPrices:      make(map[string]struct{}, 0),
+	}
+}
+
+// get - подписка, которую заменит подписка r, вызывается под subsMu. Подписки на свечи и стаканы с другими
+// параметрами не заменяются, а подписка на сделки заменяет подписку с другим источником
+func (s *subscriptions) get(r subRef) (subRef, bool) {
+	if r.kind == SubscriptionTrades {
+		src, ok := s.trades[r.id]
+		r.tradeSrc = src
+		return r, ok
+	}
+	return r, s.has(r)
+}
+
+func (s *subscriptions) put(r subRef) {
+	switch r.kind {
+	case SubscriptionCandles:
+		if s.candles[r.id] == nil {
+			s.candles[r.id] = make(map[candleSub]struct{}, 1)
+		}
+		s.candles[r.id][r.candle] = struct{}{}
+	case SubscriptionOrderBooks:
+		if s.orderBooks[r.id] == nil {
+			s.orderBooks[r.id] = make(map[int32]struct{}, 1)
+		}
+		s.orderBooks[r.id][r.depth] = struct{}{}
+	case SubscriptionTrades:
+		s.trades[r.id] = r.tradeSrc
+	case SubscriptionInfo:
+		s.tradingStatuses[r.id] = struct{}{}
+	case SubscriptionLastPrices:
+		s.lastPrices[r.id] = struct{}{}
+	}
+}
+
+func (s *subscriptions) delete(r subRef) {
+	switch r.kind {
+	case SubscriptionCandles:
+		delete(s.candles[r.id], r.candle)
+		if len(s.candles[r.id]) == 0 {
+			delete(s.candles, r.id)
+		}
+	case SubscriptionOrderBooks:
+		delete(s.orderBooks[r.id], r.depth)
+		if len(s.orderBooks[r.id]) == 0 {
+			delete(s.orderBooks, r.id)
+		}
+	case SubscriptionTrades:
+		delete(s.trades, r.id)
+	case SubscriptionInfo:
+		delete(s.tradingStatuses, r.id)
+	case SubscriptionLastPrices:
+		delete(s.lastPrices, r.id)
+	}
+}
+
+// set - сохраняет подписки, возвращает предыдущие подписки на эти инструменты для отката, вызывается под subsMu
+func (s *subscriptions) set(refs []subRef) map[subRef]subRef {
+	prev := s.snapshot(refs)
+	for _, r := range refs {
+		s.put(r)
+	}
+	return prev
+}
+
+// remove - удаляет подписки, возвращает удаленные подписки для отката, вызывается под subsMu
+func (s *subscriptions) remove(refs []subRef) map[subRef]subRef {
+	prev := s.snapshot(refs)
+	for _, r := range refs {
+		if s.has(r) {
+			s.delete(r)
+		}
+	}
+	return prev
+}
+
+func (s *subscriptions) snapshot(refs []subRef) map[subRef]subRef {
+	prev := make(map[subRef]subRef, len(refs))
+	for _, r := range refs {
+		if p, ok := s.get(r); ok {
+			prev[r] = p
+		}
+	}
+	return prev
+}
+
+// restore - откат изменений set или remove, вызывается под subsMu
+func (s *subscriptions) restore(refs []subRef, prev map[subRef]subRef) {
+	for _, r := range refs {
+		if p, ok := prev[r]; ok {
+			s.put(p)
+		} else {
+			s.delete(r)
+		}
+	}
+}
+
+// has - проверка наличия подписки с такими же параметрами, вызывается под subsMu
+func (s *subscriptions) has(r subRef) bool {
+	switch r.kind {
+	case SubscriptionCandles:
+		_, ok := s.candles[r.id][r.candle]
+		return ok
+	case SubscriptionOrderBooks:
+		_, ok := s.orderBooks[r.id][r.depth]
+		return ok
+	case SubscriptionTrades:
+		src, ok := s.trades[r.id]
+		return ok && src == r.tradeSrc
+	case SubscriptionInfo:
+		_, ok := s.tradingStatuses[r.id]
+		return ok
+	case SubscriptionLastPrices:
+		_, ok := s.lastPrices[r.id]
+		return ok
+	}
+	return false
+}
+
+// lookup - есть ли подписка на инструмент, и есть ли вообще подписки данного типа, вызывается под subsMu
+func (s *subscriptions) lookup(kind SubscriptionType, ids ...string) (found bool, hasAny bool) {
+	var size int
+	for _, id := range ids {
+		var ok bool
+		switch kind {
+		case SubscriptionCandles:
+			_, ok = s.candles[id]
+			size = len(s.candles)
+		case SubscriptionOrderBooks:
+			_, ok = s.orderBooks[id]
+			size = len(s.orderBooks)
+		case SubscriptionTrades:
+			_, ok = s.trades[id]
+			size = len(s.trades)
+		case SubscriptionInfo:
+			_, ok = s.tradingStatuses[id]
+			size = len(s.tradingStatuses)
+		case SubscriptionLastPrices:
+			_, ok = s.lastPrices[id]
+			size = len(s.lastPrices)
+		}
+		if ok {
+			return true, true
+		}
+	}
+	return false, size > 0
+}
+
+// refs - все подписки с параметрами, вызывается под subsMu
+func (s *subscriptions) refs() []subRef {
+	refs := make([]subRef, 0)
+	for id, subs := range s.candles {
+		for c := range subs {
+			refs = append(refs, subRef{kind: SubscriptionCandles, id: id, candle: c})
+		}
+	}
+	for id, depths := range s.orderBooks {
+		for d := range depths {
+			refs = append(refs, subRef{kind: SubscriptionOrderBooks, id: id, depth: d})
+		}
+	}
+	for id, src := range s.trades {
+		refs = append(refs, subRef{kind: SubscriptionTrades, id: id, tradeSrc: src})
+	}
+	for id := range s.tradingStatuses {
+		refs = append(refs, subRef{kind: SubscriptionInfo, id: id})
+	}
+	for id := range s.lastPrices {
+		refs = append(refs, subRef{kind: SubscriptionLastPrices, id: id})
+	}
+	return refs
+}
+
 // SubscribeCandle - Метод подписки на свечи с заданным интервалом
 func (mds *MarketDataStream) SubscribeCandle(ids []string, interval pb.SubscriptionInterval, waitingClose bool, candleSrc *pb.GetCandlesRequest_CandleSource) (<-chan *pb.Candle, error) {
-	err := mds.sendCandlesReq(ids, interval, pb.SubscriptionAction_SUBSCRIPTION_ACTION_SUBSCRIBE, waitingClose, candleSrc)
-	if err != nil {
+	sub := newCandleSub(interval, waitingClose, candleSrc)
+	err := mds.subscribeCommon(refsOf(ids, func(id string) subRef {
+		return subRef{kind: SubscriptionCandles, id: id, candle: sub}
+	}), func(ids []string, act pb.SubscriptionAction) error {
+		return mds.sendCandlesReq(ids, interval, act, waitingClose, candleSrc)
+	})
+	if err != nil && !isSubscriptionError(err) {
 		return nil, err
 	}
-	for _, id := range ids {
-		mds.subs.candles[id] = candleSub{interval: interval, waitingClose: waitingClose}
-	}
-	return mds.candle, nil
+	return mds.candle.out(), err
 }
 
 // UnSubscribeCandle - Метод отписки от свечей
 func (mds *MarketDataStream) UnSubscribeCandle(ids []string, interval pb.SubscriptionInterval, waitingClose bool, candleSrc *pb.GetCandlesRequest_CandleSource) error {
-	err := mds.sendCandlesReq(ids, interval, pb.SubscriptionAction_SUBSCRIPTION_ACTION_UNSUBSCRIBE, waitingClose, candleSrc)
-	if err != nil {
-		return err
-	}
-	for _, id := range ids {
-		delete(mds.subs.candles, id)
-	}
-	return nil
+	sub := newCandleSub(interval, waitingClose, candleSrc)
+	return mds.unsubscribeCommon(refsOf(ids, func(id string) subRef {
+		return subRef{kind: SubscriptionCandles, id: id, candle: sub}
+	}), func(ids []string, act pb.SubscriptionAction) error {
+		return mds.sendCandlesReq(ids, interval, act, waitingClose, candleSrc)
+	})
+}
+
+// NewCandleSubscription - Метод подписки на свечи с заданным интервалом, возвращает подписку с отдельным каналом,
+// в который приходят свечи только по инструментам ids
+func (mds *MarketDataStream) NewCandleSubscription(ids []string, interval pb.SubscriptionInterval, waitingClose bool, candleSrc *pb.GetCandlesRequest_CandleSource) (*Subscription[*pb.Candle], error) {
+	sub := newCandleSub(interval, waitingClose, candleSrc)
+	return subscribe(mds, mds.routers.candles, refsOf(ids, func(id string) subRef {
+		return subRef{kind: SubscriptionCandles, id: id, candle: sub}
+	}),
+		func(c *pb.Candle) bool {
+			return c.GetInterval() == interval
+		},
+		func(ids []string, act pb.SubscriptionAction) error {
+			return mds.sendCandlesReq(ids, interval, act, waitingClose, candleSrc)
+		})
 }
 
 func (mds *MarketDataStream) sendCandlesReq(ids []string, interval pb.SubscriptionInterval, act pb.SubscriptionAction, waitingClose bool, candleSrc *pb.GetCandlesRequest_CandleSource) error {
+	return mds.request(candlesRequest(ids, interval, act, waitingClose, candleSrc))
+}
+
+func candlesRequest(ids []string, interval pb.SubscriptionInterval, act pb.SubscriptionAction, waitingClose bool, candleSrc *pb.GetCandlesRequest_CandleSource) *pb.MarketDataRequest {
 	instruments := make([]*pb.CandleInstrument, 0, len(ids))
 	for _, id := range ids {
 		instruments = append(instruments, &pb.CandleInstrument{
@@ -80,7 +444,7 @@ func (mds *MarketDataStream) sendCandlesReq(ids []string, interval pb.Subscripti
 		})
 	}
 
-	return mds.stream.Send(&pb.MarketDataRequest{
+	return &pb.MarketDataRequest{
 		Payload: &pb.MarketDataRequest_SubscribeCandlesRequest{
 			SubscribeCandlesRequest: &pb.SubscribeCandlesRequest{
 				SubscriptionAction: act,
@@ -89,34 +453,50 @@ func (mds *MarketDataStream) sendCandlesReq(ids []string, interval pb.Subscripti
 				CandleSourceType:   candleSrc,
 			},
 		},
-	})
+	}
 }
 
 // SubscribeOrderBook - метод подписки на стаканы инструментов с одинаковой глубиной
 func (mds *MarketDataStream) SubscribeOrderBook(ids []string, depth int32) (<-chan *pb.OrderBook, error) {
-	err := mds.sendOrderBookReq(ids, depth, pb.SubscriptionAction_SUBSCRIPTION_ACTION_SUBSCRIBE)
-	if err != nil {
+	err := mds.subscribeCommon(refsOf(ids, func(id string) subRef {
+		return subRef{kind: SubscriptionOrderBooks, id: id, depth: depth}
+	}), func(ids []string, act pb.SubscriptionAction) error {
+		return mds.sendOrderBookReq(ids, depth, act)
+	})
+	if err != nil && !isSubscriptionError(err) {
 		return nil, err
 	}
-	for _, id := range ids {
-		mds.subs.orderBooks[id] = depth
-	}
-	return mds.orderBook, nil
+	return mds.orderBook.out(), err
 }
 
 // UnSubscribeOrderBook - метод отписки от стаканов инструментов
 func (mds *MarketDataStream) UnSubscribeOrderBook(ids []string, depth int32) error {
-	err := mds.sendOrderBookReq(ids, depth, pb.SubscriptionAction_SUBSCRIPTION_ACTION_UNSUBSCRIBE)
-	if err != nil {
-		return err
-	}
-	for _, id := range ids {
-		delete(mds.subs.orderBooks, id)
-	}
-	return nil
+	return mds.unsubscribeCommon(refsOf(ids, func(id string) subRef {
+		return subRef{kind: SubscriptionOrderBooks, id: id, depth: depth}
+	}), func(ids []string, act pb.SubscriptionAction) error {
+		return mds.sendOrderBookReq(ids, depth, act)
+	})
+}
+
+// NewOrderBookSubscription - метод подписки на стаканы инструментов с одинаковой глубиной, возвращает подписку
+// с отдельным каналом, в который приходят стаканы только по инструментам ids
+func (mds *MarketDataStream) NewOrderBookSubscription(ids []string, depth int32) (*Subscription[*pb.OrderBook], error) {
+	return subscribe(mds, mds.routers.orderBooks, refsOf(ids, func(id string) subRef {
+		return subRef{kind: SubscriptionOrderBooks, id: id, depth: depth}
+	}),
+		func(ob *pb.OrderBook) bool {
+			return ob.GetDepth() == depth
+		},
+		func(ids []string, act pb.SubscriptionAction) error {
+			return mds.sendOrderBookReq(ids, depth, act)
+		})
 }
 
 func (mds *MarketDataStream) sendOrderBookReq(ids []string, depth int32, act pb.SubscriptionAction) error {
+	return mds.request(orderBookRequest(ids, depth, act))
+}
+
+func orderBookRequest(ids []string, depth int32, act pb.SubscriptionAction) *pb.MarketDataRequest {
 	instruments := make([]*pb.OrderBookInstrument, 0, len(ids))
 	for _, id := range ids {
 		instruments = append(instruments, &pb.OrderBookInstrument{
@@ -124,140 +504,244 @@ func (mds *MarketDataStream) sendOrderBookReq(ids []string, depth int32, act pb.
 			InstrumentId: id,
 		})
 	}
-	return mds.stream.Send(&pb.MarketDataRequest{
+	return &pb.MarketDataRequest{
 		Payload: &pb.MarketDataRequest_SubscribeOrderBookRequest{
 			SubscribeOrderBookRequest: &pb.SubscribeOrderBookRequest{
 				SubscriptionAction: act,
 				Instruments:        instruments,
-			}}})
+			}}}
 }
 
 // SubscribeTrade - метод подписки на ленту обезличенных сделок
 func (mds *MarketDataStream) SubscribeTrade(ids []string, tradeSrc pb.TradeSourceType) (<-chan *pb.Trade, error) {
-	err := mds.sendTradesReq(ids, pb.SubscriptionAction_SUBSCRIPTION_ACTION_SUBSCRIBE, tradeSrc)
-	if err != nil {
+	err := mds.subscribeCommon(refsOf(ids, func(id string) subRef {
+		return subRef{kind: SubscriptionTrades, id: id, tradeSrc: tradeSrc}
+	}), func(ids []string, act pb.SubscriptionAction) error {
+		return mds.sendTradesReq(ids, act, tradeSrc)
+	})
+	if err != nil && !isSubscriptionError(err) {
 		return nil, err
 	}
-	for _, id := range ids {
-		mds.subs.trades[id] = tradeSrc
-	}
-	return mds.trade, nil
+	return mds.trade.out(), err
 }
 
 // UnSubscribeTrade - метод отписки от ленты обезличенных сделок
 func (mds *MarketDataStream) UnSubscribeTrade(ids []string, tradeSrc pb.TradeSourceType) error {
-	err := mds.sendTradesReq(ids, pb.SubscriptionAction_SUBSCRIPTION_ACTION_UNSUBSCRIBE, tradeSrc)
-	if err != nil {
-		return err
-	}
-	for _, id := range ids {
-		delete(mds.subs.trades, id)
-	}
-	return nil
+	return mds.unsubscribeCommon(refsOf(ids, func(id string) subRef {
+		return subRef{kind: SubscriptionTrades, id: id, tradeSrc: tradeSrc}
+	}), func(ids []string, act pb.SubscriptionAction) error {
+		return mds.sendTradesReq(ids, act, tradeSrc)
+	})
+}
+
+// NewTradeSubscription - метод подписки на ленту обезличенных сделок, возвращает подписку с отдельным каналом,
+// в который приходят сделки только по инструментам ids
+func (mds *MarketDataStream) NewTradeSubscription(ids []string, tradeSrc pb.TradeSourceType) (*Subscription[*pb.Trade], error) {
+	return subscribe(mds, mds.routers.trades, refsOf(ids, func(id string) subRef {
+		return subRef{kind: SubscriptionTrades, id: id, tradeSrc: tradeSrc}
+	}), nil,
+		func(ids []string, act pb.SubscriptionAction) error {
+			return mds.sendTradesReq(ids, act, tradeSrc)
+		})
 }
 
 func (mds *MarketDataStream) sendTradesReq(ids []string, act pb.SubscriptionAction, tradeSrc pb.TradeSourceType) error {
+	return mds.request(tradesRequest(ids, act, tradeSrc))
+}
+
+func tradesRequest(ids []string, act pb.SubscriptionAction, tradeSrc pb.TradeSourceType) *pb.MarketDataRequest {
 	instruments := make([]*pb.TradeInstrument, 0, len(ids))
 	for _, id := range ids {
 		instruments = append(instruments, &pb.TradeInstrument{
 			InstrumentId: id,
 		})
 	}
-	return mds.stream.Send(&pb.MarketDataRequest{
+	return &pb.MarketDataRequest{
 		Payload: &pb.MarketDataRequest_SubscribeTradesRequest{
 			SubscribeTradesRequest: &pb.SubscribeTradesRequest{
 				SubscriptionAction: act,
 				Instruments:        instruments,
 				TradeSource:        tradeSrc,
-			}}})
+			}}}
 }
 
 // SubscribeInfo - метод подписки на торговые статусы инструментов
 func (mds *MarketDataStream) SubscribeInfo(ids []string) (<-chan *pb.TradingStatus, error) {
-	err := mds.sendInfoReq(ids, pb.SubscriptionAction_SUBSCRIPTION_ACTION_SUBSCRIBE)
-	if err != nil {
+	err := mds.subscribeCommon(refsOf(ids, func(id string) subRef {
+		return subRef{kind: SubscriptionInfo, id: id}
+	}), func(ids []string, act pb.SubscriptionAction) error {
+		return mds.sendInfoReq(ids, act)
+	})
+	if err != nil && !isSubscriptionError(err) {
 		return nil, err
 	}
-	for _, id := range ids {
-		mds.subs.tradingStatuses[id] = struct{}{}
-	}
-	return mds.tradingStatus, nil
+	return mds.tradingStatus.out(), err
 }
 
 // UnSubscribeInfo - метод отписки от торговых статусов инструментов
 func (mds *MarketDataStream) UnSubscribeInfo(ids []string) error {
-	err := mds.sendInfoReq(ids, pb.SubscriptionAction_SUBSCRIPTION_ACTION_UNSUBSCRIBE)
-	if err != nil {
-		return err
-	}
-	for _, id := range ids {
-		delete(mds.subs.tradingStatuses, id)
-	}
-	return nil
+	return mds.unsubscribeCommon(refsOf(ids, func(id string) subRef {
+		return subRef{kind: SubscriptionInfo, id: id}
+	}), func(ids []string, act pb.SubscriptionAction) error {
+		return mds.sendInfoReq(ids, act)
+	})
+}
+
+// NewInfoSubscription - метод подписки на торговые статусы инструментов, возвращает подписку с отдельным каналом,
+// в который приходят статусы только по инструментам ids
+func (mds *MarketDataStream) NewInfoSubscription(ids []string) (*Subscription[*pb.TradingStatus], error) {
+	return subscribe(mds, mds.routers.tradingStatuses, refsOf(ids, func(id string) subRef {
+		return subRef{kind: SubscriptionInfo, id: id}
+	}), nil,
+		func(ids []string, act pb.SubscriptionAction) error {
+			return mds.sendInfoReq(ids, act)
+		})
 }
 
 func (mds *MarketDataStream) sendInfoReq(ids []string, act pb.SubscriptionAction) error {
+	return mds.request(infoRequest(ids, act))
+}
+
+func infoRequest(ids []string, act pb.SubscriptionAction) *pb.MarketDataRequest {
 	instruments := make([]*pb.InfoInstrument, 0, len(ids))
 	for _, id := range ids {
 		instruments = append(instruments, &pb.InfoInstrument{
 			InstrumentId: id,
 		})
 	}
-	return mds.stream.Send(&pb.MarketDataRequest{
+	return &pb.MarketDataRequest{
 		Payload: &pb.MarketDataRequest_SubscribeInfoRequest{
 			SubscribeInfoRequest: &pb.SubscribeInfoRequest{
 				SubscriptionAction: act,
 				Instruments:        instruments,
-			}}})
+			}}}
 }
 
 // SubscribeLastPrice - метод подписки на последние цены инструментов
 func (mds *MarketDataStream) SubscribeLastPrice(ids []string) (<-chan *pb.LastPrice, error) {
-	err := mds.sendLastPriceReq(ids, pb.SubscriptionAction_SUBSCRIPTION_ACTION_SUBSCRIBE)
-	if err != nil {
+	err := mds.subscribeCommon(refsOf(ids, func(id string) subRef {
+		return subRef{kind: SubscriptionLastPrices, id: id}
+	}), func(ids []string, act pb.SubscriptionAction) error {
+		return mds.sendLastPriceReq(ids, act)
+	})
+	if err != nil && !isSubscriptionError(err) {
 		return nil, err
 	}
-	for _, id := range ids {
-		mds.subs.lastPrices[id] = struct{}{}
-	}
-	return mds.lastPrice, nil
+	return mds.lastPrice.out(), err
 }
 
 // UnSubscribeLastPrice - метод отписки от последних цен инструментов
 func (mds *MarketDataStream) UnSubscribeLastPrice(ids []string) error {
-	err := mds.sendLastPriceReq(ids, pb.SubscriptionAction_SUBSCRIPTION_ACTION_UNSUBSCRIBE)
-	if err != nil {
-		return err
-	}
-	for _, id := range ids {
-		delete(mds.subs.lastPrices, id)
-	}
-	return nil
+	return mds.unsubscribeCommon(refsOf(ids, func(id string) subRef {
+		return subRef{kind: SubscriptionLastPrices, id: id}
+	}), func(ids []string, act pb.SubscriptionAction) error {
+		return mds.sendLastPriceReq(ids, act)
+	})
+}
+
+// NewLastPriceSubscription - метод подписки на последние цены инструментов, возвращает подписку с отдельным каналом,
+// в который приходят цены только по инструментам ids
+func (mds *MarketDataStream) NewLastPriceSubscription(ids []string) (*Subscription[*pb.LastPrice], error) {
+	return subscribe(mds, mds.routers.lastPrices, refsOf(ids, func(id string) subRef {
+		return subRef{kind: SubscriptionLastPrices, id: id}
+	}), nil,
+		func(ids []string, act pb.SubscriptionAction) error {
+			return mds.sendLastPriceReq(ids, act)
+		})
 }
 
 func (mds *MarketDataStream) sendLastPriceReq(ids []string, act pb.SubscriptionAction) error {
+	return mds.request(lastPriceRequest(ids, act))
+}
+
+func lastPriceRequest(ids []string, act pb.SubscriptionAction) *pb.MarketDataRequest {
 	instruments := make([]*pb.LastPriceInstrument, 0, len(ids))
 	for _, id := range ids {
 		instruments = append(instruments, &pb.LastPriceInstrument{
 			InstrumentId: id,
 		})
 	}
-	return mds.stream.Send(&pb.MarketDataRequest{
+	return &pb.MarketDataRequest{
 		Payload: &pb.MarketDataRequest_SubscribeLastPriceRequest{
 			SubscribeLastPriceRequest: &pb.SubscribeLastPriceRequest{
 				SubscriptionAction: act,
 				Instruments:        instruments,
-			}}})
+			}}}
 }
 
-// GetMySubscriptions - метод получения подписок в рамках данного стрима
-func (mds *MarketDataStream) GetMySubscriptions() error {
-	return mds.stream.Send(&pb.MarketDataRequest{
+// GetMySubscriptions - метод получения подписок в рамках данного стрима. Ответ приходит в стрим, поэтому
+// метод нужно вызывать, когда уже запущен Listen. Ожидание ответа ограничено mySubscriptionsTimeout
+func (mds *MarketDataStream) GetMySubscriptions() (*MySubscriptions, error) {
+	ctx, cancel := context.WithTimeout(mds.ctx, mySubscriptionsTimeout)
+	defer cancel()
+	return mds.GetMySubscriptionsCtx(ctx)
+}
+
+// GetMySubscriptionsCtx - то же, что и GetMySubscriptions, но ожидание ответа ограничено контекстом ctx
+func (mds *MarketDataStream) GetMySubscriptionsCtx(ctx context.Context) (*MySubscriptions, error) {
+	mds.sendMu.Lock()
+	snap := mds.pending.addSnapshot()
+	err := mds.sendLocked(&pb.MarketDataRequest{
 		Payload: &pb.MarketDataRequest_GetMySubscriptions{
 			GetMySubscriptions: &pb.GetMySubscriptions{}}})
+	if err == nil {
+		// сервер отвечает только по активным типам подписок, ответ на пинг отмечает конец снимка
+		err = mds.sendLocked(&pb.MarketDataRequest{
+			Payload: &pb.MarketDataRequest_Ping{
+				Ping: &pb.PingRequest{Time: TimeToTimestamp(snap.ping)}}})
+	}
+	if err != nil {
+		mds.pending.removeSnapshot(snap)
+	}
+	mds.sendMu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	return mds.pending.waitSnapshot(ctx, snap)
+}
+
+// SubscriptionResults - Метод возвращает канал статусов подписок по каждому инструменту из ответов сервера
+// на запросы подписки и отписки, в том числе на запросы, отправленные при переподключении. Если канал не читать,
+// старые статусы отбрасываются
+func (mds *MarketDataStream) SubscriptionResults() <-chan SubscriptionResult {
+	return mds.results.out()
+}
+
+// ControlMessages - Метод возвращает канал служебных сообщений стрима: ответов на запросы подписки, пингов и т.д.
+// Если канал не читать, старые сообщения отбрасываются
+func (mds *MarketDataStream) ControlMessages() <-chan *pb.MarketDataResponse {
+	return mds.control.out()
+}
+
+// request - отправка запроса подписки или отписки. Если стрим создан с опцией WithSubscriptionConfirm, метод ждет
+// ответа сервера и возвращает *SubscriptionError, если сервер отклонил подписку хотя бы на один инструмент
+func (mds *MarketDataStream) request(req *pb.MarketDataRequest) error {
+	t, _ := requestType(req)
+	mds.sendMu.Lock()
+	pr := mds.pending.add(t, requestIds(req))
+	err := mds.sendLocked(req)
+	if err != nil {
+		mds.pending.remove(pr)
+	}
+	mds.sendMu.Unlock()
+	if err != nil || !mds.opts.confirm {
+		return err
+	}
+
+	ctx, cancel := mds.ctx, context.CancelFunc(func() {})
+	if mds.opts.confirmTimeout > 0 {
+		ctx, cancel = context.WithTimeout(mds.ctx, mds.opts.confirmTimeout)
+	}
+	defer cancel()
+	results, err := mds.pending.wait(ctx, pr)
+	if err != nil {
+		return err
+	}
+	return subscriptionError(results)
 }
 
-// Listen - метод начинает слушать стрим и отправлять информацию в каналы
+// Listen - метод начинает слушать стрим и отправлять информацию в каналы. Если стрим создан с опцией WithReconnect,
+// то после обрыва соединения стрим открывается заново, а каналы остаются открытыми
 func (mds *MarketDataStream) Listen() error {
 	defer mds.shutdown()
 	for {
@@ -266,17 +750,24 @@ func (mds *MarketDataStream) Listen() error {
 			mds.mdsClient.logger.Infof("stop listening market data stream")
 			return nil
 		default:
-			resp, err := mds.stream.Recv()
+			resp, err := mds.getStream().Recv()
 			if err != nil {
+				// сервер отклонил токен, новый стрим откроется с токеном, запрошенным у провайдера заново
+				invalidateToken(mds.mdsClient.config.TokenProvider, err)
 				// если ошибка связана с завершением контекста, обрабатываем ее
 				switch {
 				case status.Code(err) == codes.Canceled:
 					mds.mdsClient.logger.Infof("stop listening market data stream")
 					return nil
+				case mds.opts.reconnect:
+					if err := mds.reconnect(err); err != nil {
+						return err
+					}
 				default:
 					return err
 				}
 			} else {
+				mds.attempt = 0
 				// логика определения того что пришло и отправка информации в нужный канал
 				mds.sendRespToChannel(resp)
 			}
@@ -284,113 +775,422 @@ func (mds *MarketDataStream) Listen() error {
 	}
 }
 
+// sendRespToChannel - отправка информации в каналы подписок с отдельными каналами и в общие каналы.
+// В общий канал информация попадает, если на инструмент подписались методом Subscribe* или если ни одна
+// подписка с отдельным каналом ее не получила
 func (mds *MarketDataStream) sendRespToChannel(resp *pb.MarketDataResponse) {
 	switch resp.GetPayload().(type) {
 	case *pb.MarketDataResponse_Candle:
-		mds.candle <- resp.GetCandle()
+		c := resp.GetCandle()
+		if mds.toCommon(SubscriptionCandles, mds.routers.candles.publish(c), c) {
+			mds.candle.send(c)
+		}
 	case *pb.MarketDataResponse_Orderbook:
-		mds.orderBook <- resp.GetOrderbook()
+		ob := resp.GetOrderbook()
+		if mds.toCommon(SubscriptionOrderBooks, mds.routers.orderBooks.publish(ob), ob) {
+			mds.orderBook.send(ob)
+		}
 	case *pb.MarketDataResponse_Trade:
-		mds.trade <- resp.GetTrade()
+		t := resp.GetTrade()
+		if mds.toCommon(SubscriptionTrades, mds.routers.trades.publish(t), t) {
+			mds.trade.send(t)
+		}
 	case *pb.MarketDataResponse_LastPrice:
-		mds.lastPrice <- resp.GetLastPrice()
+		lp := resp.GetLastPrice()
+		if mds.toCommon(SubscriptionLastPrices, mds.routers.lastPrices.publish(lp), lp) {
+			mds.lastPrice.send(lp)
+		}
 	case *pb.MarketDataResponse_TradingStatus:
-		mds.tradingStatus <- resp.GetTradingStatus()
+		ts := resp.GetTradingStatus()
+		if mds.toCommon(SubscriptionInfo, mds.routers.tradingStatuses.publish(ts), ts) {
+			mds.tradingStatus.send(ts)
+		}
 	default:
-		// mds.tech <- resp
+		if t, results, ok := mds.dispatchControl(resp); ok {
+			mds.learnAliases(mds.pending.resolve(t, results), results)
+		}
+		if ping := resp.GetPing(); ping != nil {
+			mds.pending.pong(ping)
+		}
 		mds.mdsClient.logger.Infof("info from MD stream %v", resp.String())
 	}
 }
 
+func (mds *MarketDataStream) toCommon(kind SubscriptionType, routed bool, msg marketDataMessage) bool {
+	mds.subsMu.Lock()
+	defer mds.subsMu.Unlock()
+	found, hasAny := mds.subs.lookup(kind, msg.GetInstrumentUid(), msg.GetFigi())
+	return found || (hasAny && !routed)
+}
+
+// Dropped - Метод возвращает количество сообщений, отброшенных при переполнении каналов стрима, включая каналы
+// подписок с отдельными каналами. Сообщения отбрасываются только с политикой переполнения, заданной опцией WithBuffer
+func (mds *MarketDataStream) Dropped() uint64 {
+	return mds.droppedCount()
+}
+
 func (mds *MarketDataStream) shutdown() {
 	mds.mdsClient.logger.Infof("close market data stream")
-	close(mds.candle)
-	close(mds.trade)
-	close(mds.lastPrice)
-	close(mds.orderBook)
-	close(mds.tradingStatus)
-	close(mds.tech)
+	// если Listen завершился с ошибкой, контекст отменяется, чтобы не ждать ответов на запросы
+	mds.cancel()
+	mds.pending.reset()
+	mds.routers.closeAll()
+	mds.marketDataChannels.close()
+	close(mds.reconnects)
 }
 
-// Stop - Завершение работы стрима
-func (mds *MarketDataStream) Stop() {
-	mds.cancel()
+// subscribe - создание подписки с отдельным каналом. Запрос подписки отправляется только для тех инструментов,
+// на которые в стриме еще нет подписки с такими же параметрами
+func subscribe[T marketDataMessage](mds *MarketDataStream, router *fanOut[T], refs []subRef, filter func(T) bool,
+	send func(ids []string, act pb.SubscriptionAction) error) (*Subscription[T], error) {
+	q := newStreamQueue(mds.opts.size(subscriptionBuffer), mds.opts.overflow, router.key, mds.dropped)
+	s := newSubscription[T](idsOf(refs), filter, q)
+	s.unsubscribe = func() error {
+		// канал закрывается до ожидания других операций, чтобы не блокировать отправку в него из Listen
+		router.remove(s)
+		mds.opMu.Lock()
+		defer mds.opMu.Unlock()
+		unsub := mds.release(refs)
+		if len(unsub) == 0 || mds.ctx.Err() != nil {
+			return nil
+		}
+		return send(unsub, pb.SubscriptionAction_SUBSCRIPTION_ACTION_UNSUBSCRIBE)
+	}
+
+	mds.opMu.Lock()
+	defer mds.opMu.Unlock()
+	if !router.add(s) {
+		q.close()
+		return nil, ErrStreamClosed
+	}
+
+	if sub := mds.acquire(refs); len(sub) > 0 {
+		if err := send(sub, pb.SubscriptionAction_SUBSCRIPTION_ACTION_SUBSCRIBE); err != nil {
+			router.remove(s)
+			unsub := mds.release(refs)
+			// сервер мог принять подписку на часть инструментов
+			if isSubscriptionError(err) && len(unsub) > 0 {
+				if unsubErr := send(unsub, pb.SubscriptionAction_SUBSCRIPTION_ACTION_UNSUBSCRIBE); unsubErr != nil {
+					mds.mdsClient.logger.Errorf("unsubscribe after rejected subscription err = %v", unsubErr.Error())
+				}
+			}
+			return nil, err
+		}
+	}
+	return s, nil
 }
 
-// UnSubscribeAll - Метод отписки от всей информации, отслеживаемой на данный момент
-func (mds *MarketDataStream) UnSubscribeAll() error {
-	ids := make([]string, 0)
-	if len(mds.subs.candles) > 0 {
-		candleSubs := make(map[candleSub][]string, 0)
+// acquire - увеличивает счетчики ссылок, возвращает инструменты, на которые нужно подписаться на сервере
+func (mds *MarketDataStream) acquire(refs []subRef) []string {
+	mds.subsMu.Lock()
+	defer mds.subsMu.Unlock()
+	ids := make([]string, 0, len(refs))
+	for _, r := range refs {
+		c := mds.canonical(r)
+		if mds.refs[c] == 0 && !mds.hasCommon(c) {
+			ids = append(ids, c.id)
+		}
+		mds.refs[c]++
+	}
+	return ids
+}
 
-		for id, c := range mds.subs.candles {
-			candleSubs[c] = append(candleSubs[c], id)
-			delete(mds.subs.candles, id)
+// release - уменьшает счетчики ссылок, возвращает инструменты, от которых нужно отписаться на сервере
+func (mds *MarketDataStream) release(refs []subRef) []string {
+	mds.subsMu.Lock()
+	defer mds.subsMu.Unlock()
+	ids := make([]string, 0, len(refs))
+	for _, r := range refs {
+		c := mds.canonical(r)
+		if mds.refs[c] == 0 {
+			continue
 		}
-		for c, ids := range candleSubs {
-			err := mds.UnSubscribeCandle(ids, c.interval, c.waitingClose, nil)
-			if err != nil {
-				return err
+		mds.refs[c]--
+		if mds.refs[c] == 0 {
+			delete(mds.refs, c)
+			if !mds.hasCommon(c) {
+				ids = append(ids, c.id)
 			}
 		}
 	}
+	return ids
+}
+
+// canonical - подписка с uid инструмента вместо идентификатора из запроса, если uid уже известен, вызывается
+// под subsMu
+func (mds *MarketDataStream) canonical(r subRef) subRef {
+	if uid, ok := mds.aliases[r.id]; ok {
+		r.id = uid
+	}
+	return r
+}
 
-	if len(mds.subs.trades) > 0 {
-		srcs := make(map[pb.TradeSourceType][]string, 0)
+// hasCommon - есть ли подписка с общими каналами на инструмент c по любому из его идентификаторов, вызывается
+// под subsMu
+func (mds *MarketDataStream) hasCommon(c subRef) bool {
+	if mds.subs.has(c) {
+		return true
+	}
+	for id, uid := range mds.aliases {
+		if uid != c.id || id == c.id {
+			continue
+		}
+		r := c
+		r.id = id
+		if mds.subs.has(r) {
+			return true
+		}
+	}
+	return false
+}
 
-		for id, src := range mds.subs.trades {
-			srcs[src] = append(srcs[src], id)
-			delete(mds.subs.trades, id)
+// learnAliases - запоминает uid инструментов из ответа сервера results на запрос по инструментам ids и переносит
+// счетчики ссылок на uid. Идентификатор из запроса сопоставляется с ответом по figi и uid, а если их нет -
+// по позиции в ответе
+func (mds *MarketDataStream) learnAliases(ids []string, results []SubscriptionResult) {
+	mds.subsMu.Lock()
+	defer mds.subsMu.Unlock()
+	known := make(map[string]struct{}, 2*len(results))
+	for _, r := range results {
+		if r.Status != pb.SubscriptionStatus_SUBSCRIPTION_STATUS_SUCCESS || r.InstrumentUid == "" {
+			continue
 		}
-		for src, ids := range srcs {
-			err := mds.UnSubscribeTrade(ids, src)
-			if err != nil {
-				return err
+		mds.aliases[r.InstrumentUid] = r.InstrumentUid
+		known[r.InstrumentUid] = struct{}{}
+		if r.Figi != "" {
+			mds.aliases[r.Figi] = r.InstrumentUid
+			known[r.Figi] = struct{}{}
+		}
+	}
+	if len(ids) == len(results) {
+		for i, id := range ids {
+			r := results[i]
+			if _, ok := known[id]; ok || r.Status != pb.SubscriptionStatus_SUBSCRIPTION_STATUS_SUCCESS || r.InstrumentUid == "" {
+				continue
 			}
+			mds.aliases[id] = r.InstrumentUid
+		}
+	}
+
+	for r, n := range mds.refs {
+		if c := mds.canonical(r); c != r {
+			delete(mds.refs, r)
+			mds.refs[c] += n
 		}
-		ids = nil
 	}
+}
+
+// subscribeCommon - подписка с общими каналами. Подписка сохраняется до отправки запроса, чтобы попасть
+// в восстановление подписок при переподключении, и откатывается, если запрос не удалось отправить. Если сервер
+// отклонил подписку на часть инструментов, откатываются только они
+func (mds *MarketDataStream) subscribeCommon(refs []subRef, send func(ids []string, act pb.SubscriptionAction) error) error {
+	mds.opMu.Lock()
+	defer mds.opMu.Unlock()
+
+	mds.subsMu.Lock()
+	prev := mds.subs.set(refs)
+	mds.subsMu.Unlock()
+
+	err := send(idsOf(refs), pb.SubscriptionAction_SUBSCRIPTION_ACTION_SUBSCRIBE)
+	if err != nil {
+		// подписки, принятые сервером, остаются, откатываются только отклоненные
+		if isSubscriptionError(err) {
+			refs = rejectedRefs(refs, err)
+		}
+		mds.subsMu.Lock()
+		mds.subs.restore(refs, prev)
+		mds.subsMu.Unlock()
+	}
+	return err
+}
+
+// rejectedRefs - подписки из refs, которые сервер отклонил в ошибке err. Подписка сопоставляется с ответом
+// сервера по идентификатору из запроса, который сервер возвращает в uid или figi
+func rejectedRefs(refs []subRef, err error) []subRef {
+	var subErr *SubscriptionError
+	if !errors.As(err, &subErr) {
+		return nil
+	}
+	rejected := make(map[string]struct{}, 2*len(subErr.Results))
+	for _, r := range subErr.Results {
+		if r.InstrumentUid != "" {
+			rejected[r.InstrumentUid] = struct{}{}
+		}
+		if r.Figi != "" {
+			rejected[r.Figi] = struct{}{}
+		}
+	}
+	res := make([]subRef, 0, len(subErr.Results))
+	for _, r := range refs {
+		if _, ok := rejected[r.id]; ok {
+			res = append(res, r)
+		}
+	}
+	return res
+}
+
+// unsubscribeCommon - отписка от подписок с общими каналами. Запрос отписки отправляется только для инструментов,
+// на которые нет подписок с отдельными каналами
+func (mds *MarketDataStream) unsubscribeCommon(refs []subRef, send func(ids []string, act pb.SubscriptionAction) error) error {
+	mds.opMu.Lock()
+	defer mds.opMu.Unlock()
+
+	mds.subsMu.Lock()
+	prev := mds.subs.remove(refs)
+	unsub := make([]string, 0, len(refs))
+	for _, r := range refs {
+		// на инструмент могут остаться подписки по другим его идентификаторам
+		if c := mds.canonical(r); mds.refs[c] == 0 && !mds.hasCommon(c) {
+			unsub = append(unsub, r.id)
+		}
+	}
+	mds.subsMu.Unlock()
+
+	if len(unsub) == 0 {
+		return nil
+	}
+	err := send(unsub, pb.SubscriptionAction_SUBSCRIPTION_ACTION_UNSUBSCRIBE)
+	if err != nil && !isSubscriptionError(err) {
+		mds.subsMu.Lock()
+		mds.subs.restore(refs, prev)
+		mds.subsMu.Unlock()
+	}
+	return err
+}
 
-	if len(mds.subs.tradingStatuses) > 0 {
-		for id := range mds.subs.tradingStatuses {
-			ids = append(ids, id)
-			delete(mds.subs.tradingStatuses, id)
+// sendFunc - функция отправки запроса подписки с параметрами подписки r
+func (mds *MarketDataStream) sendFunc(r subRef) func(ids []string, act pb.SubscriptionAction) error {
+	switch r.kind {
+	case SubscriptionCandles:
+		return func(ids []string, act pb.SubscriptionAction) error {
+			return mds.sendCandlesReq(ids, r.candle.interval, act, r.candle.waitingClose, r.candle.source())
 		}
-		err := mds.UnSubscribeInfo(ids)
+	case SubscriptionOrderBooks:
+		return func(ids []string, act pb.SubscriptionAction) error {
+			return mds.sendOrderBookReq(ids, r.depth, act)
+		}
+	case SubscriptionTrades:
+		return func(ids []string, act pb.SubscriptionAction) error {
+			return mds.sendTradesReq(ids, act, r.tradeSrc)
+		}
+	case SubscriptionInfo:
+		return mds.sendInfoReq
+	default:
+		return mds.sendLastPriceReq
+	}
+}
+
+func refsOf(ids []string, ref func(id string) subRef) []subRef {
+	refs := make([]subRef, 0, len(ids))
+	for _, id := range ids {
+		refs = append(refs, ref(id))
+	}
+	return refs
+}
+
+func idsOf(refs []subRef) []string {
+	ids := make([]string, 0, len(refs))
+	for _, r := range refs {
+		ids = append(ids, r.id)
+	}
+	return ids
+}
+
+// Stop - Завершение работы стрима
+func (mds *MarketDataStream) Stop() {
+	mds.cancel()
+}
+
+// UnSubscribeAll - Метод отписки от всей информации, отслеживаемой на данный момент, включая подписки
+// с отдельными каналами
+func (mds *MarketDataStream) UnSubscribeAll() error {
+	mds.subsMu.Lock()
+	candleSubs := make(map[candleSub][]string, 0)
+	orderBooks := make(map[int32][]string, 0)
+	trades := make(map[pb.TradeSourceType][]string, 0)
+	tradingStatuses := make([]string, 0, len(mds.subs.tradingStatuses))
+	lastPrices := make([]string, 0, len(mds.subs.lastPrices))
+	for _, r := range mds.subs.refs() {
+		switch r.kind {
+		case SubscriptionCandles:
+			candleSubs[r.candle] = append(candleSubs[r.candle], r.id)
+		case SubscriptionOrderBooks:
+			orderBooks[r.depth] = append(orderBooks[r.depth], r.id)
+		case SubscriptionTrades:
+			trades[r.tradeSrc] = append(trades[r.tradeSrc], r.id)
+		case SubscriptionInfo:
+			tradingStatuses = append(tradingStatuses, r.id)
+		case SubscriptionLastPrices:
+			lastPrices = append(lastPrices, r.id)
+		}
+	}
+	mds.subsMu.Unlock()
+
+	for c, ids := range candleSubs {
+		err := mds.UnSubscribeCandle(ids, c.interval, c.waitingClose, c.source())
 		if err != nil {
 			return err
 		}
-		ids = nil
 	}
 
-	if len(mds.subs.lastPrices) > 0 {
-		for id := range mds.subs.lastPrices {
-			ids = append(ids, id)
-			delete(mds.subs.lastPrices, id)
-		}
-		err := mds.UnSubscribeLastPrice(ids)
+	for src, ids := range trades {
+		err := mds.UnSubscribeTrade(ids, src)
 		if err != nil {
 			return err
 		}
-		ids = nil
 	}
 
-	if len(mds.subs.orderBooks) > 0 {
-		orderBooks := make(map[int32][]string, 0)
+	if len(tradingStatuses) > 0 {
+		err := mds.UnSubscribeInfo(tradingStatuses)
+		if err != nil {
+			return err
+		}
+	}
 
-		for id, d := range mds.subs.orderBooks {
-			orderBooks[d] = append(orderBooks[d], id)
-			delete(mds.subs.orderBooks, id)
+	if len(lastPrices) > 0 {
+		err := mds.UnSubscribeLastPrice(lastPrices)
+		if err != nil {
+			return err
 		}
+	}
 
-		for depth, ids := range orderBooks {
-			err := mds.UnSubscribeOrderBook(ids, depth)
-			if err != nil {
-				return err
-			}
+	for depth, ids := range orderBooks {
+		err := mds.UnSubscribeOrderBook(ids, depth)
+		if err != nil {
+			return err
 		}
 	}
 
+	return mds.unsubscribeHandles()
+}
+
+// unsubscribeHandles - отписка всех подписок с отдельными каналами
+func (mds *MarketDataStream) unsubscribeHandles() error {
+	for _, s := range mds.routers.candles.all() {
+		if err := s.Unsubscribe(); err != nil {
+			return err
+		}
+	}
+	for _, s := range mds.routers.orderBooks.all() {
+		if err := s.Unsubscribe(); err != nil {
+			return err
+		}
+	}
+	for _, s := range mds.routers.trades.all() {
+		if err := s.Unsubscribe(); err != nil {
+			return err
+		}
+	}
+	for _, s := range mds.routers.tradingStatuses.all() {
+		if err := s.Unsubscribe(); err != nil {
+			return err
+		}
+	}
+	for _, s := range mds.routers.lastPrices.all() {
+		if err := s.Unsubscribe(); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -399,7 +1199,7 @@ func (mds *MarketDataStream) restart(_ context.Context, attempt uint, err error)
 }
 
 func (mds *MarketDataStream) Ping(time time.Time) error {
-	return mds.stream.Send(&pb.MarketDataRequest{
+	return mds.send(&pb.MarketDataRequest{
 		Payload: &pb.MarketDataRequest_Ping{
 			Ping: &pb.PingRequest{
 				Time: TimeToTimestamp(time),
@@ -409,16 +1209,233 @@ func (mds *MarketDataStream) Ping(time time.Time) error {
 }
 
 func (mds *MarketDataStream) PingSettings(pingDelayMs int32) error {
-	return mds.stream.Send(&pb.MarketDataRequest{
+	mds.sendMu.Lock()
+	defer mds.sendMu.Unlock()
+	err := mds.sendLocked(pingSettingsRequest(pingDelayMs))
+	if err != nil {
+		return err
+	}
+	mds.subsMu.Lock()
+	mds.pingDelayMs = &pingDelayMs
+	mds.subsMu.Unlock()
+	return nil
+}
+
+func pingSettingsRequest(pingDelayMs int32) *pb.MarketDataRequest {
+	return &pb.MarketDataRequest{
 		Payload: &pb.MarketDataRequest_PingSettings{
 			PingSettings: &pb.PingDelaySettings{
 				PingDelayMs: &pingDelayMs,
 			},
 		},
-	})
+	}
+}
+
+// ReconnectEvents - Канал событий переподключения стрима, события отправляются только если стрим создан с опцией
+// WithReconnect. Если канал не читать, новые события отбрасываются
+func (mds *MarketDataStream) ReconnectEvents() <-chan ReconnectEvent {
+	return mds.reconnects
+}
+
+// openStream - открытие нового grpc стрима. При включенном переподключении ретраи интерсептора отключаются,
+// так как стрим сам открывается заново и восстанавливает подписки
+func (mds *MarketDataStream) openStream() (pb.MarketDataStreamService_MarketDataStreamClient, error) {
+	if mds.opts.reconnect {
+		return mds.mdsClient.pbClient.MarketDataStream(mds.ctx, retry.WithMax(0))
+	}
+	return mds.mdsClient.pbClient.MarketDataStream(mds.ctx, retry.WithOnRetryCallback(mds.restart))
+}
+
+func (mds *MarketDataStream) getStream() pb.MarketDataStreamService_MarketDataStreamClient {
+	mds.streamMu.RLock()
+	defer mds.streamMu.RUnlock()
+	return mds.stream
+}
+
+func (mds *MarketDataStream) setStream(stream pb.MarketDataStreamService_MarketDataStreamClient) {
+	mds.streamMu.Lock()
+	mds.stream = stream
+	mds.streamMu.Unlock()
+}
+
+// send - отправка запроса в стрим, grpc не позволяет вызывать Send одновременно из нескольких горутин
+func (mds *MarketDataStream) send(req *pb.MarketDataRequest) error {
+	mds.sendMu.Lock()
+	defer mds.sendMu.Unlock()
+	return mds.sendLocked(req)
+}
+
+// sendLocked - отправка запроса в стрим, вызывается под sendMu
+func (mds *MarketDataStream) sendLocked(req *pb.MarketDataRequest) error {
+	return mds.getStream().Send(req)
+}
+
+// reconnect - переоткрывает стрим и восстанавливает подписки, возвращает ошибку, если исчерпаны все попытки
+func (mds *MarketDataStream) reconnect(cause error) error {
+	lastErr := cause
+	for {
+		mds.attempt++
+		attempt := mds.attempt
+		if mds.opts.maxReconnects > 0 && attempt > mds.opts.maxReconnects {
+			return lastErr
+		}
+		mds.mdsClient.logger.Infof("try to reconnect md stream err = %v, attempt = %v", lastErr.Error(), attempt)
+		if stop := mds.waitReconnect(attempt); stop {
+			return nil
+		}
+		stream, err := mds.openStream()
+		if err == nil {
+			// запросы, отправленные в старый стрим, остаются без ответа
+			mds.sendMu.Lock()
+			mds.pending.reset()
+			err = mds.resubscribe(stream)
+			if err == nil {
+				mds.setStream(stream)
+			}
+			mds.sendMu.Unlock()
+		}
+		if err != nil {
+			if mds.ctx.Err() != nil {
+				return nil
+			}
+			invalidateToken(mds.mdsClient.config.TokenProvider, err)
+			lastErr = err
+			mds.sendReconnectEvent(ReconnectEvent{Attempt: attempt, Err: err, Time: time.Now()})
+			continue
+		}
+		mds.mdsClient.logger.Infof("md stream reconnected, attempt = %v", attempt)
+		mds.sendReconnectEvent(ReconnectEvent{Attempt: attempt, Err: cause, Success: true, Time: time.Now()})
+		return nil
+	}
 }
 
-//// GetTechChan - Канал для получения результатов подписки, пинга
-//func (mds *MarketDataStream) GetTechChan() <-chan *pb.MarketDataResponse {
-//	return mds.tech
-//}
+// waitReconnect - ожидание перед попыткой переподключения, возвращает true, если контекст стрима завершен
+func (mds *MarketDataStream) waitReconnect(attempt uint) bool {
+	timer := time.NewTimer(mds.opts.reconnectBackoff(mds.ctx, attempt))
+	defer timer.Stop()
+	select {
+	case <-mds.ctx.Done():
+		return true
+	case <-timer.C:
+		return false
+	}
+}
+
+// resubscribe - отправка в новый стрим всех текущих подписок
+func (mds *MarketDataStream) resubscribe(stream pb.MarketDataStreamService_MarketDataStreamClient) error {
+	mds.subsMu.Lock()
+	reqs := make([]*pb.MarketDataRequest, 0)
+	if mds.pingDelayMs != nil {
+		reqs = append(reqs, pingSettingsRequest(*mds.pingDelayMs))
+	}
+
+	// подписки с общими каналами и с отдельными каналами на один инструмент могут быть сделаны по разным
+	// идентификаторам, запрос отправляется один раз по uid, если он уже известен
+	refs := make(map[subRef]struct{}, len(mds.refs))
+	for _, r := range mds.subs.refs() {
+		refs[mds.canonical(r)] = struct{}{}
+	}
+	for r := range mds.refs {
+		refs[r] = struct{}{}
+	}
+	mds.subsMu.Unlock()
+
+	candleSubs := make(map[candleSub][]string, 0)
+	orderBooks := make(map[int32][]string, 0)
+	trades := make(map[pb.TradeSourceType][]string, 0)
+	tradingStatuses := make([]string, 0)
+	lastPrices := make([]string, 0)
+	for r := range refs {
+		switch r.kind {
+		case SubscriptionCandles:
+			candleSubs[r.candle] = append(candleSubs[r.candle], r.id)
+		case SubscriptionOrderBooks:
+			orderBooks[r.depth] = append(orderBooks[r.depth], r.id)
+		case SubscriptionTrades:
+			trades[r.tradeSrc] = append(trades[r.tradeSrc], r.id)
+		case SubscriptionInfo:
+			tradingStatuses = append(tradingStatuses, r.id)
+		case SubscriptionLastPrices:
+			lastPrices = append(lastPrices, r.id)
+		}
+	}
+
+	for c, ids := range candleSubs {
+		reqs = append(reqs, candlesRequest(ids, c.interval, pb.SubscriptionAction_SUBSCRIPTION_ACTION_SUBSCRIBE, c.waitingClose, c.source()))
+	}
+	for depth, ids := range orderBooks {
+		reqs = append(reqs, orderBookRequest(ids, depth, pb.SubscriptionAction_SUBSCRIPTION_ACTION_SUBSCRIBE))
+	}
+	for src, ids := range trades {
+		reqs = append(reqs, tradesRequest(ids, pb.SubscriptionAction_SUBSCRIPTION_ACTION_SUBSCRIBE, src))
+	}
+	if len(tradingStatuses) > 0 {
+		reqs = append(reqs, infoRequest(tradingStatuses, pb.SubscriptionAction_SUBSCRIPTION_ACTION_SUBSCRIBE))
+	}
+	if len(lastPrices) > 0 {
+		reqs = append(reqs, lastPriceRequest(lastPrices, pb.SubscriptionAction_SUBSCRIPTION_ACTION_SUBSCRIBE))
+	}
+
+	for _, req := range reqs {
+		if t, ok := requestType(req); ok {
+			// ответы на запросы при переподключении приходят только в SubscriptionResults()
+			mds.pending.abandon(mds.pending.add(t, requestIds(req)))
+		}
+		if err := stream.Send(req); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// requestType - тип подписки запроса, ok = false, если запрос не является запросом подписки
+func requestType(req *pb.MarketDataRequest) (t SubscriptionType, ok bool) {
+	switch req.GetPayload().(type) {
+	case *pb.MarketDataRequest_SubscribeCandlesRequest:
+		return SubscriptionCandles, true
+	case *pb.MarketDataRequest_SubscribeOrderBookRequest:
+		return SubscriptionOrderBooks, true
+	case *pb.MarketDataRequest_SubscribeTradesRequest:
+		return SubscriptionTrades, true
+	case *pb.MarketDataRequest_SubscribeInfoRequest:
+		return SubscriptionInfo, true
+	case *pb.MarketDataRequest_SubscribeLastPriceRequest:
+		return SubscriptionLastPrices, true
+	}
+	return 0, false
+}
+
+// requestIds - идентификаторы инструментов запроса подписки
+func requestIds(req *pb.MarketDataRequest) []string {
+	var ids []string
+	switch req.GetPayload().(type) {
+	case *pb.MarketDataRequest_SubscribeCandlesRequest:
+		for _, i := range req.GetSubscribeCandlesRequest().GetInstruments() {
+			ids = append(ids, i.GetInstrumentId())
+		}
+	case *pb.MarketDataRequest_SubscribeOrderBookRequest:
+		for _, i := range req.GetSubscribeOrderBookRequest().GetInstruments() {
+			ids = append(ids, i.GetInstrumentId())
+		}
+	case *pb.MarketDataRequest_SubscribeTradesRequest:
+		for _, i := range req.GetSubscribeTradesRequest().GetInstruments() {
+			ids = append(ids, i.GetInstrumentId())
+		}
+	case *pb.MarketDataRequest_SubscribeInfoRequest:
+		for _, i := range req.GetSubscribeInfoRequest().GetInstruments() {
+			ids = append(ids, i.GetInstrumentId())
+		}
+	case *pb.MarketDataRequest_SubscribeLastPriceRequest:
+		for _, i := range req.GetSubscribeLastPriceRequest().GetInstruments() {
+			ids = append(ids, i.GetInstrumentId())
+		}
+	}
+	return ids
+}
+
+func (mds *MarketDataStream) sendReconnectEvent(e ReconnectEvent) {
+	select {
+	case mds.reconnects <- e:
+	default:
+	}
+}
@@ -17,30 +17,88 @@ type MarketDataStreamClient struct {
 	pbClient pb.MarketDataStreamServiceClient
 }
 
-// MarketDataStream - метод возвращает стрим биржевой информации
-func (c *MarketDataStreamClient) MarketDataStream() (*MarketDataStream, error) {
-	ctx, cancel := context.WithCancel(c.ctx)
+// MarketDataStream - метод возвращает стрим биржевой информации. С опцией WithReconnect стрим
+// переподключается после обрыва соединения и восстанавливает все подписки
+func (c *MarketDataStreamClient) MarketDataStream(opts ...StreamOption) (*MarketDataStream, error) {
+	return c.MarketDataStreamCtx(c.ctx, opts...)
+}
+
+// MarketDataStreamCtx - то же, что и MarketDataStream, но с контекстом запроса ctx
+func (c *MarketDataStreamClient) MarketDataStreamCtx(ctx context.Context, opts ...StreamOption) (*MarketDataStream, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	options := newStreamOptions(opts)
 	mds := &MarketDataStream{
-		stream:        nil,
-		mdsClient:     c,
-		ctx:           ctx,
-		cancel:        cancel,
-		candle:        make(chan *pb.Candle, 1),
-		trade:         make(chan *pb.Trade, 1),
-		orderBook:     make(chan *pb.OrderBook, 1),
-		lastPrice:     make(chan *pb.LastPrice, 1),
-		tradingStatus: make(chan *pb.TradingStatus, 1),
-		tech:          make(chan *pb.MarketDataResponse, 1),
-		subs: subscriptions{
-			candles:         make(map[string]candleSub, 0),
-			orderBooks:      make(map[string]int32, 0),
-			trades:          make(map[string]pb.TradeSourceType, 0),
-			tradingStatuses: make(map[string]struct{}, 0),
-			lastPrices:      make(map[string]struct{}, 0),
-		},
+		stream:             nil,
+		mdsClient:          c,
+		opts:               options,
+		ctx:                ctx,
+		cancel:             cancel,
+		marketDataChannels: newMarketDataChannels(options),
+		reconnects:         make(chan ReconnectEvent, reconnectEventsBuffer),
+		routers:            newRouters(),
+		refs:               make(map[subRef]int, 0),
+		aliases:            make(map[string]string),
+		pending:            newPendingResults(),
+		subs:               newSubscriptions(),
 	}
 
-	stream, err := c.pbClient.MarketDataStream(ctx, retry.WithOnRetryCallback(mds.restart))
+	stream, err := mds.openStream()
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	mds.stream = stream
+	return mds, nil
+}
+
+// NewMarketDataPool - создание пула стримов биржевой информации. maxPerStream - лимит подписок на один стрим,
+// maxStreams - лимит стримов, при нулевых значениях используются DefaultMaxSubscriptionsPerStream и
+// DefaultMaxMarketDataStreams. Опции opts применяются к каждому стриму пула и к каналам пула, по умолчанию
+// стримы пула создаются с опцией WithReconnect(nil, 0)
+func (c *MarketDataStreamClient) NewMarketDataPool(maxPerStream, maxStreams int, opts ...StreamOption) *MarketDataPool {
+	return c.NewMarketDataPoolCtx(c.ctx, maxPerStream, maxStreams, opts...)
+}
+
+// NewMarketDataPoolCtx - то же, что и NewMarketDataPool, но с контекстом ctx
+func (c *MarketDataStreamClient) NewMarketDataPoolCtx(ctx context.Context, maxPerStream, maxStreams int, opts ...StreamOption) *MarketDataPool {
+	if maxPerStream <= 0 {
+		maxPerStream = DefaultMaxSubscriptionsPerStream
+	}
+	if maxStreams <= 0 {
+		maxStreams = DefaultMaxMarketDataStreams
+	}
+	opts = append([]StreamOption{WithReconnect(nil, 0)}, opts...)
+	ctx, cancel := context.WithCancel(ctx)
+	return &MarketDataPool{
+		mdsClient:          c,
+		opts:               opts,
+		perStream:          maxPerStream,
+		maxStreams:         maxStreams,
+		ctx:                ctx,
+		cancel:             cancel,
+		assigned:           make(map[subRef]*poolShard),
+		marketDataChannels: newMarketDataChannels(newStreamOptions(opts)),
+	}
+}
+
+// MarketDataServerSideStream - метод возвращает server-side стрим биржевой информации. Все подписки передаются
+// в запросе при открытии стрима и не могут быть изменены, при обрыве соединения стрим переоткрывается ретраером
+func (c *MarketDataStreamClient) MarketDataServerSideStream(req *MarketDataServerSideStreamRequest, opts ...StreamOption) (*MarketDataServerSideStream, error) {
+	return c.MarketDataServerSideStreamCtx(c.ctx, req, opts...)
+}
+
+// MarketDataServerSideStreamCtx - то же, что и MarketDataServerSideStream, но с контекстом запроса ctx
+func (c *MarketDataStreamClient) MarketDataServerSideStreamCtx(ctx context.Context, req *MarketDataServerSideStreamRequest, opts ...StreamOption) (*MarketDataServerSideStream, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	mds := &MarketDataServerSideStream{
+		stream:             nil,
+		mdsClient:          c,
+		ctx:                ctx,
+		cancel:             cancel,
+		marketDataChannels: newMarketDataChannels(newStreamOptions(opts)),
+	}
+
+	stream, err := c.pbClient.MarketDataServerSideStream(ctx, req.toPB(), retry.WithOnRetryCallback(mds.restart))
 	if err != nil {
 		cancel()
 		return nil, err
@@ -62,6 +120,13 @@ type MDStreamClient struct {
 //
 // Deprecated: Use MarketDataStreamClient.MarketDataStream()
 func (c *MDStreamClient) MarketDataStream() (*MDStream, error) {
+	return c.MarketDataStreamCtx(c.ctx)
+}
+
+// MarketDataStreamCtx - то же, что и MarketDataStream, но с контекстом запроса ctx
+//
+// Deprecated: Use MarketDataStreamClient.MarketDataStreamCtx()
+func (c *MDStreamClient) MarketDataStreamCtx(ctx context.Context) (*MDStream, error) {
 	newStreamClient := &MarketDataStreamClient{
 		conn:     c.conn,
 		config:   c.config,
@@ -69,7 +134,7 @@ func (c *MDStreamClient) MarketDataStream() (*MDStream, error) {
 		ctx:      c.ctx,
 		pbClient: c.pbClient,
 	}
-	newStream, err := newStreamClient.MarketDataStream()
+	newStream, err := newStreamClient.MarketDataStreamCtx(ctx)
 	if err != nil {
 		return nil, err
 	}
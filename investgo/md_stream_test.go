@@ -0,0 +1,677 @@
+package investgo_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/russianinvestments/invest-api-go-sdk/investgo"
+	"github.com/russianinvestments/invest-api-go-sdk/investgotest"
+	pb "github.com/russianinvestments/invest-api-go-sdk/proto"
+	"github.com/russianinvestments/invest-api-go-sdk/retry"
+)
+
+const waitTime = 5 * time.Second
+
+type nopLogger struct{}
+
+func (nopLogger) Infof(string, ...any)  {}
+func (nopLogger) Errorf(string, ...any) {}
+func (nopLogger) Fatalf(string, ...any) {}
+
+// newTestServer - тестовый сервер с n акциями и клиент к нему, возвращает uid акций
+func newTestServer(t *testing.T, n int) (*investgotest.Server, *investgo.Client, []string) {
+	t.Helper()
+	srv, err := investgotest.NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(srv.Stop)
+	uids := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		uids = append(uids, srv.AddShare(&pb.Share{
+			Figi:      fmt.Sprintf("FIGI%03d", i),
+			Ticker:    fmt.Sprintf("T%03d", i),
+			ClassCode: "TQBR",
+			Lot:       1,
+			Currency:  "rub",
+		}))
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	client, err := srv.NewClient(ctx, nopLogger{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = client.Stop()
+	})
+	return srv, client, uids
+}
+
+// listen - запуск Listen в отдельной горутине, возвращает функцию, которая останавливает стрим и проверяет,
+// что Listen завершился без ошибки
+func listen(t *testing.T, run func() error, stop func()) func() {
+	t.Helper()
+	done := make(chan error, 1)
+	go func() {
+		done <- run()
+	}()
+	var once sync.Once
+	shutdown := func() {
+		once.Do(func() {
+			stop()
+			select {
+			case err := <-done:
+				if err != nil {
+					t.Errorf("listen: %v", err)
+				}
+			case <-time.After(waitTime):
+				t.Error("listen did not return after stop")
+			}
+		})
+	}
+	t.Cleanup(shutdown)
+	return shutdown
+}
+
+// drain - чтение канала до его закрытия, wg завершается после закрытия канала
+func drain[T any](wg *sync.WaitGroup, ch <-chan T) {
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for range ch {
+		}
+	}()
+}
+
+func receive[T any](t *testing.T, ch <-chan T) T {
+	t.Helper()
+	select {
+	case v, ok := <-ch:
+		if !ok {
+			t.Fatal("channel closed")
+		}
+		return v
+	case <-time.After(waitTime):
+		t.Fatal("timeout")
+	}
+	var zero T
+	return zero
+}
+
+// waitFor - ожидание условия cond с проверкой раз в несколько миллисекунд
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(waitTime)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timeout waiting for %v", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func setPrice(t *testing.T, srv *investgotest.Server, uid string, price float64) {
+	t.Helper()
+	if err := srv.SetPrice(uid, price); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMarketDataStreamConcurrent(t *testing.T) {
+	srv, client, all := newTestServer(t, 5)
+	// на последний инструмент подписки есть весь тест, через них же читаются общие каналы
+	uids, anchor := all[:4], all[4:]
+	// с OverflowBlock Listen ждал бы чтения каналов подписок, созданных в рабочих горутинах, а подписка
+	// с подтверждением не вернет канал, пока Listen не обработает ответ
+	mds, err := client.NewMarketDataStreamClient().MarketDataStream(
+		investgo.WithSubscriptionConfirm(waitTime),
+		investgo.WithBuffer(16, investgo.OverflowDropOldest),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stop := listen(t, mds.Listen, mds.Stop)
+
+	interval := pb.SubscriptionInterval_SUBSCRIPTION_INTERVAL_ONE_MINUTE
+	var drained sync.WaitGroup
+	candles, err := mds.SubscribeCandle(anchor, interval, false, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	drain(&drained, candles)
+	statuses, err := mds.SubscribeInfo(anchor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	drain(&drained, statuses)
+	prices, err := mds.SubscribeLastPrice(anchor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	drain(&drained, prices)
+
+	// подписка с отдельным каналом живет весь тест, общие подписки на тот же инструмент не должны ее отменить
+	kept, err := mds.NewLastPriceSubscription(uids[:1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	drain(&drained, kept.Updates())
+
+	pushDone := make(chan struct{})
+	var pusher sync.WaitGroup
+	pusher.Add(1)
+	go func() {
+		defer pusher.Done()
+		for i := 0; ; i++ {
+			select {
+			case <-pushDone:
+				return
+			default:
+			}
+			_ = srv.SetPrice(all[i%len(all)], float64(100+i%50))
+			time.Sleep(100 * time.Microsecond)
+		}
+	}()
+
+	ops := []func(ids []string) error{
+		func(ids []string) error {
+			if _, err := mds.SubscribeLastPrice(ids); err != nil {
+				return err
+			}
+			return mds.UnSubscribeLastPrice(ids)
+		},
+		func(ids []string) error {
+			if _, err := mds.SubscribeCandle(ids, interval, false, nil); err != nil {
+				return err
+			}
+			return mds.UnSubscribeCandle(ids, interval, false, nil)
+		},
+		func(ids []string) error {
+			if _, err := mds.SubscribeInfo(ids); err != nil {
+				return err
+			}
+			return mds.UnSubscribeInfo(ids)
+		},
+		func(ids []string) error {
+			sub, err := mds.NewOrderBookSubscription(ids, 10)
+			if err != nil {
+				return err
+			}
+			return sub.Unsubscribe()
+		},
+		func(ids []string) error {
+			sub, err := mds.NewLastPriceSubscription(ids)
+			if err != nil {
+				return err
+			}
+			return sub.Unsubscribe()
+		},
+		func(ids []string) error {
+			if err := mds.Ping(time.Now()); err != nil {
+				return err
+			}
+			return mds.PingSettings(5000)
+		},
+		func(ids []string) error {
+			_, err := mds.GetMySubscriptions()
+			return err
+		},
+	}
+
+	var workers sync.WaitGroup
+	errs := make(chan error, 64)
+	for w := 0; w < 8; w++ {
+		workers.Add(1)
+		go func(w int) {
+			defer workers.Done()
+			for i := 0; i < 25; i++ {
+				ids := uids[(w+i)%len(uids):]
+				if err := ops[(w+i)%len(ops)](ids); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}(w)
+	}
+	workers.Wait()
+	close(pushDone)
+	pusher.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	subs, err := mds.GetMySubscriptions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	active := make(map[string]bool)
+	for _, r := range subs.LastPrices {
+		active[r.InstrumentUid] = true
+	}
+	if len(subs.LastPrices) != 2 || !active[uids[0]] || !active[anchor[0]] {
+		t.Fatalf("last price subscriptions = %+v", subs.LastPrices)
+	}
+	if len(subs.Candles) != 1 || len(subs.TradingStatuses) != 1 || len(subs.OrderBooks) != 0 {
+		t.Fatalf("subscriptions left = %+v", subs)
+	}
+
+	stop()
+	drained.Wait()
+}
+
+func TestMarketDataStreamStopWhileBusy(t *testing.T) {
+	srv, client, uids := newTestServer(t, 2)
+	mds, err := client.NewMarketDataStreamClient().MarketDataStream(investgo.WithSubscriptionConfirm(waitTime))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stop := listen(t, mds.Listen, mds.Stop)
+	prices, err := mds.SubscribeLastPrice(uids)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var drained sync.WaitGroup
+	drain(&drained, prices)
+
+	var workers sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		workers.Add(1)
+		go func(w int) {
+			defer workers.Done()
+			for i := 0; ; i++ {
+				// после Stop операции завершаются ошибкой, но не должны зависать
+				if _, err := mds.SubscribeLastPrice(uids); err != nil {
+					return
+				}
+				sub, err := mds.NewOrderBookSubscription(uids[w%2:], 1)
+				if err != nil {
+					return
+				}
+				drain(&drained, sub.Updates())
+				_ = srv.SetPrice(uids[i%2], float64(100+i%10))
+				if err := mds.Ping(time.Now()); err != nil {
+					return
+				}
+				if err := sub.Unsubscribe(); err != nil {
+					return
+				}
+			}
+		}(w)
+	}
+	time.Sleep(50 * time.Millisecond)
+	stop()
+
+	done := make(chan struct{})
+	go func() {
+		workers.Wait()
+		drained.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(waitTime):
+		t.Fatal("operations or channels did not finish after stop")
+	}
+	if _, err := mds.SubscribeLastPrice(uids); err == nil {
+		t.Fatal("subscription after stop succeeded")
+	}
+}
+
+func TestMarketDataStreamReconnect(t *testing.T) {
+	srv, client, uids := newTestServer(t, 2)
+	mds, err := client.NewMarketDataStreamClient().MarketDataStream(
+		investgo.WithReconnect(retry.BackoffLinear(10*time.Millisecond), 0),
+		investgo.WithSubscriptionConfirm(waitTime),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	listen(t, mds.Listen, mds.Stop)
+
+	prices, err := mds.SubscribeLastPrice(uids[:1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	books, err := mds.NewOrderBookSubscription(uids[1:], 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := mds.PingSettings(1000); err != nil {
+		t.Fatal(err)
+	}
+
+	// смена токена на тот же самый разрывает открытые стримы, а переподключение проходит с прежним токеном
+	srv.SetToken(investgotest.DefaultToken)
+	for {
+		e := receive(t, mds.ReconnectEvents())
+		if e.Success {
+			break
+		}
+	}
+
+	subs, err := mds.GetMySubscriptions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(subs.LastPrices) != 1 || len(subs.OrderBooks) != 1 {
+		t.Fatalf("subscriptions after reconnect = %+v", subs)
+	}
+	setPrice(t, srv, uids[0], 123)
+	if p := receive(t, prices); p.GetInstrumentUid() != uids[0] || p.GetPrice().ToFloat() != 123 {
+		t.Fatalf("last price after reconnect = %v", p)
+	}
+	if err := srv.PushOrderBook(&pb.OrderBook{InstrumentUid: uids[1]}); err != nil {
+		t.Fatal(err)
+	}
+	if ob := receive(t, books.Updates()); ob.GetInstrumentUid() != uids[1] {
+		t.Fatalf("order book after reconnect = %v", ob)
+	}
+}
+
+func TestMarketDataStreamReconnectIntervals(t *testing.T) {
+	srv, client, uids := newTestServer(t, 1)
+	mds, err := client.NewMarketDataStreamClient().MarketDataStream(
+		investgo.WithReconnect(retry.BackoffLinear(10*time.Millisecond), 0),
+		investgo.WithSubscriptionConfirm(waitTime),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	listen(t, mds.Listen, mds.Stop)
+
+	intervals := []pb.SubscriptionInterval{
+		pb.SubscriptionInterval_SUBSCRIPTION_INTERVAL_ONE_MINUTE,
+		pb.SubscriptionInterval_SUBSCRIPTION_INTERVAL_FIVE_MINUTES,
+	}
+	var candles <-chan *pb.Candle
+	for _, interval := range intervals {
+		if candles, err = mds.SubscribeCandle(uids, interval, false, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// подписка по figi и подписка с отдельным каналом по uid на тот же инструмент восстанавливаются одним запросом
+	if _, err := mds.SubscribeLastPrice([]string{"FIGI000"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mds.NewLastPriceSubscription(uids); err != nil {
+		t.Fatal(err)
+	}
+	for len(mds.SubscriptionResults()) > 0 {
+		<-mds.SubscriptionResults()
+	}
+
+	srv.SetToken(investgotest.DefaultToken)
+	for {
+		if e := receive(t, mds.ReconnectEvents()); e.Success {
+			break
+		}
+	}
+	lastPrices := 0
+	for lastPrices == 0 {
+		if r := receive(t, mds.SubscriptionResults()); r.Type == investgo.SubscriptionLastPrices {
+			lastPrices++
+		}
+	}
+	quiet := time.After(100 * time.Millisecond)
+	for quiet != nil {
+		select {
+		case r := <-mds.SubscriptionResults():
+			if r.Type == investgo.SubscriptionLastPrices {
+				lastPrices++
+			}
+		case <-quiet:
+			quiet = nil
+		}
+	}
+	if lastPrices != 1 {
+		t.Fatalf("last price resubscribed %v times", lastPrices)
+	}
+
+	subs, err := mds.GetMySubscriptions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(subs.Candles) != len(intervals) {
+		t.Fatalf("candle subscriptions after reconnect = %+v", subs.Candles)
+	}
+	for _, interval := range intervals {
+		if err := srv.PushCandle(&pb.Candle{InstrumentUid: uids[0], Interval: interval}); err != nil {
+			t.Fatal(err)
+		}
+		if c := receive(t, candles); c.GetInterval() != interval {
+			t.Fatalf("candle interval = %v, want %v", c.GetInterval(), interval)
+		}
+	}
+}
+
+// TestMarketDataStreamReconnectAttempts - стрим с отозванным токеном открывается без ошибки и разрывается
+// на первом чтении, такие попытки считаются подряд и ограничены maxAttempts
+func TestMarketDataStreamReconnectAttempts(t *testing.T) {
+	srv, client, uids := newTestServer(t, 1)
+	const attempts = 50
+	mds, err := client.NewMarketDataStreamClient().MarketDataStream(
+		investgo.WithReconnect(retry.BackoffLinear(time.Millisecond), attempts),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mds.SubscribeLastPrice(uids); err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- mds.Listen()
+	}()
+	defer mds.Stop()
+	waitSubscribed(t, srv, uids[0])
+
+	srv.SetToken("revoked")
+	select {
+	case err := <-done:
+		if status.Code(err) != codes.Unauthenticated {
+			t.Fatalf("listen err = %v, want Unauthenticated", err)
+		}
+	case <-time.After(waitTime):
+		t.Fatal("listen did not return after reconnect attempts")
+	}
+	var last uint
+	for e := range mds.ReconnectEvents() {
+		last = e.Attempt
+	}
+	if last > attempts {
+		t.Fatalf("attempt = %v, want at most %v", last, attempts)
+	}
+}
+
+func waitSubscribed(t *testing.T, srv *investgotest.Server, id string) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), waitTime)
+	defer cancel()
+	if err := srv.WaitSubscribed(ctx, id); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// overflowCase - проверка политики переполнения: после отправки n цен одного инструмента в канал с буфером 2
+// без чтения сообщения в канале и количество отброшенных сообщений должны соответствовать политике
+type overflowCase struct {
+	policy investgo.OverflowPolicy
+	// check - проверка прочитанных после отправки цен
+	check func(t *testing.T, prices []float64)
+}
+
+const overflowPushes = 20
+
+var overflowCases = map[string]overflowCase{
+	"DropOldest": {
+		policy: investgo.OverflowDropOldest,
+		check: func(t *testing.T, prices []float64) {
+			if len(prices) != 2 || prices[0] != overflowPushes-1 || prices[1] != overflowPushes {
+				t.Fatalf("prices = %v, want last two", prices)
+			}
+		},
+	},
+	"DropNewest": {
+		policy: investgo.OverflowDropNewest,
+		check: func(t *testing.T, prices []float64) {
+			if len(prices) != 2 || prices[0] != 1 || prices[1] != 2 {
+				t.Fatalf("prices = %v, want first two", prices)
+			}
+		},
+	},
+	"KeepLatest": {
+		policy: investgo.OverflowKeepLatest,
+		check: func(t *testing.T, prices []float64) {
+			// первая цена могла уйти в канал до того, как пришли остальные
+			if len(prices) == 0 || len(prices) > 2 || prices[len(prices)-1] != overflowPushes {
+				t.Fatalf("prices = %v, want latest price last", prices)
+			}
+		},
+	},
+}
+
+// checkOverflow - отправка цен и проверка канала ch со счетчиком dropped для политик с отбрасыванием сообщений
+func checkOverflow(t *testing.T, srv *investgotest.Server, uid string, tc overflowCase, ch <-chan *pb.LastPrice, dropped func() uint64) {
+	t.Helper()
+	for i := 1; i <= overflowPushes; i++ {
+		setPrice(t, srv, uid, float64(i))
+	}
+	// в канале остается не больше двух сообщений, поэтому после отбрасывания остальных все цены уже обработаны
+	waitFor(t, "dropped messages", func() bool {
+		return dropped() >= overflowPushes-2
+	})
+	var prices []float64
+	for {
+		select {
+		case p := <-ch:
+			prices = append(prices, p.GetPrice().ToFloat())
+			continue
+		case <-time.After(50 * time.Millisecond):
+		}
+		break
+	}
+	if n := uint64(len(prices)) + dropped(); n != overflowPushes {
+		t.Fatalf("received %v + dropped %v != %v", len(prices), dropped(), overflowPushes)
+	}
+	tc.check(t, prices)
+}
+
+// checkBlock - с политикой OverflowBlock все сообщения доставляются по порядку, даже если читатель медленный
+func checkBlock(t *testing.T, srv *investgotest.Server, uid string, ch <-chan *pb.LastPrice, dropped func() uint64) {
+	t.Helper()
+	go func() {
+		for i := 1; i <= overflowPushes; i++ {
+			_ = srv.SetPrice(uid, float64(i))
+		}
+	}()
+	for i := 1; i <= overflowPushes; i++ {
+		if p := receive(t, ch).GetPrice().ToFloat(); p != float64(i) {
+			t.Fatalf("price = %v, want %v", p, i)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if dropped() != 0 {
+		t.Fatalf("dropped = %v", dropped())
+	}
+}
+
+func TestMarketDataStreamOverflow(t *testing.T) {
+	t.Run("Block", func(t *testing.T) {
+		srv, client, uids := newTestServer(t, 1)
+		mds, err := client.NewMarketDataStreamClient().MarketDataStream(investgo.WithBuffer(2, investgo.OverflowBlock))
+		if err != nil {
+			t.Fatal(err)
+		}
+		prices, err := mds.SubscribeLastPrice(uids)
+		if err != nil {
+			t.Fatal(err)
+		}
+		listen(t, mds.Listen, mds.Stop)
+		waitSubscribed(t, srv, uids[0])
+		checkBlock(t, srv, uids[0], prices, mds.Dropped)
+	})
+	for name, tc := range overflowCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			srv, client, uids := newTestServer(t, 1)
+			mds, err := client.NewMarketDataStreamClient().MarketDataStream(investgo.WithBuffer(2, tc.policy))
+			if err != nil {
+				t.Fatal(err)
+			}
+			prices, err := mds.SubscribeLastPrice(uids)
+			if err != nil {
+				t.Fatal(err)
+			}
+			listen(t, mds.Listen, mds.Stop)
+			waitSubscribed(t, srv, uids[0])
+			checkOverflow(t, srv, uids[0], tc, prices, mds.Dropped)
+		})
+	}
+}
+
+func TestSubscriptionOverflow(t *testing.T) {
+	for name, tc := range overflowCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			srv, client, uids := newTestServer(t, 1)
+			mds, err := client.NewMarketDataStreamClient().MarketDataStream(investgo.WithBuffer(2, tc.policy))
+			if err != nil {
+				t.Fatal(err)
+			}
+			listen(t, mds.Listen, mds.Stop)
+			sub, err := mds.NewLastPriceSubscription(uids)
+			if err != nil {
+				t.Fatal(err)
+			}
+			waitSubscribed(t, srv, uids[0])
+			checkOverflow(t, srv, uids[0], tc, sub.Updates(), sub.Dropped)
+			if mds.Dropped() != sub.Dropped() {
+				t.Fatalf("stream dropped = %v, subscription dropped = %v", mds.Dropped(), sub.Dropped())
+			}
+		})
+	}
+}
+
+func TestMarketDataStreamRejected(t *testing.T) {
+	_, client, uids := newTestServer(t, 1)
+	mds, err := client.NewMarketDataStreamClient().MarketDataStream(investgo.WithSubscriptionConfirm(waitTime))
+	if err != nil {
+		t.Fatal(err)
+	}
+	listen(t, mds.Listen, mds.Stop)
+
+	_, err = mds.SubscribeLastPrice([]string{uids[0], "unknown"})
+	var subErr *investgo.SubscriptionError
+	if !errors.As(err, &subErr) {
+		t.Fatalf("err = %v, want *SubscriptionError", err)
+	}
+	if len(subErr.Results) != 1 || subErr.Results[0].InstrumentUid != "unknown" {
+		t.Fatalf("rejected = %+v", subErr.Results)
+	}
+	subs, err := mds.GetMySubscriptions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(subs.LastPrices) != 1 || subs.LastPrices[0].InstrumentUid != uids[0] {
+		t.Fatalf("subscriptions = %+v", subs.LastPrices)
+	}
+	// отклоненная подписка не сохраняется, и отписка от всего не отправляет ее серверу
+	if err := mds.UnSubscribeAll(); err != nil {
+		t.Fatalf("unsubscribe all: %v", err)
+	}
+	if subs, err = mds.GetMySubscriptions(); err != nil {
+		t.Fatal(err)
+	}
+	if len(subs.LastPrices) != 0 {
+		t.Fatalf("subscriptions after unsubscribe = %+v", subs.LastPrices)
+	}
+}
@@ -0,0 +1,192 @@
+package investgo
+
+import (
+	"sync"
+
+	pb "github.com/russianinvestments/invest-api-go-sdk/proto"
+)
+
+// subscriptionBuffer - размер буфера канала подписки
+const subscriptionBuffer = 1
+
+// marketDataMessage - биржевая информация по инструменту, которую можно направить подписчику
+type marketDataMessage interface {
+	GetFigi() string
+	GetInstrumentUid() string
+}
+
+// Subscription - подписка на биржевую информацию с собственным каналом. В канал подписки приходит информация
+// только по ее инструментам, поэтому разные части программы могут подписываться на разные инструменты в рамках
+// одного стрима независимо друг от друга
+type Subscription[T marketDataMessage] struct {
+	q *streamQueue[T]
+
+	ids    map[string]struct{}
+	filter func(T) bool
+
+	unsubOnce   sync.Once
+	unsubscribe func() error
+	unsubErr    error
+}
+
+func newSubscription[T marketDataMessage](ids []string, filter func(T) bool, q *streamQueue[T]) *Subscription[T] {
+	s := &Subscription[T]{
+		q:      q,
+		ids:    make(map[string]struct{}, len(ids)),
+		filter: filter,
+	}
+	for _, id := range ids {
+		s.ids[id] = struct{}{}
+	}
+	return s
+}
+
+// Updates - Метод возвращает канал подписки, канал закрывается после Unsubscribe или завершения работы стрима
+func (s *Subscription[T]) Updates() <-chan T {
+	return s.q.out()
+}
+
+// Dropped - Метод возвращает количество сообщений подписки, отброшенных при переполнении ее канала
+func (s *Subscription[T]) Dropped() uint64 {
+	return s.q.droppedCount()
+}
+
+// Instruments - Метод возвращает идентификаторы инструментов подписки
+func (s *Subscription[T]) Instruments() []string {
+	ids := make([]string, 0, len(s.ids))
+	for id := range s.ids {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// Unsubscribe - Метод отписки. Отписка на стороне сервера отправляется только для тех инструментов,
+// на которые больше нет других подписок в этом стриме. Повторный вызов возвращает результат первого
+func (s *Subscription[T]) Unsubscribe() error {
+	s.unsubOnce.Do(func() {
+		s.unsubErr = s.unsubscribe()
+	})
+	return s.unsubErr
+}
+
+func (s *Subscription[T]) match(v T) bool {
+	_, byUid := s.ids[v.GetInstrumentUid()]
+	_, byFigi := s.ids[v.GetFigi()]
+	if !byUid && !byFigi {
+		return false
+	}
+	return s.filter == nil || s.filter(v)
+}
+
+// publish - отправка в канал подписки, не блокируется после закрытия подписки
+func (s *Subscription[T]) publish(v T) {
+	s.q.send(v)
+}
+
+// fanOut - маршрутизатор биржевой информации одного типа по подпискам
+type fanOut[T marketDataMessage] struct {
+	mu   sync.RWMutex
+	subs map[*Subscription[T]]struct{}
+	// closed - стрим завершил работу, новые подписки не добавляются
+	closed bool
+	// key - ключ сообщения для политики OverflowKeepLatest
+	key func(T) string
+}
+
+func newFanOut[T marketDataMessage](key func(T) string) *fanOut[T] {
+	return &fanOut[T]{
+		subs: make(map[*Subscription[T]]struct{}),
+		key:  key,
+	}
+}
+
+// add - добавление подписки, возвращает false, если стрим уже завершил работу
+func (f *fanOut[T]) add(s *Subscription[T]) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return false
+	}
+	f.subs[s] = struct{}{}
+	return true
+}
+
+// remove - удаление подписки и закрытие ее канала
+func (f *fanOut[T]) remove(s *Subscription[T]) {
+	f.mu.Lock()
+	delete(f.subs, s)
+	f.mu.Unlock()
+	s.q.close()
+}
+
+// publish - отправка информации во все подходящие подписки, возвращает true, если хотя бы одна подписка ее получила.
+// Отправка идет без блокировки маршрутизатора, поэтому медленный читатель одной подписки не мешает отписке других
+func (f *fanOut[T]) publish(v T) bool {
+	f.mu.RLock()
+	matched := make([]*Subscription[T], 0, 1)
+	for s := range f.subs {
+		if s.match(v) {
+			matched = append(matched, s)
+		}
+	}
+	f.mu.RUnlock()
+	for _, s := range matched {
+		s.publish(v)
+	}
+	return len(matched) > 0
+}
+
+func (f *fanOut[T]) all() []*Subscription[T] {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	subs := make([]*Subscription[T], 0, len(f.subs))
+	for s := range f.subs {
+		subs = append(subs, s)
+	}
+	return subs
+}
+
+func (f *fanOut[T]) closeAll() {
+	f.mu.Lock()
+	f.closed = true
+	f.mu.Unlock()
+	for _, s := range f.all() {
+		f.remove(s)
+	}
+}
+
+// subRef - подписка на сервере по одному инструменту с параметрами, используется для подсчета ссылок
+type subRef struct {
+	kind     SubscriptionType
+	id       string
+	candle   candleSub
+	depth    int32
+	tradeSrc pb.TradeSourceType
+}
+
+// routers - маршрутизаторы подписок MarketDataStream
+type routers struct {
+	candles         *fanOut[*pb.Candle]
+	orderBooks      *fanOut[*pb.OrderBook]
+	trades          *fanOut[*pb.Trade]
+	tradingStatuses *fanOut[*pb.TradingStatus]
+	lastPrices      *fanOut[*pb.LastPrice]
+}
+
+func newRouters() routers {
+	return routers{
+		candles:         newFanOut(candleKey),
+		orderBooks:      newFanOut(orderBookKey),
+		trades:          newFanOut(instrumentKey[*pb.Trade]),
+		tradingStatuses: newFanOut(instrumentKey[*pb.TradingStatus]),
+		lastPrices:      newFanOut(instrumentKey[*pb.LastPrice]),
+	}
+}
+
+func (r *routers) closeAll() {
+	r.candles.closeAll()
+	r.orderBooks.closeAll()
+	r.trades.closeAll()
+	r.tradingStatuses.closeAll()
+	r.lastPrices.closeAll()
+}
@@ -0,0 +1,468 @@
+package investgo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	pb "github.com/russianinvestments/invest-api-go-sdk/proto"
+)
+
+// subscriptionResultsBuffer - размер буфера каналов результатов подписок и служебных сообщений стрима
+const subscriptionResultsBuffer = 64
+
+// ErrStreamClosed - стрим закрыт или переподключен до получения ответа на запрос подписки
+var ErrStreamClosed = errors.New("market data stream closed before subscription response")
+
+// SubscriptionType - тип подписки в стриме биржевой информации
+type SubscriptionType int
+
+const (
+	// SubscriptionCandles - подписка на свечи
+	SubscriptionCandles SubscriptionType = iota
+	// SubscriptionOrderBooks - подписка на стаканы
+	SubscriptionOrderBooks
+	// SubscriptionTrades - подписка на ленту обезличенных сделок
+	SubscriptionTrades
+	// SubscriptionInfo - подписка на торговые статусы
+	SubscriptionInfo
+	// SubscriptionLastPrices - подписка на последние цены
+	SubscriptionLastPrices
+)
+
+func (t SubscriptionType) String() string {
+	switch t {
+	case SubscriptionCandles:
+		return "candles"
+	case SubscriptionOrderBooks:
+		return "order books"
+	case SubscriptionTrades:
+		return "trades"
+	case SubscriptionInfo:
+		return "info"
+	case SubscriptionLastPrices:
+		return "last prices"
+	}
+	return fmt.Sprintf("SubscriptionType(%d)", int(t))
+}
+
+// SubscriptionResult - статус подписки на один инструмент из ответа сервера
+type SubscriptionResult struct {
+	// Type - тип подписки
+	Type SubscriptionType
+	// TrackingId - идентификатор запроса
+	TrackingId    string
+	Figi          string
+	InstrumentUid string
+	// Status - статус подписки, при успешной подписке SUBSCRIPTION_STATUS_SUCCESS
+	Status         pb.SubscriptionStatus
+	StreamId       string
+	SubscriptionId string
+	// Interval, WaitingClose - параметры подписки на свечи
+	Interval     pb.SubscriptionInterval
+	WaitingClose bool
+	// Depth - глубина стакана для подписки на стаканы
+	Depth int32
+	// TradeSource - источник сделок для подписки на ленту обезличенных сделок
+	TradeSource pb.TradeSourceType
+}
+
+// Err - Метод возвращает *SubscriptionError, если сервер отклонил подписку, иначе nil
+func (r SubscriptionResult) Err() error {
+	if r.Status == pb.SubscriptionStatus_SUBSCRIPTION_STATUS_SUCCESS {
+		return nil
+	}
+	return &SubscriptionError{Results: []SubscriptionResult{r}}
+}
+
+// SubscriptionError - ошибка подписки, содержит статусы всех отклоненных инструментов из ответа сервера
+type SubscriptionError struct {
+	Results []SubscriptionResult
+}
+
+func (e *SubscriptionError) Error() string {
+	b := strings.Builder{}
+	b.WriteString("subscription rejected:")
+	for i, r := range e.Results {
+		if i > 0 {
+			b.WriteString(";")
+		}
+		fmt.Fprintf(&b, " %v figi = %v, uid = %v, status = %v", r.Type, r.Figi, r.InstrumentUid, r.Status.String())
+	}
+	return b.String()
+}
+
+func isSubscriptionError(err error) bool {
+	var subErr *SubscriptionError
+	return errors.As(err, &subErr)
+}
+
+// subscriptionError - ошибка со всеми отклоненными подписками, nil если все подписки успешны
+func subscriptionError(results []SubscriptionResult) error {
+	failed := make([]SubscriptionResult, 0)
+	for _, r := range results {
+		if r.Err() != nil {
+			failed = append(failed, r)
+		}
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+	return &SubscriptionError{Results: failed}
+}
+
+// MySubscriptions - активные подписки стрима, ответ на GetMySubscriptions
+type MySubscriptions struct {
+	Candles         []SubscriptionResult
+	OrderBooks      []SubscriptionResult
+	Trades          []SubscriptionResult
+	TradingStatuses []SubscriptionResult
+	LastPrices      []SubscriptionResult
+}
+
+func (m *MySubscriptions) set(t SubscriptionType, results []SubscriptionResult) {
+	switch t {
+	case SubscriptionCandles:
+		m.Candles = results
+	case SubscriptionOrderBooks:
+		m.OrderBooks = results
+	case SubscriptionTrades:
+		m.Trades = results
+	case SubscriptionInfo:
+		m.TradingStatuses = results
+	case SubscriptionLastPrices:
+		m.LastPrices = results
+	}
+}
+
+// subscriptionResults - разбор ответа сервера на запрос подписки, ok = false, если это не ответ на подписку
+func subscriptionResults(resp *pb.MarketDataResponse) (t SubscriptionType, results []SubscriptionResult, ok bool) {
+	switch resp.GetPayload().(type) {
+	case *pb.MarketDataResponse_SubscribeCandlesResponse:
+		r := resp.GetSubscribeCandlesResponse()
+		for _, s := range r.GetCandlesSubscriptions() {
+			results = append(results, SubscriptionResult{
+				Type:           SubscriptionCandles,
+				TrackingId:     r.GetTrackingId(),
+				Figi:           s.GetFigi(),
+				InstrumentUid:  s.GetInstrumentUid(),
+				Status:         s.GetSubscriptionStatus(),
+				StreamId:       s.GetStreamId(),
+				SubscriptionId: s.GetSubscriptionId(),
+				Interval:       s.GetInterval(),
+				WaitingClose:   s.GetWaitingClose(),
+			})
+		}
+		return SubscriptionCandles, results, true
+	case *pb.MarketDataResponse_SubscribeOrderBookResponse:
+		r := resp.GetSubscribeOrderBookResponse()
+		for _, s := range r.GetOrderBookSubscriptions() {
+			results = append(results, SubscriptionResult{
+				Type:           SubscriptionOrderBooks,
+				TrackingId:     r.GetTrackingId(),
+				Figi:           s.GetFigi(),
+				InstrumentUid:  s.GetInstrumentUid(),
+				Status:         s.GetSubscriptionStatus(),
+				StreamId:       s.GetStreamId(),
+				SubscriptionId: s.GetSubscriptionId(),
+				Depth:          s.GetDepth(),
+			})
+		}
+		return SubscriptionOrderBooks, results, true
+	case *pb.MarketDataResponse_SubscribeTradesResponse:
+		r := resp.GetSubscribeTradesResponse()
+		for _, s := range r.GetTradeSubscriptions() {
+			results = append(results, SubscriptionResult{
+				Type:           SubscriptionTrades,
+				TrackingId:     r.GetTrackingId(),
+				Figi:           s.GetFigi(),
+				InstrumentUid:  s.GetInstrumentUid(),
+				Status:         s.GetSubscriptionStatus(),
+				StreamId:       s.GetStreamId(),
+				SubscriptionId: s.GetSubscriptionId(),
+				TradeSource:    r.GetTradeSource(),
+			})
+		}
+		return SubscriptionTrades, results, true
+	case *pb.MarketDataResponse_SubscribeInfoResponse:
+		r := resp.GetSubscribeInfoResponse()
+		for _, s := range r.GetInfoSubscriptions() {
+			results = append(results, SubscriptionResult{
+				Type:           SubscriptionInfo,
+				TrackingId:     r.GetTrackingId(),
+				Figi:           s.GetFigi(),
+				InstrumentUid:  s.GetInstrumentUid(),
+				Status:         s.GetSubscriptionStatus(),
+				StreamId:       s.GetStreamId(),
+				SubscriptionId: s.GetSubscriptionId(),
+			})
+		}
+		return SubscriptionInfo, results, true
+	case *pb.MarketDataResponse_SubscribeLastPriceResponse:
+		r := resp.GetSubscribeLastPriceResponse()
+		for _, s := range r.GetLastPriceSubscriptions() {
+			results = append(results, SubscriptionResult{
+				Type:           SubscriptionLastPrices,
+				TrackingId:     r.GetTrackingId(),
+				Figi:           s.GetFigi(),
+				InstrumentUid:  s.GetInstrumentUid(),
+				Status:         s.GetSubscriptionStatus(),
+				StreamId:       s.GetStreamId(),
+				SubscriptionId: s.GetSubscriptionId(),
+			})
+		}
+		return SubscriptionLastPrices, results, true
+	}
+	return 0, nil, false
+}
+
+// pendingRequest - запрос подписки или отписки, ожидающий ответа сервера
+type pendingRequest struct {
+	seq uint64
+	t   SubscriptionType
+	// list, ids - инструменты запроса в порядке запроса и множеством
+	list []string
+	ids  map[string]struct{}
+	ch   chan []SubscriptionResult
+	// abandoned - ответ больше никто не ждет. Запрос остается в очереди, чтобы поглотить свой ответ, иначе ответ
+	// достанется следующему запросу того же типа
+	abandoned bool
+}
+
+// matches - может ли ответ results быть ответом на запрос. Если в ответе есть идентификаторы инструментов,
+// хотя бы один из них должен быть в запросе
+func (r *pendingRequest) matches(results []SubscriptionResult) bool {
+	identified := false
+	for _, res := range results {
+		if res.Figi == "" && res.InstrumentUid == "" {
+			continue
+		}
+		identified = true
+		_, byFigi := r.ids[res.Figi]
+		_, byUid := r.ids[res.InstrumentUid]
+		if byFigi || byUid {
+			return true
+		}
+	}
+	return !identified
+}
+
+// pendingSnapshot - запрос GetMySubscriptions. Сервер присылает по одному ответу на каждый тип активных подписок,
+// поэтому за запросом отправляется пинг с уникальным временем, и ответ на пинг означает, что снимок собран
+type pendingSnapshot struct {
+	seq      uint64
+	ping     time.Time
+	subs     MySubscriptions
+	received map[SubscriptionType]bool
+	done     chan struct{}
+	// closed - стрим закрыт или переподключен до ответа на пинг
+	closed bool
+}
+
+// pendingResults - запросы, ожидающие ответа, в порядке отправки. Сервер отвечает на запросы в порядке их
+// отправки, поэтому ответ отдается самому раннему запросу, который может его принять
+type pendingResults struct {
+	mu        sync.Mutex
+	seq       uint64
+	lastPing  time.Time
+	requests  []*pendingRequest
+	snapshots []*pendingSnapshot
+}
+
+func newPendingResults() *pendingResults {
+	return &pendingResults{}
+}
+
+// add - добавляет запрос типа t по инструментам ids в очередь, вызывается под sendMu до отправки запроса
+func (p *pendingResults) add(t SubscriptionType, ids []string) *pendingRequest {
+	r := &pendingRequest{
+		t:    t,
+		list: ids,
+		ids:  make(map[string]struct{}, len(ids)),
+		ch:   make(chan []SubscriptionResult, 1),
+	}
+	for _, id := range ids {
+		r.ids[id] = struct{}{}
+	}
+	p.mu.Lock()
+	p.seq++
+	r.seq = p.seq
+	p.requests = append(p.requests, r)
+	p.mu.Unlock()
+	return r
+}
+
+// addSnapshot - добавляет запрос GetMySubscriptions с уникальным временем пинга, вызывается под sendMu
+func (p *pendingResults) addSnapshot() *pendingSnapshot {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	ping := time.Now()
+	if !ping.After(p.lastPing) {
+		ping = p.lastPing.Add(time.Nanosecond)
+	}
+	p.lastPing = ping
+	p.seq++
+	s := &pendingSnapshot{
+		seq:      p.seq,
+		ping:     ping,
+		received: make(map[SubscriptionType]bool),
+		done:     make(chan struct{}),
+	}
+	p.snapshots = append(p.snapshots, s)
+	return s
+}
+
+// remove - удаляет запрос из очереди, если его не удалось отправить
+func (p *pendingResults) remove(r *pendingRequest) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.removeLocked(r)
+}
+
+func (p *pendingResults) removeLocked(r *pendingRequest) {
+	for i := range p.requests {
+		if p.requests[i] == r {
+			p.requests = append(p.requests[:i:i], p.requests[i+1:]...)
+			return
+		}
+	}
+}
+
+// removeSnapshot - удаляет запрос GetMySubscriptions из очереди, если его не удалось отправить
+func (p *pendingResults) removeSnapshot(s *pendingSnapshot) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.removeSnapshotLocked(s)
+}
+
+func (p *pendingResults) removeSnapshotLocked(s *pendingSnapshot) {
+	for i := range p.snapshots {
+		if p.snapshots[i] == s {
+			p.snapshots = append(p.snapshots[:i:i], p.snapshots[i+1:]...)
+			return
+		}
+	}
+}
+
+// resolve - отдает ответ типа t самому раннему запросу, который может его принять, и возвращает инструменты этого
+// запроса. Брошенные запросы, на которые ответ явно не подходит, удаляются из очереди: сервер мог не ответить на них
+func (p *pendingResults) resolve(t SubscriptionType, results []SubscriptionResult) []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var req *pendingRequest
+	for i := 0; i < len(p.requests); i++ {
+		r := p.requests[i]
+		if r.t != t {
+			continue
+		}
+		if r.abandoned && !r.matches(results) {
+			p.requests = append(p.requests[:i:i], p.requests[i+1:]...)
+			i--
+			continue
+		}
+		req = r
+		break
+	}
+	var snap *pendingSnapshot
+	for _, s := range p.snapshots {
+		if !s.received[t] {
+			snap = s
+			break
+		}
+	}
+
+	switch {
+	case snap != nil && (req == nil || snap.seq < req.seq):
+		snap.received[t] = true
+		snap.subs.set(t, results)
+	case req != nil:
+		p.removeLocked(req)
+		if !req.abandoned {
+			req.ch <- results
+		}
+		return req.list
+	}
+	return nil
+}
+
+// pong - ответ на пинг завершает сбор снимка подписок с этим временем пинга
+func (p *pendingResults) pong(ping *pb.Ping) {
+	if ping.GetPingRequestTime() == nil {
+		return
+	}
+	at := ping.GetPingRequestTime().AsTime()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, s := range p.snapshots {
+		if s.ping.Equal(at) {
+			p.removeSnapshotLocked(s)
+			close(s.done)
+			return
+		}
+	}
+}
+
+// abandon - ответ на запрос больше не ждут, например истек таймаут ожидания
+func (p *pendingResults) abandon(r *pendingRequest) {
+	p.mu.Lock()
+	r.abandoned = true
+	p.mu.Unlock()
+}
+
+// reset - закрывает все ожидающие запросы, ответы на них в старом стриме уже не придут
+func (p *pendingResults) reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, r := range p.requests {
+		if !r.abandoned {
+			close(r.ch)
+		}
+	}
+	for _, s := range p.snapshots {
+		s.closed = true
+		close(s.done)
+	}
+	p.requests, p.snapshots = nil, nil
+}
+
+// wait - ожидание ответа на запрос подписки. Если ctx завершился раньше, запрос помечается брошенным
+func (p *pendingResults) wait(ctx context.Context, r *pendingRequest) ([]SubscriptionResult, error) {
+	select {
+	case results, ok := <-r.ch:
+		if !ok {
+			return nil, ErrStreamClosed
+		}
+		return results, nil
+	case <-ctx.Done():
+		p.abandon(r)
+		// ответ мог прийти одновременно с завершением ctx
+		select {
+		case results, ok := <-r.ch:
+			if ok {
+				return results, nil
+			}
+		default:
+		}
+		return nil, ctx.Err()
+	}
+}
+
+// waitSnapshot - ожидание снимка подписок. Если ctx завершился раньше, снимок остается в очереди и поглощает
+// ответы сервера до ответа на свой пинг
+func (p *pendingResults) waitSnapshot(ctx context.Context, s *pendingSnapshot) (*MySubscriptions, error) {
+	select {
+	case <-s.done:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if s.closed {
+		return nil, ErrStreamClosed
+	}
+	subs := s.subs
+	return &subs, nil
+}
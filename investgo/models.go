@@ -723,6 +723,26 @@ type GetHistoricCandlesRequest struct {
 	File       bool
 	FileName   string
 	Source     pb.GetCandlesRequest_CandleSource
+	// Store - хранилище свечей, см. DownloadCandlesRequest.Store
+	Store CandleStore
+}
+
+type DownloadCandlesRequest struct {
+	Instrument string
+	Interval   pb.CandleInterval
+	From       time.Time
+	To         time.Time
+	Source     pb.GetCandlesRequest_CandleSource
+	// Workers - количество одновременных запросов, по умолчанию DefaultDownloadWorkers
+	Workers int
+	// RequestsPerMinute - ограничение количества запросов в минуту, по умолчанию DefaultCandlesRequestsPerMinute
+	RequestsPerMinute int
+	// Exchange - биржа, по торговому календарю которой ищутся пропуски свечей, если пусто - пропуски не ищутся
+	Exchange string
+	// Checkpoint - продолжение загрузки с сохраненной точки, From при этом не используется
+	Checkpoint *CandlesCheckpoint
+	// Store - хранилище свечей, из которого читаются уже сохраненные интервалы и в которое сохраняются новые свечи
+	Store CandleStore
 }
 
 type GetTechAnalysisRequest struct {
@@ -748,3 +768,17 @@ type GetSignalsRequest struct {
 	Active        *pb.SignalState
 	Paging        *pb.Page
 }
+
+type MarketDataServerSideStreamRequest struct {
+	CandleInstruments    []string
+	CandleInterval       pb.SubscriptionInterval
+	WaitingClose         bool
+	CandleSource         *pb.GetCandlesRequest_CandleSource
+	OrderBookInstruments []string
+	OrderBookDepth       int32
+	TradeInstruments     []string
+	TradeSource          pb.TradeSourceType
+	InfoInstruments      []string
+	LastPriceInstruments []string
+	PingDelayMs          *int32
+}
@@ -20,8 +20,13 @@ type OperationsServiceClient struct {
 
 // GetOperations - Метод получения списка операций по счёту
 func (os *OperationsServiceClient) GetOperations(req *GetOperationsRequest) (*OperationsResponse, error) {
+	return os.GetOperationsCtx(os.ctx, req)
+}
+
+// GetOperationsCtx - то же, что и GetOperations, но с контекстом запроса ctx
+func (os *OperationsServiceClient) GetOperationsCtx(ctx context.Context, req *GetOperationsRequest) (*OperationsResponse, error) {
 	var header, trailer metadata.MD
-	resp, err := os.pbClient.GetOperations(os.ctx, &pb.OperationsRequest{
+	resp, err := os.pbClient.GetOperations(ctx, &pb.OperationsRequest{
 		AccountId: req.AccountId,
 		From:      TimeToTimestamp(req.From),
 		To:        TimeToTimestamp(req.To),
@@ -39,8 +44,13 @@ func (os *OperationsServiceClient) GetOperations(req *GetOperationsRequest) (*Op
 
 // GetPortfolio - Метод получения портфеля по счёту
 func (os *OperationsServiceClient) GetPortfolio(accountId string, currency pb.PortfolioRequest_CurrencyRequest) (*PortfolioResponse, error) {
+	return os.GetPortfolioCtx(os.ctx, accountId, currency)
+}
+
+// GetPortfolioCtx - то же, что и GetPortfolio, но с контекстом запроса ctx
+func (os *OperationsServiceClient) GetPortfolioCtx(ctx context.Context, accountId string, currency pb.PortfolioRequest_CurrencyRequest) (*PortfolioResponse, error) {
 	var header, trailer metadata.MD
-	resp, err := os.pbClient.GetPortfolio(os.ctx, &pb.PortfolioRequest{
+	resp, err := os.pbClient.GetPortfolio(ctx, &pb.PortfolioRequest{
 		AccountId: accountId,
 		Currency:  &currency,
 	}, grpc.Header(&header), grpc.Trailer(&trailer))
@@ -55,8 +65,13 @@ func (os *OperationsServiceClient) GetPortfolio(accountId string, currency pb.Po
 
 // GetPositions - Метод получения списка позиций по счёту
 func (os *OperationsServiceClient) GetPositions(accountId string) (*PositionsResponse, error) {
+	return os.GetPositionsCtx(os.ctx, accountId)
+}
+
+// GetPositionsCtx - то же, что и GetPositions, но с контекстом запроса ctx
+func (os *OperationsServiceClient) GetPositionsCtx(ctx context.Context, accountId string) (*PositionsResponse, error) {
 	var header, trailer metadata.MD
-	resp, err := os.pbClient.GetPositions(os.ctx, &pb.PositionsRequest{
+	resp, err := os.pbClient.GetPositions(ctx, &pb.PositionsRequest{
 		AccountId: accountId,
 	}, grpc.Header(&header), grpc.Trailer(&trailer))
 	if err != nil {
@@ -70,8 +85,13 @@ func (os *OperationsServiceClient) GetPositions(accountId string) (*PositionsRes
 
 // GetWithdrawLimits - Метод получения доступного остатка для вывода средств
 func (os *OperationsServiceClient) GetWithdrawLimits(accountId string) (*WithdrawLimitsResponse, error) {
+	return os.GetWithdrawLimitsCtx(os.ctx, accountId)
+}
+
+// GetWithdrawLimitsCtx - то же, что и GetWithdrawLimits, но с контекстом запроса ctx
+func (os *OperationsServiceClient) GetWithdrawLimitsCtx(ctx context.Context, accountId string) (*WithdrawLimitsResponse, error) {
 	var header, trailer metadata.MD
-	resp, err := os.pbClient.GetWithdrawLimits(os.ctx, &pb.WithdrawLimitsRequest{
+	resp, err := os.pbClient.GetWithdrawLimits(ctx, &pb.WithdrawLimitsRequest{
 		AccountId: accountId,
 	}, grpc.Header(&header), grpc.Trailer(&trailer))
 	if err != nil {
@@ -85,8 +105,13 @@ func (os *OperationsServiceClient) GetWithdrawLimits(accountId string) (*Withdra
 
 // GetBrokerReport - Метод получения брокерского отчёта
 func (os *OperationsServiceClient) GetBrokerReport(taskId string, page int32) (*GetBrokerReportResponse, error) {
+	return os.GetBrokerReportCtx(os.ctx, taskId, page)
+}
+
+// GetBrokerReportCtx - то же, что и GetBrokerReport, но с контекстом запроса ctx
+func (os *OperationsServiceClient) GetBrokerReportCtx(ctx context.Context, taskId string, page int32) (*GetBrokerReportResponse, error) {
 	var header, trailer metadata.MD
-	resp, err := os.pbClient.GetBrokerReport(os.ctx, &pb.BrokerReportRequest{
+	resp, err := os.pbClient.GetBrokerReport(ctx, &pb.BrokerReportRequest{
 		Payload: &pb.BrokerReportRequest_GetBrokerReportRequest{
 			GetBrokerReportRequest: &pb.GetBrokerReportRequest{
 				TaskId: taskId,
@@ -105,8 +130,13 @@ func (os *OperationsServiceClient) GetBrokerReport(taskId string, page int32) (*
 
 // GenerateBrokerReport - Метод получения брокерского отчёта
 func (os *OperationsServiceClient) GenerateBrokerReport(accountId string, from, to time.Time) (*GenerateBrokerReportResponse, error) {
+	return os.GenerateBrokerReportCtx(os.ctx, accountId, from, to)
+}
+
+// GenerateBrokerReportCtx - то же, что и GenerateBrokerReport, но с контекстом запроса ctx
+func (os *OperationsServiceClient) GenerateBrokerReportCtx(ctx context.Context, accountId string, from, to time.Time) (*GenerateBrokerReportResponse, error) {
 	var header, trailer metadata.MD
-	resp, err := os.pbClient.GetBrokerReport(os.ctx, &pb.BrokerReportRequest{
+	resp, err := os.pbClient.GetBrokerReport(ctx, &pb.BrokerReportRequest{
 		Payload: &pb.BrokerReportRequest_GenerateBrokerReportRequest{
 			GenerateBrokerReportRequest: &pb.GenerateBrokerReportRequest{
 				AccountId: accountId,
@@ -126,8 +156,13 @@ func (os *OperationsServiceClient) GenerateBrokerReport(accountId string, from,
 
 // GetDividentsForeignIssuer - Метод получения отчёта "Справка о доходах за пределами РФ"
 func (os *OperationsServiceClient) GetDividentsForeignIssuer(taskId string, page int32) (*GetDividendsForeignIssuerResponse, error) {
+	return os.GetDividentsForeignIssuerCtx(os.ctx, taskId, page)
+}
+
+// GetDividentsForeignIssuerCtx - то же, что и GetDividentsForeignIssuer, но с контекстом запроса ctx
+func (os *OperationsServiceClient) GetDividentsForeignIssuerCtx(ctx context.Context, taskId string, page int32) (*GetDividendsForeignIssuerResponse, error) {
 	var header, trailer metadata.MD
-	resp, err := os.pbClient.GetDividendsForeignIssuer(os.ctx, &pb.GetDividendsForeignIssuerRequest{
+	resp, err := os.pbClient.GetDividendsForeignIssuer(ctx, &pb.GetDividendsForeignIssuerRequest{
 		Payload: &pb.GetDividendsForeignIssuerRequest_GetDivForeignIssuerReport{
 			GetDivForeignIssuerReport: &pb.GetDividendsForeignIssuerReportRequest{
 				TaskId: taskId,
@@ -146,8 +181,13 @@ func (os *OperationsServiceClient) GetDividentsForeignIssuer(taskId string, page
 
 // GenerateDividentsForeignIssuer - Метод получения отчёта "Справка о доходах за пределами РФ"
 func (os *OperationsServiceClient) GenerateDividentsForeignIssuer(accountId string, from, to time.Time) (*GetDividendsForeignIssuerResponse, error) {
+	return os.GenerateDividentsForeignIssuerCtx(os.ctx, accountId, from, to)
+}
+
+// GenerateDividentsForeignIssuerCtx - то же, что и GenerateDividentsForeignIssuer, но с контекстом запроса ctx
+func (os *OperationsServiceClient) GenerateDividentsForeignIssuerCtx(ctx context.Context, accountId string, from, to time.Time) (*GetDividendsForeignIssuerResponse, error) {
 	var header, trailer metadata.MD
-	resp, err := os.pbClient.GetDividendsForeignIssuer(os.ctx, &pb.GetDividendsForeignIssuerRequest{
+	resp, err := os.pbClient.GetDividendsForeignIssuer(ctx, &pb.GetDividendsForeignIssuerRequest{
 		Payload: &pb.GetDividendsForeignIssuerRequest_GenerateDivForeignIssuerReport{
 			GenerateDivForeignIssuerReport: &pb.GenerateDividendsForeignIssuerReportRequest{
 				AccountId: accountId,
@@ -167,15 +207,25 @@ func (os *OperationsServiceClient) GenerateDividentsForeignIssuer(accountId stri
 
 // GetOperationsByCursorShort - Метод получения списка операций по счёту с пагинацией
 func (os *OperationsServiceClient) GetOperationsByCursorShort(accountId string) (*GetOperationsByCursorResponse, error) {
-	return os.GetOperationsByCursor(&GetOperationsByCursorRequest{
+	return os.GetOperationsByCursorShortCtx(os.ctx, accountId)
+}
+
+// GetOperationsByCursorShortCtx - то же, что и GetOperationsByCursorShort, но с контекстом запроса ctx
+func (os *OperationsServiceClient) GetOperationsByCursorShortCtx(ctx context.Context, accountId string) (*GetOperationsByCursorResponse, error) {
+	return os.GetOperationsByCursorCtx(ctx, &GetOperationsByCursorRequest{
 		AccountId: accountId,
 	})
 }
 
 // GetOperationsByCursor - Метод получения списка операций по счёту с пагинацией
 func (os *OperationsServiceClient) GetOperationsByCursor(req *GetOperationsByCursorRequest) (*GetOperationsByCursorResponse, error) {
+	return os.GetOperationsByCursorCtx(os.ctx, req)
+}
+
+// GetOperationsByCursorCtx - то же, что и GetOperationsByCursor, но с контекстом запроса ctx
+func (os *OperationsServiceClient) GetOperationsByCursorCtx(ctx context.Context, req *GetOperationsByCursorRequest) (*GetOperationsByCursorResponse, error) {
 	var header, trailer metadata.MD
-	resp, err := os.pbClient.GetOperationsByCursor(os.ctx, &pb.GetOperationsByCursorRequest{
+	resp, err := os.pbClient.GetOperationsByCursor(ctx, &pb.GetOperationsByCursorRequest{
 		AccountId:          req.AccountId,
 		InstrumentId:       &req.InstrumentId,
 		From:               TimeToTimestamp(req.From),
@@ -17,14 +17,22 @@ type OperationsStreamClient struct {
 }
 
 // PortfolioStream - Server-side stream обновлений портфеля
-func (o *OperationsStreamClient) PortfolioStream(accounts []string) (*PortfolioStream, error) {
-	ctx, cancel := context.WithCancel(o.ctx)
+func (o *OperationsStreamClient) PortfolioStream(accounts []string, opts ...StreamOption) (*PortfolioStream, error) {
+	return o.PortfolioStreamCtx(o.ctx, accounts, opts...)
+}
+
+// PortfolioStreamCtx - то же, что и PortfolioStream, но с контекстом запроса ctx
+func (o *OperationsStreamClient) PortfolioStreamCtx(ctx context.Context, accounts []string, opts ...StreamOption) (*PortfolioStream, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	options := newStreamOptions(opts)
 	ps := &PortfolioStream{
 		stream:           nil,
 		operationsClient: o,
-		portfolios:       make(chan *pb.PortfolioResponse),
-		ctx:              ctx,
-		cancel:           cancel,
+		portfolios: newStreamQueue(options.size(0), options.overflow, func(p *pb.PortfolioResponse) string {
+			return p.GetAccountId()
+		}, nil),
+		ctx:    ctx,
+		cancel: cancel,
 	}
 	stream, err := o.pbClient.PortfolioStream(ctx, &pb.PortfolioStreamRequest{
 		Accounts: accounts,
@@ -38,14 +46,22 @@ func (o *OperationsStreamClient) PortfolioStream(accounts []string) (*PortfolioS
 }
 
 // PositionsStream - Server-side stream обновлений информации по изменению позиций портфеля
-func (o *OperationsStreamClient) PositionsStream(accounts []string) (*PositionsStream, error) {
-	ctx, cancel := context.WithCancel(o.ctx)
+func (o *OperationsStreamClient) PositionsStream(accounts []string, opts ...StreamOption) (*PositionsStream, error) {
+	return o.PositionsStreamCtx(o.ctx, accounts, opts...)
+}
+
+// PositionsStreamCtx - то же, что и PositionsStream, но с контекстом запроса ctx
+func (o *OperationsStreamClient) PositionsStreamCtx(ctx context.Context, accounts []string, opts ...StreamOption) (*PositionsStream, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	options := newStreamOptions(opts)
 	ps := &PositionsStream{
 		stream:           nil,
 		operationsClient: o,
-		positions:        make(chan *pb.PositionData),
-		ctx:              ctx,
-		cancel:           cancel,
+		positions: newStreamQueue(options.size(0), options.overflow, func(p *pb.PositionData) string {
+			return p.GetAccountId()
+		}, nil),
+		ctx:    ctx,
+		cancel: cancel,
 	}
 	stream, err := o.pbClient.PositionsStream(ctx, &pb.PositionsStreamRequest{
 		Accounts: accounts,
@@ -0,0 +1,165 @@
+package investgo
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	pb "github.com/russianinvestments/invest-api-go-sdk/proto"
+)
+
+// lastPricesBatch - количество инструментов в одном запросе цен последних сделок для цепочки опционов
+const lastPricesBatch = 1000
+
+// OptionChain - цепочка опционов на один базовый актив: экспирации по возрастанию даты, в каждой страйки по
+// возрастанию цены с колл и пут опционами
+type OptionChain struct {
+	// BasicAsset - базовый актив опционов
+	BasicAsset string
+	// BasicAssetPositionUid - position uid базового актива
+	BasicAssetPositionUid string
+	Expirations           []*OptionExpiration
+}
+
+// OptionExpiration - опционы цепочки с одной датой экспирации
+type OptionExpiration struct {
+	Date    time.Time
+	Strikes []*OptionStrike
+}
+
+// OptionStrike - колл и пут опционы с одним страйком, nil - опциона на этом страйке нет
+type OptionStrike struct {
+	Strike *pb.MoneyValue
+	Call   *OptionQuote
+	Put    *OptionQuote
+}
+
+// OptionQuote - опцион и цена его последней сделки
+type OptionQuote struct {
+	*pb.Option
+	// LastPrice - цена последней сделки, nil - сделок не было или цены не запрашивались
+	LastPrice     *pb.Quotation
+	LastPriceTime time.Time
+}
+
+// NewOptionChain - Группировка опционов по дате экспирации и страйку. Если на одну экспирацию, страйк и направление
+// приходится несколько опционов, в цепочку попадает первый из них. Цены последних сделок lastPrices сопоставляются
+// с опционами по uid и могут быть nil
+func NewOptionChain(options []*pb.Option, lastPrices []*pb.LastPrice) *OptionChain {
+	prices := make(map[string]*pb.LastPrice, len(lastPrices))
+	for _, lp := range lastPrices {
+		prices[lp.GetInstrumentUid()] = lp
+	}
+
+	chain := &OptionChain{}
+	expirations := make(map[time.Time]*OptionExpiration)
+	strikes := make(map[*OptionExpiration]map[string]*OptionStrike)
+	for _, o := range options {
+		if chain.BasicAsset == "" {
+			chain.BasicAsset = o.GetBasicAsset()
+			chain.BasicAssetPositionUid = o.GetBasicAssetPositionUid()
+		}
+		date := o.GetExpirationDate().AsTime()
+		exp, ok := expirations[date]
+		if !ok {
+			exp = &OptionExpiration{Date: date}
+			expirations[date] = exp
+			strikes[exp] = make(map[string]*OptionStrike)
+			chain.Expirations = append(chain.Expirations, exp)
+		}
+		key := o.GetStrikePrice().ToDecimal().String()
+		strike, ok := strikes[exp][key]
+		if !ok {
+			strike = &OptionStrike{Strike: o.GetStrikePrice()}
+			strikes[exp][key] = strike
+			exp.Strikes = append(exp.Strikes, strike)
+		}
+
+		quote := &OptionQuote{Option: o}
+		if lp, ok := prices[o.GetUid()]; ok && lp.GetTime() != nil {
+			quote.LastPrice = lp.GetPrice()
+			quote.LastPriceTime = lp.GetTime().AsTime()
+		}
+		switch o.GetDirection() {
+		case pb.OptionDirection_OPTION_DIRECTION_CALL:
+			if strike.Call == nil {
+				strike.Call = quote
+			}
+		case pb.OptionDirection_OPTION_DIRECTION_PUT:
+			if strike.Put == nil {
+				strike.Put = quote
+			}
+		}
+	}
+
+	sort.Slice(chain.Expirations, func(i, j int) bool {
+		return chain.Expirations[i].Date.Before(chain.Expirations[j].Date)
+	})
+	for _, exp := range chain.Expirations {
+		sort.Slice(exp.Strikes, func(i, j int) bool {
+			return exp.Strikes[i].Strike.ToDecimal().LessThan(exp.Strikes[j].Strike.ToDecimal())
+		})
+	}
+	return chain
+}
+
+// Expiration - Экспирация в день date (по UTC), nil - в этот день опционы цепочки не экспирируются
+func (c *OptionChain) Expiration(date time.Time) *OptionExpiration {
+	y, m, d := date.UTC().Date()
+	for _, exp := range c.Expirations {
+		ey, em, ed := exp.Date.UTC().Date()
+		if y == ey && m == em && d == ed {
+			return exp
+		}
+	}
+	return nil
+}
+
+// Options - Все опционы цепочки по возрастанию экспирации и страйка, колл перед путом
+func (c *OptionChain) Options() []*OptionQuote {
+	var quotes []*OptionQuote
+	for _, exp := range c.Expirations {
+		for _, strike := range exp.Strikes {
+			if strike.Call != nil {
+				quotes = append(quotes, strike.Call)
+			}
+			if strike.Put != nil {
+				quotes = append(quotes, strike.Put)
+			}
+		}
+	}
+	return quotes
+}
+
+// OptionChain - Загрузка цепочки опционов базового актива с ценами последних сделок, базовый актив задается так же,
+// как в InstrumentsServiceClient.OptionsBy
+func (c *Client) OptionChain(basicAssetUid, basicAssetPositionUid string) (*OptionChain, error) {
+	return c.OptionChainCtx(c.ctx, basicAssetUid, basicAssetPositionUid)
+}
+
+// OptionChainCtx - то же, что и OptionChain, но с контекстом запроса ctx
+func (c *Client) OptionChainCtx(ctx context.Context, basicAssetUid, basicAssetPositionUid string) (*OptionChain, error) {
+	resp, err := c.NewInstrumentsServiceClient().OptionsByCtx(ctx, basicAssetUid, basicAssetPositionUid)
+	if err != nil {
+		return nil, err
+	}
+	options := resp.GetInstruments()
+	md := c.NewMarketDataServiceClient()
+	var prices []*pb.LastPrice
+	for start := 0; start < len(options); start += lastPricesBatch {
+		end := start + lastPricesBatch
+		if end > len(options) {
+			end = len(options)
+		}
+		ids := make([]string, 0, end-start)
+		for _, o := range options[start:end] {
+			ids = append(ids, o.GetUid())
+		}
+		lp, err := md.GetLastPricesCtx(ctx, ids)
+		if err != nil {
+			return nil, err
+		}
+		prices = append(prices, lp.GetLastPrices()...)
+	}
+	return NewOptionChain(options, prices), nil
+}
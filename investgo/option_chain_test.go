@@ -0,0 +1,163 @@
+package investgo_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/russianinvestments/invest-api-go-sdk/investgo"
+	pb "github.com/russianinvestments/invest-api-go-sdk/proto"
+)
+
+var (
+	optionCall = pb.OptionDirection_OPTION_DIRECTION_CALL
+	optionPut  = pb.OptionDirection_OPTION_DIRECTION_PUT
+)
+
+func option(uid string, direction pb.OptionDirection, strike int64, expiration time.Time) *pb.Option {
+	return &pb.Option{
+		Uid:                   uid,
+		Ticker:                uid,
+		ClassCode:             "SPBOPT",
+		BasicAsset:            "SBER",
+		BasicAssetPositionUid: "sber-position",
+		Direction:             direction,
+		StrikePrice:           pb.MoneyFromDecimal(decimal.NewFromInt(strike), "rub"),
+		ExpirationDate:        timestamppb.New(expiration),
+	}
+}
+
+// strikes - страйки экспирации в виде "страйк:колл/пут", "-" - опциона нет
+func strikes(exp *investgo.OptionExpiration) []string {
+	uid := func(q *investgo.OptionQuote) string {
+		if q == nil {
+			return "-"
+		}
+		return q.GetUid()
+	}
+	var res []string
+	for _, s := range exp.Strikes {
+		res = append(res, s.Strike.ToDecimal().String()+":"+uid(s.Call)+"/"+uid(s.Put))
+	}
+	return res
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestNewOptionChain(t *testing.T) {
+	march := time.Date(2024, 3, 20, 0, 0, 0, 0, time.UTC)
+	june := time.Date(2024, 6, 19, 0, 0, 0, 0, time.UTC)
+	options := []*pb.Option{
+		option("jun-c-300", optionCall, 300, june),
+		option("mar-c-260", optionCall, 260, march),
+		option("mar-p-250", optionPut, 250, march),
+		option("mar-c-250", optionCall, 250, march),
+		// второй колл на тот же страйк и экспирацию не попадает в цепочку
+		option("mar-c-250-dup", optionCall, 250, march),
+		option("mar-p-240", optionPut, 240, march),
+	}
+	lastPrices := []*pb.LastPrice{
+		{InstrumentUid: "mar-c-250", Price: &pb.Quotation{Units: 12, Nano: 500_000_000}, Time: timestamppb.New(march.AddDate(0, 0, -1))},
+		// цена без времени сделки не используется
+		{InstrumentUid: "mar-p-250", Price: &pb.Quotation{Units: 3}},
+	}
+	chain := investgo.NewOptionChain(options, lastPrices)
+
+	if chain.BasicAsset != "SBER" || chain.BasicAssetPositionUid != "sber-position" || len(chain.Expirations) != 2 {
+		t.Fatalf("chain = %+v", chain)
+	}
+	if !chain.Expirations[0].Date.Equal(march) || !chain.Expirations[1].Date.Equal(june) {
+		t.Fatalf("expirations = %v, %v", chain.Expirations[0].Date, chain.Expirations[1].Date)
+	}
+	if got, want := strikes(chain.Expirations[0]), []string{"240:-/mar-p-240", "250:mar-c-250/mar-p-250", "260:mar-c-260/-"}; !equalStrings(got, want) {
+		t.Fatalf("march strikes = %v, want %v", got, want)
+	}
+	if got, want := strikes(chain.Expirations[1]), []string{"300:jun-c-300/-"}; !equalStrings(got, want) {
+		t.Fatalf("june strikes = %v, want %v", got, want)
+	}
+
+	strike := chain.Expirations[0].Strikes[1]
+	if strike.Call.LastPrice.ToFloat() != 12.5 || !strike.Call.LastPriceTime.Equal(march.AddDate(0, 0, -1)) {
+		t.Fatalf("call last price = %v at %v", strike.Call.LastPrice, strike.Call.LastPriceTime)
+	}
+	if strike.Put.LastPrice != nil || !strike.Put.LastPriceTime.IsZero() {
+		t.Fatalf("put last price = %v", strike.Put.LastPrice)
+	}
+
+	// день экспирации сравнивается по UTC
+	moscow := time.FixedZone("MSK", 3*3600)
+	if exp := chain.Expiration(time.Date(2024, 6, 19, 2, 0, 0, 0, moscow)); exp != nil {
+		t.Fatalf("expiration on 18 june UTC = %v", exp.Date)
+	}
+	if exp := chain.Expiration(time.Date(2024, 6, 19, 12, 0, 0, 0, moscow)); exp != chain.Expirations[1] {
+		t.Fatalf("june expiration = %v", exp)
+	}
+
+	var uids []string
+	for _, q := range chain.Options() {
+		uids = append(uids, q.GetUid())
+	}
+	if want := []string{"mar-p-240", "mar-c-250", "mar-p-250", "mar-c-260", "jun-c-300"}; !equalStrings(uids, want) {
+		t.Fatalf("options = %v, want %v", uids, want)
+	}
+
+	if empty := investgo.NewOptionChain(nil, nil); len(empty.Expirations) != 0 || len(empty.Options()) != 0 {
+		t.Fatalf("empty chain = %+v", empty)
+	}
+}
+
+func TestClientOptionChain(t *testing.T) {
+	srv, client, uids := newTestServer(t, 2)
+	share, err := client.NewInstrumentsServiceClient().ShareByUid(uids[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := client.NewInstrumentsServiceClient().ShareByUid(uids[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	positionUid := share.GetInstrument().GetPositionUid()
+
+	march := time.Date(2024, 3, 20, 0, 0, 0, 0, time.UTC)
+	add := func(ticker string, direction pb.OptionDirection, strike int64, basic *pb.Share) string {
+		o := option("", direction, strike, march)
+		o.Ticker, o.BasicAsset, o.BasicAssetPositionUid = ticker, basic.GetTicker(), basic.GetPositionUid()
+		return srv.AddOption(o)
+	}
+	callUid := add("C250", optionCall, 250, share.GetInstrument())
+	add("P250", optionPut, 250, share.GetInstrument())
+	add("C260", optionCall, 260, share.GetInstrument())
+	// опцион на другой базовый актив не попадает в цепочку
+	add("OTHER", optionCall, 250, other.GetInstrument())
+	setPrice(t, srv, callUid, 12.5)
+
+	chain, err := client.OptionChain("", positionUid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chain.Expirations) != 1 || chain.BasicAssetPositionUid != positionUid {
+		t.Fatalf("chain = %+v", chain)
+	}
+	exp := chain.Expirations[0]
+	if len(exp.Strikes) != 2 || exp.Strikes[0].Call.GetUid() != callUid || exp.Strikes[0].Put == nil || exp.Strikes[1].Put != nil {
+		t.Fatalf("strikes = %v", strikes(exp))
+	}
+	if p := exp.Strikes[0].Call.LastPrice; p.ToFloat() != 12.5 {
+		t.Fatalf("call last price = %v", p)
+	}
+	if p := exp.Strikes[1].Call.LastPrice; p != nil {
+		t.Fatalf("last price without trades = %v", p)
+	}
+}
@@ -16,12 +16,12 @@ type OrderStateStream struct {
 	ctx    context.Context
 	cancel context.CancelFunc
 
-	states chan *pb.OrderStateStreamResponse_OrderState
+	states *streamQueue[*pb.OrderStateStreamResponse_OrderState]
 }
 
 // OrderState - Метод возвращает канал для чтения информации о состоянии поручений
 func (s *OrderStateStream) OrderState() <-chan *pb.OrderStateStreamResponse_OrderState {
-	return s.states
+	return s.states.out()
 }
 
 // Listen - метод начинает слушать стрим и отправлять информацию в канал, для получения канала: OrderState()
@@ -44,7 +44,7 @@ func (s *OrderStateStream) Listen() error {
 			} else {
 				switch resp.GetPayload().(type) {
 				case *pb.OrderStateStreamResponse_OrderState_:
-					s.states <- resp.GetOrderState()
+					s.states.send(resp.GetOrderState())
 				default:
 					s.ordersClient.logger.Infof("info from order state stream %v", resp.String())
 				}
@@ -57,9 +57,15 @@ func (s *OrderStateStream) restart(_ context.Context, attempt uint, err error) {
 	s.ordersClient.logger.Infof("try to restart order state stream err = %v, attempt = %v", err.Error(), attempt)
 }
 
+// Dropped - Метод возвращает количество сообщений, отброшенных при переполнении канала стрима. Сообщения
+// отбрасываются только с политикой переполнения, заданной опцией WithBuffer
+func (s *OrderStateStream) Dropped() uint64 {
+	return s.states.droppedCount()
+}
+
 func (s *OrderStateStream) shutdown() {
 	s.ordersClient.logger.Infof("close order state stream")
-	close(s.states)
+	s.states.close()
 }
 
 // Stop - Завершение работы стрима
@@ -19,8 +19,13 @@ type OrdersServiceClient struct {
 
 // PostOrder - Метод выставления биржевой заявки
 func (os *OrdersServiceClient) PostOrder(req *PostOrderRequest) (*PostOrderResponse, error) {
+	return os.PostOrderCtx(os.ctx, req)
+}
+
+// PostOrderCtx - то же, что и PostOrder, но с контекстом запроса ctx
+func (os *OrdersServiceClient) PostOrderCtx(ctx context.Context, req *PostOrderRequest) (*PostOrderResponse, error) {
 	var header, trailer metadata.MD
-	resp, err := os.pbClient.PostOrder(os.ctx, &pb.PostOrderRequest{
+	resp, err := os.pbClient.PostOrder(ctx, &pb.PostOrderRequest{
 		Quantity:     req.Quantity,
 		Price:        req.Price,
 		Direction:    req.Direction,
@@ -42,8 +47,13 @@ func (os *OrdersServiceClient) PostOrder(req *PostOrderRequest) (*PostOrderRespo
 
 // Buy - Метод выставления поручения на покупку инструмента
 func (os *OrdersServiceClient) Buy(req *PostOrderRequestShort) (*PostOrderResponse, error) {
+	return os.BuyCtx(os.ctx, req)
+}
+
+// BuyCtx - то же, что и Buy, но с контекстом запроса ctx
+func (os *OrdersServiceClient) BuyCtx(ctx context.Context, req *PostOrderRequestShort) (*PostOrderResponse, error) {
 	var header, trailer metadata.MD
-	resp, err := os.pbClient.PostOrder(os.ctx, &pb.PostOrderRequest{
+	resp, err := os.pbClient.PostOrder(ctx, &pb.PostOrderRequest{
 		Quantity:     req.Quantity,
 		Price:        req.Price,
 		Direction:    pb.OrderDirection_ORDER_DIRECTION_BUY,
@@ -63,8 +73,13 @@ func (os *OrdersServiceClient) Buy(req *PostOrderRequestShort) (*PostOrderRespon
 
 // Sell - Метод выставления поручения на продажу инструмента
 func (os *OrdersServiceClient) Sell(req *PostOrderRequestShort) (*PostOrderResponse, error) {
+	return os.SellCtx(os.ctx, req)
+}
+
+// SellCtx - то же, что и Sell, но с контекстом запроса ctx
+func (os *OrdersServiceClient) SellCtx(ctx context.Context, req *PostOrderRequestShort) (*PostOrderResponse, error) {
 	var header, trailer metadata.MD
-	resp, err := os.pbClient.PostOrder(os.ctx, &pb.PostOrderRequest{
+	resp, err := os.pbClient.PostOrder(ctx, &pb.PostOrderRequest{
 		Quantity:     req.Quantity,
 		Price:        req.Price,
 		Direction:    pb.OrderDirection_ORDER_DIRECTION_SELL,
@@ -84,8 +99,13 @@ func (os *OrdersServiceClient) Sell(req *PostOrderRequestShort) (*PostOrderRespo
 
 // CancelOrder - Метод отмены биржевой заявки
 func (os *OrdersServiceClient) CancelOrder(accountId, orderId string, idType *pb.OrderIdType) (*CancelOrderResponse, error) {
+	return os.CancelOrderCtx(os.ctx, accountId, orderId, idType)
+}
+
+// CancelOrderCtx - то же, что и CancelOrder, но с контекстом запроса ctx
+func (os *OrdersServiceClient) CancelOrderCtx(ctx context.Context, accountId, orderId string, idType *pb.OrderIdType) (*CancelOrderResponse, error) {
 	var header, trailer metadata.MD
-	resp, err := os.pbClient.CancelOrder(os.ctx, &pb.CancelOrderRequest{
+	resp, err := os.pbClient.CancelOrder(ctx, &pb.CancelOrderRequest{
 		AccountId:   accountId,
 		OrderId:     orderId,
 		OrderIdType: idType,
@@ -101,8 +121,13 @@ func (os *OrdersServiceClient) CancelOrder(accountId, orderId string, idType *pb
 
 // GetOrderState - Метод получения статуса торгового поручения
 func (os *OrdersServiceClient) GetOrderState(accountId, orderId string, priceType pb.PriceType, orderIDType *pb.OrderIdType) (*GetOrderStateResponse, error) {
+	return os.GetOrderStateCtx(os.ctx, accountId, orderId, priceType, orderIDType)
+}
+
+// GetOrderStateCtx - то же, что и GetOrderState, но с контекстом запроса ctx
+func (os *OrdersServiceClient) GetOrderStateCtx(ctx context.Context, accountId, orderId string, priceType pb.PriceType, orderIDType *pb.OrderIdType) (*GetOrderStateResponse, error) {
 	var header, trailer metadata.MD
-	resp, err := os.pbClient.GetOrderState(os.ctx, &pb.GetOrderStateRequest{
+	resp, err := os.pbClient.GetOrderState(ctx, &pb.GetOrderStateRequest{
 		AccountId:   accountId,
 		OrderId:     orderId,
 		PriceType:   priceType,
@@ -119,8 +144,13 @@ func (os *OrdersServiceClient) GetOrderState(accountId, orderId string, priceTyp
 
 // GetOrders - Метод получения списка активных заявок по счёту
 func (os *OrdersServiceClient) GetOrders(accountId string) (*GetOrdersResponse, error) {
+	return os.GetOrdersCtx(os.ctx, accountId)
+}
+
+// GetOrdersCtx - то же, что и GetOrders, но с контекстом запроса ctx
+func (os *OrdersServiceClient) GetOrdersCtx(ctx context.Context, accountId string) (*GetOrdersResponse, error) {
 	var header, trailer metadata.MD
-	resp, err := os.pbClient.GetOrders(os.ctx, &pb.GetOrdersRequest{
+	resp, err := os.pbClient.GetOrders(ctx, &pb.GetOrdersRequest{
 		AccountId: accountId,
 	}, grpc.Header(&header), grpc.Trailer(&trailer))
 	if err != nil {
@@ -134,8 +164,13 @@ func (os *OrdersServiceClient) GetOrders(accountId string) (*GetOrdersResponse,
 
 // ReplaceOrder - Метод изменения выставленной заявки
 func (os *OrdersServiceClient) ReplaceOrder(req *ReplaceOrderRequest) (*PostOrderResponse, error) {
+	return os.ReplaceOrderCtx(os.ctx, req)
+}
+
+// ReplaceOrderCtx - то же, что и ReplaceOrder, но с контекстом запроса ctx
+func (os *OrdersServiceClient) ReplaceOrderCtx(ctx context.Context, req *ReplaceOrderRequest) (*PostOrderResponse, error) {
 	var header, trailer metadata.MD
-	resp, err := os.pbClient.ReplaceOrder(os.ctx, &pb.ReplaceOrderRequest{
+	resp, err := os.pbClient.ReplaceOrder(ctx, &pb.ReplaceOrderRequest{
 		AccountId:      req.AccountId,
 		OrderId:        req.OrderId,
 		IdempotencyKey: req.NewOrderId,
@@ -154,8 +189,13 @@ func (os *OrdersServiceClient) ReplaceOrder(req *ReplaceOrderRequest) (*PostOrde
 
 // GetMaxLots - Расчет количества доступных для покупки/продажи лотов
 func (os *OrdersServiceClient) GetMaxLots(accountID, instrumentID string, price *pb.Quotation) (*GetMaxLotsResponse, error) {
+	return os.GetMaxLotsCtx(os.ctx, accountID, instrumentID, price)
+}
+
+// GetMaxLotsCtx - то же, что и GetMaxLots, но с контекстом запроса ctx
+func (os *OrdersServiceClient) GetMaxLotsCtx(ctx context.Context, accountID, instrumentID string, price *pb.Quotation) (*GetMaxLotsResponse, error) {
 	var header, trailer metadata.MD
-	resp, err := os.pbClient.GetMaxLots(os.ctx, &pb.GetMaxLotsRequest{
+	resp, err := os.pbClient.GetMaxLots(ctx, &pb.GetMaxLotsRequest{
 		AccountId:    accountID,
 		InstrumentId: instrumentID,
 		Price:        price,
@@ -171,9 +211,15 @@ func (os *OrdersServiceClient) GetMaxLots(accountID, instrumentID string, price
 
 // GetOrderPrice - Метод получения предварительной стоимости для лимитной заявки
 func (os *OrdersServiceClient) GetOrderPrice(accountID, instrumentID string, price *pb.Quotation,
+	direction pb.OrderDirection, quantity int64) (*GetOrderPriceResponse, error) {
+	return os.GetOrderPriceCtx(os.ctx, accountID, instrumentID, price, direction, quantity)
+}
+
+// GetOrderPriceCtx - то же, что и GetOrderPrice, но с контекстом запроса ctx
+func (os *OrdersServiceClient) GetOrderPriceCtx(ctx context.Context, accountID, instrumentID string, price *pb.Quotation,
 	direction pb.OrderDirection, quantity int64) (*GetOrderPriceResponse, error) {
 	var header, trailer metadata.MD
-	resp, err := os.pbClient.GetOrderPrice(os.ctx, &pb.GetOrderPriceRequest{
+	resp, err := os.pbClient.GetOrderPrice(ctx, &pb.GetOrderPriceRequest{
 		AccountId:    accountID,
 		InstrumentId: instrumentID,
 		Price:        price,
@@ -191,8 +237,13 @@ func (os *OrdersServiceClient) GetOrderPrice(accountID, instrumentID string, pri
 
 // PostOrderAsync - Метод выставления биржевой заявки асинхронно
 func (os *OrdersServiceClient) PostOrderAsync(req *PostOrderRequest) (*PostOrderAsyncResponse, error) {
+	return os.PostOrderAsyncCtx(os.ctx, req)
+}
+
+// PostOrderAsyncCtx - то же, что и PostOrderAsync, но с контекстом запроса ctx
+func (os *OrdersServiceClient) PostOrderAsyncCtx(ctx context.Context, req *PostOrderRequest) (*PostOrderAsyncResponse, error) {
 	var header, trailer metadata.MD
-	resp, err := os.pbClient.PostOrderAsync(os.ctx, &pb.PostOrderAsyncRequest{
+	resp, err := os.pbClient.PostOrderAsync(ctx, &pb.PostOrderAsyncRequest{
 		Quantity:     req.Quantity,
 		Price:        req.Price,
 		Direction:    req.Direction,
@@ -18,12 +18,18 @@ type OrdersStreamClient struct {
 }
 
 // TradesStream - Стрим сделок по запрашиваемым аккаунтам
-func (o *OrdersStreamClient) TradesStream(accounts []string, pingDelayMs *int32) (*TradesStream, error) {
-	ctx, cancel := context.WithCancel(o.ctx)
+func (o *OrdersStreamClient) TradesStream(accounts []string, pingDelayMs *int32, opts ...StreamOption) (*TradesStream, error) {
+	return o.TradesStreamCtx(o.ctx, accounts, pingDelayMs, opts...)
+}
+
+// TradesStreamCtx - то же, что и TradesStream, но с контекстом запроса ctx
+func (o *OrdersStreamClient) TradesStreamCtx(ctx context.Context, accounts []string, pingDelayMs *int32, opts ...StreamOption) (*TradesStream, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	options := newStreamOptions(opts)
 	ts := &TradesStream{
 		stream:       nil,
 		ordersClient: o,
-		trades:       make(chan *pb.OrderTrades),
+		trades:       newStreamQueue[*pb.OrderTrades](options.size(0), options.overflow, nil, nil),
 		ctx:          ctx,
 		cancel:       cancel,
 	}
@@ -40,14 +46,22 @@ func (o *OrdersStreamClient) TradesStream(accounts []string, pingDelayMs *int32)
 }
 
 // OrderStateStream - Стрим информации по заявкам
-func (o *OrdersStreamClient) OrderStateStream(accounts []string, pingDelayMills int32) (*OrderStateStream, error) {
-	ctx, cancel := context.WithCancel(o.ctx)
+func (o *OrdersStreamClient) OrderStateStream(accounts []string, pingDelayMills int32, opts ...StreamOption) (*OrderStateStream, error) {
+	return o.OrderStateStreamCtx(o.ctx, accounts, pingDelayMills, opts...)
+}
+
+// OrderStateStreamCtx - то же, что и OrderStateStream, но с контекстом запроса ctx
+func (o *OrdersStreamClient) OrderStateStreamCtx(ctx context.Context, accounts []string, pingDelayMills int32, opts ...StreamOption) (*OrderStateStream, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	options := newStreamOptions(opts)
 	os := &OrderStateStream{
 		stream:       nil,
 		ordersClient: o,
-		states:       make(chan *pb.OrderStateStreamResponse_OrderState),
-		ctx:          ctx,
-		cancel:       cancel,
+		states: newStreamQueue(options.size(0), options.overflow, func(s *pb.OrderStateStreamResponse_OrderState) string {
+			return s.GetOrderId()
+		}, nil),
+		ctx:    ctx,
+		cancel: cancel,
 	}
 	stream, err := o.pbClient.OrderStateStream(ctx, &pb.OrderStateStreamRequest{
 		Accounts:        accounts,
@@ -15,12 +15,12 @@ type PortfolioStream struct {
 	ctx    context.Context
 	cancel context.CancelFunc
 
-	portfolios chan *pb.PortfolioResponse
+	portfolios *streamQueue[*pb.PortfolioResponse]
 }
 
 // Portfolios - Метод возвращает канал для чтения обновлений портфеля
 func (p *PortfolioStream) Portfolios() <-chan *pb.PortfolioResponse {
-	return p.portfolios
+	return p.portfolios.out()
 }
 
 // Listen - метод начинает слушать стрим и отправлять информацию в канал, для получения канала: Portfolios()
@@ -43,7 +43,7 @@ func (p *PortfolioStream) Listen() error {
 			} else {
 				switch resp.GetPayload().(type) {
 				case *pb.PortfolioStreamResponse_Portfolio:
-					p.portfolios <- resp.GetPortfolio()
+					p.portfolios.send(resp.GetPortfolio())
 				default:
 					p.operationsClient.logger.Infof("info from Portfolio stream %v", resp.String())
 				}
@@ -56,9 +56,15 @@ func (p *PortfolioStream) restart(_ context.Context, attempt uint, err error) {
 	p.operationsClient.logger.Infof("try to restart portfolio stream err = %v, attempt = %v", err.Error(), attempt)
 }
 
+// Dropped - Метод возвращает количество сообщений, отброшенных при переполнении канала стрима. Сообщения
+// отбрасываются только с политикой переполнения, заданной опцией WithBuffer
+func (p *PortfolioStream) Dropped() uint64 {
+	return p.portfolios.droppedCount()
+}
+
 func (p *PortfolioStream) shutdown() {
 	p.operationsClient.logger.Infof("close portfolio stream")
-	close(p.portfolios)
+	p.portfolios.close()
 }
 
 // Stop - Завершение работы стрима
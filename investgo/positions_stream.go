@@ -16,12 +16,12 @@ type PositionsStream struct {
 	ctx    context.Context
 	cancel context.CancelFunc
 
-	positions chan *pb.PositionData
+	positions *streamQueue[*pb.PositionData]
 }
 
 // Positions - Метод возвращает канал для чтения обновлений информации по изменению позиций портфеля
 func (p *PositionsStream) Positions() <-chan *pb.PositionData {
-	return p.positions
+	return p.positions.out()
 }
 
 // Listen - метод начинает слушать стрим и отправлять информацию в канал, для получения канала: Positions()
@@ -44,7 +44,7 @@ func (p *PositionsStream) Listen() error {
 			} else {
 				switch resp.GetPayload().(type) {
 				case *pb.PositionsStreamResponse_Position:
-					p.positions <- resp.GetPosition()
+					p.positions.send(resp.GetPosition())
 				default:
 					p.operationsClient.logger.Infof("info from Positions stream %v", resp.String())
 				}
@@ -57,9 +57,15 @@ func (p *PositionsStream) restart(_ context.Context, attempt uint, err error) {
 	p.operationsClient.logger.Infof("try to restart positions stream err = %v, attempt = %v", err.Error(), attempt)
 }
 
+// Dropped - Метод возвращает количество сообщений, отброшенных при переполнении канала стрима. Сообщения
+// отбрасываются только с политикой переполнения, заданной опцией WithBuffer
+func (p *PositionsStream) Dropped() uint64 {
+	return p.positions.droppedCount()
+}
+
 func (p *PositionsStream) shutdown() {
 	p.operationsClient.logger.Infof("close positions stream")
-	close(p.positions)
+	p.positions.close()
 }
 
 // Stop - Завершение работы стрима
@@ -0,0 +1,199 @@
+package investgo
+
+import (
+	"context"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// DefaultRateLimits - лимиты unary запросов в минуту по сервисам по умолчанию, https://tinkoff.github.io/investAPI/limits/
+var DefaultRateLimits = map[string]int{
+	"InstrumentsService": 200,
+	"UsersService":       100,
+	"OperationsService":  200,
+	"MarketDataService":  600,
+	"OrdersService":      100,
+	"StopOrdersService":  50,
+	"SandboxService":     200,
+	"SignalService":      100,
+}
+
+// RateLimiter - ограничитель частоты unary запросов. Для каждого сервиса (или отдельного метода) ведется свой
+// token bucket: запросы сверх лимита не отправляются на сервер, а ждут своей очереди в порядке вызова. Остаток
+// лимита уточняется по заголовкам ответов x-ratelimit-remaining и x-ratelimit-reset
+type RateLimiter struct {
+	limits map[string]int
+
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+}
+
+// NewRateLimiter - Создание ограничителя с лимитами запросов в минуту limits. Ключ лимита - имя сервиса, например
+// MarketDataService, или сервис и метод, например MarketDataService/GetCandles, лимит метода важнее лимита сервиса.
+// Запросы к сервисам без лимита не ограничиваются
+func NewRateLimiter(limits map[string]int) *RateLimiter {
+	l := &RateLimiter{
+		limits:  make(map[string]int, len(limits)),
+		buckets: make(map[string]*tokenBucket),
+	}
+	for k, v := range limits {
+		l.limits[k] = v
+	}
+	return l
+}
+
+// UnaryClientInterceptor - Интерсептор, который ждет очереди перед каждым запросом, в том числе перед повторами
+// ретраера, поэтому в цепочке он должен идти после интерсепторов ретраера
+func (l *RateLimiter) UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		b := l.bucket(method)
+		if b == nil {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+		if err := b.wait(ctx); err != nil {
+			return err
+		}
+		var header, trailer metadata.MD
+		opts = append(opts, grpc.Header(&header), grpc.Trailer(&trailer))
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		if status.Code(err) == codes.ResourceExhausted {
+			b.update(0, RateLimitResetFromHeader(trailer))
+			return err
+		}
+		if err != nil {
+			header = trailer
+		}
+		b.update(RemainingLimitFromHeader(header), RateLimitResetFromHeader(header))
+		return err
+	}
+}
+
+// Wait - Ожидание очереди на запрос к методу method в формате /package.Service/Method
+func (l *RateLimiter) Wait(ctx context.Context, method string) error {
+	if b := l.bucket(method); b != nil {
+		return b.wait(ctx)
+	}
+	return nil
+}
+
+// bucket - token bucket метода, nil если для метода нет лимита
+func (l *RateLimiter) bucket(method string) *tokenBucket {
+	service, name := splitMethod(method)
+	key := service + "/" + name
+	limit, ok := l.limits[key]
+	if !ok {
+		key = service
+		limit, ok = l.limits[key]
+	}
+	if !ok || limit <= 0 {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	b, ok := l.buckets[key]
+	if !ok {
+		b = newTokenBucket(limit, time.Minute)
+		l.buckets[key] = b
+	}
+	return b
+}
+
+// splitMethod - /tinkoff.public.invest.api.contract.v1.MarketDataService/GetCandles -> MarketDataService, GetCandles
+func splitMethod(method string) (string, string) {
+	method = strings.TrimPrefix(method, "/")
+	service, name, _ := strings.Cut(method, "/")
+	if i := strings.LastIndex(service, "."); i >= 0 {
+		service = service[i+1:]
+	}
+	return service, name
+}
+
+// tokenBucket - limit запросов за period, запросы ждут в порядке вызова
+type tokenBucket struct {
+	mu       sync.Mutex
+	capacity float64
+	rate     float64 // токенов в секунду
+	tokens   float64
+	last     time.Time
+	// blocked - до этого времени запросы не отправляются, сервер сообщил, что лимит исчерпан
+	blocked time.Time
+}
+
+func newTokenBucket(limit int, period time.Duration) *tokenBucket {
+	return &tokenBucket{
+		capacity: float64(limit),
+		rate:     float64(limit) / period.Seconds(),
+		tokens:   float64(limit),
+		last:     time.Now(),
+	}
+}
+
+func (b *tokenBucket) refillLocked(now time.Time) {
+	if now.After(b.last) {
+		b.tokens += now.Sub(b.last).Seconds() * b.rate
+		if b.tokens > b.capacity {
+			b.tokens = b.capacity
+		}
+		b.last = now
+	}
+}
+
+// wait - резервирование токена и ожидание его появления. Токены резервируются под мьютексом, поэтому горутины
+// получают их в порядке вызова. Если во время ожидания сервер сообщил об исчерпании лимита, ожидание
+// продлевается до blocked
+func (b *tokenBucket) wait(ctx context.Context) error {
+	b.mu.Lock()
+	now := time.Now()
+	b.refillLocked(now)
+	b.tokens--
+	delay := time.Duration(0)
+	if b.tokens < 0 {
+		delay = time.Duration(-b.tokens / b.rate * float64(time.Second))
+	}
+	if until := b.blocked.Sub(now); until > delay {
+		delay = until
+	}
+	b.mu.Unlock()
+
+	for delay > 0 {
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			// запрос не будет отправлен, токен возвращается
+			b.mu.Lock()
+			b.tokens = math.Min(b.tokens+1, b.capacity)
+			b.mu.Unlock()
+			return ctx.Err()
+		}
+		b.mu.Lock()
+		delay = time.Until(b.blocked)
+		b.mu.Unlock()
+	}
+	return nil
+}
+
+// update - уточнение остатка лимита по ответу сервера, remaining < 0 - остаток неизвестен
+func (b *tokenBucket) update(remaining int, reset time.Duration) {
+	if remaining < 0 {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := time.Now()
+	b.refillLocked(now)
+	if float64(remaining) < b.tokens {
+		b.tokens = float64(remaining)
+	}
+	if remaining == 0 && reset > 0 && now.Add(reset).After(b.blocked) {
+		b.blocked = now.Add(reset)
+	}
+}
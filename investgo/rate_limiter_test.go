@@ -0,0 +1,183 @@
+package investgo_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/russianinvestments/invest-api-go-sdk/investgo"
+)
+
+const candlesMethod = "/tinkoff.public.invest.api.contract.v1.MarketDataService/GetCandles"
+
+// limitedInvoker - invoker, который отвечает заголовками x-ratelimit-remaining и x-ratelimit-reset
+func limitedInvoker(remaining, reset string, err error) grpc.UnaryInvoker {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		md := metadata.Pairs("x-ratelimit-remaining", remaining, "x-ratelimit-reset", reset)
+		for _, opt := range opts {
+			switch o := opt.(type) {
+			case grpc.HeaderCallOption:
+				if err == nil {
+					*o.HeaderAddr = md
+				}
+			case grpc.TrailerCallOption:
+				*o.TrailerAddr = md
+			}
+		}
+		return err
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	// 6000 запросов в минуту - токен каждые 10 мс
+	l := investgo.NewRateLimiter(map[string]int{"MarketDataService": 6000})
+	start := time.Now()
+	for i := 0; i < 6000; i++ {
+		if err := l.Wait(context.Background(), candlesMethod); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if d := time.Since(start); d > 500*time.Millisecond {
+		t.Fatalf("burst took %v", d)
+	}
+	// сверх лимита запросы ждут новых токенов: 6005-й запрос отправляется не раньше чем через 50 мс от первого
+	for i := 0; i < 5; i++ {
+		if err := l.Wait(context.Background(), candlesMethod); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if d := time.Since(start); d < 45*time.Millisecond {
+		t.Fatalf("6005 requests took %v", d)
+	}
+
+	// отмена контекста прерывает ожидание
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+	l = investgo.NewRateLimiter(map[string]int{"MarketDataService": 1})
+	if err := l.Wait(ctx, candlesMethod); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Wait(ctx, candlesMethod); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+func TestRateLimiterKeys(t *testing.T) {
+	l := investgo.NewRateLimiter(map[string]int{
+		"MarketDataService":            1,
+		"MarketDataService/GetCandles": 6000,
+	})
+	wait := func(method string) error {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		return l.Wait(ctx, method)
+	}
+	// лимит метода важнее лимита сервиса
+	for i := 0; i < 10; i++ {
+		if err := wait(candlesMethod); err != nil {
+			t.Fatalf("GetCandles: %v", err)
+		}
+	}
+	// остальные методы сервиса делят лимит сервиса
+	lastPrices := "/tinkoff.public.invest.api.contract.v1.MarketDataService/GetLastPrices"
+	if err := wait(lastPrices); err != nil {
+		t.Fatal(err)
+	}
+	if err := wait("/tinkoff.public.invest.api.contract.v1.MarketDataService/GetOrderBook"); err == nil {
+		t.Fatal("service limit not applied")
+	}
+	// сервисы без лимита не ограничиваются
+	for i := 0; i < 10; i++ {
+		if err := wait("/tinkoff.public.invest.api.contract.v1.UsersService/GetAccounts"); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestRateLimiterHeaders(t *testing.T) {
+	l := investgo.NewRateLimiter(map[string]int{"MarketDataService": 6000})
+	interceptor := l.UnaryClientInterceptor()
+	call := func(ctx context.Context, invoker grpc.UnaryInvoker) error {
+		return interceptor(ctx, candlesMethod, nil, nil, nil, invoker)
+	}
+
+	// сервер сообщил, что лимит исчерпан на секунду: следующие запросы ждут сброса
+	if err := call(context.Background(), limitedInvoker("0", "1", nil)); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	err := l.Wait(ctx, candlesMethod)
+	cancel()
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("wait after remaining = 0: %v", err)
+	}
+	start := time.Now()
+	if err := l.Wait(context.Background(), candlesMethod); err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(start); d < 500*time.Millisecond {
+		t.Fatalf("waited %v after reset", d)
+	}
+
+	// остаток из заголовка уменьшает число токенов
+	l = investgo.NewRateLimiter(map[string]int{"MarketDataService": 6000})
+	interceptor = l.UnaryClientInterceptor()
+	start = time.Now()
+	if err := call(context.Background(), limitedInvoker("1", "60", nil)); err != nil {
+		t.Fatal(err)
+	}
+	// после ответа остался один токен, второй запрос ждет еще один токен 10 мс
+	for i := 0; i < 2; i++ {
+		if err := l.Wait(context.Background(), candlesMethod); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if d := time.Since(start); d < 9*time.Millisecond {
+		t.Fatalf("waited %v after remaining = 1", d)
+	}
+}
+
+func TestRateLimiterResetWhileWaiting(t *testing.T) {
+	// 600 запросов в минуту - токен каждые 100 мс, последний токен достается запросу через интерсептор
+	l := investgo.NewRateLimiter(map[string]int{"MarketDataService": 600})
+	for i := 0; i < 599; i++ {
+		if err := l.Wait(context.Background(), candlesMethod); err != nil {
+			t.Fatal(err)
+		}
+	}
+	release := make(chan struct{})
+	called := make(chan error)
+	go func() {
+		invoker := limitedInvoker("0", "1", status.Error(codes.ResourceExhausted, "limit"))
+		called <- l.UnaryClientInterceptor()(context.Background(), candlesMethod, nil, nil, nil,
+			func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+				<-release
+				return invoker(ctx, method, req, reply, cc, opts...)
+			})
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	start := time.Now()
+	done := make(chan time.Duration)
+	go func() {
+		if err := l.Wait(context.Background(), candlesMethod); err != nil {
+			t.Error(err)
+		}
+		done <- time.Since(start)
+	}()
+	// пока запрос ждет токен, ответ ResourceExhausted блокирует запросы на секунду
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	if err := <-called; status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("err = %v", err)
+	}
+	if d := <-done; d < 900*time.Millisecond {
+		t.Fatalf("waiter resumed after %v, before reset", d)
+	}
+}
@@ -0,0 +1,212 @@
+package investgo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// виды записей в файле сессии
+const (
+	recordUnary = "unary"
+	recordOpen  = "open"
+	recordSend  = "send"
+	recordRecv  = "recv"
+	recordClose = "close"
+)
+
+// record - одна строка файла сессии
+type record struct {
+	// Time - время ответа сервера, для send - время отправки сообщения
+	Time   time.Time `json:"time"`
+	Kind   string    `json:"kind"`
+	Method string    `json:"method"`
+	// Stream - номер стрима в сессии
+	Stream uint64 `json:"stream,omitempty"`
+
+	Request  json.RawMessage `json:"request,omitempty"`
+	Response json.RawMessage `json:"response,omitempty"`
+
+	Header  metadata.MD `json:"header,omitempty"`
+	Trailer metadata.MD `json:"trailer,omitempty"`
+	// Code, Message - статус grpc, если запрос или стрим завершились ошибкой
+	Code    codes.Code `json:"code,omitempty"`
+	Message string     `json:"message,omitempty"`
+}
+
+func (r *record) setError(err error) {
+	st := status.Convert(err)
+	r.Code = st.Code()
+	r.Message = st.Message()
+}
+
+func marshalMessage(m any) json.RawMessage {
+	msg, ok := m.(proto.Message)
+	if !ok {
+		return nil
+	}
+	b, err := protojson.Marshal(msg)
+	if err != nil {
+		return nil
+	}
+	return b
+}
+
+// Recorder - запись сессии работы с API в файл для последующего воспроизведения через Replayer. Каждый unary запрос
+// с ответом, открытие стрима, сообщения стрима в обе стороны и его завершение пишутся отдельной JSON строкой со
+// временем. Интерсепторы рекордера подключаются через DialOptions при создании клиента:
+//
+//	rec, err := investgo.NewRecorder("session.jsonl")
+//	...
+//	defer rec.Close()
+//	client, err := investgo.NewClient(ctx, config, logger, rec.DialOptions()...)
+type Recorder struct {
+	mu  sync.Mutex
+	c   io.Closer
+	enc *json.Encoder
+	err error
+
+	streams atomic.Uint64
+}
+
+// NewRecorder - Создание рекордера, который пишет сессию в файл path. Существующий файл перезаписывается
+func NewRecorder(path string) (*Recorder, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	r := NewRecorderWriter(f)
+	r.c = f
+	return r, nil
+}
+
+// NewRecorderWriter - Создание рекордера, который пишет сессию в w
+func NewRecorderWriter(w io.Writer) *Recorder {
+	return &Recorder{enc: json.NewEncoder(w)}
+}
+
+// DialOptions - Опции подключения с интерсепторами рекордера для NewClient
+func (r *Recorder) DialOptions() []grpc.DialOption {
+	return []grpc.DialOption{
+		grpc.WithChainUnaryInterceptor(r.UnaryClientInterceptor()),
+		grpc.WithChainStreamInterceptor(r.StreamClientInterceptor()),
+	}
+}
+
+// Err - Первая ошибка записи в файл. Ошибки записи не влияют на запросы к API
+func (r *Recorder) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+// Close - Закрытие файла сессии
+func (r *Recorder) Close() error {
+	if r.c == nil {
+		return r.Err()
+	}
+	return errors.Join(r.Err(), r.c.Close())
+}
+
+func (r *Recorder) write(rec *record) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return
+	}
+	r.err = r.enc.Encode(rec)
+}
+
+// UnaryClientInterceptor - Интерсептор, который записывает unary запросы и ответы на них
+func (r *Recorder) UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		var header, trailer metadata.MD
+		opts = append(opts, grpc.Header(&header), grpc.Trailer(&trailer))
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		rec := &record{
+			Time:    time.Now(),
+			Kind:    recordUnary,
+			Method:  method,
+			Request: marshalMessage(req),
+			Header:  header,
+			Trailer: trailer,
+		}
+		if err != nil {
+			rec.setError(err)
+		} else {
+			rec.Response = marshalMessage(reply)
+		}
+		r.write(rec)
+		return err
+	}
+}
+
+// StreamClientInterceptor - Интерсептор, который записывает открытие стримов и все их сообщения
+func (r *Recorder) StreamClientInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		id := r.streams.Add(1)
+		cs, err := streamer(ctx, desc, cc, method, opts...)
+		rec := &record{Time: time.Now(), Kind: recordOpen, Method: method, Stream: id}
+		if err != nil {
+			rec.setError(err)
+		}
+		r.write(rec)
+		if err != nil {
+			return nil, err
+		}
+		return &recordingStream{ClientStream: cs, r: r, method: method, id: id}, nil
+	}
+}
+
+// recordingStream - стрим, который записывает свои сообщения
+type recordingStream struct {
+	grpc.ClientStream
+	r      *Recorder
+	method string
+	id     uint64
+
+	headerOnce sync.Once
+	closed     atomic.Bool
+}
+
+func (s *recordingStream) SendMsg(m any) error {
+	err := s.ClientStream.SendMsg(m)
+	if err == nil {
+		s.r.write(&record{Time: time.Now(), Kind: recordSend, Method: s.method, Stream: s.id, Request: marshalMessage(m)})
+	}
+	return err
+}
+
+func (s *recordingStream) RecvMsg(m any) error {
+	err := s.ClientStream.RecvMsg(m)
+	if err != nil {
+		// после ошибки стрим завершен, повторные вызовы RecvMsg не записываются
+		if s.closed.CompareAndSwap(false, true) {
+			rec := &record{Time: time.Now(), Kind: recordClose, Method: s.method, Stream: s.id, Trailer: s.Trailer()}
+			if !errors.Is(err, io.EOF) {
+				rec.setError(err)
+			}
+			s.r.write(rec)
+		}
+		return err
+	}
+	rec := &record{Time: time.Now(), Kind: recordRecv, Method: s.method, Stream: s.id, Response: marshalMessage(m)}
+	s.headerOnce.Do(func() {
+		// заголовки ответа к этому моменту уже получены, Header не блокируется
+		rec.Header, _ = s.Header()
+	})
+	s.r.write(rec)
+	return nil
+}
@@ -0,0 +1,305 @@
+package investgo
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// ErrNoRecordedResponse - в записанной сессии не осталось ответов на запрос
+var ErrNoRecordedResponse = errors.New("replay: no recorded response")
+
+// errReplayNetwork - клиент в режиме воспроизведения не подключается к серверу
+var errReplayNetwork = errors.New("replay: network is disabled")
+
+// ReplayerConfig - конфигурация воспроизведения сессии
+type ReplayerConfig struct {
+	// Path - файл сессии, записанный Recorder
+	Path string
+	// RecordedSpeed - ответы и сообщения стримов отдаются с теми же интервалами, что и при записи, отсчет идет от
+	// первого запроса при воспроизведении. По умолчанию ответы отдаются сразу
+	RecordedSpeed bool
+}
+
+// Replayer - воспроизведение сессии, записанной Recorder, без подключения к серверу. На каждый unary запрос
+// отдается следующий записанный ответ того же метода, каждый новый стрим получает сообщения следующего
+// записанного стрима того же метода. Сообщения, которые клиент отправляет в стрим, не проверяются. Если запись
+// стрима закончилась раньше, чем он был закрыт, стрим остается открытым до отмены контекста:
+//
+//	rep, err := investgo.NewReplayer(investgo.ReplayerConfig{Path: "session.jsonl"})
+//	...
+//	client, err := investgo.NewClient(ctx, config, logger, rep.DialOptions()...)
+type Replayer struct {
+	conf ReplayerConfig
+
+	mu      sync.Mutex
+	unary   map[string][]*record
+	streams map[string][]*replayedStream
+	// start, recorded - момент начала воспроизведения и время первой записи сессии
+	start    time.Time
+	recorded time.Time
+}
+
+// replayedStream - записанный стрим: его ответы, завершение и заголовки
+type replayedStream struct {
+	open    *record
+	records []*record
+}
+
+// NewReplayer - Загрузка сессии из файла conf.Path
+func NewReplayer(conf ReplayerConfig) (*Replayer, error) {
+	f, err := os.Open(conf.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return NewReplayerReader(f, conf)
+}
+
+// NewReplayerReader - Загрузка сессии из r, conf.Path не используется
+func NewReplayerReader(r io.Reader, conf ReplayerConfig) (*Replayer, error) {
+	rep := &Replayer{
+		conf:    conf,
+		unary:   make(map[string][]*record),
+		streams: make(map[string][]*replayedStream),
+	}
+	byId := make(map[uint64]*replayedStream)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		rec := &record{}
+		if err := json.Unmarshal(scanner.Bytes(), rec); err != nil {
+			return nil, fmt.Errorf("replay: line %v: %w", line, err)
+		}
+		if rep.recorded.IsZero() {
+			rep.recorded = rec.Time
+		}
+		switch rec.Kind {
+		case recordUnary:
+			rep.unary[rec.Method] = append(rep.unary[rec.Method], rec)
+		case recordOpen:
+			st := &replayedStream{open: rec}
+			byId[rec.Stream] = st
+			rep.streams[rec.Method] = append(rep.streams[rec.Method], st)
+		case recordRecv, recordClose:
+			st, ok := byId[rec.Stream]
+			if !ok {
+				return nil, fmt.Errorf("replay: line %v: unknown stream %v", line, rec.Stream)
+			}
+			st.records = append(st.records, rec)
+		case recordSend:
+		default:
+			return nil, fmt.Errorf("replay: line %v: unknown record kind %q", line, rec.Kind)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return rep, nil
+}
+
+// DialOptions - Опции подключения для NewClient: интерсепторы воспроизведения и отключение сетевых подключений
+func (r *Replayer) DialOptions() []grpc.DialOption {
+	return []grpc.DialOption{
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+			return nil, errReplayNetwork
+		}),
+		grpc.WithChainUnaryInterceptor(r.UnaryClientInterceptor()),
+		grpc.WithChainStreamInterceptor(r.StreamClientInterceptor()),
+	}
+}
+
+// wait - ожидание момента, в который запись rec была сделана относительно начала сессии
+func (r *Replayer) wait(ctx context.Context, rec *record) error {
+	if !r.conf.RecordedSpeed {
+		return ctx.Err()
+	}
+	r.mu.Lock()
+	at := r.start.Add(rec.Time.Sub(r.recorded))
+	r.mu.Unlock()
+	timer := time.NewTimer(time.Until(at))
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// begin - отсчет времени воспроизведения начинается с первого запроса
+func (r *Replayer) begin() {
+	r.mu.Lock()
+	if r.start.IsZero() {
+		r.start = time.Now()
+	}
+	r.mu.Unlock()
+}
+
+func (r *Replayer) nextUnary(method string) (*record, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	queue := r.unary[method]
+	if len(queue) == 0 {
+		return nil, false
+	}
+	r.unary[method] = queue[1:]
+	return queue[0], true
+}
+
+func (r *Replayer) nextStream(method string) (*replayedStream, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	queue := r.streams[method]
+	if len(queue) == 0 {
+		return nil, false
+	}
+	r.streams[method] = queue[1:]
+	return queue[0], true
+}
+
+// recordedError - ошибка из записи в том же виде, в каком ее вернул клиент при записи
+func recordedError(rec *record) error {
+	if rec.Code == codes.OK {
+		return nil
+	}
+	return NewAPIError(status.Error(rec.Code, rec.Message), rec.Trailer)
+}
+
+func unmarshalMessage(data json.RawMessage, m any) error {
+	msg, ok := m.(proto.Message)
+	if !ok || len(data) == 0 {
+		return nil
+	}
+	return protojson.UnmarshalOptions{DiscardUnknown: true}.Unmarshal(data, msg)
+}
+
+// UnaryClientInterceptor - Интерсептор, который отвечает на unary запросы из записи
+func (r *Replayer) UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		r.begin()
+		rec, ok := r.nextUnary(method)
+		if !ok {
+			return fmt.Errorf("%w: %v", ErrNoRecordedResponse, method)
+		}
+		if err := r.wait(ctx, rec); err != nil {
+			return status.FromContextError(err).Err()
+		}
+		for _, o := range opts {
+			switch o := o.(type) {
+			case grpc.HeaderCallOption:
+				*o.HeaderAddr = rec.Header
+			case grpc.TrailerCallOption:
+				*o.TrailerAddr = rec.Trailer
+			}
+		}
+		if err := recordedError(rec); err != nil {
+			return err
+		}
+		return unmarshalMessage(rec.Response, reply)
+	}
+}
+
+// StreamClientInterceptor - Интерсептор, который открывает стримы с сообщениями из записи
+func (r *Replayer) StreamClientInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		r.begin()
+		st, ok := r.nextStream(method)
+		if !ok {
+			return nil, fmt.Errorf("%w: %v", ErrNoRecordedResponse, method)
+		}
+		if err := r.wait(ctx, st.open); err != nil {
+			return nil, status.FromContextError(err).Err()
+		}
+		if err := recordedError(st.open); err != nil {
+			return nil, err
+		}
+		return &replayStream{ctx: ctx, r: r, st: st}, nil
+	}
+}
+
+// replayStream - стрим, который отдает записанные сообщения
+type replayStream struct {
+	ctx context.Context
+	r   *Replayer
+	st  *replayedStream
+
+	mu      sync.Mutex
+	next    int
+	trailer metadata.MD
+}
+
+func (s *replayStream) Header() (metadata.MD, error) {
+	for _, rec := range s.st.records {
+		if rec.Header != nil {
+			return rec.Header, nil
+		}
+	}
+	return metadata.MD{}, nil
+}
+
+func (s *replayStream) Trailer() metadata.MD {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.trailer
+}
+
+func (s *replayStream) CloseSend() error {
+	return nil
+}
+
+func (s *replayStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *replayStream) SendMsg(m any) error {
+	return nil
+}
+
+func (s *replayStream) RecvMsg(m any) error {
+	s.mu.Lock()
+	if s.next >= len(s.st.records) {
+		s.mu.Unlock()
+		// запись закончилась до завершения стрима, стрим ждет отмены контекста, как живое соединение
+		<-s.ctx.Done()
+		return status.FromContextError(s.ctx.Err()).Err()
+	}
+	rec := s.st.records[s.next]
+	if rec.Kind == recordRecv {
+		s.next++
+	}
+	s.mu.Unlock()
+
+	if err := s.r.wait(s.ctx, rec); err != nil {
+		return status.FromContextError(err).Err()
+	}
+	if rec.Kind == recordClose {
+		s.mu.Lock()
+		s.trailer = rec.Trailer
+		s.mu.Unlock()
+		if err := recordedError(rec); err != nil {
+			return err
+		}
+		return io.EOF
+	}
+	return unmarshalMessage(rec.Response, m)
+}
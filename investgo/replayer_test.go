@@ -0,0 +1,138 @@
+package investgo_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/russianinvestments/invest-api-go-sdk/investgo"
+)
+
+// recordedSession - действия клиента, которые записываются и воспроизводятся: цена, ошибка запроса и две цены из стрима
+func recordedSession(t *testing.T, client *investgo.Client, uid string, push func(price float64)) []float64 {
+	t.Helper()
+	var prices []float64
+	resp, err := client.NewMarketDataServiceClient().GetLastPrices([]string{uid})
+	if err != nil {
+		t.Fatal(err)
+	}
+	prices = append(prices, resp.GetLastPrices()[0].GetPrice().ToFloat())
+
+	_, err = client.NewInstrumentsServiceClient().InstrumentByUid("unknown")
+	if apiErr, ok := investgo.AsAPIError(err); !ok || !investgo.IsInstrumentNotFound(err) || apiErr.TrackingId == "" {
+		t.Fatalf("instrument: %v", err)
+	}
+
+	mds, err := client.NewMarketDataStreamClient().MarketDataStream()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stop := listen(t, mds.Listen, mds.Stop)
+	ch, err := mds.SubscribeLastPrice([]string{uid})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []float64{101, 102} {
+		push(p)
+		prices = append(prices, receive(t, ch).GetPrice().ToFloat())
+	}
+	stop()
+	return prices
+}
+
+func TestRecordReplay(t *testing.T) {
+	srv, _, uids := newTestServer(t, 1)
+	setPrice(t, srv, uids[0], 100)
+
+	var buf bytes.Buffer
+	rec := investgo.NewRecorderWriter(&buf)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	client, err := investgo.NewClient(ctx, srv.Config(), nopLogger{}, append(srv.DialOptions(), rec.DialOptions()...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorded := recordedSession(t, client, uids[0], func(price float64) {
+		waitSubscribed(t, srv, uids[0])
+		setPrice(t, srv, uids[0], price)
+	})
+	if err := client.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	if err := rec.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// сервер больше не нужен, воспроизведение не подключается к сети
+	srv.Stop()
+
+	rep, err := investgo.NewReplayerReader(strings.NewReader(buf.String()), investgo.ReplayerConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err = investgo.NewClient(ctx, srv.Config(), nopLogger{}, rep.DialOptions()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = client.Stop()
+	}()
+	replayed := recordedSession(t, client, uids[0], func(float64) {})
+	if len(replayed) != len(recorded) || len(recorded) != 3 {
+		t.Fatalf("replayed %v, recorded %v", replayed, recorded)
+	}
+	for i := range recorded {
+		if replayed[i] != recorded[i] {
+			t.Fatalf("replayed %v, recorded %v", replayed, recorded)
+		}
+	}
+	// записанные ответы закончились
+	_, err = client.NewMarketDataServiceClient().GetLastPrices(uids)
+	if !errors.Is(err, investgo.ErrNoRecordedResponse) {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+func TestReplayRecordedSpeed(t *testing.T) {
+	start := time.Date(2024, 3, 4, 10, 0, 0, 0, time.UTC)
+	line := func(at time.Duration, price string) string {
+		return `{"time":"` + start.Add(at).Format(time.RFC3339Nano) + `","kind":"unary",` +
+			`"method":"/tinkoff.public.invest.api.contract.v1.MarketDataService/GetLastPrices",` +
+			`"response":{"lastPrices":[{"price":{"units":"` + price + `"}}]}}` + "\n"
+	}
+	session := line(0, "1") + line(200*time.Millisecond, "2")
+	srv, _, _ := newTestServer(t, 0)
+	for _, speed := range []bool{false, true} {
+		rep, err := investgo.NewReplayerReader(strings.NewReader(session), investgo.ReplayerConfig{RecordedSpeed: speed})
+		if err != nil {
+			t.Fatal(err)
+		}
+		client, err := investgo.NewClient(context.Background(), srv.Config(), nopLogger{}, rep.DialOptions()...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		md := client.NewMarketDataServiceClient()
+		begin := time.Now()
+		for _, want := range []int64{1, 2} {
+			resp, err := md.GetLastPrices([]string{"uid"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := resp.GetLastPrices()[0].GetPrice(); got.GetUnits() != want {
+				t.Fatalf("price = %v, want %v", got, want)
+			}
+		}
+		// интервал между ответами соблюдается только с RecordedSpeed
+		if d := time.Since(begin); (d >= 200*time.Millisecond) != speed {
+			t.Fatalf("speed %v: replay took %v", speed, d)
+		}
+		_ = client.Stop()
+	}
+
+	_, err := investgo.NewReplayerReader(strings.NewReader(`{"kind":"recv","stream":1}`), investgo.ReplayerConfig{})
+	if err == nil {
+		t.Fatal("unknown stream accepted")
+	}
+}
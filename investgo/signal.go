@@ -17,8 +17,13 @@ type SignalServiceClient struct {
 
 // GetStrategies - Метод запроса стратегий
 func (s *SignalServiceClient) GetStrategies(strategyID *string) (*GetStrategiesResponse, error) {
+	return s.GetStrategiesCtx(s.ctx, strategyID)
+}
+
+// GetStrategiesCtx - то же, что и GetStrategies, но с контекстом запроса ctx
+func (s *SignalServiceClient) GetStrategiesCtx(ctx context.Context, strategyID *string) (*GetStrategiesResponse, error) {
 	var header, trailer metadata.MD
-	resp, err := s.pbClient.GetStrategies(s.ctx, &pb.GetStrategiesRequest{
+	resp, err := s.pbClient.GetStrategies(ctx, &pb.GetStrategiesRequest{
 		StrategyId: strategyID,
 	}, grpc.Header(&header), grpc.Trailer(&trailer))
 	if err != nil {
@@ -32,6 +37,11 @@ func (s *SignalServiceClient) GetStrategies(strategyID *string) (*GetStrategiesR
 
 // GetSignals - Метод запроса сигналов
 func (s *SignalServiceClient) GetSignals(request GetSignalsRequest) (*GetSignalsResponse, error) {
+	return s.GetSignalsCtx(s.ctx, request)
+}
+
+// GetSignalsCtx - то же, что и GetSignals, но с контекстом запроса ctx
+func (s *SignalServiceClient) GetSignalsCtx(ctx context.Context, request GetSignalsRequest) (*GetSignalsResponse, error) {
 	var header, trailer metadata.MD
 
 	var from, to *timestamppb.Timestamp
@@ -42,7 +52,7 @@ func (s *SignalServiceClient) GetSignals(request GetSignalsRequest) (*GetSignals
 		to = nil
 	}
 
-	resp, err := s.pbClient.GetSignals(s.ctx, &pb.GetSignalsRequest{
+	resp, err := s.pbClient.GetSignals(ctx, &pb.GetSignalsRequest{
 		SignalId:      request.SignalID,
 		StrategyId:    request.StrategyID,
 		StrategyType:  request.StrategyType,
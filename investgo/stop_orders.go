@@ -19,8 +19,13 @@ type StopOrdersServiceClient struct {
 
 // PostStopOrder - Метод выставления стоп-заявки
 func (s *StopOrdersServiceClient) PostStopOrder(req *PostStopOrderRequest) (*PostStopOrderResponse, error) {
+	return s.PostStopOrderCtx(s.ctx, req)
+}
+
+// PostStopOrderCtx - то же, что и PostStopOrder, но с контекстом запроса ctx
+func (s *StopOrdersServiceClient) PostStopOrderCtx(ctx context.Context, req *PostStopOrderRequest) (*PostStopOrderResponse, error) {
 	var header, trailer metadata.MD
-	resp, err := s.pbClient.PostStopOrder(s.ctx, &pb.PostStopOrderRequest{
+	resp, err := s.pbClient.PostStopOrder(ctx, &pb.PostStopOrderRequest{
 		Quantity:          req.Quantity,
 		Price:             req.Price,
 		StopPrice:         req.StopPrice,
@@ -47,8 +52,13 @@ func (s *StopOrdersServiceClient) PostStopOrder(req *PostStopOrderRequest) (*Pos
 
 // GetStopOrders - Метод получения списка активных стоп заявок по счёту
 func (s *StopOrdersServiceClient) GetStopOrders(accountId string) (*GetStopOrdersResponse, error) {
+	return s.GetStopOrdersCtx(s.ctx, accountId)
+}
+
+// GetStopOrdersCtx - то же, что и GetStopOrders, но с контекстом запроса ctx
+func (s *StopOrdersServiceClient) GetStopOrdersCtx(ctx context.Context, accountId string) (*GetStopOrdersResponse, error) {
 	var header, trailer metadata.MD
-	resp, err := s.pbClient.GetStopOrders(s.ctx, &pb.GetStopOrdersRequest{
+	resp, err := s.pbClient.GetStopOrders(ctx, &pb.GetStopOrdersRequest{
 		AccountId: accountId,
 	}, grpc.Header(&header), grpc.Trailer(&trailer))
 	if err != nil {
@@ -62,8 +72,13 @@ func (s *StopOrdersServiceClient) GetStopOrders(accountId string) (*GetStopOrder
 
 // CancelStopOrder - Метод отмены стоп-заявки
 func (s *StopOrdersServiceClient) CancelStopOrder(accountId, stopOrderId string) (*CancelStopOrderResponse, error) {
+	return s.CancelStopOrderCtx(s.ctx, accountId, stopOrderId)
+}
+
+// CancelStopOrderCtx - то же, что и CancelStopOrder, но с контекстом запроса ctx
+func (s *StopOrdersServiceClient) CancelStopOrderCtx(ctx context.Context, accountId, stopOrderId string) (*CancelStopOrderResponse, error) {
 	var header, trailer metadata.MD
-	resp, err := s.pbClient.CancelStopOrder(s.ctx, &pb.CancelStopOrderRequest{
+	resp, err := s.pbClient.CancelStopOrder(ctx, &pb.CancelStopOrderRequest{
 		AccountId:   accountId,
 		StopOrderId: stopOrderId,
 	}, grpc.Header(&header), grpc.Trailer(&trailer))
@@ -0,0 +1,99 @@
+package investgo
+
+import (
+	"time"
+
+	"github.com/russianinvestments/invest-api-go-sdk/retry"
+)
+
+// StreamOption - опция настройки стрима
+type StreamOption func(o *streamOptions)
+
+// OverflowPolicy - политика поведения канала стрима при переполнении буфера
+type OverflowPolicy int
+
+const (
+	// OverflowBlock - чтение из стрима ждет, пока в канале освободится место. Политика по умолчанию
+	OverflowBlock OverflowPolicy = iota
+	// OverflowDropOldest - из канала отбрасывается самое старое сообщение, чтобы записать новое
+	OverflowDropOldest
+	// OverflowDropNewest - новое сообщение отбрасывается, если канал заполнен
+	OverflowDropNewest
+	// OverflowKeepLatest - для каждого инструмента (для стримов портфеля и позиций - для каждого счета, для стрима
+	// состояний поручений - для каждой заявки) хранится только последнее непрочитанное сообщение, размер буфера
+	// не используется. Для стрима сделок по поручениям работает как OverflowDropOldest
+	OverflowKeepLatest
+)
+
+// maxReconnectWait - максимальное время ожидания между попытками переподключения по умолчанию
+const maxReconnectWait = 30 * time.Second
+
+type streamOptions struct {
+	reconnect        bool
+	reconnectBackoff retry.BackoffFunc
+	maxReconnects    uint
+
+	// bufferSize - размер буфера каналов, -1 - размер по умолчанию для стрима
+	bufferSize int
+	overflow   OverflowPolicy
+
+	confirm        bool
+	confirmTimeout time.Duration
+}
+
+func newStreamOptions(opts []StreamOption) *streamOptions {
+	o := &streamOptions{
+		reconnectBackoff: retry.BackoffExponentialWithMax(WAIT_BETWEEN, maxReconnectWait),
+		bufferSize:       -1,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// size - размер буфера каналов стрима, def - размер по умолчанию
+func (o *streamOptions) size(def int) int {
+	if o.bufferSize < 0 {
+		return def
+	}
+	return o.bufferSize
+}
+
+// WithReconnect - Включает автоматическое переподключение стрима после обрыва соединения, используется только
+// в MarketDataStream.
+// backoff - функция ожидания между попытками, если nil, то время ожидания растет экспоненциально от WAIT_BETWEEN
+// до 30 секунд.
+// maxAttempts - максимальное количество попыток переподключения подряд, 0 - без ограничений. Попытка считается
+// успешной, когда из нового стрима пришло первое сообщение, до этого номер попытки не сбрасывается
+func WithReconnect(backoff retry.BackoffFunc, maxAttempts uint) StreamOption {
+	return func(o *streamOptions) {
+		o.reconnect = true
+		if backoff != nil {
+			o.reconnectBackoff = backoff
+		}
+		o.maxReconnects = maxAttempts
+	}
+}
+
+// WithBuffer - Задает размер буфера каналов стрима и политику при их переполнении. Применяется ко всем каналам
+// стрима, включая каналы подписок MarketDataStream. Количество отброшенных сообщений можно получить методом
+// Dropped() у стрима. Для политик с отбрасыванием сообщений размер буфера не меньше 1
+func WithBuffer(size int, policy OverflowPolicy) StreamOption {
+	return func(o *streamOptions) {
+		o.bufferSize = size
+		o.overflow = policy
+	}
+}
+
+// WithSubscriptionConfirm - Методы подписки и отписки ждут ответа сервера и возвращают *SubscriptionError, если сервер
+// отклонил подписку хотя бы на один инструмент, используется только в MarketDataStream. Ответ приходит в стрим,
+// поэтому подписываться нужно после запуска Listen и не из горутины, которая читает каналы стрима с политикой
+// OverflowBlock.
+// timeout - максимальное время ожидания ответа, 0 - без ограничений
+func WithSubscriptionConfirm(timeout time.Duration) StreamOption {
+	return func(o *streamOptions) {
+		o.confirm = true
+		o.confirmTimeout = timeout
+	}
+}
@@ -0,0 +1,181 @@
+package investgo
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// streamQueue - канал стрима с политикой переполнения
+type streamQueue[T any] struct {
+	ch     chan T
+	policy OverflowPolicy
+	// key - ключ для OverflowKeepLatest, если nil, то OverflowKeepLatest работает как OverflowDropOldest
+	key func(T) string
+
+	// dropped - счетчик сообщений, отброшенных этим каналом, total - общий счетчик стрима
+	dropped atomic.Uint64
+	total   *atomic.Uint64
+
+	done     chan struct{}
+	stopOnce sync.Once
+	// closeMu - закрытие канала ждет завершения текущих отправок, closed - канал закрыт
+	closeMu sync.RWMutex
+	closed  bool
+
+	// pending, order, notify - последние сообщения по ключам для OverflowKeepLatest
+	mu      sync.Mutex
+	pending map[string]T
+	order   []string
+	notify  chan struct{}
+	pumped  chan struct{}
+}
+
+func newStreamQueue[T any](size int, policy OverflowPolicy, key func(T) string, total *atomic.Uint64) *streamQueue[T] {
+	if policy == OverflowKeepLatest && key == nil {
+		policy = OverflowDropOldest
+	}
+	if size < 0 {
+		size = 0
+	}
+	q := &streamQueue[T]{
+		policy: policy,
+		key:    key,
+		total:  total,
+		done:   make(chan struct{}),
+	}
+	if policy == OverflowKeepLatest {
+		// сообщения ожидают отправки в pending, поэтому в канале не должно накапливаться устаревших значений
+		q.ch = make(chan T)
+		q.pending = make(map[string]T)
+		q.notify = make(chan struct{}, 1)
+		q.pumped = make(chan struct{})
+		go q.pump()
+		return q
+	}
+	// для отбрасывания сообщений нужен хотя бы один слот в буфере
+	if size == 0 && policy != OverflowBlock {
+		size = 1
+	}
+	q.ch = make(chan T, size)
+	return q
+}
+
+// out - канал для чтения
+func (q *streamQueue[T]) out() <-chan T {
+	return q.ch
+}
+
+// send - отправка сообщения в канал согласно политике переполнения, после закрытия канала сообщение отбрасывается
+func (q *streamQueue[T]) send(v T) {
+	q.closeMu.RLock()
+	defer q.closeMu.RUnlock()
+	if q.closed {
+		return
+	}
+	switch q.policy {
+	case OverflowDropNewest:
+		select {
+		case q.ch <- v:
+		default:
+			q.drop()
+		}
+	case OverflowDropOldest:
+		for {
+			select {
+			case q.ch <- v:
+				return
+			case <-q.done:
+				return
+			default:
+			}
+			select {
+			case <-q.ch:
+				q.drop()
+			default:
+			}
+		}
+	case OverflowKeepLatest:
+		k := q.key(v)
+		q.mu.Lock()
+		if _, ok := q.pending[k]; ok {
+			q.drop()
+		} else {
+			q.order = append(q.order, k)
+		}
+		q.pending[k] = v
+		q.mu.Unlock()
+		select {
+		case q.notify <- struct{}{}:
+		default:
+		}
+	default:
+		select {
+		case q.ch <- v:
+		case <-q.done:
+		}
+	}
+}
+
+// pump - отправка ожидающих сообщений в канал для OverflowKeepLatest
+func (q *streamQueue[T]) pump() {
+	defer close(q.pumped)
+	defer close(q.ch)
+	for {
+		q.mu.Lock()
+		if len(q.order) == 0 {
+			q.mu.Unlock()
+			select {
+			case <-q.notify:
+				continue
+			case <-q.done:
+				return
+			}
+		}
+		k := q.order[0]
+		q.order = q.order[1:]
+		v := q.pending[k]
+		delete(q.pending, k)
+		q.mu.Unlock()
+
+		select {
+		case q.ch <- v:
+		case <-q.done:
+			return
+		}
+	}
+}
+
+func (q *streamQueue[T]) drop() {
+	q.dropped.Add(1)
+	if q.total != nil {
+		q.total.Add(1)
+	}
+}
+
+// droppedCount - количество отброшенных сообщений
+func (q *streamQueue[T]) droppedCount() uint64 {
+	return q.dropped.Load()
+}
+
+// stop - разблокирует ожидающую отправку, после stop сообщения больше не доставляются
+func (q *streamQueue[T]) stop() {
+	q.stopOnce.Do(func() {
+		close(q.done)
+	})
+}
+
+// close - закрытие канала, может вызываться одновременно с send
+func (q *streamQueue[T]) close() {
+	q.stop()
+	q.closeMu.Lock()
+	defer q.closeMu.Unlock()
+	if q.closed {
+		return
+	}
+	q.closed = true
+	if q.policy == OverflowKeepLatest {
+		<-q.pumped
+		return
+	}
+	close(q.ch)
+}
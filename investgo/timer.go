@@ -59,7 +59,7 @@ func (t *Timer) Start(ctx context.Context) error {
 			to := from.Add(DAY)
 
 			// получаем ближайшие два торговых дня
-			resp, err := t.instrumentsService.TradingSchedules(t.exchange, from, to)
+			resp, err := t.instrumentsService.TradingSchedulesCtx(ctxTimer, t.exchange, from, to)
 			if err != nil {
 				return err
 			}
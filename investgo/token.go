@@ -0,0 +1,175 @@
+package investgo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrEmptyToken - провайдер не смог получить токен доступа
+var ErrEmptyToken = errors.New("empty api token")
+
+// TokenProvider - источник токена доступа. Клиент запрашивает токен перед каждым unary запросом и открытием стрима,
+// поэтому новый токен начинает использоваться без пересоздания клиента и стримов
+type TokenProvider interface {
+	Token(ctx context.Context) (string, error)
+}
+
+// TokenInvalidator - провайдер, который кэширует токен. Клиент вызывает InvalidateToken, когда сервер отклоняет
+// токен ошибкой Unauthenticated, и запрашивает токен заново при переподключении стрима
+type TokenInvalidator interface {
+	InvalidateToken()
+}
+
+// StaticToken - неизменный токен
+type StaticToken string
+
+// Token - Токен доступа
+func (t StaticToken) Token(context.Context) (string, error) {
+	return string(t), nil
+}
+
+// TokenFunc - провайдер из функции, например обращения к внешнему хранилищу секретов. Функция вызывается перед каждым
+// запросом, для дорогих обращений ее можно обернуть в NewCachedToken
+type TokenFunc func(ctx context.Context) (string, error)
+
+// Token - Токен доступа
+func (f TokenFunc) Token(ctx context.Context) (string, error) {
+	return f(ctx)
+}
+
+// EnvToken - Провайдер, который читает токен из переменной окружения name при каждом запросе
+func EnvToken(name string) TokenProvider {
+	return TokenFunc(func(context.Context) (string, error) {
+		token := strings.TrimSpace(os.Getenv(name))
+		if token == "" {
+			return "", fmt.Errorf("%w: environment variable %v is not set", ErrEmptyToken, name)
+		}
+		return token, nil
+	})
+}
+
+// FileToken - провайдер, который читает токен из файла и перечитывает его после каждого изменения файла
+type FileToken struct {
+	path string
+
+	mu      sync.Mutex
+	token   string
+	modTime time.Time
+	size    int64
+}
+
+// NewFileToken - Создание провайдера для файла path. Файл содержит только токен, пробелы и переводы строк по краям
+// игнорируются
+func NewFileToken(path string) *FileToken {
+	return &FileToken{path: path}
+}
+
+// Token - Токен из файла. Файл перечитывается, если изменились время его модификации или размер
+func (f *FileToken) Token(context.Context) (string, error) {
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return "", err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.token != "" && info.ModTime().Equal(f.modTime) && info.Size() == f.size {
+		return f.token, nil
+	}
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		return "", err
+	}
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		return "", fmt.Errorf("%w: file %v is empty", ErrEmptyToken, f.path)
+	}
+	f.token, f.modTime, f.size = token, info.ModTime(), info.Size()
+	return token, nil
+}
+
+// CachedToken - провайдер, который кэширует токен другого провайдера на время ttl
+type CachedToken struct {
+	p   TokenProvider
+	ttl time.Duration
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+// NewCachedToken - Создание кэша токена провайдера p. Кэш сбрасывается по истечении ttl и после ответа сервера
+// Unauthenticated, ttl = 0 - токен хранится до ошибки
+func NewCachedToken(p TokenProvider, ttl time.Duration) *CachedToken {
+	return &CachedToken{p: p, ttl: ttl}
+}
+
+// Token - Токен из кэша или, если кэш пуст или устарел, из исходного провайдера
+func (c *CachedToken) Token(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.token != "" && (c.ttl == 0 || time.Now().Before(c.expires)) {
+		return c.token, nil
+	}
+	token, err := c.p.Token(ctx)
+	if err != nil {
+		return "", err
+	}
+	c.token, c.expires = token, time.Now().Add(c.ttl)
+	return token, nil
+}
+
+// InvalidateToken - Сброс кэша, следующий запрос получит токен из исходного провайдера
+func (c *CachedToken) InvalidateToken() {
+	c.mu.Lock()
+	c.token = ""
+	c.mu.Unlock()
+	if inv, ok := c.p.(TokenInvalidator); ok {
+		inv.InvalidateToken()
+	}
+}
+
+// tokenCredentials - авторизация запросов токеном из TokenProvider
+type tokenCredentials struct {
+	p TokenProvider
+}
+
+func (t tokenCredentials) GetRequestMetadata(ctx context.Context, _ ...string) (map[string]string, error) {
+	token, err := t.p.Token(ctx)
+	if err != nil {
+		// без явного кода grpc считает ошибку временной (Unavailable) и запрос повторяется ретраером
+		return nil, status.Errorf(codes.Unauthenticated, "token provider: %v", err)
+	}
+	return map[string]string{"authorization": "Bearer " + token}, nil
+}
+
+func (t tokenCredentials) RequireTransportSecurity() bool {
+	return true
+}
+
+// invalidateToken - сброс кэша провайдера после ошибки авторизации
+func invalidateToken(p TokenProvider, err error) {
+	if status.Code(err) != codes.Unauthenticated {
+		return
+	}
+	if inv, ok := p.(TokenInvalidator); ok {
+		inv.InvalidateToken()
+	}
+}
+
+// tokenUnaryInterceptor - сброс кэша провайдера, если сервер отклонил токен unary запроса
+func tokenUnaryInterceptor(p TokenProvider) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		invalidateToken(p, err)
+		return err
+	}
+}
@@ -15,12 +15,12 @@ type TradesStream struct {
 	ctx    context.Context
 	cancel context.CancelFunc
 
-	trades chan *pb.OrderTrades
+	trades *streamQueue[*pb.OrderTrades]
 }
 
 // Trades - Метод возвращает канал для чтения информации о торговых поручениях
 func (t *TradesStream) Trades() <-chan *pb.OrderTrades {
-	return t.trades
+	return t.trades.out()
 }
 
 // Listen - метод начинает слушать стрим и отправлять информацию в канал, для получения канала: Trades()
@@ -43,7 +43,7 @@ func (t *TradesStream) Listen() error {
 			} else {
 				switch resp.GetPayload().(type) {
 				case *pb.TradesStreamResponse_OrderTrades:
-					t.trades <- resp.GetOrderTrades()
+					t.trades.send(resp.GetOrderTrades())
 				default:
 					t.ordersClient.logger.Infof("info from Trades stream %v", resp.String())
 				}
@@ -56,9 +56,15 @@ func (t *TradesStream) restart(_ context.Context, attempt uint, err error) {
 	t.ordersClient.logger.Infof("try to restart trades stream err = %v, attempt = %v", err.Error(), attempt)
 }
 
+// Dropped - Метод возвращает количество сообщений, отброшенных при переполнении канала стрима. Сообщения
+// отбрасываются только с политикой переполнения, заданной опцией WithBuffer
+func (t *TradesStream) Dropped() uint64 {
+	return t.trades.droppedCount()
+}
+
 func (t *TradesStream) shutdown() {
 	t.ordersClient.logger.Infof("close trades stream")
-	close(t.trades)
+	t.trades.close()
 }
 
 // Stop - Завершение работы стрима
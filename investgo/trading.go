@@ -0,0 +1,204 @@
+package investgo
+
+import (
+	"context"
+
+	pb "github.com/russianinvestments/invest-api-go-sdk/proto"
+)
+
+// TradingService - торговые методы, общие для песочницы и реального контура. Клиент из NewTradingServiceClient
+// отправляет запросы в SandboxService или OrdersService в зависимости от Config.Environment, поэтому код робота
+// не меняется при переходе из песочницы в реальный контур
+type TradingService interface {
+	// PostOrder - Метод выставления заявки
+	PostOrder(req *PostOrderRequest) (*PostOrderResponse, error)
+	// PostOrderCtx - то же, что и PostOrder, но с контекстом запроса ctx
+	PostOrderCtx(ctx context.Context, req *PostOrderRequest) (*PostOrderResponse, error)
+	// Buy - Метод выставления поручения на покупку инструмента
+	Buy(req *PostOrderRequestShort) (*PostOrderResponse, error)
+	// BuyCtx - то же, что и Buy, но с контекстом запроса ctx
+	BuyCtx(ctx context.Context, req *PostOrderRequestShort) (*PostOrderResponse, error)
+	// Sell - Метод выставления поручения на продажу инструмента
+	Sell(req *PostOrderRequestShort) (*PostOrderResponse, error)
+	// SellCtx - то же, что и Sell, но с контекстом запроса ctx
+	SellCtx(ctx context.Context, req *PostOrderRequestShort) (*PostOrderResponse, error)
+	// ReplaceOrder - Метод изменения выставленной заявки
+	ReplaceOrder(req *ReplaceOrderRequest) (*PostOrderResponse, error)
+	// ReplaceOrderCtx - то же, что и ReplaceOrder, но с контекстом запроса ctx
+	ReplaceOrderCtx(ctx context.Context, req *ReplaceOrderRequest) (*PostOrderResponse, error)
+	// CancelOrder - Метод отмены заявки
+	CancelOrder(accountId, orderId string) (*CancelOrderResponse, error)
+	// CancelOrderCtx - то же, что и CancelOrder, но с контекстом запроса ctx
+	CancelOrderCtx(ctx context.Context, accountId, orderId string) (*CancelOrderResponse, error)
+	// GetOrders - Метод получения списка активных заявок по счёту
+	GetOrders(accountId string) (*GetOrdersResponse, error)
+	// GetOrdersCtx - то же, что и GetOrders, но с контекстом запроса ctx
+	GetOrdersCtx(ctx context.Context, accountId string) (*GetOrdersResponse, error)
+	// GetOrderState - Метод получения статуса заявки
+	GetOrderState(accountId, orderId string) (*GetOrderStateResponse, error)
+	// GetOrderStateCtx - то же, что и GetOrderState, но с контекстом запроса ctx
+	GetOrderStateCtx(ctx context.Context, accountId, orderId string) (*GetOrderStateResponse, error)
+	// GetMaxLots - Расчет количества доступных для покупки/продажи лотов
+	GetMaxLots(accountId, instrumentId string, price *pb.Quotation) (*GetMaxLotsResponse, error)
+	// GetMaxLotsCtx - то же, что и GetMaxLots, но с контекстом запроса ctx
+	GetMaxLotsCtx(ctx context.Context, accountId, instrumentId string, price *pb.Quotation) (*GetMaxLotsResponse, error)
+}
+
+// NewTradingServiceClient - создание торгового клиента для контура из Config.Environment: в песочнице запросы
+// идут в SandboxService, в реальном контуре и на собственном эндпоинте - в OrdersService
+func (c *Client) NewTradingServiceClient() TradingService {
+	if c.Config.Environment == EnvironmentSandbox {
+		return &sandboxTrading{s: c.NewSandboxServiceClient()}
+	}
+	return &ordersTrading{os: c.NewOrdersServiceClient()}
+}
+
+// ordersTrading - TradingService поверх OrdersServiceClient
+type ordersTrading struct {
+	os *OrdersServiceClient
+}
+
+func (t *ordersTrading) PostOrder(req *PostOrderRequest) (*PostOrderResponse, error) {
+	return t.os.PostOrder(req)
+}
+
+func (t *ordersTrading) PostOrderCtx(ctx context.Context, req *PostOrderRequest) (*PostOrderResponse, error) {
+	return t.os.PostOrderCtx(ctx, req)
+}
+
+func (t *ordersTrading) Buy(req *PostOrderRequestShort) (*PostOrderResponse, error) {
+	return t.os.Buy(req)
+}
+
+func (t *ordersTrading) BuyCtx(ctx context.Context, req *PostOrderRequestShort) (*PostOrderResponse, error) {
+	return t.os.BuyCtx(ctx, req)
+}
+
+func (t *ordersTrading) Sell(req *PostOrderRequestShort) (*PostOrderResponse, error) {
+	return t.os.Sell(req)
+}
+
+func (t *ordersTrading) SellCtx(ctx context.Context, req *PostOrderRequestShort) (*PostOrderResponse, error) {
+	return t.os.SellCtx(ctx, req)
+}
+
+func (t *ordersTrading) ReplaceOrder(req *ReplaceOrderRequest) (*PostOrderResponse, error) {
+	return t.os.ReplaceOrder(req)
+}
+
+func (t *ordersTrading) ReplaceOrderCtx(ctx context.Context, req *ReplaceOrderRequest) (*PostOrderResponse, error) {
+	return t.os.ReplaceOrderCtx(ctx, req)
+}
+
+func (t *ordersTrading) CancelOrder(accountId, orderId string) (*CancelOrderResponse, error) {
+	return t.os.CancelOrder(accountId, orderId, nil)
+}
+
+func (t *ordersTrading) CancelOrderCtx(ctx context.Context, accountId, orderId string) (*CancelOrderResponse, error) {
+	return t.os.CancelOrderCtx(ctx, accountId, orderId, nil)
+}
+
+func (t *ordersTrading) GetOrders(accountId string) (*GetOrdersResponse, error) {
+	return t.os.GetOrders(accountId)
+}
+
+func (t *ordersTrading) GetOrdersCtx(ctx context.Context, accountId string) (*GetOrdersResponse, error) {
+	return t.os.GetOrdersCtx(ctx, accountId)
+}
+
+func (t *ordersTrading) GetOrderState(accountId, orderId string) (*GetOrderStateResponse, error) {
+	return t.os.GetOrderState(accountId, orderId, pb.PriceType_PRICE_TYPE_UNSPECIFIED, nil)
+}
+
+func (t *ordersTrading) GetOrderStateCtx(ctx context.Context, accountId, orderId string) (*GetOrderStateResponse, error) {
+	return t.os.GetOrderStateCtx(ctx, accountId, orderId, pb.PriceType_PRICE_TYPE_UNSPECIFIED, nil)
+}
+
+func (t *ordersTrading) GetMaxLots(accountId, instrumentId string, price *pb.Quotation) (*GetMaxLotsResponse, error) {
+	return t.os.GetMaxLots(accountId, instrumentId, price)
+}
+
+func (t *ordersTrading) GetMaxLotsCtx(ctx context.Context, accountId, instrumentId string, price *pb.Quotation) (*GetMaxLotsResponse, error) {
+	return t.os.GetMaxLotsCtx(ctx, accountId, instrumentId, price)
+}
+
+// sandboxTrading - TradingService поверх SandboxServiceClient
+type sandboxTrading struct {
+	s *SandboxServiceClient
+}
+
+func (t *sandboxTrading) PostOrder(req *PostOrderRequest) (*PostOrderResponse, error) {
+	return t.s.PostSandboxOrder(req)
+}
+
+func (t *sandboxTrading) PostOrderCtx(ctx context.Context, req *PostOrderRequest) (*PostOrderResponse, error) {
+	return t.s.PostSandboxOrderCtx(ctx, req)
+}
+
+func (t *sandboxTrading) Buy(req *PostOrderRequestShort) (*PostOrderResponse, error) {
+	return t.BuyCtx(t.s.ctx, req)
+}
+
+func (t *sandboxTrading) BuyCtx(ctx context.Context, req *PostOrderRequestShort) (*PostOrderResponse, error) {
+	return t.s.PostSandboxOrderCtx(ctx, fullOrderRequest(req, pb.OrderDirection_ORDER_DIRECTION_BUY))
+}
+
+func (t *sandboxTrading) Sell(req *PostOrderRequestShort) (*PostOrderResponse, error) {
+	return t.SellCtx(t.s.ctx, req)
+}
+
+func (t *sandboxTrading) SellCtx(ctx context.Context, req *PostOrderRequestShort) (*PostOrderResponse, error) {
+	return t.s.PostSandboxOrderCtx(ctx, fullOrderRequest(req, pb.OrderDirection_ORDER_DIRECTION_SELL))
+}
+
+func (t *sandboxTrading) ReplaceOrder(req *ReplaceOrderRequest) (*PostOrderResponse, error) {
+	return t.s.ReplaceSandboxOrder(req)
+}
+
+func (t *sandboxTrading) ReplaceOrderCtx(ctx context.Context, req *ReplaceOrderRequest) (*PostOrderResponse, error) {
+	return t.s.ReplaceSandboxOrderCtx(ctx, req)
+}
+
+func (t *sandboxTrading) CancelOrder(accountId, orderId string) (*CancelOrderResponse, error) {
+	return t.s.CancelSandboxOrder(accountId, orderId)
+}
+
+func (t *sandboxTrading) CancelOrderCtx(ctx context.Context, accountId, orderId string) (*CancelOrderResponse, error) {
+	return t.s.CancelSandboxOrderCtx(ctx, accountId, orderId)
+}
+
+func (t *sandboxTrading) GetOrders(accountId string) (*GetOrdersResponse, error) {
+	return t.s.GetSandboxOrders(accountId)
+}
+
+func (t *sandboxTrading) GetOrdersCtx(ctx context.Context, accountId string) (*GetOrdersResponse, error) {
+	return t.s.GetSandboxOrdersCtx(ctx, accountId)
+}
+
+func (t *sandboxTrading) GetOrderState(accountId, orderId string) (*GetOrderStateResponse, error) {
+	return t.s.GetSandboxOrderState(accountId, orderId)
+}
+
+func (t *sandboxTrading) GetOrderStateCtx(ctx context.Context, accountId, orderId string) (*GetOrderStateResponse, error) {
+	return t.s.GetSandboxOrderStateCtx(ctx, accountId, orderId)
+}
+
+func (t *sandboxTrading) GetMaxLots(accountId, instrumentId string, price *pb.Quotation) (*GetMaxLotsResponse, error) {
+	return t.s.GetSandboxMaxLots(accountId, instrumentId, price)
+}
+
+func (t *sandboxTrading) GetMaxLotsCtx(ctx context.Context, accountId, instrumentId string, price *pb.Quotation) (*GetMaxLotsResponse, error) {
+	return t.s.GetSandboxMaxLotsCtx(ctx, accountId, instrumentId, price)
+}
+
+func fullOrderRequest(req *PostOrderRequestShort, direction pb.OrderDirection) *PostOrderRequest {
+	return &PostOrderRequest{
+		InstrumentId: req.InstrumentId,
+		Quantity:     req.Quantity,
+		Price:        req.Price,
+		Direction:    direction,
+		AccountId:    req.AccountId,
+		OrderType:    req.OrderType,
+		OrderId:      req.OrderId,
+	}
+}
@@ -19,8 +19,13 @@ type UsersServiceClient struct {
 
 // GetAccounts - Метод получения счетов пользователя
 func (us *UsersServiceClient) GetAccounts(status *pb.AccountStatus) (*GetAccountsResponse, error) {
+	return us.GetAccountsCtx(us.ctx, status)
+}
+
+// GetAccountsCtx - то же, что и GetAccounts, но с контекстом запроса ctx
+func (us *UsersServiceClient) GetAccountsCtx(ctx context.Context, status *pb.AccountStatus) (*GetAccountsResponse, error) {
 	var header, trailer metadata.MD
-	resp, err := us.pbClient.GetAccounts(us.ctx, &pb.GetAccountsRequest{
+	resp, err := us.pbClient.GetAccounts(ctx, &pb.GetAccountsRequest{
 		Status: status,
 	}, grpc.Header(&header), grpc.Trailer(&trailer))
 	if err != nil {
@@ -34,8 +39,13 @@ func (us *UsersServiceClient) GetAccounts(status *pb.AccountStatus) (*GetAccount
 
 // GetMarginAttributes - Расчёт маржинальных показателей по счёту
 func (us *UsersServiceClient) GetMarginAttributes(accountId string) (*GetMarginAttributesResponse, error) {
+	return us.GetMarginAttributesCtx(us.ctx, accountId)
+}
+
+// GetMarginAttributesCtx - то же, что и GetMarginAttributes, но с контекстом запроса ctx
+func (us *UsersServiceClient) GetMarginAttributesCtx(ctx context.Context, accountId string) (*GetMarginAttributesResponse, error) {
 	var header, trailer metadata.MD
-	resp, err := us.pbClient.GetMarginAttributes(us.ctx, &pb.GetMarginAttributesRequest{
+	resp, err := us.pbClient.GetMarginAttributes(ctx, &pb.GetMarginAttributesRequest{
 		AccountId: accountId,
 	}, grpc.Header(&header), grpc.Trailer(&trailer))
 	if err != nil {
@@ -49,8 +59,13 @@ func (us *UsersServiceClient) GetMarginAttributes(accountId string) (*GetMarginA
 
 // GetUserTariff - Запрос тарифа пользователя
 func (us *UsersServiceClient) GetUserTariff() (*GetUserTariffResponse, error) {
+	return us.GetUserTariffCtx(us.ctx)
+}
+
+// GetUserTariffCtx - то же, что и GetUserTariff, но с контекстом запроса ctx
+func (us *UsersServiceClient) GetUserTariffCtx(ctx context.Context) (*GetUserTariffResponse, error) {
 	var header, trailer metadata.MD
-	resp, err := us.pbClient.GetUserTariff(us.ctx, &pb.GetUserTariffRequest{}, grpc.Header(&header), grpc.Trailer(&trailer))
+	resp, err := us.pbClient.GetUserTariff(ctx, &pb.GetUserTariffRequest{}, grpc.Header(&header), grpc.Trailer(&trailer))
 	if err != nil {
 		header = trailer
 	}
@@ -62,8 +77,13 @@ func (us *UsersServiceClient) GetUserTariff() (*GetUserTariffResponse, error) {
 
 // GetInfo - Метод получения информации о пользователе
 func (us *UsersServiceClient) GetInfo() (*GetInfoResponse, error) {
+	return us.GetInfoCtx(us.ctx)
+}
+
+// GetInfoCtx - то же, что и GetInfo, но с контекстом запроса ctx
+func (us *UsersServiceClient) GetInfoCtx(ctx context.Context) (*GetInfoResponse, error) {
 	var header, trailer metadata.MD
-	resp, err := us.pbClient.GetInfo(us.ctx, &pb.GetInfoRequest{}, grpc.Header(&header), grpc.Trailer(&trailer))
+	resp, err := us.pbClient.GetInfo(ctx, &pb.GetInfoRequest{}, grpc.Header(&header), grpc.Trailer(&trailer))
 	if err != nil {
 		header = trailer
 	}
@@ -0,0 +1,344 @@
+package investgotest
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/russianinvestments/invest-api-go-sdk/investgo"
+	pb "github.com/russianinvestments/invest-api-go-sdk/proto"
+)
+
+// account - счет и его состояние
+type account struct {
+	info *pb.Account
+	// rate - комиссия брокера в долях от суммы сделки
+	rate decimal.Decimal
+
+	// money, blocked - доступные и заблокированные заявками деньги по валютам
+	money   map[string]decimal.Decimal
+	blocked map[string]decimal.Decimal
+	// positions - позиции по uid инструмента
+	positions  map[string]*position
+	orders     []*order
+	stopOrders []*stopOrder
+	operations []*pb.Operation
+}
+
+// position - позиция по инструменту, количество в штуках
+type position struct {
+	inst    *instrument
+	balance int64
+	blocked int64
+	avg     decimal.Decimal
+}
+
+// apply - изменение позиции на qty штук по цене price с пересчетом средней цены
+func (p *position) apply(qty int64, price decimal.Decimal) {
+	switch {
+	case p.balance == 0 || (p.balance > 0) == (qty > 0):
+		total := p.avg.Mul(decimal.NewFromInt(abs(p.balance))).Add(price.Mul(decimal.NewFromInt(abs(qty))))
+		p.avg = total.Div(decimal.NewFromInt(abs(p.balance) + abs(qty)))
+	case abs(qty) > abs(p.balance):
+		// позиция перевернулась, средняя цена новой позиции - цена сделки
+		p.avg = price
+	}
+	p.balance += qty
+	if p.balance == 0 {
+		p.avg = decimal.Zero
+	}
+}
+
+func abs(v int64) int64 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+// events - отложенная отправка событий в стримы, выполняется после снятия блокировки сервера
+type events []func()
+
+func (ev *events) add(f func()) {
+	*ev = append(*ev, f)
+}
+
+func (ev events) publish() {
+	for _, f := range ev {
+		f()
+	}
+}
+
+func (s *Server) openAccount(id, name string, typ pb.AccountType) *account {
+	acc := &account{
+		info: &pb.Account{
+			Id:          id,
+			Type:        typ,
+			Name:        name,
+			Status:      pb.AccountStatus_ACCOUNT_STATUS_OPEN,
+			OpenedDate:  timestamppb.New(s.now()),
+			AccessLevel: pb.AccessLevel_ACCOUNT_ACCESS_LEVEL_FULL_ACCESS,
+		},
+		rate:      decimal.NewFromFloat(s.commission),
+		money:     make(map[string]decimal.Decimal),
+		blocked:   make(map[string]decimal.Decimal),
+		positions: make(map[string]*position),
+	}
+	s.accounts[id] = acc
+	s.accountIds = append(s.accountIds, id)
+	return acc
+}
+
+// OpenAccount - Открытие нового счета, возвращает его идентификатор
+func (s *Server) OpenAccount(name string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.openAccount(uuid.New().String(), name, pb.AccountType_ACCOUNT_TYPE_TINKOFF).info.GetId()
+}
+
+// SetAccessLevel - Изменение уровня доступа токена к счету, который возвращает GetAccounts
+func (s *Server) SetAccessLevel(accountId string, level pb.AccessLevel) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	acc, ok := s.accounts[accountId]
+	if !ok {
+		return apiError(codes.NotFound, investgo.ErrCodeAccountNotFound, "Счет не найден")
+	}
+	acc.info.AccessLevel = level
+	return nil
+}
+
+// PayIn - Пополнение счета на amount в валюте currency
+func (s *Server) PayIn(accountId, currency string, amount float64) error {
+	s.mu.Lock()
+	acc, err := s.account(accountId)
+	if err != nil {
+		s.mu.Unlock()
+		return err
+	}
+	var ev events
+	s.payIn(acc, currency, decimal.NewFromFloat(amount), &ev)
+	s.mu.Unlock()
+	ev.publish()
+	return nil
+}
+
+func (s *Server) payIn(acc *account, currency string, amount decimal.Decimal, ev *events) {
+	currency = strings.ToLower(currency)
+	acc.money[currency] = acc.money[currency].Add(amount)
+	acc.operations = append(acc.operations, &pb.Operation{
+		Id:            uuid.New().String(),
+		Currency:      currency,
+		Payment:       pb.MoneyFromDecimal(amount, currency),
+		State:         pb.OperationState_OPERATION_STATE_EXECUTED,
+		Date:          timestamppb.New(s.now()),
+		Type:          "Пополнение брокерского счета",
+		OperationType: pb.OperationType_OPERATION_TYPE_INPUT,
+	})
+	s.accountChanged(acc, ev)
+}
+
+// SetPosition - Установка позиции по инструменту на счете: quantity в штуках (отрицательное значение - шорт),
+// avgPrice - средняя цена позиции
+func (s *Server) SetPosition(accountId, instrumentId string, quantity int64, avgPrice float64) error {
+	s.mu.Lock()
+	acc, err := s.account(accountId)
+	if err != nil {
+		s.mu.Unlock()
+		return err
+	}
+	inst, err := s.instrumentOrErr(instrumentId)
+	if err != nil {
+		s.mu.Unlock()
+		return err
+	}
+	pos := acc.position(inst)
+	pos.balance = quantity
+	pos.avg = decimal.NewFromFloat(avgPrice)
+	var ev events
+	s.accountChanged(acc, &ev)
+	s.mu.Unlock()
+	ev.publish()
+	return nil
+}
+
+func (s *Server) account(id string) (*account, error) {
+	acc, ok := s.accounts[id]
+	if !ok || acc.info.GetStatus() != pb.AccountStatus_ACCOUNT_STATUS_OPEN {
+		return nil, apiError(codes.NotFound, investgo.ErrCodeAccountNotFound, "Счет не найден")
+	}
+	return acc, nil
+}
+
+func (a *account) position(inst *instrument) *position {
+	pos, ok := a.positions[inst.uid()]
+	if !ok {
+		pos = &position{inst: inst}
+		a.positions[inst.uid()] = pos
+	}
+	return pos
+}
+
+// sortedPositions - позиции счета в порядке добавления инструментов в каталог
+func (s *Server) sortedPositions(acc *account) []*position {
+	positions := make([]*position, 0, len(acc.positions))
+	for _, pos := range acc.positions {
+		if pos.balance != 0 || pos.blocked != 0 {
+			positions = append(positions, pos)
+		}
+	}
+	order := make(map[*instrument]int, len(s.instruments))
+	for i, inst := range s.instruments {
+		order[inst] = i
+	}
+	sort.Slice(positions, func(i, j int) bool {
+		return order[positions[i].inst] < order[positions[j].inst]
+	})
+	return positions
+}
+
+func sortedCurrencies(m ...map[string]decimal.Decimal) []string {
+	set := make(map[string]struct{})
+	for _, mm := range m {
+		for c := range mm {
+			set[c] = struct{}{}
+		}
+	}
+	currencies := make([]string, 0, len(set))
+	for c := range set {
+		currencies = append(currencies, c)
+	}
+	sort.Strings(currencies)
+	return currencies
+}
+
+// accountChanged - отправка нового портфеля и позиций счета в стримы
+func (s *Server) accountChanged(acc *account, ev *events) {
+	id := acc.info.GetId()
+	portfolio := s.portfolio(acc)
+	positions := s.positionData(acc)
+	ev.add(func() {
+		s.portfolios.publish(id, &pb.PortfolioStreamResponse{
+			Payload: &pb.PortfolioStreamResponse_Portfolio{Portfolio: portfolio},
+		})
+		s.positions.publish(id, &pb.PositionsStreamResponse{
+			Payload: &pb.PositionsStreamResponse_Position{Position: positions},
+		})
+	})
+}
+
+// portfolio - портфель счета. Суммы по валютам складываются без конвертации, поэтому итоговые значения
+// имеют смысл, только если все инструменты и деньги в одной валюте
+func (s *Server) portfolio(acc *account) *pb.PortfolioResponse {
+	totals := make(map[pb.InstrumentType]decimal.Decimal)
+	var yield, invested decimal.Decimal
+	resp := &pb.PortfolioResponse{AccountId: acc.info.GetId()}
+	for _, pos := range s.sortedPositions(acc) {
+		inst := pos.inst
+		price := pos.avg
+		if inst.hasPrice {
+			price = inst.price
+		}
+		qty := decimal.NewFromInt(pos.balance)
+		currency := inst.info.GetCurrency()
+		expected := price.Sub(pos.avg).Mul(qty)
+		yield = yield.Add(expected)
+		invested = invested.Add(pos.avg.Mul(qty).Abs())
+		totals[inst.info.GetInstrumentKind()] = totals[inst.info.GetInstrumentKind()].Add(price.Mul(qty))
+		resp.Positions = append(resp.Positions, &pb.PortfolioPosition{
+			Figi:                     inst.info.GetFigi(),
+			InstrumentType:           inst.info.GetInstrumentType(),
+			Quantity:                 pb.QuotationFromDecimal(qty),
+			AveragePositionPrice:     pb.MoneyFromDecimal(pos.avg, currency),
+			ExpectedYield:            pb.QuotationFromDecimal(expected),
+			AveragePositionPricePt:   pb.QuotationFromDecimal(pos.avg),
+			CurrentPrice:             pb.MoneyFromDecimal(price, currency),
+			AveragePositionPriceFifo: pb.MoneyFromDecimal(pos.avg, currency),
+			QuantityLots:             pb.QuotationFromDecimal(qty.Div(decimal.NewFromInt(inst.lot())).Truncate(0)),
+			Blocked:                  pos.blocked != 0,
+			BlockedLots:              pb.QuotationFromDecimal(decimal.NewFromInt(pos.blocked / inst.lot())),
+			PositionUid:              inst.info.GetPositionUid(),
+			InstrumentUid:            inst.uid(),
+			ExpectedYieldFifo:        pb.QuotationFromDecimal(expected),
+		})
+	}
+	var money decimal.Decimal
+	for _, c := range sortedCurrencies(acc.money, acc.blocked) {
+		money = money.Add(acc.money[c]).Add(acc.blocked[c])
+	}
+	totals[pb.InstrumentType_INSTRUMENT_TYPE_CURRENCY] = totals[pb.InstrumentType_INSTRUMENT_TYPE_CURRENCY].Add(money)
+
+	var total decimal.Decimal
+	for _, v := range totals {
+		total = total.Add(v)
+	}
+	resp.TotalAmountShares = pb.MoneyFromDecimal(totals[pb.InstrumentType_INSTRUMENT_TYPE_SHARE], "rub")
+	resp.TotalAmountBonds = pb.MoneyFromDecimal(totals[pb.InstrumentType_INSTRUMENT_TYPE_BOND], "rub")
+	resp.TotalAmountEtf = pb.MoneyFromDecimal(totals[pb.InstrumentType_INSTRUMENT_TYPE_ETF], "rub")
+	resp.TotalAmountCurrencies = pb.MoneyFromDecimal(totals[pb.InstrumentType_INSTRUMENT_TYPE_CURRENCY], "rub")
+	resp.TotalAmountFutures = pb.MoneyFromDecimal(totals[pb.InstrumentType_INSTRUMENT_TYPE_FUTURES], "rub")
+	resp.TotalAmountOptions = pb.MoneyFromDecimal(totals[pb.InstrumentType_INSTRUMENT_TYPE_OPTION], "rub")
+	resp.TotalAmountSp = pb.MoneyFromDecimal(decimal.Zero, "rub")
+	resp.TotalAmountPortfolio = pb.MoneyFromDecimal(total, "rub")
+	resp.ExpectedYield = pb.QuotationFromDecimal(decimal.Zero)
+	if !invested.IsZero() {
+		resp.ExpectedYield = pb.QuotationFromDecimal(yield.Div(invested).Mul(decimal.NewFromInt(100)).Round(2))
+	}
+	return resp
+}
+
+// positions - позиции счета в формате OperationsService.GetPositions
+func (s *Server) positionsResponse(acc *account) *pb.PositionsResponse {
+	resp := &pb.PositionsResponse{AccountId: acc.info.GetId()}
+	for _, c := range sortedCurrencies(acc.money, acc.blocked) {
+		resp.Money = append(resp.Money, pb.MoneyFromDecimal(acc.money[c], c))
+		if !acc.blocked[c].IsZero() {
+			resp.Blocked = append(resp.Blocked, pb.MoneyFromDecimal(acc.blocked[c], c))
+		}
+	}
+	for _, pos := range s.sortedPositions(acc) {
+		inst := pos.inst
+		switch inst.info.GetInstrumentKind() {
+		case pb.InstrumentType_INSTRUMENT_TYPE_FUTURES:
+			resp.Futures = append(resp.Futures, &pb.PositionsFutures{
+				Figi: inst.info.GetFigi(), Blocked: pos.blocked, Balance: pos.balance - pos.blocked,
+				PositionUid: inst.info.GetPositionUid(), InstrumentUid: inst.uid(),
+			})
+		case pb.InstrumentType_INSTRUMENT_TYPE_OPTION:
+			resp.Options = append(resp.Options, &pb.PositionsOptions{
+				Blocked: pos.blocked, Balance: pos.balance - pos.blocked,
+				PositionUid: inst.info.GetPositionUid(), InstrumentUid: inst.uid(),
+			})
+		default:
+			resp.Securities = append(resp.Securities, &pb.PositionsSecurities{
+				Figi: inst.info.GetFigi(), Blocked: pos.blocked, Balance: pos.balance - pos.blocked,
+				PositionUid: inst.info.GetPositionUid(), InstrumentUid: inst.uid(),
+				InstrumentType: inst.info.GetInstrumentType(),
+			})
+		}
+	}
+	return resp
+}
+
+// positionData - позиции счета в формате стрима позиций
+func (s *Server) positionData(acc *account) *pb.PositionData {
+	positions := s.positionsResponse(acc)
+	data := &pb.PositionData{
+		AccountId:  acc.info.GetId(),
+		Securities: positions.GetSecurities(),
+		Futures:    positions.GetFutures(),
+		Options:    positions.GetOptions(),
+		Date:       timestamppb.New(s.now()),
+	}
+	for _, c := range sortedCurrencies(acc.money, acc.blocked) {
+		data.Money = append(data.Money, &pb.PositionsMoney{
+			AvailableValue: pb.MoneyFromDecimal(acc.money[c], c),
+			BlockedValue:   pb.MoneyFromDecimal(acc.blocked[c], c),
+		})
+	}
+	return data
+}
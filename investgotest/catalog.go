@@ -0,0 +1,476 @@
+package investgotest
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "github.com/russianinvestments/invest-api-go-sdk/proto"
+)
+
+// defaultMinPriceIncrement - шаг цены инструмента, если он не задан
+var defaultMinPriceIncrement = &pb.Quotation{Units: 0, Nano: 10_000_000}
+
+// instrument - инструмент каталога и его биржевая информация
+type instrument struct {
+	info *pb.Instrument
+
+	share    *pb.Share
+	bond     *pb.Bond
+	etf      *pb.Etf
+	future   *pb.Future
+	currency *pb.Currency
+	option   *pb.Option
+
+	price      decimal.Decimal
+	priceTime  time.Time
+	hasPrice   bool
+	closePrice decimal.Decimal
+	closeTime  time.Time
+	hasClose   bool
+
+	candles    map[pb.CandleInterval][]*pb.HistoricCandle
+	trades     []*pb.Trade
+	book       *pb.OrderBook
+	coupons    []*pb.Coupon
+	bondEvents []*pb.GetBondEventsResponse_BondEvent
+	dividends  []*pb.Dividend
+}
+
+func (i *instrument) uid() string {
+	return i.info.GetUid()
+}
+
+func (i *instrument) setTradingStatus(status pb.SecurityTradingStatus) {
+	i.info.TradingStatus = status
+	switch {
+	case i.share != nil:
+		i.share.TradingStatus = status
+	case i.bond != nil:
+		i.bond.TradingStatus = status
+	case i.etf != nil:
+		i.etf.TradingStatus = status
+	case i.future != nil:
+		i.future.TradingStatus = status
+	case i.currency != nil:
+		i.currency.TradingStatus = status
+	case i.option != nil:
+		i.option.TradingStatus = status
+	}
+}
+
+func (i *instrument) lot() int64 {
+	return int64(i.info.GetLot())
+}
+
+func (i *instrument) minPriceIncrement() decimal.Decimal {
+	return i.info.GetMinPriceIncrement().ToDecimal()
+}
+
+func (i *instrument) short() *pb.InstrumentShort {
+	return &pb.InstrumentShort{
+		Isin:                  i.info.GetIsin(),
+		Figi:                  i.info.GetFigi(),
+		Ticker:                i.info.GetTicker(),
+		ClassCode:             i.info.GetClassCode(),
+		InstrumentType:        i.info.GetInstrumentType(),
+		Name:                  i.info.GetName(),
+		Uid:                   i.info.GetUid(),
+		PositionUid:           i.info.GetPositionUid(),
+		InstrumentKind:        i.info.GetInstrumentKind(),
+		ApiTradeAvailableFlag: i.info.GetApiTradeAvailableFlag(),
+		ForIisFlag:            i.info.GetForIisFlag(),
+		ForQualInvestorFlag:   i.info.GetForQualInvestorFlag(),
+		WeekendFlag:           i.info.GetWeekendFlag(),
+		BlockedTcaFlag:        i.info.GetBlockedTcaFlag(),
+		Lot:                   i.info.GetLot(),
+	}
+}
+
+// setDefaults - заполнение полей инструмента, без которых сервер не может с ним работать
+func setDefaults(uid, positionUid, currency *string, lot *int32, inc **pb.Quotation, status *pb.SecurityTradingStatus) {
+	if *uid == "" {
+		*uid = uuid.New().String()
+	}
+	if *positionUid == "" {
+		*positionUid = uuid.New().String()
+	}
+	if *currency == "" {
+		*currency = "rub"
+	}
+	if *lot == 0 {
+		*lot = 1
+	}
+	if *inc == nil {
+		*inc = proto.Clone(defaultMinPriceIncrement).(*pb.Quotation)
+	}
+	if *status == pb.SecurityTradingStatus_SECURITY_TRADING_STATUS_UNSPECIFIED {
+		*status = pb.SecurityTradingStatus_SECURITY_TRADING_STATUS_NORMAL_TRADING
+	}
+}
+
+// AddShare - Добавление акции в каталог. Незаполненные uid, position uid, лот, валюта и шаг цены заполняются
+// значениями по умолчанию. Возвращает uid инструмента
+func (s *Server) AddShare(share *pb.Share) string {
+	share = proto.Clone(share).(*pb.Share)
+	setDefaults(&share.Uid, &share.PositionUid, &share.Currency, &share.Lot, &share.MinPriceIncrement, &share.TradingStatus)
+	return s.addInstrument(&instrument{share: share, info: &pb.Instrument{
+		Figi: share.Figi, Ticker: share.Ticker, ClassCode: share.ClassCode, Isin: share.Isin, Lot: share.Lot,
+		Currency: share.Currency, ShortEnabledFlag: share.ShortEnabledFlag, Name: share.Name, Exchange: share.Exchange,
+		TradingStatus: share.TradingStatus, MinPriceIncrement: share.MinPriceIncrement,
+		ApiTradeAvailableFlag: share.ApiTradeAvailableFlag, BuyAvailableFlag: share.BuyAvailableFlag,
+		SellAvailableFlag: share.SellAvailableFlag, Uid: share.Uid, PositionUid: share.PositionUid,
+		AssetUid: share.AssetUid, InstrumentType: "share", InstrumentKind: pb.InstrumentType_INSTRUMENT_TYPE_SHARE,
+	}})
+}
+
+// AddBond - Добавление облигации в каталог, см. AddShare
+func (s *Server) AddBond(bond *pb.Bond) string {
+	bond = proto.Clone(bond).(*pb.Bond)
+	setDefaults(&bond.Uid, &bond.PositionUid, &bond.Currency, &bond.Lot, &bond.MinPriceIncrement, &bond.TradingStatus)
+	return s.addInstrument(&instrument{bond: bond, info: &pb.Instrument{
+		Figi: bond.Figi, Ticker: bond.Ticker, ClassCode: bond.ClassCode, Isin: bond.Isin, Lot: bond.Lot,
+		Currency: bond.Currency, ShortEnabledFlag: bond.ShortEnabledFlag, Name: bond.Name, Exchange: bond.Exchange,
+		TradingStatus: bond.TradingStatus, MinPriceIncrement: bond.MinPriceIncrement,
+		ApiTradeAvailableFlag: bond.ApiTradeAvailableFlag, BuyAvailableFlag: bond.BuyAvailableFlag,
+		SellAvailableFlag: bond.SellAvailableFlag, Uid: bond.Uid, PositionUid: bond.PositionUid,
+		AssetUid: bond.AssetUid, InstrumentType: "bond", InstrumentKind: pb.InstrumentType_INSTRUMENT_TYPE_BOND,
+	}})
+}
+
+// AddEtf - Добавление фонда в каталог, см. AddShare
+func (s *Server) AddEtf(etf *pb.Etf) string {
+	etf = proto.Clone(etf).(*pb.Etf)
+	setDefaults(&etf.Uid, &etf.PositionUid, &etf.Currency, &etf.Lot, &etf.MinPriceIncrement, &etf.TradingStatus)
+	return s.addInstrument(&instrument{etf: etf, info: &pb.Instrument{
+		Figi: etf.Figi, Ticker: etf.Ticker, ClassCode: etf.ClassCode, Isin: etf.Isin, Lot: etf.Lot,
+		Currency: etf.Currency, ShortEnabledFlag: etf.ShortEnabledFlag, Name: etf.Name, Exchange: etf.Exchange,
+		TradingStatus: etf.TradingStatus, MinPriceIncrement: etf.MinPriceIncrement,
+		ApiTradeAvailableFlag: etf.ApiTradeAvailableFlag, BuyAvailableFlag: etf.BuyAvailableFlag,
+		SellAvailableFlag: etf.SellAvailableFlag, Uid: etf.Uid, PositionUid: etf.PositionUid,
+		AssetUid: etf.AssetUid, InstrumentType: "etf", InstrumentKind: pb.InstrumentType_INSTRUMENT_TYPE_ETF,
+	}})
+}
+
+// AddFuture - Добавление фьючерса в каталог, см. AddShare. Гарантийное обеспечение сервер не учитывает,
+// сделки с фьючерсами рассчитываются как с акциями
+func (s *Server) AddFuture(future *pb.Future) string {
+	future = proto.Clone(future).(*pb.Future)
+	setDefaults(&future.Uid, &future.PositionUid, &future.Currency, &future.Lot, &future.MinPriceIncrement, &future.TradingStatus)
+	return s.addInstrument(&instrument{future: future, info: &pb.Instrument{
+		Figi: future.Figi, Ticker: future.Ticker, ClassCode: future.ClassCode, Lot: future.Lot,
+		Currency: future.Currency, ShortEnabledFlag: future.ShortEnabledFlag, Name: future.Name, Exchange: future.Exchange,
+		TradingStatus: future.TradingStatus, MinPriceIncrement: future.MinPriceIncrement,
+		ApiTradeAvailableFlag: future.ApiTradeAvailableFlag, BuyAvailableFlag: future.BuyAvailableFlag,
+		SellAvailableFlag: future.SellAvailableFlag, Uid: future.Uid, PositionUid: future.PositionUid,
+		InstrumentType: "futures", InstrumentKind: pb.InstrumentType_INSTRUMENT_TYPE_FUTURES,
+	}})
+}
+
+// AddCurrency - Добавление валюты в каталог, см. AddShare
+func (s *Server) AddCurrency(currency *pb.Currency) string {
+	currency = proto.Clone(currency).(*pb.Currency)
+	setDefaults(&currency.Uid, &currency.PositionUid, &currency.Currency, &currency.Lot, &currency.MinPriceIncrement, &currency.TradingStatus)
+	return s.addInstrument(&instrument{currency: currency, info: &pb.Instrument{
+		Figi: currency.Figi, Ticker: currency.Ticker, ClassCode: currency.ClassCode, Isin: currency.Isin, Lot: currency.Lot,
+		Currency: currency.Currency, ShortEnabledFlag: currency.ShortEnabledFlag, Name: currency.Name, Exchange: currency.Exchange,
+		TradingStatus: currency.TradingStatus, MinPriceIncrement: currency.MinPriceIncrement,
+		ApiTradeAvailableFlag: currency.ApiTradeAvailableFlag, BuyAvailableFlag: currency.BuyAvailableFlag,
+		SellAvailableFlag: currency.SellAvailableFlag, Uid: currency.Uid, PositionUid: currency.PositionUid,
+		InstrumentType: "currency", InstrumentKind: pb.InstrumentType_INSTRUMENT_TYPE_CURRENCY,
+	}})
+}
+
+// AddOption - Добавление опциона в каталог, см. AddShare
+func (s *Server) AddOption(option *pb.Option) string {
+	option = proto.Clone(option).(*pb.Option)
+	setDefaults(&option.Uid, &option.PositionUid, &option.Currency, &option.Lot, &option.MinPriceIncrement, &option.TradingStatus)
+	return s.addInstrument(&instrument{option: option, info: &pb.Instrument{
+		Ticker: option.Ticker, ClassCode: option.ClassCode, Lot: option.Lot,
+		Currency: option.Currency, ShortEnabledFlag: option.ShortEnabledFlag, Name: option.Name, Exchange: option.Exchange,
+		TradingStatus: option.TradingStatus, MinPriceIncrement: option.MinPriceIncrement,
+		ApiTradeAvailableFlag: option.ApiTradeAvailableFlag, BuyAvailableFlag: option.BuyAvailableFlag,
+		SellAvailableFlag: option.SellAvailableFlag, Uid: option.Uid, PositionUid: option.PositionUid,
+		InstrumentType: "option", InstrumentKind: pb.InstrumentType_INSTRUMENT_TYPE_OPTION,
+	}})
+}
+
+func (s *Server) addInstrument(inst *instrument) string {
+	inst.candles = make(map[pb.CandleInterval][]*pb.HistoricCandle)
+	s.mu.Lock()
+	s.instruments = append(s.instruments, inst)
+	s.mu.Unlock()
+	return inst.uid()
+}
+
+// findInstrument - поиск инструмента по uid, figi, position uid, тикеру или тикеру с классом в формате
+// ticker_classcode
+func (s *Server) findInstrument(id string) *instrument {
+	if id == "" {
+		return nil
+	}
+	for _, inst := range s.instruments {
+		info := inst.info
+		if id == info.GetUid() || id == info.GetFigi() || id == info.GetPositionUid() || id == info.GetTicker() ||
+			id == info.GetTicker()+"_"+info.GetClassCode() {
+			return inst
+		}
+	}
+	return nil
+}
+
+// findInstrumentBy - поиск инструмента по типу идентификатора, как в InstrumentRequest
+func (s *Server) findInstrumentBy(idType pb.InstrumentIdType, classCode, id string) *instrument {
+	for _, inst := range s.instruments {
+		info := inst.info
+		var ok bool
+		switch idType {
+		case pb.InstrumentIdType_INSTRUMENT_ID_TYPE_FIGI:
+			ok = id == info.GetFigi()
+		case pb.InstrumentIdType_INSTRUMENT_ID_TYPE_TICKER:
+			ok = id == info.GetTicker() && (classCode == "" || classCode == info.GetClassCode())
+		case pb.InstrumentIdType_INSTRUMENT_ID_TYPE_UID:
+			ok = id == info.GetUid()
+		case pb.InstrumentIdType_INSTRUMENT_ID_TYPE_POSITION_UID:
+			ok = id == info.GetPositionUid()
+		default:
+			return s.findInstrument(id)
+		}
+		if ok && id != "" {
+			return inst
+		}
+	}
+	return nil
+}
+
+// instrumentOrErr - поиск инструмента под блокировкой для методов, которые задают состояние сервера
+func (s *Server) instrumentOrErr(id string) (*instrument, error) {
+	inst := s.findInstrument(id)
+	if inst == nil {
+		return nil, fmt.Errorf("investgotest: instrument %q not found", id)
+	}
+	return inst, nil
+}
+
+// SetPrice - Установка цены последней сделки по инструменту. Подписчики последних цен получают новую цену,
+// после чего сервер исполняет выставленные лимитные заявки и стоп-заявки, которые стали исполнимы по этой цене
+func (s *Server) SetPrice(instrumentId string, price float64) error {
+	s.mu.Lock()
+	inst, err := s.instrumentOrErr(instrumentId)
+	if err != nil {
+		s.mu.Unlock()
+		return err
+	}
+	inst.price = roundToIncrement(decimal.NewFromFloat(price), inst.minPriceIncrement())
+	inst.priceTime = s.now()
+	inst.hasPrice = true
+	lp := &pb.LastPrice{
+		Figi:          inst.info.GetFigi(),
+		Price:         pb.QuotationFromDecimal(inst.price),
+		Time:          timestamppb.New(inst.priceTime),
+		InstrumentUid: inst.uid(),
+		LastPriceType: pb.LastPriceType_LAST_PRICE_EXCHANGE,
+	}
+	ev := s.match(inst)
+	s.mu.Unlock()
+
+	s.mdStreams.publish(inst, subLastPrices, 0, &pb.MarketDataResponse{
+		Payload: &pb.MarketDataResponse_LastPrice{LastPrice: lp},
+	})
+	ev.publish()
+	return nil
+}
+
+// SetClosePrice - Установка цены закрытия торговой сессии для GetClosePrices
+func (s *Server) SetClosePrice(instrumentId string, price float64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	inst, err := s.instrumentOrErr(instrumentId)
+	if err != nil {
+		return err
+	}
+	inst.closePrice = decimal.NewFromFloat(price)
+	inst.closeTime = s.now()
+	inst.hasClose = true
+	return nil
+}
+
+// SetTradingStatus - Установка торгового статуса инструмента. Заявки принимаются только в статусе
+// SECURITY_TRADING_STATUS_NORMAL_TRADING, подписчики торговых статусов получают новый статус
+func (s *Server) SetTradingStatus(instrumentId string, status pb.SecurityTradingStatus) error {
+	s.mu.Lock()
+	inst, err := s.instrumentOrErr(instrumentId)
+	if err != nil {
+		s.mu.Unlock()
+		return err
+	}
+	inst.setTradingStatus(status)
+	ts := &pb.TradingStatus{
+		Figi:                     inst.info.GetFigi(),
+		TradingStatus:            status,
+		Time:                     timestamppb.New(s.now()),
+		LimitOrderAvailableFlag:  tradable(inst),
+		MarketOrderAvailableFlag: tradable(inst),
+		InstrumentUid:            inst.uid(),
+	}
+	s.mu.Unlock()
+
+	s.mdStreams.publish(inst, subInfo, 0, &pb.MarketDataResponse{
+		Payload: &pb.MarketDataResponse_TradingStatus{TradingStatus: ts},
+	})
+	return nil
+}
+
+// AddCandles - Добавление исторических свечей инструмента для GetCandles
+func (s *Server) AddCandles(instrumentId string, interval pb.CandleInterval, candles ...*pb.HistoricCandle) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	inst, err := s.instrumentOrErr(instrumentId)
+	if err != nil {
+		return err
+	}
+	list := inst.candles[interval]
+	for _, c := range candles {
+		list = append(list, proto.Clone(c).(*pb.HistoricCandle))
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		return list[i].GetTime().AsTime().Before(list[j].GetTime().AsTime())
+	})
+	inst.candles[interval] = list
+	return nil
+}
+
+// AddCoupons - Добавление купонов облигации для GetBondCoupons
+func (s *Server) AddCoupons(instrumentId string, coupons ...*pb.Coupon) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	inst, err := s.instrumentOrErr(instrumentId)
+	if err != nil {
+		return err
+	}
+	for _, c := range coupons {
+		c = proto.Clone(c).(*pb.Coupon)
+		if c.Figi == "" {
+			c.Figi = inst.info.GetFigi()
+		}
+		inst.coupons = append(inst.coupons, c)
+	}
+	return nil
+}
+
+// AddBondEvents - Добавление событий облигации (купоны, оферты, погашения и амортизации) для GetBondEvents
+func (s *Server) AddBondEvents(instrumentId string, events ...*pb.GetBondEventsResponse_BondEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	inst, err := s.instrumentOrErr(instrumentId)
+	if err != nil {
+		return err
+	}
+	for _, e := range events {
+		e = proto.Clone(e).(*pb.GetBondEventsResponse_BondEvent)
+		if e.InstrumentId == "" {
+			e.InstrumentId = inst.uid()
+		}
+		inst.bondEvents = append(inst.bondEvents, e)
+	}
+	sort.SliceStable(inst.bondEvents, func(i, j int) bool {
+		return inst.bondEvents[i].GetEventDate().AsTime().Before(inst.bondEvents[j].GetEventDate().AsTime())
+	})
+	return nil
+}
+
+// AddDividends - Добавление дивидендов для GetDividends
+func (s *Server) AddDividends(instrumentId string, dividends ...*pb.Dividend) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	inst, err := s.instrumentOrErr(instrumentId)
+	if err != nil {
+		return err
+	}
+	for _, d := range dividends {
+		inst.dividends = append(inst.dividends, proto.Clone(d).(*pb.Dividend))
+	}
+	return nil
+}
+
+// SetTradingSchedule - Установка расписания торгов площадки для TradingSchedules. Для площадок без
+// расписания сервер отдает торговые дни с понедельника по пятницу с 07:00 до 15:40 UTC
+func (s *Server) SetTradingSchedule(schedule *pb.TradingSchedule) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.schedules[strings.ToLower(schedule.GetExchange())] = proto.Clone(schedule).(*pb.TradingSchedule)
+}
+
+// PushCandle - Отправка свечи подписчикам свечей инструмента с тем же интервалом. Инструмент определяется
+// по InstrumentUid или Figi свечи
+func (s *Server) PushCandle(candle *pb.Candle) error {
+	candle = proto.Clone(candle).(*pb.Candle)
+	inst, err := s.pushTarget(candle.GetInstrumentUid(), candle.GetFigi())
+	if err != nil {
+		return err
+	}
+	candle.InstrumentUid, candle.Figi = inst.uid(), inst.info.GetFigi()
+	s.mdStreams.publish(inst, subCandles, int32(candle.GetInterval()), &pb.MarketDataResponse{
+		Payload: &pb.MarketDataResponse_Candle{Candle: candle},
+	})
+	return nil
+}
+
+// PushOrderBook - Отправка стакана подписчикам стаканов инструмента, стакан также становится ответом GetOrderBook
+func (s *Server) PushOrderBook(ob *pb.OrderBook) error {
+	ob = proto.Clone(ob).(*pb.OrderBook)
+	inst, err := s.pushTarget(ob.GetInstrumentUid(), ob.GetFigi())
+	if err != nil {
+		return err
+	}
+	ob.InstrumentUid, ob.Figi = inst.uid(), inst.info.GetFigi()
+	if ob.Time == nil {
+		ob.Time = timestamppb.New(s.now())
+	}
+	s.mu.Lock()
+	inst.book = ob
+	s.mu.Unlock()
+	s.mdStreams.publish(inst, subOrderBooks, 0, &pb.MarketDataResponse{
+		Payload: &pb.MarketDataResponse_Orderbook{Orderbook: ob},
+	})
+	return nil
+}
+
+// PushTrade - Отправка обезличенной сделки подписчикам сделок инструмента, сделка также попадает в GetLastTrades
+func (s *Server) PushTrade(trade *pb.Trade) error {
+	trade = proto.Clone(trade).(*pb.Trade)
+	inst, err := s.pushTarget(trade.GetInstrumentUid(), trade.GetFigi())
+	if err != nil {
+		return err
+	}
+	trade.InstrumentUid, trade.Figi = inst.uid(), inst.info.GetFigi()
+	if trade.Time == nil {
+		trade.Time = timestamppb.New(s.now())
+	}
+	s.mu.Lock()
+	inst.trades = append(inst.trades, trade)
+	s.mu.Unlock()
+	s.mdStreams.publish(inst, subTrades, 0, &pb.MarketDataResponse{
+		Payload: &pb.MarketDataResponse_Trade{Trade: trade},
+	})
+	return nil
+}
+
+func (s *Server) pushTarget(uid, figi string) (*instrument, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if uid != "" {
+		return s.instrumentOrErr(uid)
+	}
+	return s.instrumentOrErr(figi)
+}
+
+func tradable(inst *instrument) bool {
+	return inst.info.GetTradingStatus() == pb.SecurityTradingStatus_SECURITY_TRADING_STATUS_NORMAL_TRADING
+}
@@ -0,0 +1,32 @@
+package investgotest
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// roundToIncrement - округление цены до шага цены инструмента
+func roundToIncrement(price, inc decimal.Decimal) decimal.Decimal {
+	if inc.IsZero() {
+		return price
+	}
+	return price.Div(inc).Round(0).Mul(inc)
+}
+
+// inRange - время t попадает в полуинтервал [from, to). Граница, равная nil или нулевому time.Time, не задана:
+// клиент investgo передает незаполненные даты именно так
+func inRange(t, from, to *timestamppb.Timestamp) bool {
+	if bounded(from) && t.AsTime().Before(from.AsTime()) {
+		return false
+	}
+	if bounded(to) && !t.AsTime().Before(to.AsTime()) {
+		return false
+	}
+	return true
+}
+
+func bounded(ts *timestamppb.Timestamp) bool {
+	return ts != nil && ts.AsTime().After(time.Time{})
+}
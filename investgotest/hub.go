@@ -0,0 +1,103 @@
+package investgotest
+
+import (
+	"sync"
+)
+
+// notifier - оповещение об изменении подписок для WaitSubscribed
+type notifier struct {
+	mu sync.Mutex
+	ch chan struct{}
+}
+
+func newNotifier() *notifier {
+	return &notifier{ch: make(chan struct{})}
+}
+
+// wait - канал закроется при следующем изменении подписок
+func (n *notifier) wait() <-chan struct{} {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.ch
+}
+
+func (n *notifier) notify() {
+	n.mu.Lock()
+	close(n.ch)
+	n.ch = make(chan struct{})
+	n.mu.Unlock()
+}
+
+// hubSub - подписка стрима на события счетов
+type hubSub[T any] struct {
+	accounts map[string]struct{}
+	ch       chan T
+	done     <-chan struct{}
+}
+
+// hub - рассылка событий по счетам в стримы сделок, заявок, портфеля и позиций
+type hub[T any] struct {
+	mu   sync.Mutex
+	subs map[*hubSub[T]]struct{}
+	n    *notifier
+}
+
+func newHub[T any](n *notifier) *hub[T] {
+	return &hub[T]{
+		subs: make(map[*hubSub[T]]struct{}),
+		n:    n,
+	}
+}
+
+func (h *hub[T]) subscribe(accounts []string, done <-chan struct{}) *hubSub[T] {
+	sub := &hubSub[T]{
+		accounts: make(map[string]struct{}, len(accounts)),
+		ch:       make(chan T, streamBuffer),
+		done:     done,
+	}
+	for _, id := range accounts {
+		sub.accounts[id] = struct{}{}
+	}
+	h.mu.Lock()
+	h.subs[sub] = struct{}{}
+	h.mu.Unlock()
+	h.n.notify()
+	return sub
+}
+
+func (h *hub[T]) unsubscribe(sub *hubSub[T]) {
+	h.mu.Lock()
+	delete(h.subs, sub)
+	h.mu.Unlock()
+	h.n.notify()
+}
+
+func (h *hub[T]) subscribed(account string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for sub := range h.subs {
+		if _, ok := sub.accounts[account]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// publish - отправка события во все стримы, подписанные на счет. Если стрим не успевает читать,
+// отправка ждет, пока он освободит буфер или завершится
+func (h *hub[T]) publish(account string, v T) {
+	h.mu.Lock()
+	matched := make([]*hubSub[T], 0, 1)
+	for sub := range h.subs {
+		if _, ok := sub.accounts[account]; ok {
+			matched = append(matched, sub)
+		}
+	}
+	h.mu.Unlock()
+	for _, sub := range matched {
+		select {
+		case sub.ch <- v:
+		case <-sub.done:
+		}
+	}
+}
@@ -0,0 +1,364 @@
+package investgotest
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/russianinvestments/invest-api-go-sdk/investgo"
+	pb "github.com/russianinvestments/invest-api-go-sdk/proto"
+)
+
+func errInstrumentNotFound() error {
+	return apiError(codes.NotFound, investgo.ErrCodeInstrumentNotFound, "Инструмент не найден")
+}
+
+// instrumentBy - копия инструмента нужного типа по запросу *By методов, get возвращает nil для других типов
+func instrumentBy[T proto.Message](s *Server, req *pb.InstrumentRequest, get func(*instrument) T) (T, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var zero T
+	inst := s.findInstrumentBy(req.GetIdType(), req.GetClassCode(), req.GetId())
+	if inst == nil {
+		return zero, errInstrumentNotFound()
+	}
+	v := get(inst)
+	if !v.ProtoReflect().IsValid() {
+		return zero, errInstrumentNotFound()
+	}
+	return proto.Clone(v).(T), nil
+}
+
+// listInstruments - все инструменты каталога, для которых get возвращает не nil
+func listInstruments[T proto.Message](s *Server, get func(*instrument) T) []T {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list := make([]T, 0)
+	for _, inst := range s.instruments {
+		if v := get(inst); v.ProtoReflect().IsValid() {
+			list = append(list, proto.Clone(v).(T))
+		}
+	}
+	return list
+}
+
+func (s *Server) TradingSchedules(ctx context.Context, req *pb.TradingSchedulesRequest) (*pb.TradingSchedulesResponse, error) {
+	from, to := s.now(), s.now()
+	if req.GetFrom() != nil {
+		from = req.GetFrom().AsTime()
+	}
+	if req.GetTo() != nil {
+		to = req.GetTo().AsTime()
+	}
+	if to.Before(from) {
+		return nil, apiError(codes.InvalidArgument, 30005, "Некорректный период")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	exchanges := make([]string, 0)
+	if req.GetExchange() != "" {
+		exchanges = append(exchanges, req.GetExchange())
+	} else {
+		for _, sch := range s.schedules {
+			exchanges = append(exchanges, sch.GetExchange())
+		}
+	}
+	resp := &pb.TradingSchedulesResponse{}
+	for _, exchange := range exchanges {
+		sch := &pb.TradingSchedule{Exchange: exchange}
+		if stored, ok := s.schedules[strings.ToLower(exchange)]; ok {
+			for _, day := range stored.GetDays() {
+				date := day.GetDate().AsTime()
+				if !date.Before(truncateDay(from)) && !date.After(to) {
+					sch.Days = append(sch.Days, proto.Clone(day).(*pb.TradingDay))
+				}
+			}
+		} else {
+			sch.Days = defaultTradingDays(from, to)
+		}
+		resp.Exchanges = append(resp.Exchanges, sch)
+	}
+	return resp, nil
+}
+
+func truncateDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// defaultTradingDays - расписание по умолчанию: торги по будним дням с 07:00 до 15:40 UTC
+func defaultTradingDays(from, to time.Time) []*pb.TradingDay {
+	days := make([]*pb.TradingDay, 0)
+	for day := truncateDay(from.UTC()); !day.After(to); day = day.AddDate(0, 0, 1) {
+		td := &pb.TradingDay{Date: timestamppb.New(day)}
+		if wd := day.Weekday(); wd != time.Saturday && wd != time.Sunday {
+			td.IsTradingDay = true
+			td.StartTime = timestamppb.New(day.Add(7 * time.Hour))
+			td.EndTime = timestamppb.New(day.Add(15*time.Hour + 40*time.Minute))
+		}
+		days = append(days, td)
+	}
+	return days
+}
+
+func (s *Server) BondBy(ctx context.Context, req *pb.InstrumentRequest) (*pb.BondResponse, error) {
+	v, err := instrumentBy(s, req, func(i *instrument) *pb.Bond { return i.bond })
+	if err != nil {
+		return nil, err
+	}
+	return &pb.BondResponse{Instrument: v}, nil
+}
+
+func (s *Server) Bonds(ctx context.Context, req *pb.InstrumentsRequest) (*pb.BondsResponse, error) {
+	return &pb.BondsResponse{Instruments: listInstruments(s, func(i *instrument) *pb.Bond { return i.bond })}, nil
+}
+
+func (s *Server) GetBondCoupons(ctx context.Context, req *pb.GetBondCouponsRequest) (*pb.GetBondCouponsResponse, error) {
+	id := req.GetInstrumentId()
+	if id == "" {
+		id = req.GetFigi()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	inst := s.findInstrument(id)
+	if inst == nil || inst.bond == nil {
+		return nil, errInstrumentNotFound()
+	}
+	resp := &pb.GetBondCouponsResponse{}
+	for _, c := range inst.coupons {
+		if inRange(c.GetCouponDate(), req.GetFrom(), req.GetTo()) {
+			resp.Events = append(resp.Events, proto.Clone(c).(*pb.Coupon))
+		}
+	}
+	return resp, nil
+}
+
+func (s *Server) GetBondEvents(ctx context.Context, req *pb.GetBondEventsRequest) (*pb.GetBondEventsResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	inst := s.findInstrument(req.GetInstrumentId())
+	if inst == nil || inst.bond == nil {
+		return nil, errInstrumentNotFound()
+	}
+	resp := &pb.GetBondEventsResponse{}
+	for _, e := range inst.bondEvents {
+		if req.GetType() != pb.GetBondEventsRequest_EVENT_TYPE_UNSPECIFIED && e.GetEventType() != req.GetType() {
+			continue
+		}
+		if inRange(e.GetEventDate(), req.GetFrom(), req.GetTo()) {
+			resp.Events = append(resp.Events, proto.Clone(e).(*pb.GetBondEventsResponse_BondEvent))
+		}
+	}
+	return resp, nil
+}
+
+func (s *Server) CurrencyBy(ctx context.Context, req *pb.InstrumentRequest) (*pb.CurrencyResponse, error) {
+	v, err := instrumentBy(s, req, func(i *instrument) *pb.Currency { return i.currency })
+	if err != nil {
+		return nil, err
+	}
+	return &pb.CurrencyResponse{Instrument: v}, nil
+}
+
+func (s *Server) Currencies(ctx context.Context, req *pb.InstrumentsRequest) (*pb.CurrenciesResponse, error) {
+	return &pb.CurrenciesResponse{Instruments: listInstruments(s, func(i *instrument) *pb.Currency { return i.currency })}, nil
+}
+
+func (s *Server) EtfBy(ctx context.Context, req *pb.InstrumentRequest) (*pb.EtfResponse, error) {
+	v, err := instrumentBy(s, req, func(i *instrument) *pb.Etf { return i.etf })
+	if err != nil {
+		return nil, err
+	}
+	return &pb.EtfResponse{Instrument: v}, nil
+}
+
+func (s *Server) Etfs(ctx context.Context, req *pb.InstrumentsRequest) (*pb.EtfsResponse, error) {
+	return &pb.EtfsResponse{Instruments: listInstruments(s, func(i *instrument) *pb.Etf { return i.etf })}, nil
+}
+
+func (s *Server) FutureBy(ctx context.Context, req *pb.InstrumentRequest) (*pb.FutureResponse, error) {
+	v, err := instrumentBy(s, req, func(i *instrument) *pb.Future { return i.future })
+	if err != nil {
+		return nil, err
+	}
+	return &pb.FutureResponse{Instrument: v}, nil
+}
+
+func (s *Server) Futures(ctx context.Context, req *pb.InstrumentsRequest) (*pb.FuturesResponse, error) {
+	return &pb.FuturesResponse{Instruments: listInstruments(s, func(i *instrument) *pb.Future { return i.future })}, nil
+}
+
+// GetFuturesMargin - гарантийное обеспечение и стоимость шага цены из фьючерса каталога
+func (s *Server) GetFuturesMargin(ctx context.Context, req *pb.GetFuturesMarginRequest) (*pb.GetFuturesMarginResponse, error) {
+	id := req.GetInstrumentId()
+	if id == "" {
+		id = req.GetFigi()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	inst := s.findInstrument(id)
+	if inst == nil || inst.future == nil {
+		return nil, errInstrumentNotFound()
+	}
+	f := proto.Clone(inst.future).(*pb.Future)
+	return &pb.GetFuturesMarginResponse{
+		InitialMarginOnBuy:      f.GetInitialMarginOnBuy(),
+		InitialMarginOnSell:     f.GetInitialMarginOnSell(),
+		MinPriceIncrement:       f.GetMinPriceIncrement(),
+		MinPriceIncrementAmount: f.GetMinPriceIncrementAmount(),
+	}, nil
+}
+
+func (s *Server) OptionBy(ctx context.Context, req *pb.InstrumentRequest) (*pb.OptionResponse, error) {
+	v, err := instrumentBy(s, req, func(i *instrument) *pb.Option { return i.option })
+	if err != nil {
+		return nil, err
+	}
+	return &pb.OptionResponse{Instrument: v}, nil
+}
+
+func (s *Server) Options(ctx context.Context, req *pb.InstrumentsRequest) (*pb.OptionsResponse, error) {
+	return &pb.OptionsResponse{Instruments: listInstruments(s, func(i *instrument) *pb.Option { return i.option })}, nil
+}
+
+func (s *Server) OptionsBy(ctx context.Context, req *pb.FilterOptionsRequest) (*pb.OptionsResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	resp := &pb.OptionsResponse{}
+	for _, inst := range s.instruments {
+		o := inst.option
+		if o == nil {
+			continue
+		}
+		if req.GetBasicAssetPositionUid() != "" && o.GetBasicAssetPositionUid() != req.GetBasicAssetPositionUid() {
+			continue
+		}
+		if uid := req.GetBasicAssetUid(); uid != "" && o.GetBasicAsset() != uid {
+			// базовый актив опциона можно указать и через uid или asset uid базового инструмента
+			basic := s.findInstrument(o.GetBasicAssetPositionUid())
+			if basic == nil || (basic.uid() != uid && basic.info.GetAssetUid() != uid) {
+				continue
+			}
+		}
+		resp.Instruments = append(resp.Instruments, proto.Clone(o).(*pb.Option))
+	}
+	return resp, nil
+}
+
+func (s *Server) ShareBy(ctx context.Context, req *pb.InstrumentRequest) (*pb.ShareResponse, error) {
+	v, err := instrumentBy(s, req, func(i *instrument) *pb.Share { return i.share })
+	if err != nil {
+		return nil, err
+	}
+	return &pb.ShareResponse{Instrument: v}, nil
+}
+
+func (s *Server) Shares(ctx context.Context, req *pb.InstrumentsRequest) (*pb.SharesResponse, error) {
+	return &pb.SharesResponse{Instruments: listInstruments(s, func(i *instrument) *pb.Share { return i.share })}, nil
+}
+
+func (s *Server) GetInstrumentBy(ctx context.Context, req *pb.InstrumentRequest) (*pb.InstrumentResponse, error) {
+	v, err := instrumentBy(s, req, func(i *instrument) *pb.Instrument { return i.info })
+	if err != nil {
+		return nil, err
+	}
+	return &pb.InstrumentResponse{Instrument: v}, nil
+}
+
+func (s *Server) GetDividends(ctx context.Context, req *pb.GetDividendsRequest) (*pb.GetDividendsResponse, error) {
+	id := req.GetInstrumentId()
+	if id == "" {
+		id = req.GetFigi()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	inst := s.findInstrument(id)
+	if inst == nil {
+		return nil, errInstrumentNotFound()
+	}
+	resp := &pb.GetDividendsResponse{}
+	for _, d := range inst.dividends {
+		if inRange(d.GetRecordDate(), req.GetFrom(), req.GetTo()) {
+			resp.Dividends = append(resp.Dividends, proto.Clone(d).(*pb.Dividend))
+		}
+	}
+	return resp, nil
+}
+
+func (s *Server) GetFavorites(ctx context.Context, req *pb.GetFavoritesRequest) (*pb.GetFavoritesResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return &pb.GetFavoritesResponse{FavoriteInstruments: s.favoriteInstruments()}, nil
+}
+
+func (s *Server) EditFavorites(ctx context.Context, req *pb.EditFavoritesRequest) (*pb.EditFavoritesResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, in := range req.GetInstruments() {
+		id := in.GetInstrumentId()
+		if id == "" {
+			id = in.GetFigi()
+		}
+		inst := s.findInstrument(id)
+		if inst == nil {
+			return nil, errInstrumentNotFound()
+		}
+		uid := inst.uid()
+		for i, fav := range s.favorites {
+			if fav == uid {
+				s.favorites = append(s.favorites[:i], s.favorites[i+1:]...)
+				break
+			}
+		}
+		if req.GetActionType() == pb.EditFavoritesActionType_EDIT_FAVORITES_ACTION_TYPE_ADD {
+			s.favorites = append(s.favorites, uid)
+		}
+	}
+	return &pb.EditFavoritesResponse{FavoriteInstruments: s.favoriteInstruments()}, nil
+}
+
+func (s *Server) favoriteInstruments() []*pb.FavoriteInstrument {
+	favorites := make([]*pb.FavoriteInstrument, 0, len(s.favorites))
+	for _, uid := range s.favorites {
+		inst := s.findInstrument(uid)
+		favorites = append(favorites, &pb.FavoriteInstrument{
+			Figi:                  inst.info.GetFigi(),
+			Ticker:                inst.info.GetTicker(),
+			ClassCode:             inst.info.GetClassCode(),
+			Isin:                  inst.info.GetIsin(),
+			InstrumentType:        inst.info.GetInstrumentType(),
+			Name:                  inst.info.GetName(),
+			Uid:                   inst.uid(),
+			ApiTradeAvailableFlag: inst.info.GetApiTradeAvailableFlag(),
+			InstrumentKind:        inst.info.GetInstrumentKind(),
+		})
+	}
+	return favorites
+}
+
+func (s *Server) FindInstrument(ctx context.Context, req *pb.FindInstrumentRequest) (*pb.FindInstrumentResponse, error) {
+	query := strings.ToLower(req.GetQuery())
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	resp := &pb.FindInstrumentResponse{}
+	for _, inst := range s.instruments {
+		info := inst.info
+		if req.InstrumentKind != nil && info.GetInstrumentKind() != req.GetInstrumentKind() {
+			continue
+		}
+		if req.ApiTradeAvailableFlag != nil && info.GetApiTradeAvailableFlag() != req.GetApiTradeAvailableFlag() {
+			continue
+		}
+		for _, field := range []string{info.GetTicker(), info.GetFigi(), info.GetIsin(), info.GetName(), info.GetUid()} {
+			if field != "" && strings.Contains(strings.ToLower(field), query) {
+				resp.Instruments = append(resp.Instruments, inst.short())
+				break
+			}
+		}
+	}
+	return resp, nil
+}
@@ -0,0 +1,86 @@
+package investgotest_test
+
+import (
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "github.com/russianinvestments/invest-api-go-sdk/proto"
+)
+
+func TestInstruments(t *testing.T) {
+	_, client, uid := setup(t)
+	instruments := client.NewInstrumentsServiceClient()
+
+	share, err := instruments.ShareByFigi(sberFigi)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if share.GetInstrument().GetUid() != uid || share.GetInstrument().GetLot() != 10 {
+		t.Fatalf("share = %v", share.GetInstrument())
+	}
+	if _, err := instruments.ShareByFigi("unknown"); err == nil {
+		t.Fatal("unknown share found")
+	}
+	shares, err := instruments.Shares(pb.InstrumentStatus_INSTRUMENT_STATUS_BASE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(shares.GetInstruments()) != 1 {
+		t.Fatalf("shares = %v", len(shares.GetInstruments()))
+	}
+	found, err := instruments.FindInstrument("sber")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found.GetInstruments()) != 1 || found.GetInstruments()[0].GetUid() != uid {
+		t.Fatalf("found = %v", found.GetInstruments())
+	}
+
+	now := time.Now()
+	schedules, err := instruments.TradingSchedules("MOEX", now, now.Add(7*24*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(schedules.GetExchanges()) == 0 {
+		t.Fatal("no trading schedules")
+	}
+}
+
+func TestBondCouponsAndEvents(t *testing.T) {
+	srv, client, _ := setup(t)
+	instruments := client.NewInstrumentsServiceClient()
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	bond := srv.AddBond(&pb.Bond{Figi: "RU000A0JX0J2", Ticker: "SU26207RMFS9", ClassCode: "TQOB", Lot: 1, Currency: "rub"})
+	err := srv.AddCoupons(bond,
+		&pb.Coupon{CouponDate: timestamppb.New(start.AddDate(0, 6, 0)), CouponNumber: 1},
+		&pb.Coupon{CouponDate: timestamppb.New(start.AddDate(1, 0, 0)), CouponNumber: 2},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = srv.AddBondEvents(bond,
+		&pb.GetBondEventsResponse_BondEvent{EventDate: timestamppb.New(start.AddDate(1, 0, 0)), EventType: pb.GetBondEventsRequest_EVENT_TYPE_MTY},
+		&pb.GetBondEventsResponse_BondEvent{EventDate: timestamppb.New(start.AddDate(0, 6, 0)), EventType: pb.GetBondEventsRequest_EVENT_TYPE_CPN},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	coupons, err := instruments.GetBondCoupons(bond, start, start.AddDate(0, 9, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(coupons.GetEvents()) != 1 || coupons.GetEvents()[0].GetCouponNumber() != 1 {
+		t.Fatalf("coupons = %v", coupons.GetEvents())
+	}
+	events, err := instruments.GetBondEvents(bond, pb.GetBondEventsRequest_EVENT_TYPE_MTY, time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events.GetEvents()) != 1 || events.GetEvents()[0].GetEventType() != pb.GetBondEventsRequest_EVENT_TYPE_MTY {
+		t.Fatalf("events = %v", events.GetEvents())
+	}
+}
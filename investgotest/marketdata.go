@@ -0,0 +1,197 @@
+package investgotest
+
+import (
+	"context"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "github.com/russianinvestments/invest-api-go-sdk/proto"
+)
+
+// orderBookDepths - допустимая глубина стакана
+var orderBookDepths = map[int32]bool{1: true, 10: true, 20: true, 30: true, 40: true, 50: true}
+
+func (s *Server) GetCandles(ctx context.Context, req *pb.GetCandlesRequest) (*pb.GetCandlesResponse, error) {
+	id := req.GetInstrumentId()
+	if id == "" {
+		id = req.GetFigi()
+	}
+	if req.GetFrom() == nil || req.GetTo() == nil || !req.GetFrom().AsTime().Before(req.GetTo().AsTime()) {
+		return nil, apiError(codes.InvalidArgument, 30005, "Некорректный период")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	inst := s.findInstrument(id)
+	if inst == nil {
+		return nil, errInstrumentNotFound()
+	}
+	resp := &pb.GetCandlesResponse{}
+	for _, c := range inst.candles[req.GetInterval()] {
+		if req.Limit != nil && len(resp.Candles) >= int(req.GetLimit()) {
+			break
+		}
+		if inRange(c.GetTime(), req.GetFrom(), req.GetTo()) {
+			resp.Candles = append(resp.Candles, proto.Clone(c).(*pb.HistoricCandle))
+		}
+	}
+	return resp, nil
+}
+
+func (s *Server) GetLastPrices(ctx context.Context, req *pb.GetLastPricesRequest) (*pb.GetLastPricesResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ids := append(append([]string{}, req.GetInstrumentId()...), req.GetFigi()...)
+	resp := &pb.GetLastPricesResponse{}
+	for _, id := range ids {
+		inst := s.findInstrument(id)
+		if inst == nil {
+			return nil, errInstrumentNotFound()
+		}
+		if !inst.hasPrice {
+			continue
+		}
+		resp.LastPrices = append(resp.LastPrices, &pb.LastPrice{
+			Figi:          inst.info.GetFigi(),
+			Price:         pb.QuotationFromDecimal(inst.price),
+			Time:          timestamppb.New(inst.priceTime),
+			InstrumentUid: inst.uid(),
+			LastPriceType: pb.LastPriceType_LAST_PRICE_EXCHANGE,
+		})
+	}
+	return resp, nil
+}
+
+// GetOrderBook - Последний стакан из PushOrderBook. Если стакан не задан, сервер отдает пустой стакан с
+// ценой последней сделки
+func (s *Server) GetOrderBook(ctx context.Context, req *pb.GetOrderBookRequest) (*pb.GetOrderBookResponse, error) {
+	id := req.GetInstrumentId()
+	if id == "" {
+		id = req.GetFigi()
+	}
+	if !orderBookDepths[req.GetDepth()] {
+		return nil, apiError(codes.InvalidArgument, 30008, "Некорректная глубина стакана")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	inst := s.findInstrument(id)
+	if inst == nil {
+		return nil, errInstrumentNotFound()
+	}
+	resp := &pb.GetOrderBookResponse{
+		Figi:          inst.info.GetFigi(),
+		InstrumentUid: inst.uid(),
+		Depth:         req.GetDepth(),
+	}
+	if inst.book != nil {
+		book := trimOrderBook(inst.book, req.GetDepth())
+		resp.Bids, resp.Asks = book.GetBids(), book.GetAsks()
+		resp.LimitUp, resp.LimitDown = book.GetLimitUp(), book.GetLimitDown()
+		resp.OrderbookTs = book.GetTime()
+	}
+	if inst.hasPrice {
+		resp.LastPrice = pb.QuotationFromDecimal(inst.price)
+		resp.LastPriceTs = timestamppb.New(inst.priceTime)
+	}
+	if inst.hasClose {
+		resp.ClosePrice = pb.QuotationFromDecimal(inst.closePrice)
+		resp.ClosePriceTs = timestamppb.New(inst.closeTime)
+	}
+	return resp, nil
+}
+
+// trimOrderBook - копия стакана глубиной не больше depth
+func trimOrderBook(ob *pb.OrderBook, depth int32) *pb.OrderBook {
+	ob = proto.Clone(ob).(*pb.OrderBook)
+	if len(ob.Bids) > int(depth) {
+		ob.Bids = ob.Bids[:depth]
+	}
+	if len(ob.Asks) > int(depth) {
+		ob.Asks = ob.Asks[:depth]
+	}
+	ob.Depth = depth
+	return ob
+}
+
+func (s *Server) GetTradingStatus(ctx context.Context, req *pb.GetTradingStatusRequest) (*pb.GetTradingStatusResponse, error) {
+	id := req.GetInstrumentId()
+	if id == "" {
+		id = req.GetFigi()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	inst := s.findInstrument(id)
+	if inst == nil {
+		return nil, errInstrumentNotFound()
+	}
+	return tradingStatus(inst), nil
+}
+
+func (s *Server) GetTradingStatuses(ctx context.Context, req *pb.GetTradingStatusesRequest) (*pb.GetTradingStatusesResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	resp := &pb.GetTradingStatusesResponse{}
+	for _, id := range req.GetInstrumentId() {
+		inst := s.findInstrument(id)
+		if inst == nil {
+			return nil, errInstrumentNotFound()
+		}
+		resp.TradingStatuses = append(resp.TradingStatuses, tradingStatus(inst))
+	}
+	return resp, nil
+}
+
+func tradingStatus(inst *instrument) *pb.GetTradingStatusResponse {
+	return &pb.GetTradingStatusResponse{
+		Figi:                        inst.info.GetFigi(),
+		TradingStatus:               inst.info.GetTradingStatus(),
+		LimitOrderAvailableFlag:     tradable(inst),
+		MarketOrderAvailableFlag:    tradable(inst),
+		ApiTradeAvailableFlag:       inst.info.GetApiTradeAvailableFlag(),
+		InstrumentUid:               inst.uid(),
+		BestpriceOrderAvailableFlag: tradable(inst),
+	}
+}
+
+func (s *Server) GetLastTrades(ctx context.Context, req *pb.GetLastTradesRequest) (*pb.GetLastTradesResponse, error) {
+	id := req.GetInstrumentId()
+	if id == "" {
+		id = req.GetFigi()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	inst := s.findInstrument(id)
+	if inst == nil {
+		return nil, errInstrumentNotFound()
+	}
+	resp := &pb.GetLastTradesResponse{}
+	for _, t := range inst.trades {
+		if inRange(t.GetTime(), req.GetFrom(), req.GetTo()) {
+			resp.Trades = append(resp.Trades, proto.Clone(t).(*pb.Trade))
+		}
+	}
+	return resp, nil
+}
+
+func (s *Server) GetClosePrices(ctx context.Context, req *pb.GetClosePricesRequest) (*pb.GetClosePricesResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	resp := &pb.GetClosePricesResponse{}
+	for _, in := range req.GetInstruments() {
+		inst := s.findInstrument(in.GetInstrumentId())
+		if inst == nil {
+			return nil, errInstrumentNotFound()
+		}
+		if !inst.hasClose {
+			continue
+		}
+		resp.ClosePrices = append(resp.ClosePrices, &pb.InstrumentClosePriceResponse{
+			Figi:          inst.info.GetFigi(),
+			InstrumentUid: inst.uid(),
+			Price:         pb.QuotationFromDecimal(inst.closePrice),
+			Time:          timestamppb.New(inst.closeTime),
+		})
+	}
+	return resp, nil
+}
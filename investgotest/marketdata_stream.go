@@ -0,0 +1,419 @@
+package investgotest
+
+import (
+	"errors"
+	"io"
+	"sync"
+
+	"github.com/google/uuid"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "github.com/russianinvestments/invest-api-go-sdk/proto"
+)
+
+// subKind - тип подписки в стриме биржевой информации
+type subKind int
+
+const (
+	subCandles subKind = iota
+	subOrderBooks
+	subTrades
+	subInfo
+	subLastPrices
+)
+
+// mdKey - подписка стрима на инструмент, param - интервал для свечей
+type mdKey struct {
+	kind  subKind
+	uid   string
+	param int32
+}
+
+// mdSub - параметры подписки для GetMySubscriptions и отправки стаканов
+type mdSub struct {
+	inst         *instrument
+	depth        int32
+	waitingClose bool
+	tradeSource  pb.TradeSourceType
+	id           string
+}
+
+// mdStream - открытый стрим биржевой информации
+type mdStream struct {
+	id   string
+	out  chan *pb.MarketDataResponse
+	done <-chan struct{}
+
+	mu   sync.Mutex
+	subs map[mdKey]mdSub
+}
+
+func (st *mdStream) send(resp *pb.MarketDataResponse) {
+	select {
+	case st.out <- resp:
+	case <-st.done:
+	}
+}
+
+// mdStreams - все открытые стримы биржевой информации
+type mdStreams struct {
+	mu      sync.Mutex
+	streams map[*mdStream]struct{}
+	n       *notifier
+}
+
+func newMDStreams(n *notifier) *mdStreams {
+	return &mdStreams{
+		streams: make(map[*mdStream]struct{}),
+		n:       n,
+	}
+}
+
+func (m *mdStreams) open(done <-chan struct{}) *mdStream {
+	st := &mdStream{
+		id:   uuid.New().String(),
+		out:  make(chan *pb.MarketDataResponse, streamBuffer),
+		done: done,
+		subs: make(map[mdKey]mdSub),
+	}
+	m.mu.Lock()
+	m.streams[st] = struct{}{}
+	m.mu.Unlock()
+	return st
+}
+
+func (m *mdStreams) close(st *mdStream) {
+	m.mu.Lock()
+	delete(m.streams, st)
+	m.mu.Unlock()
+	m.n.notify()
+}
+
+func (m *mdStreams) all() []*mdStream {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	streams := make([]*mdStream, 0, len(m.streams))
+	for st := range m.streams {
+		streams = append(streams, st)
+	}
+	return streams
+}
+
+func (m *mdStreams) subscribed(inst *instrument) bool {
+	for _, st := range m.all() {
+		st.mu.Lock()
+		for key := range st.subs {
+			if key.uid == inst.uid() {
+				st.mu.Unlock()
+				return true
+			}
+		}
+		st.mu.Unlock()
+	}
+	return false
+}
+
+// publish - отправка биржевой информации во все стримы, подписанные на инструмент. Стаканы обрезаются до
+// глубины подписки
+func (m *mdStreams) publish(inst *instrument, kind subKind, param int32, resp *pb.MarketDataResponse) {
+	key := mdKey{kind: kind, uid: inst.uid(), param: param}
+	for _, st := range m.all() {
+		st.mu.Lock()
+		sub, ok := st.subs[key]
+		st.mu.Unlock()
+		if !ok {
+			continue
+		}
+		if ob := resp.GetOrderbook(); ob != nil {
+			st.send(&pb.MarketDataResponse{
+				Payload: &pb.MarketDataResponse_Orderbook{Orderbook: trimOrderBook(ob, sub.depth)},
+			})
+			continue
+		}
+		st.send(resp)
+	}
+}
+
+// marketDataStreamServer - реализация MarketDataStreamService
+type marketDataStreamServer struct {
+	pb.UnimplementedMarketDataStreamServiceServer
+	s *Server
+}
+
+func (m *marketDataStreamServer) MarketDataStream(stream pb.MarketDataStreamService_MarketDataStreamServer) error {
+	ctx := stream.Context()
+	st := m.s.mdStreams.open(ctx.Done())
+	defer m.s.mdStreams.close(st)
+
+	errCh := make(chan error, 1)
+	go func() {
+		for {
+			req, err := stream.Recv()
+			if err != nil {
+				errCh <- err
+				return
+			}
+			for _, resp := range m.handle(st, req) {
+				st.send(resp)
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-errCh:
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		case resp := <-st.out:
+			if err := stream.Send(resp); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (m *marketDataStreamServer) MarketDataServerSideStream(req *pb.MarketDataServerSideStreamRequest, stream pb.MarketDataStreamService_MarketDataServerSideStreamServer) error {
+	ctx := stream.Context()
+	st := m.s.mdStreams.open(ctx.Done())
+	defer m.s.mdStreams.close(st)
+
+	requests := []*pb.MarketDataRequest{
+		{Payload: &pb.MarketDataRequest_SubscribeCandlesRequest{SubscribeCandlesRequest: req.GetSubscribeCandlesRequest()}},
+		{Payload: &pb.MarketDataRequest_SubscribeOrderBookRequest{SubscribeOrderBookRequest: req.GetSubscribeOrderBookRequest()}},
+		{Payload: &pb.MarketDataRequest_SubscribeTradesRequest{SubscribeTradesRequest: req.GetSubscribeTradesRequest()}},
+		{Payload: &pb.MarketDataRequest_SubscribeInfoRequest{SubscribeInfoRequest: req.GetSubscribeInfoRequest()}},
+		{Payload: &pb.MarketDataRequest_SubscribeLastPriceRequest{SubscribeLastPriceRequest: req.GetSubscribeLastPriceRequest()}},
+	}
+	for _, r := range requests {
+		for _, resp := range m.handle(st, r) {
+			if err := stream.Send(resp); err != nil {
+				return err
+			}
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case resp := <-st.out:
+			if err := stream.Send(resp); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// handle - обработка запроса стрима, возвращает ответы сервера
+func (m *marketDataStreamServer) handle(st *mdStream, req *pb.MarketDataRequest) []*pb.MarketDataResponse {
+	switch {
+	case req.GetSubscribeCandlesRequest() != nil:
+		return []*pb.MarketDataResponse{m.candles(st, req.GetSubscribeCandlesRequest())}
+	case req.GetSubscribeOrderBookRequest() != nil:
+		return []*pb.MarketDataResponse{m.orderBooks(st, req.GetSubscribeOrderBookRequest())}
+	case req.GetSubscribeTradesRequest() != nil:
+		return []*pb.MarketDataResponse{m.trades(st, req.GetSubscribeTradesRequest())}
+	case req.GetSubscribeInfoRequest() != nil:
+		return []*pb.MarketDataResponse{m.info(st, req.GetSubscribeInfoRequest())}
+	case req.GetSubscribeLastPriceRequest() != nil:
+		return []*pb.MarketDataResponse{m.lastPrices(st, req.GetSubscribeLastPriceRequest())}
+	case req.GetGetMySubscriptions() != nil:
+		return m.mySubscriptions(st)
+	case req.GetPing() != nil:
+		return []*pb.MarketDataResponse{{Payload: &pb.MarketDataResponse_Ping{Ping: &pb.Ping{
+			Time:            timestamppb.New(m.s.now()),
+			StreamId:        st.id,
+			PingRequestTime: req.GetPing().GetTime(),
+		}}}}
+	}
+	return nil
+}
+
+// update - подписка или отписка стрима от инструмента, возвращает найденный инструмент и статус подписки
+func (m *marketDataStreamServer) update(st *mdStream, action pb.SubscriptionAction, kind subKind, id string, param int32, sub mdSub) (*instrument, pb.SubscriptionStatus) {
+	m.s.mu.Lock()
+	inst := m.s.findInstrument(id)
+	m.s.mu.Unlock()
+	if inst == nil {
+		return nil, pb.SubscriptionStatus_SUBSCRIPTION_STATUS_INSTRUMENT_NOT_FOUND
+	}
+	key := mdKey{kind: kind, uid: inst.uid(), param: param}
+	st.mu.Lock()
+	switch action {
+	case pb.SubscriptionAction_SUBSCRIPTION_ACTION_SUBSCRIBE:
+		sub.inst = inst
+		sub.id = uuid.New().String()
+		st.subs[key] = sub
+	case pb.SubscriptionAction_SUBSCRIPTION_ACTION_UNSUBSCRIBE:
+		delete(st.subs, key)
+	default:
+		st.mu.Unlock()
+		return inst, pb.SubscriptionStatus_SUBSCRIPTION_STATUS_SUBSCRIPTION_ACTION_IS_INVALID
+	}
+	st.mu.Unlock()
+	m.s.subscriptions.notify()
+	return inst, pb.SubscriptionStatus_SUBSCRIPTION_STATUS_SUCCESS
+}
+
+func instrumentId(id, figi string) string {
+	if id != "" {
+		return id
+	}
+	return figi
+}
+
+func (m *marketDataStreamServer) candles(st *mdStream, req *pb.SubscribeCandlesRequest) *pb.MarketDataResponse {
+	resp := &pb.SubscribeCandlesResponse{TrackingId: uuid.New().String()}
+	for _, in := range req.GetInstruments() {
+		id := instrumentId(in.GetInstrumentId(), in.GetFigi())
+		res := &pb.CandleSubscription{
+			Figi:             in.GetFigi(),
+			Interval:         in.GetInterval(),
+			InstrumentUid:    id,
+			WaitingClose:     req.GetWaitingClose(),
+			StreamId:         st.id,
+			CandleSourceType: req.CandleSourceType,
+		}
+		if in.GetInterval() == pb.SubscriptionInterval_SUBSCRIPTION_INTERVAL_UNSPECIFIED {
+			res.SubscriptionStatus = pb.SubscriptionStatus_SUBSCRIPTION_STATUS_INTERVAL_IS_INVALID
+		} else {
+			inst, status := m.update(st, req.GetSubscriptionAction(), subCandles, id, int32(in.GetInterval()), mdSub{waitingClose: req.GetWaitingClose()})
+			res.SubscriptionStatus = status
+			if inst != nil {
+				res.Figi, res.InstrumentUid = inst.info.GetFigi(), inst.uid()
+			}
+		}
+		resp.CandlesSubscriptions = append(resp.CandlesSubscriptions, res)
+	}
+	return &pb.MarketDataResponse{Payload: &pb.MarketDataResponse_SubscribeCandlesResponse{SubscribeCandlesResponse: resp}}
+}
+
+func (m *marketDataStreamServer) orderBooks(st *mdStream, req *pb.SubscribeOrderBookRequest) *pb.MarketDataResponse {
+	resp := &pb.SubscribeOrderBookResponse{TrackingId: uuid.New().String()}
+	for _, in := range req.GetInstruments() {
+		id := instrumentId(in.GetInstrumentId(), in.GetFigi())
+		res := &pb.OrderBookSubscription{
+			Figi:          in.GetFigi(),
+			Depth:         in.GetDepth(),
+			InstrumentUid: id,
+			StreamId:      st.id,
+			OrderBookType: in.GetOrderBookType(),
+		}
+		if !orderBookDepths[in.GetDepth()] && req.GetSubscriptionAction() == pb.SubscriptionAction_SUBSCRIPTION_ACTION_SUBSCRIBE {
+			res.SubscriptionStatus = pb.SubscriptionStatus_SUBSCRIPTION_STATUS_DEPTH_IS_INVALID
+		} else {
+			inst, status := m.update(st, req.GetSubscriptionAction(), subOrderBooks, id, 0, mdSub{depth: in.GetDepth()})
+			res.SubscriptionStatus = status
+			if inst != nil {
+				res.Figi, res.InstrumentUid = inst.info.GetFigi(), inst.uid()
+			}
+		}
+		resp.OrderBookSubscriptions = append(resp.OrderBookSubscriptions, res)
+	}
+	return &pb.MarketDataResponse{Payload: &pb.MarketDataResponse_SubscribeOrderBookResponse{SubscribeOrderBookResponse: resp}}
+}
+
+func (m *marketDataStreamServer) trades(st *mdStream, req *pb.SubscribeTradesRequest) *pb.MarketDataResponse {
+	resp := &pb.SubscribeTradesResponse{TrackingId: uuid.New().String(), TradeSource: req.GetTradeSource()}
+	for _, in := range req.GetInstruments() {
+		id := instrumentId(in.GetInstrumentId(), in.GetFigi())
+		inst, status := m.update(st, req.GetSubscriptionAction(), subTrades, id, 0, mdSub{tradeSource: req.GetTradeSource()})
+		res := &pb.TradeSubscription{Figi: in.GetFigi(), InstrumentUid: id, SubscriptionStatus: status, StreamId: st.id}
+		if inst != nil {
+			res.Figi, res.InstrumentUid = inst.info.GetFigi(), inst.uid()
+		}
+		resp.TradeSubscriptions = append(resp.TradeSubscriptions, res)
+	}
+	return &pb.MarketDataResponse{Payload: &pb.MarketDataResponse_SubscribeTradesResponse{SubscribeTradesResponse: resp}}
+}
+
+func (m *marketDataStreamServer) info(st *mdStream, req *pb.SubscribeInfoRequest) *pb.MarketDataResponse {
+	resp := &pb.SubscribeInfoResponse{TrackingId: uuid.New().String()}
+	for _, in := range req.GetInstruments() {
+		id := instrumentId(in.GetInstrumentId(), in.GetFigi())
+		inst, status := m.update(st, req.GetSubscriptionAction(), subInfo, id, 0, mdSub{})
+		res := &pb.InfoSubscription{Figi: in.GetFigi(), InstrumentUid: id, SubscriptionStatus: status, StreamId: st.id}
+		if inst != nil {
+			res.Figi, res.InstrumentUid = inst.info.GetFigi(), inst.uid()
+		}
+		resp.InfoSubscriptions = append(resp.InfoSubscriptions, res)
+	}
+	return &pb.MarketDataResponse{Payload: &pb.MarketDataResponse_SubscribeInfoResponse{SubscribeInfoResponse: resp}}
+}
+
+func (m *marketDataStreamServer) lastPrices(st *mdStream, req *pb.SubscribeLastPriceRequest) *pb.MarketDataResponse {
+	resp := &pb.SubscribeLastPriceResponse{TrackingId: uuid.New().String()}
+	for _, in := range req.GetInstruments() {
+		id := instrumentId(in.GetInstrumentId(), in.GetFigi())
+		inst, status := m.update(st, req.GetSubscriptionAction(), subLastPrices, id, 0, mdSub{})
+		res := &pb.LastPriceSubscription{Figi: in.GetFigi(), InstrumentUid: id, SubscriptionStatus: status, StreamId: st.id}
+		if inst != nil {
+			res.Figi, res.InstrumentUid = inst.info.GetFigi(), inst.uid()
+		}
+		resp.LastPriceSubscriptions = append(resp.LastPriceSubscriptions, res)
+	}
+	return &pb.MarketDataResponse{Payload: &pb.MarketDataResponse_SubscribeLastPriceResponse{SubscribeLastPriceResponse: resp}}
+}
+
+// mySubscriptions - ответ на GetMySubscriptions: по одному ответу на каждый тип, на который есть активные подписки,
+// как и у реального сервера
+func (m *marketDataStreamServer) mySubscriptions(st *mdStream) []*pb.MarketDataResponse {
+	candles := &pb.SubscribeCandlesResponse{TrackingId: uuid.New().String()}
+	books := &pb.SubscribeOrderBookResponse{TrackingId: uuid.New().String()}
+	trades := &pb.SubscribeTradesResponse{TrackingId: uuid.New().String()}
+	info := &pb.SubscribeInfoResponse{TrackingId: uuid.New().String()}
+	prices := &pb.SubscribeLastPriceResponse{TrackingId: uuid.New().String()}
+
+	success := pb.SubscriptionStatus_SUBSCRIPTION_STATUS_SUCCESS
+	st.mu.Lock()
+	for key, sub := range st.subs {
+		figi, uid := sub.inst.info.GetFigi(), key.uid
+		switch key.kind {
+		case subCandles:
+			candles.CandlesSubscriptions = append(candles.CandlesSubscriptions, &pb.CandleSubscription{
+				Figi: figi, InstrumentUid: uid, Interval: pb.SubscriptionInterval(key.param), WaitingClose: sub.waitingClose,
+				SubscriptionStatus: success, StreamId: st.id, SubscriptionId: sub.id,
+			})
+		case subOrderBooks:
+			books.OrderBookSubscriptions = append(books.OrderBookSubscriptions, &pb.OrderBookSubscription{
+				Figi: figi, InstrumentUid: uid, Depth: sub.depth, SubscriptionStatus: success, StreamId: st.id, SubscriptionId: sub.id,
+			})
+		case subTrades:
+			trades.TradeSource = sub.tradeSource
+			trades.TradeSubscriptions = append(trades.TradeSubscriptions, &pb.TradeSubscription{
+				Figi: figi, InstrumentUid: uid, SubscriptionStatus: success, StreamId: st.id, SubscriptionId: sub.id,
+			})
+		case subInfo:
+			info.InfoSubscriptions = append(info.InfoSubscriptions, &pb.InfoSubscription{
+				Figi: figi, InstrumentUid: uid, SubscriptionStatus: success, StreamId: st.id, SubscriptionId: sub.id,
+			})
+		case subLastPrices:
+			prices.LastPriceSubscriptions = append(prices.LastPriceSubscriptions, &pb.LastPriceSubscription{
+				Figi: figi, InstrumentUid: uid, SubscriptionStatus: success, StreamId: st.id, SubscriptionId: sub.id,
+			})
+		}
+	}
+	st.mu.Unlock()
+
+	var resp []*pb.MarketDataResponse
+	if len(candles.GetCandlesSubscriptions()) > 0 {
+		resp = append(resp, &pb.MarketDataResponse{Payload: &pb.MarketDataResponse_SubscribeCandlesResponse{SubscribeCandlesResponse: candles}})
+	}
+	if len(books.GetOrderBookSubscriptions()) > 0 {
+		resp = append(resp, &pb.MarketDataResponse{Payload: &pb.MarketDataResponse_SubscribeOrderBookResponse{SubscribeOrderBookResponse: books}})
+	}
+	if len(trades.GetTradeSubscriptions()) > 0 {
+		resp = append(resp, &pb.MarketDataResponse{Payload: &pb.MarketDataResponse_SubscribeTradesResponse{SubscribeTradesResponse: trades}})
+	}
+	if len(info.GetInfoSubscriptions()) > 0 {
+		resp = append(resp, &pb.MarketDataResponse{Payload: &pb.MarketDataResponse_SubscribeInfoResponse{SubscribeInfoResponse: info}})
+	}
+	if len(prices.GetLastPriceSubscriptions()) > 0 {
+		resp = append(resp, &pb.MarketDataResponse{Payload: &pb.MarketDataResponse_SubscribeLastPriceResponse{SubscribeLastPriceResponse: prices}})
+	}
+	return resp
+}
@@ -0,0 +1,138 @@
+package investgotest_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/russianinvestments/invest-api-go-sdk/investgo"
+	pb "github.com/russianinvestments/invest-api-go-sdk/proto"
+)
+
+func TestMarketData(t *testing.T) {
+	srv, client, uid := setup(t)
+	md := client.NewMarketDataServiceClient()
+
+	prices, err := md.GetLastPrices([]string{uid})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(prices.GetLastPrices()) != 1 || prices.GetLastPrices()[0].GetPrice().ToFloat() != 250 {
+		t.Fatalf("last prices = %v", prices.GetLastPrices())
+	}
+
+	start := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	err = srv.AddCandles(uid, pb.CandleInterval_CANDLE_INTERVAL_1_MIN,
+		&pb.HistoricCandle{Time: timestamppb.New(start.Add(time.Minute)), Close: quotation("251"), IsComplete: true},
+		&pb.HistoricCandle{Time: timestamppb.New(start), Close: quotation("250"), IsComplete: true},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	candles, err := md.GetCandles(uid, pb.CandleInterval_CANDLE_INTERVAL_1_MIN, start, start.Add(time.Hour),
+		pb.GetCandlesRequest_CANDLE_SOURCE_UNSPECIFIED, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(candles.GetCandles()) != 2 || !candles.GetCandles()[0].GetTime().AsTime().Equal(start) {
+		t.Fatalf("candles = %v", candles.GetCandles())
+	}
+
+	err = srv.PushOrderBook(&pb.OrderBook{
+		InstrumentUid: uid,
+		Depth:         10,
+		Bids:          []*pb.Order{{Price: quotation("249.9"), Quantity: 5}},
+		Asks:          []*pb.Order{{Price: quotation("250.1"), Quantity: 3}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	book, err := md.GetOrderBook(uid, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(book.GetBids()) != 1 || len(book.GetAsks()) != 1 || book.GetLastPrice().ToFloat() != 250 {
+		t.Fatalf("order book = %v", book.GetOrderBookResponse)
+	}
+	if _, err := md.GetOrderBook(uid, 7); err == nil {
+		t.Fatal("invalid depth accepted")
+	}
+
+	status, err := md.GetTradingStatus(uid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.GetTradingStatus() != pb.SecurityTradingStatus_SECURITY_TRADING_STATUS_NORMAL_TRADING {
+		t.Fatalf("trading status = %v", status.GetTradingStatus())
+	}
+}
+
+func TestMarketDataStream(t *testing.T) {
+	srv, client, uid := setup(t)
+	mds, err := client.NewMarketDataStreamClient().MarketDataStream(investgo.WithSubscriptionConfirm(waitTime))
+	if err != nil {
+		t.Fatal(err)
+	}
+	listen(t, mds.Listen, mds.Stop)
+
+	prices, err := mds.SubscribeLastPrice([]string{uid})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.SetPrice(uid, 251); err != nil {
+		t.Fatal(err)
+	}
+	if p := receive(t, prices); p.GetInstrumentUid() != uid || p.GetPrice().ToFloat() != 251 {
+		t.Fatalf("last price = %v", p)
+	}
+
+	books, err := mds.SubscribeOrderBook([]string{uid}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = srv.PushOrderBook(&pb.OrderBook{
+		InstrumentUid: uid,
+		Bids:          []*pb.Order{{Price: quotation("250.9"), Quantity: 1}, {Price: quotation("250.8"), Quantity: 1}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ob := receive(t, books); len(ob.GetBids()) != 1 || ob.GetDepth() != 1 {
+		t.Fatalf("order book = %v", ob)
+	}
+
+	subs, err := mds.GetMySubscriptions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(subs.LastPrices) != 1 || len(subs.OrderBooks) != 1 || len(subs.Candles) != 0 {
+		t.Fatalf("subscriptions = %+v", subs)
+	}
+
+	_, err = mds.SubscribeTrade([]string{"unknown"}, pb.TradeSourceType_TRADE_SOURCE_ALL)
+	var subErr *investgo.SubscriptionError
+	if !errors.As(err, &subErr) {
+		t.Fatalf("subscription to unknown instrument: err = %v", err)
+	}
+}
+
+func TestMarketDataServerSideStream(t *testing.T) {
+	srv, client, uid := setup(t)
+	stream, err := client.NewMarketDataStreamClient().MarketDataServerSideStream(&investgo.MarketDataServerSideStreamRequest{
+		LastPriceInstruments: []string{uid},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	listen(t, stream.Listen, stream.Stop)
+
+	waitSubscribed(t, srv, uid)
+	if err := srv.SetPrice(uid, 252); err != nil {
+		t.Fatal(err)
+	}
+	if p := receive(t, stream.LastPrices()); p.GetPrice().ToFloat() != 252 {
+		t.Fatalf("last price = %v", p)
+	}
+}
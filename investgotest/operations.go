@@ -0,0 +1,261 @@
+package investgotest
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/russianinvestments/invest-api-go-sdk/proto"
+)
+
+const (
+	// defaultCursorLimit, maxCursorLimit - размер страницы GetOperationsByCursor
+	defaultCursorLimit = 100
+	maxCursorLimit     = 1000
+)
+
+func (s *Server) GetOperations(ctx context.Context, req *pb.OperationsRequest) (*pb.OperationsResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	acc, err := s.account(req.GetAccountId())
+	if err != nil {
+		return nil, err
+	}
+	resp := &pb.OperationsResponse{}
+	for _, op := range acc.operations {
+		if req.State != nil && req.GetState() != pb.OperationState_OPERATION_STATE_UNSPECIFIED && op.GetState() != req.GetState() {
+			continue
+		}
+		if req.GetFigi() != "" && op.GetFigi() != req.GetFigi() {
+			continue
+		}
+		if !inRange(op.GetDate(), req.GetFrom(), req.GetTo()) {
+			continue
+		}
+		resp.Operations = append(resp.Operations, proto.Clone(op).(*pb.Operation))
+	}
+	return resp, nil
+}
+
+func (s *Server) GetPortfolio(ctx context.Context, req *pb.PortfolioRequest) (*pb.PortfolioResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	acc, err := s.account(req.GetAccountId())
+	if err != nil {
+		return nil, err
+	}
+	return s.portfolio(acc), nil
+}
+
+func (s *Server) GetPositions(ctx context.Context, req *pb.PositionsRequest) (*pb.PositionsResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	acc, err := s.account(req.GetAccountId())
+	if err != nil {
+		return nil, err
+	}
+	return s.positionsResponse(acc), nil
+}
+
+func (s *Server) GetWithdrawLimits(ctx context.Context, req *pb.WithdrawLimitsRequest) (*pb.WithdrawLimitsResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	acc, err := s.account(req.GetAccountId())
+	if err != nil {
+		return nil, err
+	}
+	positions := s.positionsResponse(acc)
+	return &pb.WithdrawLimitsResponse{Money: positions.GetMoney(), Blocked: positions.GetBlocked()}, nil
+}
+
+// GetOperationsByCursor - Операции от новых к старым, курсор - номер операции в этой последовательности
+func (s *Server) GetOperationsByCursor(ctx context.Context, req *pb.GetOperationsByCursorRequest) (*pb.GetOperationsByCursorResponse, error) {
+	limit := defaultCursorLimit
+	if req.Limit != nil {
+		limit = int(req.GetLimit())
+	}
+	if limit <= 0 || limit > maxCursorLimit {
+		return nil, apiError(codes.InvalidArgument, 30001, "Некорректный лимит")
+	}
+	start := 0
+	if req.GetCursor() != "" {
+		n, err := strconv.Atoi(req.GetCursor())
+		if err != nil || n < 0 {
+			return nil, apiError(codes.InvalidArgument, 30001, "Некорректный курсор")
+		}
+		start = n
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	acc, err := s.account(req.GetAccountId())
+	if err != nil {
+		return nil, err
+	}
+	var inst *instrument
+	if req.GetInstrumentId() != "" {
+		if inst = s.findInstrument(req.GetInstrumentId()); inst == nil {
+			return nil, errInstrumentNotFound()
+		}
+	}
+	types := make(map[pb.OperationType]bool, len(req.GetOperationTypes()))
+	for _, t := range req.GetOperationTypes() {
+		types[t] = true
+	}
+
+	filtered := make([]*pb.Operation, 0, len(acc.operations))
+	for i := len(acc.operations) - 1; i >= 0; i-- {
+		op := acc.operations[i]
+		if inst != nil && op.GetInstrumentUid() != inst.uid() {
+			continue
+		}
+		if len(types) > 0 && !types[op.GetOperationType()] {
+			continue
+		}
+		if req.GetWithoutCommissions() && op.GetOperationType() == pb.OperationType_OPERATION_TYPE_BROKER_FEE {
+			continue
+		}
+		if !inRange(op.GetDate(), req.GetFrom(), req.GetTo()) {
+			continue
+		}
+		filtered = append(filtered, op)
+	}
+
+	resp := &pb.GetOperationsByCursorResponse{}
+	for i := start; i < len(filtered) && i < start+limit; i++ {
+		resp.Items = append(resp.Items, operationItem(acc, filtered[i], strconv.Itoa(i), req.GetWithoutTrades()))
+	}
+	if start+limit < len(filtered) {
+		resp.HasNext = true
+		resp.NextCursor = strconv.Itoa(start + limit)
+	}
+	return resp, nil
+}
+
+func operationItem(acc *account, op *pb.Operation, cursor string, withoutTrades bool) *pb.OperationItem {
+	item := &pb.OperationItem{
+		Cursor:            cursor,
+		BrokerAccountId:   acc.info.GetId(),
+		Id:                op.GetId(),
+		ParentOperationId: op.GetParentOperationId(),
+		Name:              op.GetType(),
+		Date:              op.GetDate(),
+		Type:              op.GetOperationType(),
+		Description:       op.GetType(),
+		State:             op.GetState(),
+		InstrumentUid:     op.GetInstrumentUid(),
+		Figi:              op.GetFigi(),
+		InstrumentType:    op.GetInstrumentType(),
+		PositionUid:       op.GetPositionUid(),
+		Payment:           op.GetPayment(),
+		Price:             op.GetPrice(),
+		Quantity:          op.GetQuantity(),
+		QuantityDone:      op.GetQuantity() - op.GetQuantityRest(),
+		QuantityRest:      op.GetQuantityRest(),
+		AssetUid:          op.GetAssetUid(),
+	}
+	if !withoutTrades && len(op.GetTrades()) > 0 {
+		item.TradesInfo = &pb.OperationItemTrades{}
+		for _, t := range op.GetTrades() {
+			item.TradesInfo.Trades = append(item.TradesInfo.Trades, &pb.OperationItemTrade{
+				Num:      t.GetTradeId(),
+				Date:     t.GetDateTime(),
+				Quantity: t.GetQuantity(),
+				Price:    t.GetPrice(),
+			})
+		}
+	}
+	return proto.Clone(item).(*pb.OperationItem)
+}
+
+// operationsStreamServer - реализация OperationsStreamService
+type operationsStreamServer struct {
+	pb.UnimplementedOperationsStreamServiceServer
+	s *Server
+}
+
+func (o *operationsStreamServer) PortfolioStream(req *pb.PortfolioStreamRequest, stream pb.OperationsStreamService_PortfolioStreamServer) error {
+	ctx := stream.Context()
+	result := &pb.PortfolioSubscriptionResult{TrackingId: uuid.New().String(), StreamId: uuid.New().String()}
+	for _, id := range req.GetAccounts() {
+		status := pb.PortfolioSubscriptionStatus_PORTFOLIO_SUBSCRIPTION_STATUS_SUCCESS
+		if !o.s.accountExists(id) {
+			status = pb.PortfolioSubscriptionStatus_PORTFOLIO_SUBSCRIPTION_STATUS_ACCOUNT_NOT_FOUND
+		}
+		result.Accounts = append(result.Accounts, &pb.AccountSubscriptionStatus{AccountId: id, SubscriptionStatus: status})
+	}
+	sub := o.s.portfolios.subscribe(req.GetAccounts(), ctx.Done())
+	defer o.s.portfolios.unsubscribe(sub)
+	err := stream.Send(&pb.PortfolioStreamResponse{
+		Payload: &pb.PortfolioStreamResponse_Subscriptions{Subscriptions: result},
+	})
+	if err != nil {
+		return err
+	}
+	return sendLoop(ctx, sub, stream.Send)
+}
+
+func (o *operationsStreamServer) PositionsStream(req *pb.PositionsStreamRequest, stream pb.OperationsStreamService_PositionsStreamServer) error {
+	ctx := stream.Context()
+	result := &pb.PositionsSubscriptionResult{TrackingId: uuid.New().String(), StreamId: uuid.New().String()}
+	for _, id := range req.GetAccounts() {
+		status := pb.PositionsAccountSubscriptionStatus_POSITIONS_SUBSCRIPTION_STATUS_SUCCESS
+		if !o.s.accountExists(id) {
+			status = pb.PositionsAccountSubscriptionStatus_POSITIONS_SUBSCRIPTION_STATUS_ACCOUNT_NOT_FOUND
+		}
+		result.Accounts = append(result.Accounts, &pb.PositionsSubscriptionStatus{AccountId: id, SubscriptionStatus: status})
+	}
+	sub := o.s.positions.subscribe(req.GetAccounts(), ctx.Done())
+	defer o.s.positions.unsubscribe(sub)
+	err := stream.Send(&pb.PositionsStreamResponse{
+		Payload: &pb.PositionsStreamResponse_Subscriptions{Subscriptions: result},
+	})
+	if err != nil {
+		return err
+	}
+	if req.GetWithInitialPositions() {
+		for _, id := range req.GetAccounts() {
+			o.s.mu.Lock()
+			acc, err := o.s.account(id)
+			var positions *pb.PositionsResponse
+			if err == nil {
+				positions = o.s.positionsResponse(acc)
+			}
+			o.s.mu.Unlock()
+			if positions == nil {
+				continue
+			}
+			err = stream.Send(&pb.PositionsStreamResponse{
+				Payload: &pb.PositionsStreamResponse_InitialPositions{InitialPositions: positions},
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return sendLoop(ctx, sub, stream.Send)
+}
+
+func (s *Server) accountExists(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err := s.account(id)
+	return err == nil
+}
+
+// sendLoop - отправка событий подписки в стрим до его завершения
+func sendLoop[T any](ctx context.Context, sub *hubSub[T], send func(T) error) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case v := <-sub.ch:
+			if err := send(v); err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package investgotest_test
+
+import (
+	"testing"
+
+	"github.com/russianinvestments/invest-api-go-sdk/investgo"
+	"github.com/russianinvestments/invest-api-go-sdk/investgotest"
+	pb "github.com/russianinvestments/invest-api-go-sdk/proto"
+)
+
+func TestOperations(t *testing.T) {
+	srv, client, uid := setup(t)
+	operations := client.NewOperationsServiceClient()
+	if err := srv.PayIn(investgotest.DefaultAccountId, "rub", 10000); err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.SetPosition(investgotest.DefaultAccountId, uid, 20, 240); err != nil {
+		t.Fatal(err)
+	}
+
+	positions, err := operations.GetPositions(investgotest.DefaultAccountId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(positions.GetMoney()) != 1 || positions.GetMoney()[0].ToFloat() != 10000 {
+		t.Fatalf("money = %v", positions.GetMoney())
+	}
+	if len(positions.GetSecurities()) != 1 || positions.GetSecurities()[0].GetBalance() != 20 {
+		t.Fatalf("securities = %v", positions.GetSecurities())
+	}
+
+	portfolio, err := operations.GetPortfolio(investgotest.DefaultAccountId, pb.PortfolioRequest_RUB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(portfolio.GetPositions()) != 1 || portfolio.GetPositions()[0].GetCurrentPrice().ToFloat() != 250 {
+		t.Fatalf("portfolio = %v", portfolio.GetPositions())
+	}
+
+	ops, err := operations.GetOperations(&investgo.GetOperationsRequest{AccountId: investgotest.DefaultAccountId})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ops.GetOperations()) == 0 {
+		t.Fatal("no pay in operation")
+	}
+	if _, err := operations.GetPositions("unknown"); err == nil {
+		t.Fatal("positions of unknown account")
+	}
+}
+
+func TestPortfolioStream(t *testing.T) {
+	srv, client, _ := setup(t)
+	stream, err := client.NewOperationsStreamClient().PortfolioStream([]string{investgotest.DefaultAccountId})
+	if err != nil {
+		t.Fatal(err)
+	}
+	listen(t, stream.Listen, stream.Stop)
+	waitSubscribed(t, srv, investgotest.DefaultAccountId)
+
+	if err := srv.PayIn(investgotest.DefaultAccountId, "rub", 500); err != nil {
+		t.Fatal(err)
+	}
+	if p := receive(t, stream.Portfolios()); p.GetAccountId() != investgotest.DefaultAccountId {
+		t.Fatalf("portfolio = %v", p)
+	}
+}
+
+func TestPositionsStream(t *testing.T) {
+	srv, client, _ := setup(t)
+	stream, err := client.NewOperationsStreamClient().PositionsStream([]string{investgotest.DefaultAccountId})
+	if err != nil {
+		t.Fatal(err)
+	}
+	listen(t, stream.Listen, stream.Stop)
+	waitSubscribed(t, srv, investgotest.DefaultAccountId)
+
+	if err := srv.PayIn(investgotest.DefaultAccountId, "rub", 500); err != nil {
+		t.Fatal(err)
+	}
+	if p := receive(t, stream.Positions()); len(p.GetMoney()) != 1 {
+		t.Fatalf("positions = %v", p)
+	}
+}
+
+func TestSandbox(t *testing.T) {
+	_, client, uid := setup(t)
+	sandbox := client.NewSandboxServiceClient()
+
+	account, err := sandbox.OpenSandboxAccount()
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := account.GetAccountId()
+	balance, err := sandbox.SandboxPayIn(&investgo.SandboxPayInRequest{AccountId: id, Currency: "rub", Unit: 5000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if balance.GetBalance().ToFloat() != 5000 {
+		t.Fatalf("balance = %v", balance.GetBalance())
+	}
+
+	order, err := sandbox.PostSandboxOrder(&investgo.PostOrderRequest{
+		InstrumentId: uid,
+		Quantity:     1,
+		Direction:    pb.OrderDirection_ORDER_DIRECTION_BUY,
+		AccountId:    id,
+		OrderType:    pb.OrderType_ORDER_TYPE_MARKET,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if order.GetExecutionReportStatus() != pb.OrderExecutionReportStatus_EXECUTION_REPORT_STATUS_FILL {
+		t.Fatalf("sandbox order = %v", order.PostOrderResponse)
+	}
+	positions, err := sandbox.GetSandboxPositions(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(positions.GetSecurities()) != 1 || positions.GetMoney()[0].ToFloat() != 2500 {
+		t.Fatalf("sandbox positions = %v", positions.PositionsResponse)
+	}
+	if _, err := sandbox.CloseSandboxAccount(id); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,645 @@
+package investgotest
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/russianinvestments/invest-api-go-sdk/investgo"
+	pb "github.com/russianinvestments/invest-api-go-sdk/proto"
+)
+
+// order - торговое поручение
+type order struct {
+	id        string
+	requestId string
+	acc       *account
+	inst      *instrument
+	direction pb.OrderDirection
+	orderType pb.OrderType
+	lots      int64
+	// price - цена одной штуки для лимитной заявки, для рыночной - цена на момент выставления
+	price    decimal.Decimal
+	status   pb.OrderExecutionReportStatus
+	created  time.Time
+	executed int64
+	stages   []*pb.OrderStage
+	// amount, commission - сумма исполненных сделок и комиссия по ним
+	amount     decimal.Decimal
+	commission decimal.Decimal
+	// reserved - деньги, которые блокируются под заявку на покупку
+	reserved decimal.Decimal
+	// blocked - под заявку заблокированы деньги или бумаги
+	blocked bool
+}
+
+func (o *order) pieces() int64 {
+	return o.lots * o.inst.lot()
+}
+
+func (o *order) active() bool {
+	return o.status == pb.OrderExecutionReportStatus_EXECUTION_REPORT_STATUS_NEW ||
+		o.status == pb.OrderExecutionReportStatus_EXECUTION_REPORT_STATUS_PARTIALLYFILL
+}
+
+func (o *order) buy() bool {
+	return o.direction == pb.OrderDirection_ORDER_DIRECTION_BUY
+}
+
+func (o *order) currency() string {
+	return o.inst.info.GetCurrency()
+}
+
+// executedPrice - средняя цена исполнения одной штуки
+func (o *order) executedPrice() decimal.Decimal {
+	if o.executed == 0 {
+		return decimal.Zero
+	}
+	return o.amount.Div(decimal.NewFromInt(o.executed * o.inst.lot()))
+}
+
+func (o *order) response() *pb.PostOrderResponse {
+	c := o.currency()
+	initial := o.price.Mul(decimal.NewFromInt(o.pieces()))
+	return &pb.PostOrderResponse{
+		OrderId:               o.id,
+		ExecutionReportStatus: o.status,
+		LotsRequested:         o.lots,
+		LotsExecuted:          o.executed,
+		InitialOrderPrice:     pb.MoneyFromDecimal(initial, c),
+		ExecutedOrderPrice:    pb.MoneyFromDecimal(o.executedPrice(), c),
+		TotalOrderAmount:      pb.MoneyFromDecimal(o.amount.Add(o.commission), c),
+		InitialCommission:     pb.MoneyFromDecimal(o.acc.commission(initial), c),
+		ExecutedCommission:    pb.MoneyFromDecimal(o.commission, c),
+		Figi:                  o.inst.info.GetFigi(),
+		Direction:             o.direction,
+		InitialSecurityPrice:  pb.MoneyFromDecimal(o.price, c),
+		OrderType:             o.orderType,
+		InitialOrderPricePt:   pb.QuotationFromDecimal(o.price),
+		InstrumentUid:         o.inst.uid(),
+		OrderRequestId:        o.requestId,
+	}
+}
+
+func (o *order) state() *pb.OrderState {
+	c := o.currency()
+	initial := o.price.Mul(decimal.NewFromInt(o.pieces()))
+	return &pb.OrderState{
+		OrderId:               o.id,
+		ExecutionReportStatus: o.status,
+		LotsRequested:         o.lots,
+		LotsExecuted:          o.executed,
+		InitialOrderPrice:     pb.MoneyFromDecimal(initial, c),
+		ExecutedOrderPrice:    pb.MoneyFromDecimal(o.executedPrice(), c),
+		TotalOrderAmount:      pb.MoneyFromDecimal(o.amount.Add(o.commission), c),
+		AveragePositionPrice:  pb.MoneyFromDecimal(o.executedPrice(), c),
+		InitialCommission:     pb.MoneyFromDecimal(o.acc.commission(initial), c),
+		ExecutedCommission:    pb.MoneyFromDecimal(o.commission, c),
+		Figi:                  o.inst.info.GetFigi(),
+		Direction:             o.direction,
+		InitialSecurityPrice:  pb.MoneyFromDecimal(o.price, c),
+		Stages:                o.stages,
+		ServiceCommission:     pb.MoneyFromDecimal(decimal.Zero, c),
+		Currency:              c,
+		OrderType:             o.orderType,
+		OrderDate:             timestamppb.New(o.created),
+		InstrumentUid:         o.inst.uid(),
+		OrderRequestId:        o.requestId,
+	}
+}
+
+func (o *order) streamState() *pb.OrderStateStreamResponse_OrderState {
+	c := o.currency()
+	var trades []*pb.OrderTrade
+	for _, st := range o.stages {
+		trades = append(trades, &pb.OrderTrade{
+			DateTime: st.GetExecutionTime(),
+			Price:    pb.QuotationFromDecimal(st.GetPrice().ToDecimal()),
+			Quantity: st.GetQuantity(),
+			TradeId:  st.GetTradeId(),
+		})
+	}
+	requestId := o.requestId
+	state := &pb.OrderStateStreamResponse_OrderState{
+		OrderId:               o.id,
+		OrderRequestId:        &requestId,
+		CreatedAt:             timestamppb.New(o.created),
+		ExecutionReportStatus: o.status,
+		Ticker:                o.inst.info.GetTicker(),
+		ClassCode:             o.inst.info.GetClassCode(),
+		LotSize:               o.inst.info.GetLot(),
+		Direction:             o.direction,
+		OrderType:             o.orderType,
+		AccountId:             o.acc.info.GetId(),
+		InitialOrderPrice:     pb.MoneyFromDecimal(o.price.Mul(decimal.NewFromInt(o.pieces())), c),
+		OrderPrice:            pb.MoneyFromDecimal(o.price, c),
+		Amount:                pb.MoneyFromDecimal(o.amount, c),
+		ExecutedOrderPrice:    pb.MoneyFromDecimal(o.executedPrice(), c),
+		Currency:              c,
+		LotsRequested:         o.lots,
+		LotsExecuted:          o.executed,
+		LotsLeft:              o.lots - o.executed,
+		Trades:                trades,
+		Exchange:              o.inst.info.GetExchange(),
+		InstrumentUid:         o.inst.uid(),
+	}
+	if o.status == pb.OrderExecutionReportStatus_EXECUTION_REPORT_STATUS_CANCELLED {
+		state.LotsCancelled = o.lots - o.executed
+	}
+	if !o.active() {
+		state.CompletionTime = timestamppb.New(o.created)
+		if len(o.stages) > 0 {
+			state.CompletionTime = o.stages[len(o.stages)-1].GetExecutionTime()
+		}
+	}
+	return state
+}
+
+// commission - комиссия брокера за сделку на сумму amount
+func (a *account) commission(amount decimal.Decimal) decimal.Decimal {
+	return amount.Mul(a.rate).Round(2)
+}
+
+// orderRequest - параметры выставления заявки, общие для PostOrder, PostOrderAsync и стоп-заявок
+type orderRequest struct {
+	accountId    string
+	instrumentId string
+	requestId    string
+	lots         int64
+	price        *pb.Quotation
+	direction    pb.OrderDirection
+	orderType    pb.OrderType
+	timeInForce  pb.TimeInForceType
+}
+
+// postOrder - выставление заявки. Рыночная заявка исполняется сразу по текущей цене, лимитная - если ее цена
+// не хуже текущей, иначе заявка ждет, пока SetPrice не сделает ее исполнимой
+func (s *Server) postOrder(req orderRequest, ev *events) (*order, error) {
+	acc, err := s.account(req.accountId)
+	if err != nil {
+		return nil, err
+	}
+	if req.requestId != "" {
+		for _, o := range acc.orders {
+			if o.requestId == req.requestId {
+				return o, nil
+			}
+		}
+	}
+	inst := s.findInstrument(req.instrumentId)
+	if inst == nil {
+		return nil, errInstrumentNotFound()
+	}
+	if req.lots <= 0 {
+		return nil, apiError(codes.InvalidArgument, 30003, "Количество лотов должно быть положительным числом")
+	}
+	if req.direction != pb.OrderDirection_ORDER_DIRECTION_BUY && req.direction != pb.OrderDirection_ORDER_DIRECTION_SELL {
+		return nil, apiError(codes.InvalidArgument, 30001, "Некорректное направление заявки")
+	}
+	if !tradable(inst) {
+		return nil, apiError(codes.FailedPrecondition, investgo.ErrCodeInstrumentNotTradable, "Инструмент недоступен для торгов")
+	}
+
+	o := &order{
+		id:        uuid.New().String(),
+		requestId: req.requestId,
+		acc:       acc,
+		inst:      inst,
+		direction: req.direction,
+		orderType: req.orderType,
+		lots:      req.lots,
+		status:    pb.OrderExecutionReportStatus_EXECUTION_REPORT_STATUS_NEW,
+		created:   s.now(),
+	}
+	switch req.orderType {
+	case pb.OrderType_ORDER_TYPE_MARKET, pb.OrderType_ORDER_TYPE_BESTPRICE:
+		if !inst.hasPrice {
+			return nil, apiError(codes.FailedPrecondition, investgo.ErrCodeInstrumentNotTradable, "Нет цены для исполнения рыночной заявки")
+		}
+		o.price = inst.price
+	case pb.OrderType_ORDER_TYPE_LIMIT:
+		if req.price == nil {
+			return nil, apiError(codes.InvalidArgument, 30001, "Не указана цена лимитной заявки")
+		}
+		o.price = req.price.ToDecimal()
+		if !roundToIncrement(o.price, inst.minPriceIncrement()).Equal(o.price) {
+			return nil, apiError(codes.InvalidArgument, 30009, "Цена не кратна шагу цены инструмента")
+		}
+	default:
+		return nil, apiError(codes.InvalidArgument, 30001, "Некорректный тип заявки")
+	}
+
+	pieces := decimal.NewFromInt(o.pieces())
+	if o.buy() {
+		amount := o.price.Mul(pieces)
+		need := amount.Add(acc.commission(amount))
+		if acc.money[o.currency()].LessThan(need) {
+			return nil, apiError(codes.InvalidArgument, investgo.ErrCodeNotEnoughBalance, "Недостаточно средств для совершения сделки")
+		}
+		o.reserved = need
+	} else {
+		pos := acc.position(inst)
+		if !inst.info.GetShortEnabledFlag() && pos.balance-pos.blocked < o.pieces() {
+			return nil, apiError(codes.InvalidArgument, investgo.ErrCodeNotEnoughAssets, "Недостаточно активов для совершения сделки")
+		}
+	}
+
+	acc.orders = append(acc.orders, o)
+	if s.marketable(o) {
+		s.fill(o, inst.price, ev)
+		return o, nil
+	}
+	if req.timeInForce == pb.TimeInForceType_TIME_IN_FORCE_FILL_AND_KILL || req.timeInForce == pb.TimeInForceType_TIME_IN_FORCE_FILL_OR_KILL {
+		o.status = pb.OrderExecutionReportStatus_EXECUTION_REPORT_STATUS_CANCELLED
+		s.orderChanged(o, ev)
+		return o, nil
+	}
+	s.reserve(o)
+	s.orderChanged(o, ev)
+	s.accountChanged(acc, ev)
+	return o, nil
+}
+
+// marketable - заявку можно исполнить по текущей цене
+func (s *Server) marketable(o *order) bool {
+	if !o.inst.hasPrice {
+		return false
+	}
+	switch o.orderType {
+	case pb.OrderType_ORDER_TYPE_MARKET, pb.OrderType_ORDER_TYPE_BESTPRICE:
+		return true
+	}
+	if o.buy() {
+		return o.price.GreaterThanOrEqual(o.inst.price)
+	}
+	return o.price.LessThanOrEqual(o.inst.price)
+}
+
+// reserve - блокировка денег или бумаг под выставленную заявку
+func (s *Server) reserve(o *order) {
+	o.blocked = true
+	c := o.currency()
+	if o.buy() {
+		o.acc.money[c] = o.acc.money[c].Sub(o.reserved)
+		o.acc.blocked[c] = o.acc.blocked[c].Add(o.reserved)
+		return
+	}
+	o.acc.position(o.inst).blocked += o.pieces()
+}
+
+// release - снятие блокировки заявки
+func (s *Server) release(o *order) {
+	if !o.blocked {
+		return
+	}
+	o.blocked = false
+	c := o.currency()
+	if o.buy() {
+		o.acc.money[c] = o.acc.money[c].Add(o.reserved)
+		o.acc.blocked[c] = o.acc.blocked[c].Sub(o.reserved)
+		if o.acc.blocked[c].IsZero() {
+			delete(o.acc.blocked, c)
+		}
+		return
+	}
+	o.acc.position(o.inst).blocked -= o.pieces()
+}
+
+// fill - полное исполнение заявки по цене price
+func (s *Server) fill(o *order, price decimal.Decimal, ev *events) {
+	s.release(o)
+	acc, inst, c := o.acc, o.inst, o.currency()
+	now := s.now()
+	pieces := o.pieces()
+	amount := price.Mul(decimal.NewFromInt(pieces))
+	commission := acc.commission(amount)
+	pos := acc.position(inst)
+
+	opType, opName, payment := pb.OperationType_OPERATION_TYPE_BUY, "Покупка ценных бумаг", amount.Neg()
+	if o.buy() {
+		acc.money[c] = acc.money[c].Sub(amount).Sub(commission)
+		pos.apply(pieces, price)
+	} else {
+		acc.money[c] = acc.money[c].Add(amount).Sub(commission)
+		pos.apply(-pieces, price)
+		opType, opName, payment = pb.OperationType_OPERATION_TYPE_SELL, "Продажа ценных бумаг", amount
+	}
+
+	tradeId := uuid.New().String()
+	o.executed = o.lots
+	o.amount = amount
+	o.commission = commission
+	o.status = pb.OrderExecutionReportStatus_EXECUTION_REPORT_STATUS_FILL
+	o.stages = append(o.stages, &pb.OrderStage{
+		Price:         pb.MoneyFromDecimal(price, c),
+		Quantity:      o.lots,
+		TradeId:       tradeId,
+		ExecutionTime: timestamppb.New(now),
+	})
+
+	acc.operations = append(acc.operations, &pb.Operation{
+		Id:             tradeId,
+		Currency:       c,
+		Payment:        pb.MoneyFromDecimal(payment, c),
+		Price:          pb.MoneyFromDecimal(price, c),
+		State:          pb.OperationState_OPERATION_STATE_EXECUTED,
+		Quantity:       pieces,
+		Figi:           inst.info.GetFigi(),
+		InstrumentType: inst.info.GetInstrumentType(),
+		Date:           timestamppb.New(now),
+		Type:           opName,
+		OperationType:  opType,
+		Trades: []*pb.OperationTrade{{
+			TradeId:  tradeId,
+			DateTime: timestamppb.New(now),
+			Quantity: pieces,
+			Price:    pb.MoneyFromDecimal(price, c),
+		}},
+		AssetUid:      inst.info.GetAssetUid(),
+		PositionUid:   inst.info.GetPositionUid(),
+		InstrumentUid: inst.uid(),
+	})
+	if !commission.IsZero() {
+		acc.operations = append(acc.operations, &pb.Operation{
+			Id:                uuid.New().String(),
+			ParentOperationId: tradeId,
+			Currency:          c,
+			Payment:           pb.MoneyFromDecimal(commission.Neg(), c),
+			State:             pb.OperationState_OPERATION_STATE_EXECUTED,
+			Figi:              inst.info.GetFigi(),
+			InstrumentType:    inst.info.GetInstrumentType(),
+			Date:              timestamppb.New(now),
+			Type:              "Удержание комиссии за операцию",
+			OperationType:     pb.OperationType_OPERATION_TYPE_BROKER_FEE,
+			PositionUid:       inst.info.GetPositionUid(),
+			InstrumentUid:     inst.uid(),
+		})
+	}
+
+	trades := &pb.OrderTrades{
+		OrderId:   o.id,
+		CreatedAt: timestamppb.New(now),
+		Direction: o.direction,
+		Figi:      inst.info.GetFigi(),
+		Trades: []*pb.OrderTrade{{
+			DateTime: timestamppb.New(now),
+			Price:    pb.QuotationFromDecimal(price),
+			Quantity: pieces,
+			TradeId:  tradeId,
+		}},
+		AccountId:     acc.info.GetId(),
+		InstrumentUid: inst.uid(),
+	}
+	ev.add(func() {
+		s.trades.publish(acc.info.GetId(), &pb.TradesStreamResponse{
+			Payload: &pb.TradesStreamResponse_OrderTrades{OrderTrades: trades},
+		})
+	})
+	s.orderChanged(o, ev)
+	s.accountChanged(acc, ev)
+}
+
+// cancel - отмена активной заявки
+func (s *Server) cancel(o *order, ev *events) {
+	s.release(o)
+	o.status = pb.OrderExecutionReportStatus_EXECUTION_REPORT_STATUS_CANCELLED
+	s.orderChanged(o, ev)
+	s.accountChanged(o.acc, ev)
+}
+
+func (s *Server) orderChanged(o *order, ev *events) {
+	id := o.acc.info.GetId()
+	state := o.streamState()
+	ev.add(func() {
+		s.orderStates.publish(id, &pb.OrderStateStreamResponse{
+			Payload: &pb.OrderStateStreamResponse_OrderState_{OrderState: state},
+		})
+	})
+}
+
+// match - исполнение заявок и стоп-заявок по инструменту после изменения цены
+func (s *Server) match(inst *instrument) events {
+	var ev events
+	for _, id := range s.accountIds {
+		acc := s.accounts[id]
+		changed := false
+		for _, o := range acc.orders {
+			if o.inst == inst && o.active() && s.marketable(o) {
+				// выставленная лимитная заявка исполняется по своей цене
+				s.fill(o, o.price, &ev)
+				changed = true
+			}
+		}
+		s.triggerStopOrders(acc, inst, &ev)
+		if !changed {
+			if _, ok := acc.positions[inst.uid()]; ok {
+				s.accountChanged(acc, &ev)
+			}
+		}
+	}
+	return ev
+}
+
+func (s *Server) findOrder(acc *account, orderId string, idType pb.OrderIdType) (*order, error) {
+	for _, o := range acc.orders {
+		if (idType == pb.OrderIdType_ORDER_ID_TYPE_REQUEST && o.requestId == orderId) ||
+			(idType != pb.OrderIdType_ORDER_ID_TYPE_REQUEST && o.id == orderId) {
+			return o, nil
+		}
+	}
+	return nil, apiError(codes.NotFound, investgo.ErrCodeOrderNotFound, "Заявка не найдена")
+}
+
+func (s *Server) PostOrder(ctx context.Context, req *pb.PostOrderRequest) (*pb.PostOrderResponse, error) {
+	s.mu.Lock()
+	var ev events
+	o, err := s.postOrder(orderRequest{
+		accountId:    req.GetAccountId(),
+		instrumentId: instrumentId(req.GetInstrumentId(), req.GetFigi()),
+		requestId:    req.GetOrderId(),
+		lots:         req.GetQuantity(),
+		price:        req.GetPrice(),
+		direction:    req.GetDirection(),
+		orderType:    req.GetOrderType(),
+		timeInForce:  req.GetTimeInForce(),
+	}, &ev)
+	var resp *pb.PostOrderResponse
+	if err == nil {
+		resp = o.response()
+	}
+	s.mu.Unlock()
+	ev.publish()
+	return resp, err
+}
+
+func (s *Server) PostOrderAsync(ctx context.Context, req *pb.PostOrderAsyncRequest) (*pb.PostOrderAsyncResponse, error) {
+	s.mu.Lock()
+	var ev events
+	o, err := s.postOrder(orderRequest{
+		accountId:    req.GetAccountId(),
+		instrumentId: req.GetInstrumentId(),
+		requestId:    req.GetOrderId(),
+		lots:         req.GetQuantity(),
+		price:        req.GetPrice(),
+		direction:    req.GetDirection(),
+		orderType:    req.GetOrderType(),
+		timeInForce:  req.GetTimeInForce(),
+	}, &ev)
+	var resp *pb.PostOrderAsyncResponse
+	if err == nil {
+		resp = &pb.PostOrderAsyncResponse{OrderRequestId: o.requestId, ExecutionReportStatus: o.status}
+	}
+	s.mu.Unlock()
+	ev.publish()
+	return resp, err
+}
+
+func (s *Server) CancelOrder(ctx context.Context, req *pb.CancelOrderRequest) (*pb.CancelOrderResponse, error) {
+	s.mu.Lock()
+	var ev events
+	err := s.cancelOrder(req, &ev)
+	s.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	ev.publish()
+	return &pb.CancelOrderResponse{Time: timestamppb.New(s.now())}, nil
+}
+
+func (s *Server) cancelOrder(req *pb.CancelOrderRequest, ev *events) error {
+	acc, err := s.account(req.GetAccountId())
+	if err != nil {
+		return err
+	}
+	o, err := s.findOrder(acc, req.GetOrderId(), req.GetOrderIdType())
+	if err != nil {
+		return err
+	}
+	if !o.active() {
+		return apiError(codes.InvalidArgument, investgo.ErrCodeOrderNotFound, "Заявка уже исполнена или отменена")
+	}
+	s.cancel(o, ev)
+	return nil
+}
+
+func (s *Server) GetOrderState(ctx context.Context, req *pb.GetOrderStateRequest) (*pb.OrderState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	acc, err := s.account(req.GetAccountId())
+	if err != nil {
+		return nil, err
+	}
+	o, err := s.findOrder(acc, req.GetOrderId(), req.GetOrderIdType())
+	if err != nil {
+		return nil, err
+	}
+	return o.state(), nil
+}
+
+func (s *Server) GetOrders(ctx context.Context, req *pb.GetOrdersRequest) (*pb.GetOrdersResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	acc, err := s.account(req.GetAccountId())
+	if err != nil {
+		return nil, err
+	}
+	resp := &pb.GetOrdersResponse{}
+	for _, o := range acc.orders {
+		if o.active() {
+			resp.Orders = append(resp.Orders, o.state())
+		}
+	}
+	return resp, nil
+}
+
+// ReplaceOrder - Замена заявки: старая заявка отменяется, новая выставляется с теми же параметрами, новым
+// количеством и ценой
+func (s *Server) ReplaceOrder(ctx context.Context, req *pb.ReplaceOrderRequest) (*pb.PostOrderResponse, error) {
+	s.mu.Lock()
+	var ev events
+	resp, err := s.replaceOrder(req, &ev)
+	s.mu.Unlock()
+	ev.publish()
+	return resp, err
+}
+
+func (s *Server) replaceOrder(req *pb.ReplaceOrderRequest, ev *events) (*pb.PostOrderResponse, error) {
+	acc, err := s.account(req.GetAccountId())
+	if err != nil {
+		return nil, err
+	}
+	old, err := s.findOrder(acc, req.GetOrderId(), pb.OrderIdType_ORDER_ID_TYPE_EXCHANGE)
+	if err != nil {
+		return nil, err
+	}
+	if !old.active() {
+		return nil, apiError(codes.InvalidArgument, investgo.ErrCodeOrderNotFound, "Заявка уже исполнена или отменена")
+	}
+	s.cancel(old, ev)
+	o, err := s.postOrder(orderRequest{
+		accountId:    acc.info.GetId(),
+		instrumentId: old.inst.uid(),
+		requestId:    req.GetIdempotencyKey(),
+		lots:         req.GetQuantity(),
+		price:        req.GetPrice(),
+		direction:    old.direction,
+		orderType:    old.orderType,
+	}, ev)
+	if err != nil {
+		return nil, err
+	}
+	return o.response(), nil
+}
+
+func (s *Server) GetMaxLots(ctx context.Context, req *pb.GetMaxLotsRequest) (*pb.GetMaxLotsResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	acc, err := s.account(req.GetAccountId())
+	if err != nil {
+		return nil, err
+	}
+	inst := s.findInstrument(req.GetInstrumentId())
+	if inst == nil {
+		return nil, errInstrumentNotFound()
+	}
+	c := inst.info.GetCurrency()
+	money := acc.money[c]
+	maxLots := func(price decimal.Decimal) int64 {
+		lotPrice := price.Mul(decimal.NewFromInt(inst.lot()))
+		lotPrice = lotPrice.Add(acc.commission(lotPrice))
+		if !lotPrice.IsPositive() {
+			return 0
+		}
+		return money.Div(lotPrice).IntPart()
+	}
+	var buyLots, marketLots int64
+	if inst.hasPrice {
+		marketLots = maxLots(inst.price)
+		buyLots = marketLots
+	}
+	if req.GetPrice() != nil {
+		buyLots = maxLots(req.GetPrice().ToDecimal())
+	}
+	pos := acc.position(inst)
+	sellLots := (pos.balance - pos.blocked) / inst.lot()
+	if sellLots < 0 {
+		sellLots = 0
+	}
+	buy := &pb.GetMaxLotsResponse_BuyLimitsView{
+		BuyMoneyAmount:   pb.QuotationFromDecimal(money),
+		BuyMaxLots:       buyLots,
+		BuyMaxMarketLots: marketLots,
+	}
+	sell := &pb.GetMaxLotsResponse_SellLimitsView{SellMaxLots: sellLots}
+	return &pb.GetMaxLotsResponse{
+		Currency:         c,
+		BuyLimits:        buy,
+		BuyMarginLimits:  buy,
+		SellLimits:       sell,
+		SellMarginLimits: sell,
+	}, nil
+}
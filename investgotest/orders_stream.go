@@ -0,0 +1,48 @@
+package investgotest
+
+import (
+	"github.com/google/uuid"
+
+	pb "github.com/russianinvestments/invest-api-go-sdk/proto"
+)
+
+// ordersStreamServer - реализация OrdersStreamService
+type ordersStreamServer struct {
+	pb.UnimplementedOrdersStreamServiceServer
+	s *Server
+}
+
+func (o *ordersStreamServer) TradesStream(req *pb.TradesStreamRequest, stream pb.OrdersStreamService_TradesStreamServer) error {
+	ctx := stream.Context()
+	sub := o.s.trades.subscribe(req.GetAccounts(), ctx.Done())
+	defer o.s.trades.unsubscribe(sub)
+	err := stream.Send(&pb.TradesStreamResponse{
+		Payload: &pb.TradesStreamResponse_Subscription{Subscription: subscriptionResponse(req.GetAccounts())},
+	})
+	if err != nil {
+		return err
+	}
+	return sendLoop(ctx, sub, stream.Send)
+}
+
+func (o *ordersStreamServer) OrderStateStream(req *pb.OrderStateStreamRequest, stream pb.OrdersStreamService_OrderStateStreamServer) error {
+	ctx := stream.Context()
+	sub := o.s.orderStates.subscribe(req.GetAccounts(), ctx.Done())
+	defer o.s.orderStates.unsubscribe(sub)
+	err := stream.Send(&pb.OrderStateStreamResponse{
+		Payload: &pb.OrderStateStreamResponse_Subscription{Subscription: subscriptionResponse(req.GetAccounts())},
+	})
+	if err != nil {
+		return err
+	}
+	return sendLoop(ctx, sub, stream.Send)
+}
+
+func subscriptionResponse(accounts []string) *pb.SubscriptionResponse {
+	return &pb.SubscriptionResponse{
+		TrackingId: uuid.New().String(),
+		Status:     pb.ResultSubscriptionStatus_RESULT_SUBSCRIPTION_STATUS_OK,
+		StreamId:   uuid.New().String(),
+		Accounts:   accounts,
+	}
+}
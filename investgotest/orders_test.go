@@ -0,0 +1,155 @@
+package investgotest_test
+
+import (
+	"testing"
+
+	"github.com/russianinvestments/invest-api-go-sdk/investgo"
+	"github.com/russianinvestments/invest-api-go-sdk/investgotest"
+	pb "github.com/russianinvestments/invest-api-go-sdk/proto"
+)
+
+func TestOrders(t *testing.T) {
+	srv, client, uid := setup(t)
+	orders := client.NewOrdersServiceClient()
+	if err := srv.PayIn(investgotest.DefaultAccountId, "rub", 10000); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := orders.Buy(&investgo.PostOrderRequestShort{
+		InstrumentId: uid,
+		Quantity:     2,
+		AccountId:    investgotest.DefaultAccountId,
+		OrderType:    pb.OrderType_ORDER_TYPE_MARKET,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.GetExecutionReportStatus() != pb.OrderExecutionReportStatus_EXECUTION_REPORT_STATUS_FILL || resp.GetLotsExecuted() != 2 {
+		t.Fatalf("market order = %v", resp.PostOrderResponse)
+	}
+	if _, err := orders.Buy(&investgo.PostOrderRequestShort{
+		InstrumentId: uid,
+		Quantity:     10,
+		AccountId:    investgotest.DefaultAccountId,
+		OrderType:    pb.OrderType_ORDER_TYPE_MARKET,
+	}); err == nil {
+		t.Fatal("order without enough money accepted")
+	}
+
+	limit, err := orders.Buy(&investgo.PostOrderRequestShort{
+		InstrumentId: uid,
+		Quantity:     1,
+		Price:        quotation("240"),
+		AccountId:    investgotest.DefaultAccountId,
+		OrderType:    pb.OrderType_ORDER_TYPE_LIMIT,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if limit.GetExecutionReportStatus() != pb.OrderExecutionReportStatus_EXECUTION_REPORT_STATUS_NEW {
+		t.Fatalf("limit order = %v", limit.PostOrderResponse)
+	}
+	active, err := orders.GetOrders(investgotest.DefaultAccountId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(active.GetOrders()) != 1 {
+		t.Fatalf("orders = %v", active.GetOrders())
+	}
+	if _, err := orders.CancelOrder(investgotest.DefaultAccountId, limit.GetOrderId(), nil); err != nil {
+		t.Fatal(err)
+	}
+	active, err = orders.GetOrders(investgotest.DefaultAccountId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(active.GetOrders()) != 0 {
+		t.Fatalf("orders after cancel = %v", active.GetOrders())
+	}
+}
+
+func TestStopOrders(t *testing.T) {
+	srv, client, uid := setup(t)
+	stopOrders := client.NewStopOrdersServiceClient()
+	if err := srv.SetPosition(investgotest.DefaultAccountId, uid, 10, 250); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := stopOrders.PostStopOrder(&investgo.PostStopOrderRequest{
+		InstrumentId:   uid,
+		Quantity:       1,
+		StopPrice:      quotation("245"),
+		Direction:      pb.StopOrderDirection_STOP_ORDER_DIRECTION_SELL,
+		AccountId:      investgotest.DefaultAccountId,
+		ExpirationType: pb.StopOrderExpirationType_STOP_ORDER_EXPIRATION_TYPE_GOOD_TILL_CANCEL,
+		StopOrderType:  pb.StopOrderType_STOP_ORDER_TYPE_STOP_LOSS,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	list, err := stopOrders.GetStopOrders(investgotest.DefaultAccountId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.GetStopOrders()) != 1 || list.GetStopOrders()[0].GetStopOrderId() != resp.GetStopOrderId() {
+		t.Fatalf("stop orders = %v", list.GetStopOrders())
+	}
+	if _, err := stopOrders.CancelStopOrder(investgotest.DefaultAccountId, resp.GetStopOrderId()); err != nil {
+		t.Fatal(err)
+	}
+	list, err = stopOrders.GetStopOrders(investgotest.DefaultAccountId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.GetStopOrders()) != 0 {
+		t.Fatalf("stop orders after cancel = %v", list.GetStopOrders())
+	}
+}
+
+// buy - пополнение счета и рыночная заявка на покупку одного лота
+func buy(t *testing.T, srv *investgotest.Server, client *investgo.Client, uid string) *investgo.PostOrderResponse {
+	t.Helper()
+	if err := srv.PayIn(investgotest.DefaultAccountId, "rub", 10000); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.NewOrdersServiceClient().Buy(&investgo.PostOrderRequestShort{
+		InstrumentId: uid,
+		Quantity:     1,
+		AccountId:    investgotest.DefaultAccountId,
+		OrderType:    pb.OrderType_ORDER_TYPE_MARKET,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func TestTradesStream(t *testing.T) {
+	srv, client, uid := setup(t)
+	stream, err := client.NewOrdersStreamClient().TradesStream([]string{investgotest.DefaultAccountId}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	listen(t, stream.Listen, stream.Stop)
+	waitSubscribed(t, srv, investgotest.DefaultAccountId)
+
+	resp := buy(t, srv, client, uid)
+	if tr := receive(t, stream.Trades()); tr.GetOrderId() != resp.GetOrderId() || len(tr.GetTrades()) == 0 {
+		t.Fatalf("trades = %v", tr)
+	}
+}
+
+func TestOrderStateStream(t *testing.T) {
+	srv, client, uid := setup(t)
+	stream, err := client.NewOrdersStreamClient().OrderStateStream([]string{investgotest.DefaultAccountId}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	listen(t, stream.Listen, stream.Stop)
+	waitSubscribed(t, srv, investgotest.DefaultAccountId)
+
+	resp := buy(t, srv, client, uid)
+	if st := receive(t, stream.OrderState()); st.GetOrderId() != resp.GetOrderId() {
+		t.Fatalf("order state = %v", st)
+	}
+}
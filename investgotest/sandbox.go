@@ -0,0 +1,108 @@
+package investgotest
+
+import (
+	"context"
+	"strings"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+
+	pb "github.com/russianinvestments/invest-api-go-sdk/proto"
+)
+
+// sandboxServer - реализация SandboxService поверх того же состояния, что и боевые сервисы: счета песочницы
+// видны в UsersService, а заявки исполняются так же, как через OrdersService
+type sandboxServer struct {
+	pb.UnimplementedSandboxServiceServer
+	s *Server
+}
+
+func (sb *sandboxServer) OpenSandboxAccount(ctx context.Context, req *pb.OpenSandboxAccountRequest) (*pb.OpenSandboxAccountResponse, error) {
+	sb.s.mu.Lock()
+	defer sb.s.mu.Unlock()
+	acc := sb.s.openAccount(uuid.New().String(), req.GetName(), pb.AccountType_ACCOUNT_TYPE_TINKOFF)
+	return &pb.OpenSandboxAccountResponse{AccountId: acc.info.GetId()}, nil
+}
+
+func (sb *sandboxServer) GetSandboxAccounts(ctx context.Context, req *pb.GetAccountsRequest) (*pb.GetAccountsResponse, error) {
+	return sb.s.GetAccounts(ctx, req)
+}
+
+func (sb *sandboxServer) CloseSandboxAccount(ctx context.Context, req *pb.CloseSandboxAccountRequest) (*pb.CloseSandboxAccountResponse, error) {
+	sb.s.mu.Lock()
+	defer sb.s.mu.Unlock()
+	acc, err := sb.s.account(req.GetAccountId())
+	if err != nil {
+		return nil, err
+	}
+	acc.info.Status = pb.AccountStatus_ACCOUNT_STATUS_CLOSED
+	return &pb.CloseSandboxAccountResponse{}, nil
+}
+
+func (sb *sandboxServer) PostSandboxOrder(ctx context.Context, req *pb.PostOrderRequest) (*pb.PostOrderResponse, error) {
+	return sb.s.PostOrder(ctx, req)
+}
+
+func (sb *sandboxServer) ReplaceSandboxOrder(ctx context.Context, req *pb.ReplaceOrderRequest) (*pb.PostOrderResponse, error) {
+	return sb.s.ReplaceOrder(ctx, req)
+}
+
+func (sb *sandboxServer) GetSandboxOrders(ctx context.Context, req *pb.GetOrdersRequest) (*pb.GetOrdersResponse, error) {
+	return sb.s.GetOrders(ctx, req)
+}
+
+func (sb *sandboxServer) CancelSandboxOrder(ctx context.Context, req *pb.CancelOrderRequest) (*pb.CancelOrderResponse, error) {
+	return sb.s.CancelOrder(ctx, req)
+}
+
+func (sb *sandboxServer) GetSandboxOrderState(ctx context.Context, req *pb.GetOrderStateRequest) (*pb.OrderState, error) {
+	return sb.s.GetOrderState(ctx, req)
+}
+
+func (sb *sandboxServer) GetSandboxPositions(ctx context.Context, req *pb.PositionsRequest) (*pb.PositionsResponse, error) {
+	return sb.s.GetPositions(ctx, req)
+}
+
+func (sb *sandboxServer) GetSandboxOperations(ctx context.Context, req *pb.OperationsRequest) (*pb.OperationsResponse, error) {
+	return sb.s.GetOperations(ctx, req)
+}
+
+func (sb *sandboxServer) GetSandboxOperationsByCursor(ctx context.Context, req *pb.GetOperationsByCursorRequest) (*pb.GetOperationsByCursorResponse, error) {
+	return sb.s.GetOperationsByCursor(ctx, req)
+}
+
+func (sb *sandboxServer) GetSandboxPortfolio(ctx context.Context, req *pb.PortfolioRequest) (*pb.PortfolioResponse, error) {
+	return sb.s.GetPortfolio(ctx, req)
+}
+
+// SandboxPayIn - Пополнение счета, в ответе баланс счета в валюте пополнения
+func (sb *sandboxServer) SandboxPayIn(ctx context.Context, req *pb.SandboxPayInRequest) (*pb.SandboxPayInResponse, error) {
+	amount := req.GetAmount().ToDecimal()
+	if !amount.IsPositive() {
+		return nil, apiError(codes.InvalidArgument, 30001, "Сумма пополнения должна быть положительной")
+	}
+	currency := strings.ToLower(req.GetAmount().GetCurrency())
+	if currency == "" {
+		currency = "rub"
+	}
+	sb.s.mu.Lock()
+	acc, err := sb.s.account(req.GetAccountId())
+	if err != nil {
+		sb.s.mu.Unlock()
+		return nil, err
+	}
+	var ev events
+	sb.s.payIn(acc, currency, amount, &ev)
+	balance := pb.MoneyFromDecimal(acc.money[currency], currency)
+	sb.s.mu.Unlock()
+	ev.publish()
+	return &pb.SandboxPayInResponse{Balance: balance}, nil
+}
+
+func (sb *sandboxServer) GetSandboxWithdrawLimits(ctx context.Context, req *pb.WithdrawLimitsRequest) (*pb.WithdrawLimitsResponse, error) {
+	return sb.s.GetWithdrawLimits(ctx, req)
+}
+
+func (sb *sandboxServer) GetSandboxMaxLots(ctx context.Context, req *pb.GetMaxLotsRequest) (*pb.GetMaxLotsResponse, error) {
+	return sb.s.GetMaxLots(ctx, req)
+}
@@ -0,0 +1,383 @@
+// Package investgotest - тестовый сервер Invest API, который работает в памяти процесса через bufconn.
+//
+// Сервер реализует все сервисы из proto и хранит состояние, которое тест может задавать сам: каталог
+// инструментов, цены, счета, позиции и исторические свечи. Заявки исполняются по заданным ценам, а стримы
+// получают как изменения состояния (сделки, портфель, позиции), так и биржевую информацию, которую тест
+// отправляет через Push* методы. Клиент investgo подключается к серверу через DialOptions:
+//
+//	srv, err := investgotest.NewServer()
+//	...
+//	defer srv.Stop()
+//	srv.AddShare(&pb.Share{Figi: "BBG004730N88", Ticker: "SBER", ClassCode: "TQBR", Lot: 10, Currency: "rub"})
+//	srv.SetPrice("BBG004730N88", 250.5)
+//	srv.PayIn(investgotest.DefaultAccountId, "rub", 100000)
+//	client, err := investgo.NewClient(ctx, srv.Config(), logger, srv.DialOptions()...)
+package investgotest
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"math/big"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/russianinvestments/invest-api-go-sdk/investgo"
+	pb "github.com/russianinvestments/invest-api-go-sdk/proto"
+)
+
+const (
+	// DefaultAccountId - идентификатор счета, который открыт на сервере сразу после создания
+	DefaultAccountId = "investgotest-account"
+	// DefaultToken - токен, с которым сервер принимает запросы, если не задан WithToken
+	DefaultToken = "investgotest-token"
+	// Endpoint - адрес сервера для investgo.Config, фактическое подключение идет через bufconn
+	Endpoint = "investgotest:443"
+
+	serverName = "investgotest"
+	bufferSize = 1 << 20
+	// streamBuffer - размер буфера сообщений одного стрима
+	streamBuffer = 1024
+)
+
+// Option - настройка тестового сервера
+type Option func(s *Server)
+
+// WithToken - Сервер принимает только запросы с этим токеном, остальные завершаются ошибкой Unauthenticated
+func WithToken(token string) Option {
+	return func(s *Server) {
+		s.token = token
+	}
+}
+
+// WithClock - Источник времени сервера, по умолчанию time.Now. Удобно для детерминированных тестов
+func WithClock(now func() time.Time) Option {
+	return func(s *Server) {
+		s.now = now
+	}
+}
+
+// WithCommission - Комиссия брокера в долях от суммы сделки, например 0.0005. По умолчанию комиссии нет
+func WithCommission(rate float64) Option {
+	return func(s *Server) {
+		s.commission = rate
+	}
+}
+
+// Server - тестовый сервер Invest API
+type Server struct {
+	pb.UnimplementedInstrumentsServiceServer
+	pb.UnimplementedMarketDataServiceServer
+	pb.UnimplementedOperationsServiceServer
+	pb.UnimplementedOrdersServiceServer
+	pb.UnimplementedStopOrdersServiceServer
+	pb.UnimplementedUsersServiceServer
+	pb.UnimplementedSignalServiceServer
+
+	token      string
+	now        func() time.Time
+	commission float64
+
+	lis   *bufconn.Listener
+	grpc  *grpc.Server
+	creds credentials.TransportCredentials
+
+	// mu - защищает все состояние сервера
+	mu          sync.Mutex
+	instruments []*instrument
+	accounts    map[string]*account
+	accountIds  []string
+	favorites   []string
+	schedules   map[string]*pb.TradingSchedule
+
+	mdStreams     *mdStreams
+	trades        *hub[*pb.TradesStreamResponse]
+	orderStates   *hub[*pb.OrderStateStreamResponse]
+	portfolios    *hub[*pb.PortfolioStreamResponse]
+	positions     *hub[*pb.PositionsStreamResponse]
+	subscriptions *notifier
+	// revoked - оповещение о смене токена, открытые стримы завершаются с ошибкой Unauthenticated
+	revoked *notifier
+}
+
+// NewServer - создание и запуск тестового сервера. На сервере сразу открыт счет DefaultAccountId
+func NewServer(opts ...Option) (*Server, error) {
+	n := newNotifier()
+	s := &Server{
+		token:         DefaultToken,
+		now:           time.Now,
+		accounts:      make(map[string]*account),
+		schedules:     make(map[string]*pb.TradingSchedule),
+		trades:        newHub[*pb.TradesStreamResponse](n),
+		orderStates:   newHub[*pb.OrderStateStreamResponse](n),
+		portfolios:    newHub[*pb.PortfolioStreamResponse](n),
+		positions:     newHub[*pb.PositionsStreamResponse](n),
+		mdStreams:     newMDStreams(n),
+		subscriptions: n,
+		revoked:       newNotifier(),
+	}
+	for _, o := range opts {
+		o(s)
+	}
+	s.openAccount(DefaultAccountId, "investgotest", pb.AccountType_ACCOUNT_TYPE_TINKOFF)
+
+	serverTLS, clientTLS, err := selfSignedTLS()
+	if err != nil {
+		return nil, err
+	}
+	s.creds = credentials.NewTLS(clientTLS)
+	s.lis = bufconn.Listen(bufferSize)
+	s.grpc = grpc.NewServer(
+		grpc.Creds(credentials.NewTLS(serverTLS)),
+		grpc.ChainUnaryInterceptor(s.unaryInterceptor),
+		grpc.ChainStreamInterceptor(s.streamInterceptor),
+	)
+	pb.RegisterInstrumentsServiceServer(s.grpc, s)
+	pb.RegisterMarketDataServiceServer(s.grpc, s)
+	pb.RegisterMarketDataStreamServiceServer(s.grpc, &marketDataStreamServer{s: s})
+	pb.RegisterOperationsServiceServer(s.grpc, s)
+	pb.RegisterOperationsStreamServiceServer(s.grpc, &operationsStreamServer{s: s})
+	pb.RegisterOrdersServiceServer(s.grpc, s)
+	pb.RegisterOrdersStreamServiceServer(s.grpc, &ordersStreamServer{s: s})
+	pb.RegisterSandboxServiceServer(s.grpc, &sandboxServer{s: s})
+	pb.RegisterStopOrdersServiceServer(s.grpc, s)
+	pb.RegisterUsersServiceServer(s.grpc, s)
+	pb.RegisterSignalServiceServer(s.grpc, s)
+
+	go func() {
+		_ = s.grpc.Serve(s.lis)
+	}()
+	return s, nil
+}
+
+// DialOptions - Опции подключения к серверу для investgo.NewClient или grpc.Dial
+func (s *Server) DialOptions() []grpc.DialOption {
+	return []grpc.DialOption{
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return s.lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(s.creds),
+	}
+}
+
+// Config - Конфигурация investgo для подключения к серверу со счетом DefaultAccountId
+func (s *Server) Config() investgo.Config {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return investgo.Config{
+		EndPoint:    Endpoint,
+		Environment: investgo.EnvironmentCustom,
+		Token:       s.token,
+		AppName:     "investgotest",
+		AccountId:   DefaultAccountId,
+	}
+}
+
+// NewClient - Создание клиента investgo, подключенного к серверу
+func (s *Server) NewClient(ctx context.Context, l investgo.Logger) (*investgo.Client, error) {
+	return investgo.NewClient(ctx, s.Config(), l, s.DialOptions()...)
+}
+
+// Stop - Остановка сервера, все открытые стримы завершаются
+func (s *Server) Stop() {
+	s.grpc.Stop()
+	_ = s.lis.Close()
+}
+
+// WaitSubscribed - Ожидание подписки в любом стриме на инструмент или счет с идентификатором id. Подписки
+// обрабатываются сервером асинхронно, поэтому перед отправкой биржевой информации через Push* тест может
+// дождаться, пока клиент действительно подпишется
+func (s *Server) WaitSubscribed(ctx context.Context, id string) error {
+	for {
+		changed := s.subscriptions.wait()
+		if s.subscribed(id) {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-changed:
+		}
+	}
+}
+
+func (s *Server) subscribed(id string) bool {
+	s.mu.Lock()
+	inst := s.findInstrument(id)
+	s.mu.Unlock()
+	if inst != nil && s.mdStreams.subscribed(inst) {
+		return true
+	}
+	return s.trades.subscribed(id) || s.orderStates.subscribed(id) || s.portfolios.subscribed(id) || s.positions.subscribed(id)
+}
+
+func (s *Server) unaryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	trackingId := uuid.New().String()
+	_ = grpc.SetHeader(ctx, metadata.Pairs("x-tracking-id", trackingId))
+	if err := s.authorize(ctx); err != nil {
+		_ = grpc.SetTrailer(ctx, errorTrailer(err, trackingId))
+		return nil, toStatus(err)
+	}
+	resp, err := handler(ctx, req)
+	if err != nil {
+		_ = grpc.SetTrailer(ctx, errorTrailer(err, trackingId))
+		return nil, toStatus(err)
+	}
+	return resp, nil
+}
+
+func (s *Server) streamInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	trackingId := uuid.New().String()
+	_ = ss.SetHeader(metadata.Pairs("x-tracking-id", trackingId))
+	revoked := s.revoked.wait()
+	err := s.authorize(ss.Context())
+	if err == nil {
+		ctx, cancel := context.WithCancel(ss.Context())
+		go func() {
+			select {
+			case <-revoked:
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+		err = handler(srv, &revocableStream{ServerStream: ss, ctx: ctx})
+		cancel()
+		select {
+		case <-revoked:
+			err = errUnauthenticated()
+		default:
+		}
+	}
+	if err != nil {
+		// grpc проверяет трейлеры стримов и не пропускает значения с символами не из ASCII, поэтому описание ошибки
+		// не передается, клиент возьмет его по коду ошибки
+		md := errorTrailer(err, trackingId)
+		md.Delete("message")
+		ss.SetTrailer(md)
+		return toStatus(err)
+	}
+	return nil
+}
+
+// SetToken - Смена токена, с которым сервер принимает запросы. Стримы, открытые со старым токеном, завершаются
+// ошибкой Unauthenticated, как при отзыве токена в настоящем API
+func (s *Server) SetToken(token string) {
+	s.mu.Lock()
+	s.token = token
+	s.mu.Unlock()
+	s.revoked.notify()
+}
+
+func (s *Server) authorize(ctx context.Context) error {
+	s.mu.Lock()
+	token := s.token
+	s.mu.Unlock()
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, v := range md.Get("authorization") {
+		if strings.TrimPrefix(v, "Bearer ") == token {
+			return nil
+		}
+	}
+	return errUnauthenticated()
+}
+
+func errUnauthenticated() error {
+	return apiError(codes.Unauthenticated, investgo.ErrCodeTokenNotFound, "Токен доступа не найден или не активен")
+}
+
+// revocableStream - стрим, контекст которого отменяется при смене токена
+type revocableStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *revocableStream) Context() context.Context {
+	return s.ctx
+}
+
+// serverError - ошибка API, которую сервер отдает в формате настоящего Invest API: код ошибки в статусе,
+// описание в трейлере message
+type serverError struct {
+	code    codes.Code
+	apiCode int
+	message string
+}
+
+func apiError(code codes.Code, apiCode int, message string) error {
+	return &serverError{code: code, apiCode: apiCode, message: message}
+}
+
+func (e *serverError) Error() string {
+	return strconv.Itoa(e.apiCode) + ": " + e.message
+}
+
+func toStatus(err error) error {
+	var se *serverError
+	if errors.As(err, &se) {
+		return status.Error(se.code, strconv.Itoa(se.apiCode))
+	}
+	return err
+}
+
+func errorTrailer(err error, trackingId string) metadata.MD {
+	md := metadata.Pairs("x-tracking-id", trackingId)
+	var se *serverError
+	if errors.As(err, &se) {
+		md.Set("message", se.message)
+	}
+	return md
+}
+
+// selfSignedTLS - сертификат сервера, созданный при запуске, клиент доверяет только ему
+func selfSignedTLS() (*tls.Config, *tls.Config, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: serverName},
+		DNSNames:     []string{serverName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * 365 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IsCA:         true,
+
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, nil, err
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	serverTLS := &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key, Leaf: cert}},
+	}
+	clientTLS := &tls.Config{
+		RootCAs:    pool,
+		ServerName: serverName,
+	}
+	return serverTLS, clientTLS, nil
+}
@@ -0,0 +1,190 @@
+package investgotest_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/russianinvestments/invest-api-go-sdk/investgo"
+	"github.com/russianinvestments/invest-api-go-sdk/investgotest"
+	pb "github.com/russianinvestments/invest-api-go-sdk/proto"
+	"github.com/russianinvestments/invest-api-go-sdk/retry"
+)
+
+const (
+	sberFigi = "BBG004730N88"
+	waitTime = 5 * time.Second
+)
+
+type nopLogger struct{}
+
+func (nopLogger) Infof(string, ...any)  {}
+func (nopLogger) Errorf(string, ...any) {}
+func (nopLogger) Fatalf(string, ...any) {}
+
+// setup - сервер с одной акцией по цене 250 и клиент к нему, все останавливается по завершении теста
+func setup(t *testing.T, opts ...investgotest.Option) (*investgotest.Server, *investgo.Client, string) {
+	t.Helper()
+	srv, err := investgotest.NewServer(opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(srv.Stop)
+	uid := srv.AddShare(&pb.Share{Figi: sberFigi, Ticker: "SBER", ClassCode: "TQBR", Lot: 10, Currency: "rub"})
+	if err := srv.SetPrice(uid, 250); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	client, err := srv.NewClient(ctx, nopLogger{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = client.Stop()
+	})
+	return srv, client, uid
+}
+
+// receive - первое значение из канала или ошибка теста по таймауту
+func receive[T any](t *testing.T, ch <-chan T) T {
+	t.Helper()
+	select {
+	case v, ok := <-ch:
+		if !ok {
+			t.Fatal("channel closed")
+		}
+		return v
+	case <-time.After(waitTime):
+		t.Fatal("timeout")
+	}
+	var zero T
+	return zero
+}
+
+// listen - запуск Listen стрима с остановкой по завершении теста
+func listen(t *testing.T, run func() error, stop func()) {
+	t.Helper()
+	done := make(chan error, 1)
+	go func() {
+		done <- run()
+	}()
+	t.Cleanup(func() {
+		stop()
+		if err := <-done; err != nil {
+			t.Errorf("listen: %v", err)
+		}
+	})
+}
+
+func quotation(v string) *pb.Quotation {
+	return pb.QuotationFromDecimal(decimal.RequireFromString(v))
+}
+
+func waitSubscribed(t *testing.T, srv *investgotest.Server, id string) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), waitTime)
+	defer cancel()
+	if err := srv.WaitSubscribed(ctx, id); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUsers(t *testing.T) {
+	_, client, _ := setup(t)
+	users := client.NewUsersServiceClient()
+
+	accounts, err := users.GetAccounts(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(accounts.GetAccounts()) != 1 || accounts.GetAccounts()[0].GetId() != investgotest.DefaultAccountId {
+		t.Fatalf("accounts = %v", accounts.GetAccounts())
+	}
+	tariff, err := users.GetUserTariff()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tariff.GetUnaryLimits()) == 0 {
+		t.Fatal("no unary limits")
+	}
+	if _, err := users.GetInfo(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestToken(t *testing.T) {
+	srv, client, _ := setup(t)
+	srv.SetToken("another")
+	if _, err := client.NewUsersServiceClient().GetInfo(); err == nil {
+		t.Fatal("request with revoked token succeeded")
+	}
+}
+
+// TestTokenRotation - после отзыва токена стрим переподключается с новым токеном из файла, хотя провайдер
+// кэширует токен
+func TestTokenRotation(t *testing.T) {
+	srv, _, uid := setup(t)
+	path := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(path, []byte(investgotest.DefaultToken), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	conf := srv.Config()
+	conf.Token = ""
+	conf.TokenProvider = investgo.NewCachedToken(investgo.NewFileToken(path), 0)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	client, err := investgo.NewClient(ctx, conf, nopLogger{}, srv.DialOptions()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = client.Stop()
+	}()
+
+	mds, err := client.NewMarketDataStreamClient().MarketDataStream(
+		investgo.WithReconnect(retry.BackoffLinear(10*time.Millisecond), 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	prices, err := mds.SubscribeLastPrice([]string{uid})
+	if err != nil {
+		t.Fatal(err)
+	}
+	listen(t, mds.Listen, mds.Stop)
+	waitSubscribed(t, srv, uid)
+
+	if err := os.WriteFile(path, []byte("rotated-token"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	srv.SetToken("rotated-token")
+	for {
+		if e := receive(t, mds.ReconnectEvents()); e.Success {
+			break
+		}
+	}
+	waitSubscribed(t, srv, uid)
+	if err := srv.SetPrice(uid, 260); err != nil {
+		t.Fatal(err)
+	}
+	for {
+		if p := receive(t, prices); p.GetPrice().ToFloat() == 260 {
+			break
+		}
+	}
+	if _, err := client.NewUsersServiceClient().GetInfo(); err != nil {
+		t.Fatalf("request with rotated token: %v", err)
+	}
+}
+
+func TestSignals(t *testing.T) {
+	_, client, _ := setup(t)
+	if _, err := client.NewSignalServiceClient().GetStrategies(nil); err != nil {
+		t.Fatal(err)
+	}
+}
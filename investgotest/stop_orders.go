@@ -0,0 +1,203 @@
+package investgotest
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/russianinvestments/invest-api-go-sdk/investgo"
+	pb "github.com/russianinvestments/invest-api-go-sdk/proto"
+)
+
+// stopOrder - стоп-заявка, которая выставляет обычную заявку при достижении цены активации
+type stopOrder struct {
+	id             string
+	inst           *instrument
+	direction      pb.StopOrderDirection
+	stopType       pb.StopOrderType
+	lots           int64
+	price          *pb.Quotation
+	stopPrice      decimal.Decimal
+	expirationType pb.StopOrderExpirationType
+	expire         time.Time
+	status         pb.StopOrderStatusOption
+	created        time.Time
+	activated      time.Time
+	orderId        string
+}
+
+func (so *stopOrder) proto() *pb.StopOrder {
+	c := so.inst.info.GetCurrency()
+	resp := &pb.StopOrder{
+		StopOrderId:   so.id,
+		LotsRequested: so.lots,
+		Figi:          so.inst.info.GetFigi(),
+		Direction:     so.direction,
+		Currency:      c,
+		OrderType:     so.stopType,
+		CreateDate:    timestamppb.New(so.created),
+		Price:         pb.MoneyFromDecimal(so.price.ToDecimal(), c),
+		StopPrice:     pb.MoneyFromDecimal(so.stopPrice, c),
+		InstrumentUid: so.inst.uid(),
+		Status:        so.status,
+	}
+	if !so.activated.IsZero() {
+		resp.ActivationDateTime = timestamppb.New(so.activated)
+	}
+	if !so.expire.IsZero() {
+		resp.ExpirationTime = timestamppb.New(so.expire)
+	}
+	if so.orderId != "" {
+		id := so.orderId
+		resp.ExchangeOrderId = &id
+	}
+	return resp
+}
+
+// triggered - цена price достигла цены активации стоп-заявки
+func (so *stopOrder) triggered(price decimal.Decimal) bool {
+	buy := so.direction == pb.StopOrderDirection_STOP_ORDER_DIRECTION_BUY
+	if so.stopType == pb.StopOrderType_STOP_ORDER_TYPE_TAKE_PROFIT {
+		// тейк-профит на покупку срабатывает при падении цены, на продажу - при росте
+		if buy {
+			return price.LessThanOrEqual(so.stopPrice)
+		}
+		return price.GreaterThanOrEqual(so.stopPrice)
+	}
+	if buy {
+		return price.GreaterThanOrEqual(so.stopPrice)
+	}
+	return price.LessThanOrEqual(so.stopPrice)
+}
+
+// expireStopOrders - перевод просроченных стоп-заявок в статус EXPIRED
+func (s *Server) expireStopOrders(acc *account) {
+	now := s.now()
+	for _, so := range acc.stopOrders {
+		if so.status == pb.StopOrderStatusOption_STOP_ORDER_STATUS_ACTIVE && !so.expire.IsZero() && now.After(so.expire) {
+			so.status = pb.StopOrderStatusOption_STOP_ORDER_STATUS_EXPIRED
+		}
+	}
+}
+
+// triggerStopOrders - активация стоп-заявок по инструменту после изменения цены. Стоп-лимит выставляет
+// лимитную заявку по цене стоп-заявки, остальные типы - рыночную
+func (s *Server) triggerStopOrders(acc *account, inst *instrument, ev *events) {
+	s.expireStopOrders(acc)
+	for _, so := range acc.stopOrders {
+		if so.inst != inst || so.status != pb.StopOrderStatusOption_STOP_ORDER_STATUS_ACTIVE || !so.triggered(inst.price) {
+			continue
+		}
+		req := orderRequest{
+			accountId:    acc.info.GetId(),
+			instrumentId: inst.uid(),
+			lots:         so.lots,
+			direction:    pb.OrderDirection_ORDER_DIRECTION_BUY,
+			orderType:    pb.OrderType_ORDER_TYPE_MARKET,
+		}
+		if so.direction == pb.StopOrderDirection_STOP_ORDER_DIRECTION_SELL {
+			req.direction = pb.OrderDirection_ORDER_DIRECTION_SELL
+		}
+		if so.stopType == pb.StopOrderType_STOP_ORDER_TYPE_STOP_LIMIT {
+			req.orderType = pb.OrderType_ORDER_TYPE_LIMIT
+			req.price = so.price
+		}
+		so.activated = s.now()
+		o, err := s.postOrder(req, ev)
+		if err != nil {
+			so.status = pb.StopOrderStatusOption_STOP_ORDER_STATUS_CANCELED
+			continue
+		}
+		so.status = pb.StopOrderStatusOption_STOP_ORDER_STATUS_EXECUTED
+		so.orderId = o.id
+	}
+}
+
+func (s *Server) PostStopOrder(ctx context.Context, req *pb.PostStopOrderRequest) (*pb.PostStopOrderResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	acc, err := s.account(req.GetAccountId())
+	if err != nil {
+		return nil, err
+	}
+	inst := s.findInstrument(instrumentId(req.GetInstrumentId(), req.GetFigi()))
+	if inst == nil {
+		return nil, errInstrumentNotFound()
+	}
+	if req.GetQuantity() <= 0 {
+		return nil, apiError(codes.InvalidArgument, 30003, "Количество лотов должно быть положительным числом")
+	}
+	if req.GetStopPrice() == nil {
+		return nil, apiError(codes.InvalidArgument, 30001, "Не указана цена активации стоп-заявки")
+	}
+	if req.GetStopOrderType() == pb.StopOrderType_STOP_ORDER_TYPE_STOP_LIMIT && req.GetPrice() == nil {
+		return nil, apiError(codes.InvalidArgument, 30001, "Не указана цена стоп-лимит заявки")
+	}
+	so := &stopOrder{
+		id:             uuid.New().String(),
+		inst:           inst,
+		direction:      req.GetDirection(),
+		stopType:       req.GetStopOrderType(),
+		lots:           req.GetQuantity(),
+		price:          req.GetPrice(),
+		stopPrice:      req.GetStopPrice().ToDecimal(),
+		expirationType: req.GetExpirationType(),
+		status:         pb.StopOrderStatusOption_STOP_ORDER_STATUS_ACTIVE,
+		created:        s.now(),
+	}
+	if req.GetExpirationType() == pb.StopOrderExpirationType_STOP_ORDER_EXPIRATION_TYPE_GOOD_TILL_DATE {
+		so.expire = req.GetExpireDate().AsTime()
+	}
+	acc.stopOrders = append(acc.stopOrders, so)
+	return &pb.PostStopOrderResponse{StopOrderId: so.id, OrderRequestId: req.GetOrderId()}, nil
+}
+
+func (s *Server) GetStopOrders(ctx context.Context, req *pb.GetStopOrdersRequest) (*pb.GetStopOrdersResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	acc, err := s.account(req.GetAccountId())
+	if err != nil {
+		return nil, err
+	}
+	s.expireStopOrders(acc)
+	want := req.GetStatus()
+	if want == pb.StopOrderStatusOption_STOP_ORDER_STATUS_UNSPECIFIED {
+		want = pb.StopOrderStatusOption_STOP_ORDER_STATUS_ACTIVE
+	}
+	resp := &pb.GetStopOrdersResponse{}
+	for _, so := range acc.stopOrders {
+		if want != pb.StopOrderStatusOption_STOP_ORDER_STATUS_ALL && so.status != want {
+			continue
+		}
+		if !inRange(timestamppb.New(so.created), req.GetFrom(), req.GetTo()) {
+			continue
+		}
+		resp.StopOrders = append(resp.StopOrders, so.proto())
+	}
+	return resp, nil
+}
+
+func (s *Server) CancelStopOrder(ctx context.Context, req *pb.CancelStopOrderRequest) (*pb.CancelStopOrderResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	acc, err := s.account(req.GetAccountId())
+	if err != nil {
+		return nil, err
+	}
+	s.expireStopOrders(acc)
+	for _, so := range acc.stopOrders {
+		if so.id != req.GetStopOrderId() {
+			continue
+		}
+		if so.status != pb.StopOrderStatusOption_STOP_ORDER_STATUS_ACTIVE {
+			break
+		}
+		so.status = pb.StopOrderStatusOption_STOP_ORDER_STATUS_CANCELED
+		return &pb.CancelStopOrderResponse{Time: timestamppb.New(s.now())}, nil
+	}
+	return nil, apiError(codes.NotFound, investgo.ErrCodeStopOrderNotFound, "Стоп-заявка не найдена")
+}
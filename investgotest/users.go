@@ -0,0 +1,88 @@
+package investgotest
+
+import (
+	"context"
+	"sort"
+
+	"github.com/shopspring/decimal"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/russianinvestments/invest-api-go-sdk/investgo"
+	pb "github.com/russianinvestments/invest-api-go-sdk/proto"
+)
+
+// services - сервисы для лимитов из GetUserTariff
+var services = map[string]string{
+	"InstrumentsService": pb.InstrumentsService_ServiceDesc.ServiceName,
+	"UsersService":       pb.UsersService_ServiceDesc.ServiceName,
+	"OperationsService":  pb.OperationsService_ServiceDesc.ServiceName,
+	"MarketDataService":  pb.MarketDataService_ServiceDesc.ServiceName,
+	"OrdersService":      pb.OrdersService_ServiceDesc.ServiceName,
+	"StopOrdersService":  pb.StopOrdersService_ServiceDesc.ServiceName,
+	"SandboxService":     pb.SandboxService_ServiceDesc.ServiceName,
+	"SignalService":      pb.SignalService_ServiceDesc.ServiceName,
+}
+
+// GetAccounts - Счета сервера в порядке открытия, закрытые счета возвращаются только при фильтре по статусу
+func (s *Server) GetAccounts(ctx context.Context, req *pb.GetAccountsRequest) (*pb.GetAccountsResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	resp := &pb.GetAccountsResponse{}
+	for _, id := range s.accountIds {
+		info := s.accounts[id].info
+		if req.Status != nil && req.GetStatus() != pb.AccountStatus_ACCOUNT_STATUS_ALL && info.GetStatus() != req.GetStatus() {
+			continue
+		}
+		resp.Accounts = append(resp.Accounts, proto.Clone(info).(*pb.Account))
+	}
+	return resp, nil
+}
+
+func (s *Server) GetMarginAttributes(ctx context.Context, req *pb.GetMarginAttributesRequest) (*pb.GetMarginAttributesResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	acc, err := s.account(req.GetAccountId())
+	if err != nil {
+		return nil, err
+	}
+	return &pb.GetMarginAttributesResponse{
+		LiquidPortfolio:       s.portfolio(acc).GetTotalAmountPortfolio(),
+		StartingMargin:        pb.MoneyFromDecimal(decimal.Zero, "rub"),
+		MinimalMargin:         pb.MoneyFromDecimal(decimal.Zero, "rub"),
+		FundsSufficiencyLevel: pb.QuotationFromDecimal(decimal.Zero),
+		AmountOfMissingFunds:  pb.MoneyFromDecimal(decimal.Zero, "rub"),
+	}, nil
+}
+
+// GetUserTariff - Лимиты unary запросов из investgo.DefaultRateLimits
+func (s *Server) GetUserTariff(ctx context.Context, req *pb.GetUserTariffRequest) (*pb.GetUserTariffResponse, error) {
+	names := make([]string, 0, len(investgo.DefaultRateLimits))
+	for name := range investgo.DefaultRateLimits {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	resp := &pb.GetUserTariffResponse{}
+	for _, name := range names {
+		service, ok := services[name]
+		if !ok {
+			continue
+		}
+		resp.UnaryLimits = append(resp.UnaryLimits, &pb.UnaryLimit{
+			LimitPerMinute: int32(investgo.DefaultRateLimits[name]),
+			Methods:        []string{service + "/*"},
+		})
+	}
+	return resp, nil
+}
+
+func (s *Server) GetInfo(ctx context.Context, req *pb.GetInfoRequest) (*pb.GetInfoResponse, error) {
+	return &pb.GetInfoResponse{Tariff: "investor", UserId: "investgotest"}, nil
+}
+
+func (s *Server) GetStrategies(ctx context.Context, req *pb.GetStrategiesRequest) (*pb.GetStrategiesResponse, error) {
+	return &pb.GetStrategiesResponse{}, nil
+}
+
+func (s *Server) GetSignals(ctx context.Context, req *pb.GetSignalsRequest) (*pb.GetSignalsResponse, error) {
+	return &pb.GetSignalsResponse{}, nil
+}
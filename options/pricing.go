@@ -0,0 +1,218 @@
+// Package options - оценка опционов по моделям Блэка-Шоулза и Блэка-76: теоретическая цена, подразумеваемая
+// волатильность и греки (дельта, гамма, вега, тета), а также улыбка волатильности по цепочке опционов
+// investgo.OptionChain. Модели рассчитаны на европейские опционы, для американских опционов оценка приближенная.
+package options
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	pb "github.com/russianinvestments/invest-api-go-sdk/proto"
+)
+
+var (
+	// ErrInvalidParams - параметры модели вне допустимой области: неположительные цены, срок или волатильность
+	ErrInvalidParams = errors.New("options: invalid params")
+	// ErrNoImpliedVolatility - цена опциона вне границ, которые допускает модель, или метод не сошелся
+	ErrNoImpliedVolatility = errors.New("options: implied volatility not found")
+)
+
+// year - длительность года для перевода срока до экспирации в годы
+const year = 365 * 24 * time.Hour
+
+// Model - модель оценки опциона
+type Model int
+
+const (
+	// ModelBlackScholes - модель Блэка-Шоулза для опционов на акции и индексы, Underlying - спот цена
+	ModelBlackScholes Model = iota
+	// ModelBlack76 - модель Блэка для опционов на фьючерсы, Underlying - цена фьючерса
+	ModelBlack76
+)
+
+// Params - параметры оценки опциона
+type Params struct {
+	Model Model
+	// Direction - колл или пут
+	Direction pb.OptionDirection
+	// Underlying - цена базового актива: спот для ModelBlackScholes, фьючерс для ModelBlack76
+	Underlying float64
+	Strike     float64
+	// Expiry - срок до экспирации в годах, см. YearsTo
+	Expiry float64
+	// Rate - безрисковая ставка, непрерывное начисление, 0.16 = 16% годовых
+	Rate float64
+	// Dividend - непрерывная дивидендная доходность базового актива, только для ModelBlackScholes
+	Dividend float64
+	// Volatility - годовая волатильность, 0.3 = 30%
+	Volatility float64
+}
+
+// Greeks - чувствительности цены опциона
+type Greeks struct {
+	// Delta - изменение цены опциона при росте цены базового актива на 1
+	Delta float64
+	// Gamma - изменение дельты при росте цены базового актива на 1
+	Gamma float64
+	// Vega - изменение цены опциона при росте волатильности на 1 (100 п.п.), на 1 п.п. - Vega / 100
+	Vega float64
+	// Theta - изменение цены опциона за год, за календарный день - Theta / 365
+	Theta float64
+}
+
+// YearsTo - Срок от now до expiration в годах по 365 дней, для прошедшей даты - 0
+func YearsTo(expiration, now time.Time) float64 {
+	if !expiration.After(now) {
+		return 0
+	}
+	return float64(expiration.Sub(now)) / float64(year)
+}
+
+// FromOption - Параметры оценки опциона o: направление, страйк и срок до экспирации от now. Волатильность
+// не заполняется, ее нужно задать или найти через ImpliedVolatility
+func FromOption(o *pb.Option, model Model, underlying, rate float64, now time.Time) Params {
+	return Params{
+		Model:      model,
+		Direction:  o.GetDirection(),
+		Underlying: underlying,
+		Strike:     o.GetStrikePrice().ToFloat(),
+		Expiry:     YearsTo(o.GetExpirationDate().AsTime(), now),
+		Rate:       rate,
+	}
+}
+
+// spot - цена базового актива и дивидендная доходность в терминах Блэка-Шоулза. Модель Блэка-76 совпадает с
+// моделью Блэка-Шоулза, в которой доходность базового актива равна безрисковой ставке
+func (p Params) spot() (s, q float64) {
+	if p.Model == ModelBlack76 {
+		return p.Underlying, p.Rate
+	}
+	return p.Underlying, p.Dividend
+}
+
+func (p Params) validate(needVolatility bool) error {
+	switch {
+	case p.Direction != pb.OptionDirection_OPTION_DIRECTION_CALL && p.Direction != pb.OptionDirection_OPTION_DIRECTION_PUT:
+		return fmt.Errorf("%w: direction %v", ErrInvalidParams, p.Direction)
+	case !(p.Underlying > 0) || !(p.Strike > 0):
+		return fmt.Errorf("%w: underlying %v, strike %v", ErrInvalidParams, p.Underlying, p.Strike)
+	case !(p.Expiry > 0):
+		return fmt.Errorf("%w: expiry %v", ErrInvalidParams, p.Expiry)
+	case needVolatility && !(p.Volatility > 0):
+		return fmt.Errorf("%w: volatility %v", ErrInvalidParams, p.Volatility)
+	}
+	return nil
+}
+
+func normCDF(x float64) float64 {
+	return 0.5 * math.Erfc(-x/math.Sqrt2)
+}
+
+func normPDF(x float64) float64 {
+	return math.Exp(-x*x/2) / math.Sqrt(2*math.Pi)
+}
+
+func (p Params) d1d2() (d1, d2 float64) {
+	s, q := p.spot()
+	sqrtT := math.Sqrt(p.Expiry)
+	d1 = (math.Log(s/p.Strike) + (p.Rate-q+p.Volatility*p.Volatility/2)*p.Expiry) / (p.Volatility * sqrtT)
+	return d1, d1 - p.Volatility*sqrtT
+}
+
+func (p Params) price() float64 {
+	s, q := p.spot()
+	d1, d2 := p.d1d2()
+	dq, dr := math.Exp(-q*p.Expiry), math.Exp(-p.Rate*p.Expiry)
+	if p.Direction == pb.OptionDirection_OPTION_DIRECTION_CALL {
+		return s*dq*normCDF(d1) - p.Strike*dr*normCDF(d2)
+	}
+	return p.Strike*dr*normCDF(-d2) - s*dq*normCDF(-d1)
+}
+
+// Price - Теоретическая цена опциона
+func Price(p Params) (float64, error) {
+	if err := p.validate(true); err != nil {
+		return 0, err
+	}
+	return p.price(), nil
+}
+
+// ComputeGreeks - Греки опциона
+func ComputeGreeks(p Params) (Greeks, error) {
+	if err := p.validate(true); err != nil {
+		return Greeks{}, err
+	}
+	s, q := p.spot()
+	d1, d2 := p.d1d2()
+	sqrtT := math.Sqrt(p.Expiry)
+	dq, dr := math.Exp(-q*p.Expiry), math.Exp(-p.Rate*p.Expiry)
+
+	g := Greeks{
+		Gamma: dq * normPDF(d1) / (s * p.Volatility * sqrtT),
+		Vega:  s * dq * normPDF(d1) * sqrtT,
+	}
+	decay := -s * dq * normPDF(d1) * p.Volatility / (2 * sqrtT)
+	if p.Direction == pb.OptionDirection_OPTION_DIRECTION_CALL {
+		g.Delta = dq * normCDF(d1)
+		g.Theta = decay - p.Rate*p.Strike*dr*normCDF(d2) + q*s*dq*normCDF(d1)
+	} else {
+		g.Delta = -dq * normCDF(-d1)
+		g.Theta = decay + p.Rate*p.Strike*dr*normCDF(-d2) - q*s*dq*normCDF(-d1)
+	}
+	return g, nil
+}
+
+// ImpliedVolatility - Волатильность, при которой теоретическая цена опциона равна price. p.Volatility не
+// используется. Цена должна быть строго между внутренней стоимостью и верхней границей модели
+func ImpliedVolatility(p Params, price float64) (float64, error) {
+	if err := p.validate(false); err != nil {
+		return 0, err
+	}
+	s, q := p.spot()
+	fwd := s * math.Exp(-q*p.Expiry)
+	strike := p.Strike * math.Exp(-p.Rate*p.Expiry)
+	lower, upper := math.Max(fwd-strike, 0), fwd
+	if p.Direction == pb.OptionDirection_OPTION_DIRECTION_PUT {
+		lower, upper = math.Max(strike-fwd, 0), strike
+	}
+	if !(price > lower) || !(price < upper) {
+		return 0, fmt.Errorf("%w: price %v is outside (%v, %v)", ErrNoImpliedVolatility, price, lower, upper)
+	}
+
+	const (
+		tolerance = 1e-10
+		maxIter   = 100
+	)
+	// цена монотонно растет по волатильности, поэтому метод Ньютона страхуется делением отрезка пополам
+	lo, hi := 1e-9, 10.0
+	// начальное приближение Бреннера-Субраманьяма для опциона около денег
+	vol := math.Sqrt(2*math.Pi/p.Expiry) * price / fwd
+	if !(vol > lo && vol < hi) {
+		vol = 0.3
+	}
+	for i := 0; i < maxIter; i++ {
+		p.Volatility = vol
+		diff := p.price() - price
+		if math.Abs(diff) < tolerance {
+			return vol, nil
+		}
+		if diff > 0 {
+			hi = vol
+		} else {
+			lo = vol
+		}
+		d1, _ := p.d1d2()
+		vega := fwd * normPDF(d1) * math.Sqrt(p.Expiry)
+		next := vol - diff/vega
+		if vega < tolerance || !(next > lo && next < hi) {
+			next = (lo + hi) / 2
+		}
+		if math.Abs(next-vol) < tolerance {
+			return next, nil
+		}
+		vol = next
+	}
+	return 0, fmt.Errorf("%w: no convergence for price %v", ErrNoImpliedVolatility, price)
+}
@@ -0,0 +1,263 @@
+package options_test
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/russianinvestments/invest-api-go-sdk/options"
+	pb "github.com/russianinvestments/invest-api-go-sdk/proto"
+)
+
+const (
+	call = pb.OptionDirection_OPTION_DIRECTION_CALL
+	put  = pb.OptionDirection_OPTION_DIRECTION_PUT
+)
+
+func near(t *testing.T, what string, got, want, tol float64) {
+	t.Helper()
+	if math.Abs(got-want) > tol {
+		t.Errorf("%v = %.10f, want %.10f ± %v", what, got, want, tol)
+	}
+}
+
+// Примеры из Hull, Options, Futures, and Other Derivatives, цены пересчитаны по формулам с точностью до 1e-9
+func TestPrice(t *testing.T) {
+	tests := []struct {
+		name string
+		p    options.Params
+		want float64
+	}{
+		{
+			name: "black-scholes call",
+			p:    options.Params{Direction: call, Underlying: 42, Strike: 40, Expiry: 0.5, Rate: 0.1, Volatility: 0.2},
+			want: 4.759422393,
+		},
+		{
+			name: "black-scholes put",
+			p:    options.Params{Direction: put, Underlying: 42, Strike: 40, Expiry: 0.5, Rate: 0.1, Volatility: 0.2},
+			want: 0.808599373,
+		},
+		{
+			name: "index call with dividend",
+			p: options.Params{Direction: call, Underlying: 930, Strike: 900, Expiry: 2.0 / 12, Rate: 0.08,
+				Dividend: 0.03, Volatility: 0.2},
+			want: 51.832956796,
+		},
+		{
+			name: "index put with dividend",
+			p: options.Params{Direction: put, Underlying: 930, Strike: 900, Expiry: 2.0 / 12, Rate: 0.08,
+				Dividend: 0.03, Volatility: 0.2},
+			want: 14.550996774,
+		},
+		{
+			name: "black-76 put",
+			p: options.Params{Model: options.ModelBlack76, Direction: put, Underlying: 20, Strike: 20,
+				Expiry: 4.0 / 12, Rate: 0.09, Volatility: 0.25},
+			want: 1.116641457,
+		},
+		{
+			// дивидендная доходность в модели Блэка-76 не используется
+			name: "black-76 ignores dividend",
+			p: options.Params{Model: options.ModelBlack76, Direction: put, Underlying: 20, Strike: 20,
+				Expiry: 4.0 / 12, Rate: 0.09, Dividend: 0.5, Volatility: 0.25},
+			want: 1.116641457,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := options.Price(tc.p)
+			if err != nil {
+				t.Fatal(err)
+			}
+			near(t, "price", got, tc.want, 1e-8)
+		})
+	}
+}
+
+func TestPutCallParity(t *testing.T) {
+	for _, model := range []options.Model{options.ModelBlackScholes, options.ModelBlack76} {
+		p := options.Params{Model: model, Underlying: 105, Strike: 100, Expiry: 0.75, Rate: 0.16, Dividend: 0.02, Volatility: 0.35}
+		p.Direction = call
+		c, err := options.Price(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		p.Direction = put
+		pp, err := options.Price(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		q := p.Dividend
+		if model == options.ModelBlack76 {
+			q = p.Rate
+		}
+		want := p.Underlying*math.Exp(-q*p.Expiry) - p.Strike*math.Exp(-p.Rate*p.Expiry)
+		near(t, "call - put", c-pp, want, 1e-9)
+	}
+}
+
+func TestComputeGreeks(t *testing.T) {
+	// Hull, пример для колла без дивидендов: delta 0.522, gamma 0.066, vega 12.1, theta -4.31 в год
+	p := options.Params{Direction: call, Underlying: 49, Strike: 50, Expiry: 20.0 / 52, Rate: 0.05, Volatility: 0.2}
+	g, err := options.ComputeGreeks(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	near(t, "delta", g.Delta, 0.5216046611, 1e-9)
+	near(t, "gamma", g.Gamma, 0.0655440393, 1e-9)
+	near(t, "vega", g.Vega, 12.1054798826, 1e-8)
+	near(t, "theta", g.Theta, -4.3053298229, 1e-8)
+}
+
+// TestGreeksFiniteDifference - греки совпадают с конечными разностями цены для обеих моделей и направлений
+func TestGreeksFiniteDifference(t *testing.T) {
+	price := func(p options.Params) float64 {
+		v, err := options.Price(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+	for _, model := range []options.Model{options.ModelBlackScholes, options.ModelBlack76} {
+		for _, dir := range []pb.OptionDirection{call, put} {
+			p := options.Params{Model: model, Direction: dir, Underlying: 95, Strike: 100, Expiry: 0.4, Rate: 0.12,
+				Dividend: 0.04, Volatility: 0.3}
+			g, err := options.ComputeGreeks(p)
+			if err != nil {
+				t.Fatal(err)
+			}
+			const h = 1e-3
+			up, down := p, p
+			up.Underlying += h
+			down.Underlying -= h
+			near(t, "delta", g.Delta, (price(up)-price(down))/(2*h), 1e-6)
+			near(t, "gamma", g.Gamma, (price(up)-2*price(p)+price(down))/(h*h), 1e-4)
+
+			up, down = p, p
+			up.Volatility += h
+			down.Volatility -= h
+			near(t, "vega", g.Vega, (price(up)-price(down))/(2*h), 1e-5)
+
+			// тета - изменение цены с течением времени, то есть при уменьшении срока
+			up, down = p, p
+			up.Expiry -= h
+			down.Expiry += h
+			near(t, "theta", g.Theta, (price(up)-price(down))/(2*h), 1e-5)
+		}
+	}
+}
+
+func TestImpliedVolatility(t *testing.T) {
+	for _, model := range []options.Model{options.ModelBlackScholes, options.ModelBlack76} {
+		for _, dir := range []pb.OptionDirection{call, put} {
+			for _, strike := range []float64{60, 95, 100, 110, 160} {
+				for _, vol := range []float64{0.05, 0.2, 0.6, 2} {
+					p := options.Params{Model: model, Direction: dir, Underlying: 100, Strike: strike, Expiry: 0.5,
+						Rate: 0.1, Dividend: 0.02, Volatility: vol}
+					price, err := options.Price(p)
+					if err != nil {
+						t.Fatal(err)
+					}
+					// временная стоимость - превышение цены над ценой при почти нулевой волатильности. Если она
+					// неотличима от нуля в float64, волатильность по цене восстановить нельзя
+					intrinsic := p
+					intrinsic.Volatility = 1e-6
+					bound, err := options.Price(intrinsic)
+					if err != nil {
+						t.Fatal(err)
+					}
+					timeValue := price - bound
+					got, err := options.ImpliedVolatility(p, price)
+					if errors.Is(err, options.ErrNoImpliedVolatility) && timeValue < 1e-9 {
+						continue
+					}
+					if err != nil {
+						t.Fatalf("model %v, %v, strike %v, vol %v: %v", model, dir, strike, vol, err)
+					}
+					// точность волатильности ограничена вегой: цена восстанавливается до 1e-9
+					p.Volatility = got
+					repriced, err := options.Price(p)
+					if err != nil {
+						t.Fatal(err)
+					}
+					near(t, "repriced", repriced, price, 1e-9)
+					if timeValue > 1e-3 {
+						near(t, "volatility", got, vol, 1e-6)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestImpliedVolatilityBounds(t *testing.T) {
+	p := options.Params{Direction: call, Underlying: 42, Strike: 40, Expiry: 0.5, Rate: 0.1}
+	// границы колла: дисконтированная внутренняя стоимость и цена базового актива
+	lower := 42 - 40*math.Exp(-0.1*0.5)
+	for _, price := range []float64{0, lower - 0.01, lower, 42, 50, math.NaN()} {
+		if _, err := options.ImpliedVolatility(p, price); !errors.Is(err, options.ErrNoImpliedVolatility) {
+			t.Errorf("price %v: err = %v, want ErrNoImpliedVolatility", price, err)
+		}
+	}
+	// границы пута: 0 для пута вне денег и дисконтированный страйк
+	p.Direction = put
+	for _, price := range []float64{0, 40 * math.Exp(-0.1*0.5), -1} {
+		if _, err := options.ImpliedVolatility(p, price); !errors.Is(err, options.ErrNoImpliedVolatility) {
+			t.Errorf("put price %v: err = %v, want ErrNoImpliedVolatility", price, err)
+		}
+	}
+	// цена чуть выше нижней границы дает волатильность около нуля, чуть ниже верхней - очень большую
+	p.Direction = call
+	low, err := options.ImpliedVolatility(p, lower+1e-6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if low > 0.05 {
+		t.Errorf("volatility near lower bound = %v", low)
+	}
+	high, err := options.ImpliedVolatility(p, 41.9)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if high < 2 {
+		t.Errorf("volatility near upper bound = %v", high)
+	}
+}
+
+func TestInvalidParams(t *testing.T) {
+	valid := options.Params{Direction: call, Underlying: 42, Strike: 40, Expiry: 0.5, Rate: 0.1, Volatility: 0.2}
+	tests := map[string]func(p *options.Params){
+		"direction":  func(p *options.Params) { p.Direction = pb.OptionDirection_OPTION_DIRECTION_UNSPECIFIED },
+		"underlying": func(p *options.Params) { p.Underlying = 0 },
+		"strike":     func(p *options.Params) { p.Strike = -40 },
+		"expiry":     func(p *options.Params) { p.Expiry = 0 },
+		"nan":        func(p *options.Params) { p.Underlying = math.NaN() },
+	}
+	for name, modify := range tests {
+		p := valid
+		modify(&p)
+		if _, err := options.Price(p); !errors.Is(err, options.ErrInvalidParams) {
+			t.Errorf("%v: Price err = %v", name, err)
+		}
+		if _, err := options.ComputeGreeks(p); !errors.Is(err, options.ErrInvalidParams) {
+			t.Errorf("%v: ComputeGreeks err = %v", name, err)
+		}
+		if _, err := options.ImpliedVolatility(p, 4); !errors.Is(err, options.ErrInvalidParams) {
+			t.Errorf("%v: ImpliedVolatility err = %v", name, err)
+		}
+	}
+
+	// волатильность нужна для цены и греков, но не для подразумеваемой волатильности
+	p := valid
+	p.Volatility = 0
+	if _, err := options.Price(p); !errors.Is(err, options.ErrInvalidParams) {
+		t.Errorf("zero volatility: Price err = %v", err)
+	}
+	if _, err := options.ComputeGreeks(p); !errors.Is(err, options.ErrInvalidParams) {
+		t.Errorf("zero volatility: ComputeGreeks err = %v", err)
+	}
+	if _, err := options.ImpliedVolatility(p, 4.759422393); err != nil {
+		t.Errorf("ImpliedVolatility without volatility: %v", err)
+	}
+}
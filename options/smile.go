@@ -0,0 +1,100 @@
+package options
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/russianinvestments/invest-api-go-sdk/investgo"
+	pb "github.com/russianinvestments/invest-api-go-sdk/proto"
+)
+
+// SmilePoint - подразумеваемая волатильность на одном страйке
+type SmilePoint struct {
+	Strike float64
+	// Moneyness - ln(страйк / форвардная цена базового актива)
+	Moneyness float64
+	// Direction - опцион, по цене которого рассчитана волатильность
+	Direction  pb.OptionDirection
+	Price      float64
+	Volatility float64
+}
+
+// Smile - улыбка волатильности одной экспирации, точки по возрастанию страйка
+type Smile struct {
+	Expiration time.Time
+	Points     []SmilePoint
+}
+
+// BuildSmile - Улыбка волатильности экспирации exp по ценам последних сделок. На каждом страйке используется опцион
+// вне денег (пут ниже форвардной цены, колл выше), а если по нему нет цены - второй опцион страйка. Страйки без цен
+// и цены, для которых нет подразумеваемой волатильности, пропускаются
+func BuildSmile(exp *investgo.OptionExpiration, model Model, underlying, rate float64, now time.Time) *Smile {
+	smile := &Smile{Expiration: exp.Date}
+	for _, strike := range exp.Strikes {
+		first, second := strike.Call, strike.Put
+		k := strike.Strike.ToFloat()
+		p := Params{Model: model, Underlying: underlying, Strike: k, Expiry: YearsTo(exp.Date, now), Rate: rate}
+		s, q := p.spot()
+		fwd := s * math.Exp((rate-q)*p.Expiry)
+		if k < fwd {
+			first, second = second, first
+		}
+		for _, quote := range []*investgo.OptionQuote{first, second} {
+			if quote == nil || quote.LastPrice == nil {
+				continue
+			}
+			p.Direction = quote.GetDirection()
+			price := quote.LastPrice.ToFloat()
+			vol, err := ImpliedVolatility(p, price)
+			if err != nil {
+				continue
+			}
+			smile.Points = append(smile.Points, SmilePoint{
+				Strike:     k,
+				Moneyness:  math.Log(k / fwd),
+				Direction:  p.Direction,
+				Price:      price,
+				Volatility: vol,
+			})
+			break
+		}
+	}
+	sort.Slice(smile.Points, func(i, j int) bool {
+		return smile.Points[i].Strike < smile.Points[j].Strike
+	})
+	return smile
+}
+
+// ChainSmiles - Улыбки всех экспираций цепочки, см. BuildSmile. Экспирации, которые уже прошли, пропускаются
+func ChainSmiles(chain *investgo.OptionChain, model Model, underlying, rate float64, now time.Time) []*Smile {
+	smiles := make([]*Smile, 0, len(chain.Expirations))
+	for _, exp := range chain.Expirations {
+		if !exp.Date.After(now) {
+			continue
+		}
+		smiles = append(smiles, BuildSmile(exp, model, underlying, rate, now))
+	}
+	return smiles
+}
+
+// Volatility - Волатильность на страйке strike: линейная интерполяция между соседними точками улыбки, за краями
+// улыбки - волатильность крайней точки. Для улыбки без точек - 0
+func (s *Smile) Volatility(strike float64) float64 {
+	points := s.Points
+	if len(points) == 0 {
+		return 0
+	}
+	i := sort.Search(len(points), func(i int) bool {
+		return points[i].Strike >= strike
+	})
+	switch {
+	case i == 0:
+		return points[0].Volatility
+	case i == len(points):
+		return points[len(points)-1].Volatility
+	}
+	left, right := points[i-1], points[i]
+	w := (strike - left.Strike) / (right.Strike - left.Strike)
+	return left.Volatility + w*(right.Volatility-left.Volatility)
+}
@@ -0,0 +1,204 @@
+// Package orderbook - локальные стаканы инструментов, которые поддерживаются по снимкам из стрима биржевой информации.
+// Цены в стаканах переводятся из Quotation с округлением до шага цены инструмента, для стакана доступны лучшие цены,
+// спред, средняя цена, микроцена, дисбаланс и объем на нескольких уровнях.
+package orderbook
+
+import (
+	"sort"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	pb "github.com/russianinvestments/invest-api-go-sdk/proto"
+)
+
+// Level - уровень стакана
+type Level struct {
+	// Price - цена за 1 инструмент, округленная до шага цены
+	Price float64
+	// Ticks - цена в шагах цены инструмента, 0 если шаг цены неизвестен
+	Ticks int64
+	// Quantity - количество в лотах
+	Quantity int64
+}
+
+// Book - снимок стакана инструмента. Снимок не изменяется после создания, поэтому его можно читать из разных горутин
+type Book struct {
+	Figi          string
+	InstrumentUid string
+	Depth         int32
+	// Bids - заявки на покупку по убыванию цены, Asks - заявки на продажу по возрастанию цены
+	Bids []Level
+	Asks []Level
+	// LimitUp, LimitDown - верхний и нижний лимит цены
+	LimitUp   float64
+	LimitDown float64
+	// MinPriceIncrement - шаг цены инструмента, 0 если неизвестен
+	MinPriceIncrement float64
+	// Time - время формирования стакана на бирже
+	Time time.Time
+	// Consistent - флаг консистентности стакана, false - не все заявки попали в стакан
+	Consistent bool
+	// InconsistentInRow - количество неконсистентных снимков подряд, включая этот
+	InconsistentInRow int
+	// Resynced - стакан получен методом GetOrderBook, а не из стрима
+	Resynced bool
+}
+
+// BestBid - лучшая цена покупки, ok = false, если заявок на покупку нет
+func (b *Book) BestBid() (Level, bool) {
+	if len(b.Bids) == 0 {
+		return Level{}, false
+	}
+	return b.Bids[0], true
+}
+
+// BestAsk - лучшая цена продажи, ok = false, если заявок на продажу нет
+func (b *Book) BestAsk() (Level, bool) {
+	if len(b.Asks) == 0 {
+		return Level{}, false
+	}
+	return b.Asks[0], true
+}
+
+// Spread - разница между лучшими ценами продажи и покупки, 0 если одной из сторон нет
+func (b *Book) Spread() float64 {
+	bid, okBid := b.BestBid()
+	ask, okAsk := b.BestAsk()
+	if !okBid || !okAsk {
+		return 0
+	}
+	return decimal.NewFromFloat(ask.Price).Sub(decimal.NewFromFloat(bid.Price)).InexactFloat64()
+}
+
+// Mid - средняя цена между лучшими ценами покупки и продажи, 0 если одной из сторон нет
+func (b *Book) Mid() float64 {
+	bid, okBid := b.BestBid()
+	ask, okAsk := b.BestAsk()
+	if !okBid || !okAsk {
+		return 0
+	}
+	return decimal.NewFromFloat(bid.Price).Add(decimal.NewFromFloat(ask.Price)).Div(decimal.NewFromInt(2)).InexactFloat64()
+}
+
+// Microprice - средняя цена, взвешенная по объемам на лучших уровнях: чем больше объем на покупку,
+// тем ближе микроцена к цене продажи. 0 если одной из сторон нет
+func (b *Book) Microprice() float64 {
+	bid, okBid := b.BestBid()
+	ask, okAsk := b.BestAsk()
+	if !okBid || !okAsk {
+		return 0
+	}
+	total := bid.Quantity + ask.Quantity
+	if total == 0 {
+		return b.Mid()
+	}
+	return (bid.Price*float64(ask.Quantity) + ask.Price*float64(bid.Quantity)) / float64(total)
+}
+
+// Imbalance - дисбаланс объемов на первых levels уровнях от -1 (только продажи) до 1 (только покупки).
+// Объем уровня i (начиная с 0) учитывается с весом 1/(i+1), levels <= 0 - все уровни
+func (b *Book) Imbalance(levels int) float64 {
+	bids := weightedVolume(b.Bids, levels)
+	asks := weightedVolume(b.Asks, levels)
+	if bids+asks == 0 {
+		return 0
+	}
+	return (bids - asks) / (bids + asks)
+}
+
+// BidVolume - суммарный объем в лотах на первых levels уровнях покупки, levels <= 0 - все уровни
+func (b *Book) BidVolume(levels int) int64 {
+	return volume(b.Bids, levels)
+}
+
+// AskVolume - суммарный объем в лотах на первых levels уровнях продажи, levels <= 0 - все уровни
+func (b *Book) AskVolume(levels int) int64 {
+	return volume(b.Asks, levels)
+}
+
+func volume(side []Level, levels int) int64 {
+	var res int64
+	for i, l := range side {
+		if levels > 0 && i >= levels {
+			break
+		}
+		res += l.Quantity
+	}
+	return res
+}
+
+func weightedVolume(side []Level, levels int) float64 {
+	var res float64
+	for i, l := range side {
+		if levels > 0 && i >= levels {
+			break
+		}
+		res += float64(l.Quantity) / float64(i+1)
+	}
+	return res
+}
+
+// price - цена с округлением до шага цены inc, если шаг цены известен
+func price(q *pb.Quotation, inc decimal.Decimal) (float64, int64) {
+	d := q.ToDecimal()
+	if inc.IsZero() {
+		return d.InexactFloat64(), 0
+	}
+	ticks := d.Div(inc).Round(0)
+	return ticks.Mul(inc).InexactFloat64(), ticks.IntPart()
+}
+
+func levels(orders []*pb.Order, inc decimal.Decimal, desc bool) []Level {
+	res := make([]Level, 0, len(orders))
+	for _, o := range orders {
+		p, ticks := price(o.GetPrice(), inc)
+		res = append(res, Level{Price: p, Ticks: ticks, Quantity: o.GetQuantity()})
+	}
+	sort.SliceStable(res, func(i, j int) bool {
+		if desc {
+			return res[i].Price > res[j].Price
+		}
+		return res[i].Price < res[j].Price
+	})
+	return res
+}
+
+// FromOrderBook - стакан из снимка стрима биржевой информации, inc - шаг цены инструмента, nil если неизвестен
+func FromOrderBook(ob *pb.OrderBook, inc *pb.Quotation) *Book {
+	d := inc.ToDecimal()
+	limitUp, _ := price(ob.GetLimitUp(), d)
+	limitDown, _ := price(ob.GetLimitDown(), d)
+	return &Book{
+		Figi:              ob.GetFigi(),
+		InstrumentUid:     ob.GetInstrumentUid(),
+		Depth:             ob.GetDepth(),
+		Bids:              levels(ob.GetBids(), d, true),
+		Asks:              levels(ob.GetAsks(), d, false),
+		LimitUp:           limitUp,
+		LimitDown:         limitDown,
+		MinPriceIncrement: d.InexactFloat64(),
+		Time:              ob.GetTime().AsTime(),
+		Consistent:        ob.GetIsConsistent(),
+	}
+}
+
+// FromGetOrderBook - стакан из ответа метода GetOrderBook, inc - шаг цены инструмента, nil если неизвестен
+func FromGetOrderBook(ob *pb.GetOrderBookResponse, inc *pb.Quotation) *Book {
+	d := inc.ToDecimal()
+	limitUp, _ := price(ob.GetLimitUp(), d)
+	limitDown, _ := price(ob.GetLimitDown(), d)
+	return &Book{
+		Figi:              ob.GetFigi(),
+		InstrumentUid:     ob.GetInstrumentUid(),
+		Depth:             ob.GetDepth(),
+		Bids:              levels(ob.GetBids(), d, true),
+		Asks:              levels(ob.GetAsks(), d, false),
+		LimitUp:           limitUp,
+		LimitDown:         limitDown,
+		MinPriceIncrement: d.InexactFloat64(),
+		Time:              ob.GetOrderbookTs().AsTime(),
+		Consistent:        true,
+		Resynced:          true,
+	}
+}
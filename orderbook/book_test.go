@@ -0,0 +1,113 @@
+package orderbook_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/russianinvestments/invest-api-go-sdk/orderbook"
+	pb "github.com/russianinvestments/invest-api-go-sdk/proto"
+)
+
+func q(v string) *pb.Quotation {
+	return pb.QuotationFromDecimal(decimal.RequireFromString(v))
+}
+
+func order(price string, quantity int64) *pb.Order {
+	return &pb.Order{Price: q(price), Quantity: quantity}
+}
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestBookPrices(t *testing.T) {
+	b := orderbook.FromOrderBook(&pb.OrderBook{
+		Depth: 10,
+		Bids:  []*pb.Order{order("99.5", 10), order("100", 30)},
+		Asks:  []*pb.Order{order("101", 10), order("100.5", 10)},
+	}, nil)
+	// уровни сортируются от лучшей цены
+	if bid, _ := b.BestBid(); bid.Price != 100 || bid.Quantity != 30 {
+		t.Fatalf("best bid = %+v", bid)
+	}
+	if ask, _ := b.BestAsk(); ask.Price != 100.5 || ask.Quantity != 10 {
+		t.Fatalf("best ask = %+v", ask)
+	}
+	if b.Spread() != 0.5 || b.Mid() != 100.25 {
+		t.Fatalf("spread, mid = %v, %v", b.Spread(), b.Mid())
+	}
+	// объем на покупку в 3 раза больше, микроцена ближе к цене продажи: (100 * 10 + 100.5 * 30) / 40
+	if got := b.Microprice(); !near(got, 100.375) {
+		t.Fatalf("microprice = %v", got)
+	}
+	if b.BidVolume(1) != 30 || b.BidVolume(0) != 40 || b.AskVolume(5) != 20 {
+		t.Fatalf("volumes = %v %v %v", b.BidVolume(1), b.BidVolume(0), b.AskVolume(5))
+	}
+
+	empty := orderbook.FromOrderBook(&pb.OrderBook{Bids: []*pb.Order{order("100", 1)}}, nil)
+	if empty.Spread() != 0 || empty.Mid() != 0 || empty.Microprice() != 0 {
+		t.Fatalf("one-sided book spread, mid, microprice = %v, %v, %v", empty.Spread(), empty.Mid(), empty.Microprice())
+	}
+	if _, ok := empty.BestAsk(); ok {
+		t.Fatal("best ask in one-sided book")
+	}
+}
+
+func TestBookImbalance(t *testing.T) {
+	b := orderbook.FromOrderBook(&pb.OrderBook{
+		Bids: []*pb.Order{order("100", 10), order("99", 20)},
+		Asks: []*pb.Order{order("101", 10), order("102", 60)},
+	}, nil)
+	tests := []struct {
+		levels int
+		want   float64
+	}{
+		// первые уровни равны
+		{levels: 1, want: 0},
+		// покупки 10 + 20/2 = 20, продажи 10 + 60/2 = 40
+		{levels: 2, want: -1.0 / 3},
+		{levels: 0, want: -1.0 / 3},
+	}
+	for _, tc := range tests {
+		if got := b.Imbalance(tc.levels); !near(got, tc.want) {
+			t.Fatalf("imbalance(%v) = %v, want %v", tc.levels, got, tc.want)
+		}
+	}
+	bids := orderbook.FromOrderBook(&pb.OrderBook{Bids: []*pb.Order{order("100", 5)}}, nil)
+	if got := bids.Imbalance(0); got != 1 {
+		t.Fatalf("bids only imbalance = %v", got)
+	}
+	if got := orderbook.FromOrderBook(&pb.OrderBook{}, nil).Imbalance(0); got != 0 {
+		t.Fatalf("empty imbalance = %v", got)
+	}
+}
+
+func TestBookTicks(t *testing.T) {
+	b := orderbook.FromOrderBook(&pb.OrderBook{
+		// цены не кратны шагу 0.05 и округляются до ближайшего шага
+		Bids:      []*pb.Order{order("100.02", 1), order("99.93", 1)},
+		Asks:      []*pb.Order{order("100.08", 1)},
+		LimitUp:   q("110.01"),
+		LimitDown: q("89.99"),
+	}, q("0.05"))
+	want := []orderbook.Level{{Price: 100, Ticks: 2000, Quantity: 1}, {Price: 99.95, Ticks: 1999, Quantity: 1}}
+	for i, l := range b.Bids {
+		if !near(l.Price, want[i].Price) || l.Ticks != want[i].Ticks {
+			t.Fatalf("bid %v = %+v, want %+v", i, l, want[i])
+		}
+	}
+	if ask, _ := b.BestAsk(); !near(ask.Price, 100.1) || ask.Ticks != 2002 {
+		t.Fatalf("best ask = %+v", ask)
+	}
+	if b.LimitUp != 110 || b.LimitDown != 90 || b.MinPriceIncrement != 0.05 {
+		t.Fatalf("limits, increment = %v %v %v", b.LimitUp, b.LimitDown, b.MinPriceIncrement)
+	}
+
+	// без шага цены цены не округляются, а Ticks = 0
+	b = orderbook.FromOrderBook(&pb.OrderBook{Bids: []*pb.Order{order("100.02", 1)}}, nil)
+	if bid, _ := b.BestBid(); bid.Price != 100.02 || bid.Ticks != 0 {
+		t.Fatalf("best bid = %+v", bid)
+	}
+}
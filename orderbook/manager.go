@@ -0,0 +1,259 @@
+package orderbook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/russianinvestments/invest-api-go-sdk/investgo"
+	pb "github.com/russianinvestments/invest-api-go-sdk/proto"
+)
+
+// ErrNoMarketDataClient - ресинхронизация невозможна, так как в менеджер не передан клиент сервиса котировок
+var ErrNoMarketDataClient = errors.New("orderbook: market data client is not set")
+
+// Option - настройка менеджера стаканов
+type Option func(m *Manager)
+
+// WithAutoResync - Если очередной снимок из стрима неконсистентный, менеджер запрашивает стакан методом GetOrderBook
+// с глубиной depth (0 - глубина снимка), но не чаще, чем раз в minInterval по каждому инструменту
+func WithAutoResync(minInterval time.Duration, depth int32) Option {
+	return func(m *Manager) {
+		m.autoResync = true
+		m.resyncInterval = minInterval
+		m.resyncDepth = depth
+	}
+}
+
+// WithErrorHandler - Обработчик ошибок получения шага цены и ресинхронизации в Run. Такие ошибки не завершают Run,
+// стакан из снимка все равно сохраняется и передается в handler
+func WithErrorHandler(h func(err error)) Option {
+	return func(m *Manager) {
+		m.onError = h
+	}
+}
+
+// Manager - менеджер локальных стаканов, хранит последний стакан по каждому инструменту.
+// Методы менеджера можно вызывать из разных горутин
+type Manager struct {
+	md          *investgo.MarketDataServiceClient
+	instruments *investgo.InstrumentsServiceClient
+
+	autoResync     bool
+	resyncInterval time.Duration
+	resyncDepth    int32
+	onError        func(err error)
+
+	mu sync.RWMutex
+	// books - стаканы по instrument_uid (или figi, если uid в снимке нет), keys - ключ стакана по uid и figi
+	books      map[string]*Book
+	keys       map[string]string
+	increments map[string]*pb.Quotation
+	lastResync map[string]time.Time
+}
+
+// NewManager - Создание менеджера стаканов. md нужен для ресинхронизации стаканов, instruments - для получения шага цены
+// инструментов, любой из клиентов может быть nil. Без instruments шаг цены можно задать методом SetMinPriceIncrement,
+// иначе цены не округляются
+func NewManager(md *investgo.MarketDataServiceClient, instruments *investgo.InstrumentsServiceClient, opts ...Option) *Manager {
+	m := &Manager{
+		md:          md,
+		instruments: instruments,
+		books:       make(map[string]*Book),
+		keys:        make(map[string]string),
+		increments:  make(map[string]*pb.Quotation),
+		lastResync:  make(map[string]time.Time),
+	}
+	for _, o := range opts {
+		o(m)
+	}
+	return m
+}
+
+// SetMinPriceIncrement - Задать шаг цены инструмента, id - figi или instrument_uid
+func (m *Manager) SetMinPriceIncrement(id string, inc *pb.Quotation) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.increments[id] = inc
+}
+
+// Update - Обновление стакана снимком из стрима. Возвращает новый стакан инструмента, если снимок неконсистентный
+// и включена автоматическая ресинхронизация, то стакан, полученный методом GetOrderBook. Если не удалось получить
+// шаг цены или ресинхронизировать стакан, то возвращается стакан из снимка вместе с ошибкой, без шага цены его
+// цены не округляются
+func (m *Manager) Update(ob *pb.OrderBook) (*Book, error) {
+	return m.UpdateCtx(context.Background(), ob)
+}
+
+// UpdateCtx - то же, что и Update, но с контекстом запросов шага цены и ресинхронизации ctx
+func (m *Manager) UpdateCtx(ctx context.Context, ob *pb.OrderBook) (*Book, error) {
+	if ob == nil {
+		return nil, errors.New("orderbook: nil order book")
+	}
+	inc, incErr := m.increment(ctx, ob.GetInstrumentUid(), ob.GetFigi())
+	book := FromOrderBook(ob, inc)
+	key := m.store(book)
+	if incErr != nil {
+		return book, incErr
+	}
+
+	if book.Consistent || !m.autoResync || m.md == nil || !m.resyncDue(key) {
+		return book, nil
+	}
+	depth := m.resyncDepth
+	if depth == 0 {
+		depth = book.Depth
+	}
+	resynced, err := m.ResyncCtx(ctx, key, depth)
+	if err != nil {
+		return book, err
+	}
+	return resynced, nil
+}
+
+// Resync - Запрос стакана методом GetOrderBook и замена им локального стакана, id - figi или instrument_uid
+func (m *Manager) Resync(id string, depth int32) (*Book, error) {
+	return m.ResyncCtx(context.Background(), id, depth)
+}
+
+// ResyncCtx - Запрос стакана методом GetOrderBook и замена им локального стакана, id - figi или instrument_uid
+func (m *Manager) ResyncCtx(ctx context.Context, id string, depth int32) (*Book, error) {
+	if m.md == nil {
+		return nil, ErrNoMarketDataClient
+	}
+	m.mu.Lock()
+	m.lastResync[m.keyLocked(id)] = time.Now()
+	m.mu.Unlock()
+
+	resp, err := m.md.GetOrderBookCtx(ctx, id, depth)
+	if err != nil {
+		return nil, fmt.Errorf("orderbook: resync %v: %w", id, err)
+	}
+	inc, err := m.increment(ctx, resp.GetInstrumentUid(), resp.GetFigi())
+	if err != nil {
+		return nil, err
+	}
+	book := FromGetOrderBook(resp.GetOrderBookResponse, inc)
+	m.store(book)
+	return book, nil
+}
+
+// Book - Последний стакан инструмента, id - figi или instrument_uid
+func (m *Manager) Book(id string) (*Book, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	b, ok := m.books[m.keyLocked(id)]
+	return b, ok
+}
+
+// Books - Последние стаканы всех инструментов
+func (m *Manager) Books() []*Book {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	books := make([]*Book, 0, len(m.books))
+	for _, b := range m.books {
+		books = append(books, b)
+	}
+	return books
+}
+
+// Run - Обновление стаканов из канала стрима, например из MarketDataStream.SubscribeOrderBook, до закрытия канала
+// или отмены контекста. handler, если не nil, вызывается с каждым новым стаканом. Ошибки получения шага цены и
+// ресинхронизации передаются в обработчик из WithErrorHandler, а Run продолжает работу со стаканом из снимка
+func (m *Manager) Run(ctx context.Context, orderBooks <-chan *pb.OrderBook, handler func(b *Book)) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case ob, ok := <-orderBooks:
+			if !ok {
+				return nil
+			}
+			book, err := m.UpdateCtx(ctx, ob)
+			if err != nil && m.onError != nil {
+				m.onError(err)
+			}
+			if book == nil {
+				continue
+			}
+			if handler != nil {
+				handler(book)
+			}
+		}
+	}
+}
+
+// store - сохранение стакана, возвращает ключ инструмента
+func (m *Manager) store(b *Book) string {
+	key := b.InstrumentUid
+	if key == "" {
+		key = b.Figi
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if prev, ok := m.books[key]; ok && !b.Consistent && !b.Resynced {
+		b.InconsistentInRow = prev.InconsistentInRow + 1
+	} else if !b.Consistent {
+		b.InconsistentInRow = 1
+	}
+	m.books[key] = b
+	m.keys[key] = key
+	if b.Figi != "" {
+		m.keys[b.Figi] = key
+	}
+	return key
+}
+
+func (m *Manager) keyLocked(id string) string {
+	if key, ok := m.keys[id]; ok {
+		return key
+	}
+	return id
+}
+
+// resyncDue - прошло ли minInterval с последней ресинхронизации инструмента
+func (m *Manager) resyncDue(key string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	last, ok := m.lastResync[key]
+	return !ok || time.Since(last) >= m.resyncInterval
+}
+
+// increment - шаг цены инструмента из кэша или из InstrumentsService, nil если шаг цены получить негде
+func (m *Manager) increment(ctx context.Context, uid, figi string) (*pb.Quotation, error) {
+	m.mu.RLock()
+	inc, ok := m.increments[uid]
+	if !ok {
+		inc, ok = m.increments[figi]
+	}
+	m.mu.RUnlock()
+	if ok || m.instruments == nil {
+		return inc, nil
+	}
+
+	var resp *investgo.InstrumentResponse
+	var err error
+	id := uid
+	if uid != "" {
+		resp, err = m.instruments.InstrumentByUidCtx(ctx, uid)
+	} else {
+		id = figi
+		resp, err = m.instruments.InstrumentByFigiCtx(ctx, figi)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("orderbook: min price increment %v: %w", id, err)
+	}
+	inc = resp.GetInstrument().GetMinPriceIncrement()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if uid != "" {
+		m.increments[uid] = inc
+	}
+	if figi != "" {
+		m.increments[figi] = inc
+	}
+	return inc, nil
+}
@@ -0,0 +1,154 @@
+package orderbook_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/russianinvestments/invest-api-go-sdk/investgotest"
+	"github.com/russianinvestments/invest-api-go-sdk/orderbook"
+	pb "github.com/russianinvestments/invest-api-go-sdk/proto"
+)
+
+type nopLogger struct{}
+
+func (nopLogger) Infof(string, ...any)  {}
+func (nopLogger) Errorf(string, ...any) {}
+func (nopLogger) Fatalf(string, ...any) {}
+
+// newManager - тестовый сервер с акцией с шагом цены 0.05 и менеджер стаканов с клиентами к нему
+func newManager(t *testing.T, opts ...orderbook.Option) (*investgotest.Server, *orderbook.Manager, string) {
+	t.Helper()
+	srv, err := investgotest.NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(srv.Stop)
+	uid := srv.AddShare(&pb.Share{Figi: "BBG004730N88", Ticker: "SBER", ClassCode: "TQBR", MinPriceIncrement: q("0.05")})
+	client, err := srv.NewClient(context.Background(), nopLogger{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = client.Stop()
+	})
+	m := orderbook.NewManager(client.NewMarketDataServiceClient(), client.NewInstrumentsServiceClient(), opts...)
+	return srv, m, uid
+}
+
+func snapshot(uid string, consistent bool) *pb.OrderBook {
+	return &pb.OrderBook{
+		InstrumentUid: uid,
+		Depth:         10,
+		Bids:          []*pb.Order{order("100.02", 5)},
+		Asks:          []*pb.Order{order("100.13", 5)},
+		IsConsistent:  consistent,
+	}
+}
+
+func TestManagerInconsistentInRow(t *testing.T) {
+	_, m, uid := newManager(t)
+	for i, want := range []int{1, 2, 3} {
+		b, err := m.Update(snapshot(uid, false))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if b.InconsistentInRow != want {
+			t.Fatalf("update %v: inconsistent in row = %v, want %v", i, b.InconsistentInRow, want)
+		}
+	}
+	ob := snapshot(uid, true)
+	ob.Figi = "BBG004730N88"
+	b, err := m.Update(ob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.InconsistentInRow != 0 {
+		t.Fatalf("consistent: inconsistent in row = %v", b.InconsistentInRow)
+	}
+	// шаг цены получен из InstrumentsService
+	if bid, _ := b.BestBid(); bid.Price != 100 || bid.Ticks != 2000 {
+		t.Fatalf("best bid = %+v", bid)
+	}
+	if got, ok := m.Book("BBG004730N88"); !ok || got != b {
+		t.Fatalf("book by figi = %v, %v", got, ok)
+	}
+}
+
+func TestManagerAutoResync(t *testing.T) {
+	srv, m, uid := newManager(t, orderbook.WithAutoResync(time.Hour, 0))
+	if err := srv.PushOrderBook(&pb.OrderBook{
+		InstrumentUid: uid,
+		Depth:         10,
+		Bids:          []*pb.Order{order("99", 7)},
+		Asks:          []*pb.Order{order("101", 7)},
+		IsConsistent:  true,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	b, err := m.Update(snapshot(uid, false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !b.Resynced || b.InconsistentInRow != 0 {
+		t.Fatalf("resynced, inconsistent in row = %v, %v", b.Resynced, b.InconsistentInRow)
+	}
+	if bid, _ := b.BestBid(); bid.Price != 99 || bid.Quantity != 7 {
+		t.Fatalf("best bid = %+v", bid)
+	}
+	// повторная ресинхронизация не раньше minInterval, счетчик продолжается от стакана GetOrderBook
+	b, err = m.Update(snapshot(uid, false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.Resynced || b.InconsistentInRow != 1 {
+		t.Fatalf("resynced, inconsistent in row = %v, %v", b.Resynced, b.InconsistentInRow)
+	}
+}
+
+func TestManagerErrors(t *testing.T) {
+	_, m, _ := newManager(t, orderbook.WithAutoResync(0, 0))
+
+	// инструмента нет на сервере: шаг цены не получен, стакан из снимка сохраняется без округления
+	unknown := uuid.New().String()
+	b, err := m.Update(snapshot(unknown, true))
+	if err == nil || b == nil {
+		t.Fatalf("unknown instrument: book = %v, err = %v", b, err)
+	}
+	if bid, _ := b.BestBid(); bid.Price != 100.02 || bid.Ticks != 0 {
+		t.Fatalf("best bid = %+v", bid)
+	}
+	if _, ok := m.Book(unknown); !ok {
+		t.Fatal("book not stored")
+	}
+
+	// ресинхронизация не удалась: возвращается стакан из снимка
+	m.SetMinPriceIncrement(unknown, q("0.01"))
+	b, err = m.Update(snapshot(unknown, false))
+	if err == nil || b == nil || b.Resynced || b.InconsistentInRow != 1 {
+		t.Fatalf("failed resync: book = %+v, err = %v", b, err)
+	}
+}
+
+func TestManagerRunErrors(t *testing.T) {
+	var errs []error
+	_, m, uid := newManager(t, orderbook.WithErrorHandler(func(err error) {
+		errs = append(errs, err)
+	}))
+	// ошибка получения шага цены не завершает Run
+	ch := make(chan *pb.OrderBook, 2)
+	ch <- snapshot(uuid.New().String(), true)
+	ch <- snapshot(uid, true)
+	close(ch)
+	var books []*orderbook.Book
+	if err := m.Run(context.Background(), ch, func(b *orderbook.Book) {
+		books = append(books, b)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if len(books) != 2 || len(errs) != 1 {
+		t.Fatalf("books = %v, errors = %v", books, errs)
+	}
+}
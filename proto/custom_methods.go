@@ -1,8 +1,29 @@
 package investapi
 
 import (
+	"errors"
 	"fmt"
 	"math"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+var billion = decimal.NewFromInt(1_000_000_000)
+
+// ErrCurrencyMismatch - арифметика над суммами в разных валютах
+var ErrCurrencyMismatch = errors.New("currency mismatch")
+
+// RoundingMode - способ округления цены до шага цены инструмента
+type RoundingMode int
+
+const (
+	// RoundNearest - до ближайшего шага, половина шага округляется от нуля
+	RoundNearest RoundingMode = iota
+	// RoundFloor - до ближайшего шага вниз
+	RoundFloor
+	// RoundCeil - до ближайшего шага вверх
+	RoundCeil
 )
 
 // ToFloat - get value as float64 number
@@ -29,6 +50,131 @@ func (mv *MoneyValue) ToFloat() float64 {
 	return float64(0)
 }
 
+// ToDecimal - get value as exact decimal number
+func (q *Quotation) ToDecimal() decimal.Decimal {
+	if q == nil {
+		return decimal.Zero
+	}
+	return decimal.New(q.Units, 0).Add(decimal.New(int64(q.Nano), -9))
+}
+
+// ToDecimal - get value as exact decimal number
+func (mv *MoneyValue) ToDecimal() decimal.Decimal {
+	if mv == nil {
+		return decimal.Zero
+	}
+	return decimal.New(mv.Units, 0).Add(decimal.New(int64(mv.Nano), -9))
+}
+
+// QuotationFromDecimal - Перевод decimal в Quotation, знаки после девятого округляются до ближайшего
+func QuotationFromDecimal(d decimal.Decimal) *Quotation {
+	d = d.Round(9)
+	units := d.IntPart()
+	nano := d.Sub(decimal.New(units, 0)).Mul(billion).IntPart()
+	return &Quotation{Units: units, Nano: int32(nano)}
+}
+
+// MoneyFromDecimal - Перевод decimal в MoneyValue в валюте currency
+func MoneyFromDecimal(d decimal.Decimal, currency string) *MoneyValue {
+	q := QuotationFromDecimal(d)
+	return &MoneyValue{Currency: currency, Units: q.Units, Nano: q.Nano}
+}
+
+// Add - Сумма q + other, nil считается нулем
+func (q *Quotation) Add(other *Quotation) *Quotation {
+	return QuotationFromDecimal(q.ToDecimal().Add(other.ToDecimal()))
+}
+
+// Sub - Разность q - other, nil считается нулем
+func (q *Quotation) Sub(other *Quotation) *Quotation {
+	return QuotationFromDecimal(q.ToDecimal().Sub(other.ToDecimal()))
+}
+
+// Mul - Произведение q * n, например цена на количество лотов
+func (q *Quotation) Mul(n int64) *Quotation {
+	return QuotationFromDecimal(q.ToDecimal().Mul(decimal.NewFromInt(n)))
+}
+
+// Cmp - Сравнение q и other: -1, если q < other, 0, если равны, 1, если q > other
+func (q *Quotation) Cmp(other *Quotation) int {
+	return q.ToDecimal().Cmp(other.ToDecimal())
+}
+
+// IsZero - Проверка на ноль, nil считается нулем
+func (q *Quotation) IsZero() bool {
+	return q.ToDecimal().IsZero()
+}
+
+// RoundToIncrement - Округление до шага цены increment (min_price_increment инструмента) способом mode.
+// Нулевой шаг возвращает копию q
+func (q *Quotation) RoundToIncrement(increment *Quotation, mode RoundingMode) *Quotation {
+	d, inc := q.ToDecimal(), increment.ToDecimal()
+	if inc.IsZero() {
+		return QuotationFromDecimal(d)
+	}
+	steps := d.Div(inc)
+	switch mode {
+	case RoundFloor:
+		steps = steps.Floor()
+	case RoundCeil:
+		steps = steps.Ceil()
+	default:
+		steps = steps.Round(0)
+	}
+	return QuotationFromDecimal(steps.Mul(inc))
+}
+
+// sameCurrency - валюты в ответах API приходят в разном регистре
+func (mv *MoneyValue) sameCurrency(other *MoneyValue) error {
+	if mv == nil || other == nil || strings.EqualFold(mv.Currency, other.Currency) {
+		return nil
+	}
+	return fmt.Errorf("%w: %v and %v", ErrCurrencyMismatch, mv.Currency, other.Currency)
+}
+
+// currency - валюта результата операции над mv и other, nil считается нулем в валюте другого операнда
+func (mv *MoneyValue) currency(other *MoneyValue) string {
+	if mv == nil {
+		return other.GetCurrency()
+	}
+	return mv.Currency
+}
+
+// Add - Сумма mv + other. Для сумм в разных валютах возвращает ErrCurrencyMismatch
+func (mv *MoneyValue) Add(other *MoneyValue) (*MoneyValue, error) {
+	if err := mv.sameCurrency(other); err != nil {
+		return nil, err
+	}
+	return MoneyFromDecimal(mv.ToDecimal().Add(other.ToDecimal()), mv.currency(other)), nil
+}
+
+// Sub - Разность mv - other. Для сумм в разных валютах возвращает ErrCurrencyMismatch
+func (mv *MoneyValue) Sub(other *MoneyValue) (*MoneyValue, error) {
+	if err := mv.sameCurrency(other); err != nil {
+		return nil, err
+	}
+	return MoneyFromDecimal(mv.ToDecimal().Sub(other.ToDecimal()), mv.currency(other)), nil
+}
+
+// Mul - Произведение mv * n в той же валюте
+func (mv *MoneyValue) Mul(n int64) *MoneyValue {
+	return MoneyFromDecimal(mv.ToDecimal().Mul(decimal.NewFromInt(n)), mv.GetCurrency())
+}
+
+// Cmp - Сравнение mv и other: -1, если mv < other, 0, если равны, 1, если mv > other. Для сумм в разных
+// валютах возвращает ErrCurrencyMismatch
+func (mv *MoneyValue) Cmp(other *MoneyValue) (int, error) {
+	if err := mv.sameCurrency(other); err != nil {
+		return 0, err
+	}
+	return mv.ToDecimal().Cmp(other.ToDecimal()), nil
+}
+
+// IsZero - Проверка на ноль, nil считается нулем
+func (mv *MoneyValue) IsZero() bool {
+	return mv.ToDecimal().IsZero()
+}
+
 // ToCSV - return historic candle in csv format (time in unix): time;open;close;high;low;volume
 func (hc *HistoricCandle) ToCSV() string {
 	return fmt.Sprintf("%v;%.9f;%.9f;%.9f;%.9f;%v", hc.GetTime().AsTime().Unix(), hc.GetOpen().ToFloat(),
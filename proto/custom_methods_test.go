@@ -0,0 +1,130 @@
+package investapi_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/shopspring/decimal"
+
+	pb "github.com/russianinvestments/invest-api-go-sdk/proto"
+)
+
+func q(units int64, nano int32) *pb.Quotation {
+	return &pb.Quotation{Units: units, Nano: nano}
+}
+
+func d(v string) decimal.Decimal {
+	return decimal.RequireFromString(v)
+}
+
+func TestQuotationFromDecimal(t *testing.T) {
+	tests := []struct {
+		in   string
+		want *pb.Quotation
+	}{
+		{in: "0", want: q(0, 0)},
+		{in: "114.25", want: q(114, 250_000_000)},
+		// у отрицательных чисел units и nano одного знака
+		{in: "-1.5", want: q(-1, -500_000_000)},
+		{in: "-0.000000001", want: q(0, -1)},
+		// округление десятого знака переносится в units, nano не достигает 1e9
+		{in: "0.9999999996", want: q(1, 0)},
+		{in: "-0.9999999996", want: q(-1, 0)},
+		{in: "2.9999999994", want: q(2, 999_999_999)},
+		{in: "-2.9999999995", want: q(-3, 0)},
+	}
+	for _, tc := range tests {
+		got := pb.QuotationFromDecimal(d(tc.in))
+		if got.Units != tc.want.Units || got.Nano != tc.want.Nano {
+			t.Fatalf("QuotationFromDecimal(%v) = %v, want %v", tc.in, got, tc.want)
+		}
+		if back := got.ToDecimal(); !back.Equal(d(tc.in).Round(9)) {
+			t.Fatalf("ToDecimal(%v) = %v", got, back)
+		}
+	}
+}
+
+func TestQuotationArithmetic(t *testing.T) {
+	// сложение с переносом nano в units
+	if got := q(0, 600_000_000).Add(q(0, 400_000_000)); got.Units != 1 || got.Nano != 0 {
+		t.Fatalf("add = %v", got)
+	}
+	if got := q(1, 0).Sub(q(1, 500_000_000)); got.Units != 0 || got.Nano != -500_000_000 {
+		t.Fatalf("sub = %v", got)
+	}
+	if got := q(0, 333_333_333).Mul(3); got.Units != 0 || got.Nano != 999_999_999 {
+		t.Fatalf("mul = %v", got)
+	}
+	var zero *pb.Quotation
+	if got := zero.Add(q(1, 1)); got.Cmp(q(1, 1)) != 0 || !zero.IsZero() {
+		t.Fatalf("nil add = %v", got)
+	}
+	if q(-1, -1).Cmp(q(-1, 0)) != -1 {
+		t.Fatal("cmp")
+	}
+}
+
+func TestRoundToIncrement(t *testing.T) {
+	inc := q(0, 50_000_000)
+	tests := []struct {
+		in                   string
+		nearest, floor, ceil string
+	}{
+		{in: "1.15", nearest: "1.15", floor: "1.15", ceil: "1.15"},
+		{in: "1.17", nearest: "1.15", floor: "1.15", ceil: "1.2"},
+		// половина шага округляется от нуля
+		{in: "1.175", nearest: "1.2", floor: "1.15", ceil: "1.2"},
+		{in: "-1.17", nearest: "-1.15", floor: "-1.2", ceil: "-1.15"},
+		{in: "-1.175", nearest: "-1.2", floor: "-1.2", ceil: "-1.15"},
+		{in: "-0.01", nearest: "0", floor: "-0.05", ceil: "0"},
+	}
+	for _, tc := range tests {
+		p := pb.QuotationFromDecimal(d(tc.in))
+		for mode, want := range map[pb.RoundingMode]string{pb.RoundNearest: tc.nearest, pb.RoundFloor: tc.floor, pb.RoundCeil: tc.ceil} {
+			if got := p.RoundToIncrement(inc, mode).ToDecimal(); !got.Equal(d(want)) {
+				t.Fatalf("RoundToIncrement(%v, %v) = %v, want %v", tc.in, mode, got, want)
+			}
+		}
+	}
+	// нулевой шаг не округляет
+	if got := q(1, 234_567_891).RoundToIncrement(nil, pb.RoundFloor); got.Units != 1 || got.Nano != 234_567_891 {
+		t.Fatalf("zero increment = %v", got)
+	}
+	// шаг больше единицы
+	if got := q(12345, 0).RoundToIncrement(q(10, 0), pb.RoundNearest); got.Units != 12350 || got.Nano != 0 {
+		t.Fatalf("increment 10 = %v", got)
+	}
+}
+
+func TestMoneyValue(t *testing.T) {
+	rub := pb.MoneyFromDecimal(d("100.5"), "rub")
+	sum, err := rub.Add(pb.MoneyFromDecimal(d("0.5"), "RUB"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sum.Units != 101 || sum.Nano != 0 || sum.Currency != "rub" {
+		t.Fatalf("add = %v", sum)
+	}
+	if got := rub.Mul(3); !got.ToDecimal().Equal(d("301.5")) || got.Currency != "rub" {
+		t.Fatalf("mul = %v", got)
+	}
+	// nil считается нулем в валюте другого операнда
+	var zero *pb.MoneyValue
+	if got, err := zero.Sub(rub); err != nil || !got.ToDecimal().Equal(d("-100.5")) || got.Currency != "rub" {
+		t.Fatalf("nil sub = %v, %v", got, err)
+	}
+
+	usd := pb.MoneyFromDecimal(d("1"), "usd")
+	if _, err := rub.Add(usd); !errors.Is(err, pb.ErrCurrencyMismatch) {
+		t.Fatalf("add err = %v", err)
+	}
+	if _, err := rub.Sub(usd); !errors.Is(err, pb.ErrCurrencyMismatch) {
+		t.Fatalf("sub err = %v", err)
+	}
+	if _, err := rub.Cmp(usd); !errors.Is(err, pb.ErrCurrencyMismatch) {
+		t.Fatalf("cmp err = %v", err)
+	}
+	if c, err := rub.Cmp(sum); err != nil || c != -1 {
+		t.Fatalf("cmp = %v, %v", c, err)
+	}
+}
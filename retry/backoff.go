@@ -46,6 +46,24 @@ func BackoffExponential(scalar time.Duration) BackoffFunc {
 	}
 }
 
+// BackoffExponentialWithMax produces increasing intervals like BackoffExponential does, but never waits longer
+// than max. The interval is computed without overflow, so it stays at max for any number of attempts.
+func BackoffExponentialWithMax(scalar, max time.Duration) BackoffFunc {
+	return func(ctx context.Context, attempt uint) time.Duration {
+		if attempt == 0 {
+			return 0
+		}
+		d := scalar
+		for i := uint(1); i < attempt && d > 0 && d < max; i++ {
+			d *= 2
+		}
+		if d > max {
+			return max
+		}
+		return d
+	}
+}
+
 // BackoffExponentialWithJitter creates an exponential backoff like
 // BackoffExponential does, but adds jitter.
 //func BackoffExponentialWithJitter(scalar time.Duration, jitterFraction float64) BackoffFunc {
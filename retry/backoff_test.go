@@ -0,0 +1,23 @@
+package retry
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestBackoffExponentialWithMax(t *testing.T) {
+	backoff := BackoffExponentialWithMax(500*time.Millisecond, 30*time.Second)
+	want := []time.Duration{0, 500 * time.Millisecond, time.Second, 2 * time.Second, 4 * time.Second}
+	for attempt, w := range want {
+		if d := backoff(context.Background(), uint(attempt)); d != w {
+			t.Errorf("attempt %v: %v, want %v", attempt, d, w)
+		}
+	}
+	// without the cap the interval overflows time.Duration at about the 36th attempt
+	for attempt := uint(7); attempt < 1000; attempt++ {
+		if d := backoff(context.Background(), attempt); d != 30*time.Second {
+			t.Fatalf("attempt %v: %v, want 30s", attempt, d)
+		}
+	}
+}